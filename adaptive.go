@@ -0,0 +1,153 @@
+package huffman
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// maxAdaptiveCount is the count at which an AdaptiveCoder halves all of its
+// counts, so that the model favors recent history and never overflows.
+const maxAdaptiveCount = 1 << 16
+
+// ErrSnapshotMismatch is returned by AdaptiveCoder.Restore when the snapshot
+// was taken from a coder with a different alphabet size or interval.
+var ErrSnapshotMismatch = errors.New("snapshot does not match this AdaptiveCoder")
+
+// AdaptiveCoder is a Huffman coder whose code adapts to the data: it counts
+// each Symbol as it is written or read, and rebuilds its code from those
+// counts after every interval Symbols.  An encoder and a decoder which start
+// from the same state and see the same Symbols always agree on the code, so
+// no tables need to be transmitted.
+//
+// An AdaptiveCoder is safe for concurrent use, although interleaving Symbols
+// from several goroutines is only meaningful if the other side observes them
+// in the same order.
+//
+// Snapshot and Restore checkpoint the model, which allows seeking within an
+// adaptive stream (by saving a snapshot at each seek point) and rolling back
+// a speculative decode after an error.
+//
+type AdaptiveCoder struct {
+	mu       sync.Mutex
+	e        Encoder
+	d        Decoder
+	counts   []uint32
+	interval int
+	pending  int
+}
+
+// AdaptiveSnapshot is a saved copy of the state of an AdaptiveCoder.  It is
+// immutable, and may be restored any number of times.
+type AdaptiveSnapshot struct {
+	counts   []uint32
+	sizes    []byte
+	interval int
+	pending  int
+}
+
+// NewAdaptiveCoder returns a new AdaptiveCoder for numSymbols Symbols, which
+// rebuilds its code after every interval Symbols.  Every Symbol starts with a
+// count of 1, so the initial code is flat.  Since every Symbol always has a
+// code, numSymbols may not exceed 1<<16.
+func NewAdaptiveCoder(numSymbols int, interval int) *AdaptiveCoder {
+	if numSymbols <= 0 || numSymbols > 1<<maxBitsPerCode {
+		panic(fmt.Errorf("NewAdaptiveCoder: numSymbols %d out of range", numSymbols))
+	}
+	if interval <= 0 {
+		panic(fmt.Errorf("NewAdaptiveCoder: interval %d must be positive", interval))
+	}
+	ac := &AdaptiveCoder{counts: make([]uint32, numSymbols), interval: interval}
+	for index := range ac.counts {
+		ac.counts[index] = 1
+	}
+	ac.rebuild()
+	return ac
+}
+
+// Encoder returns a copy of the current code of this AdaptiveCoder.
+func (ac *AdaptiveCoder) Encoder() *Encoder {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return ac.e.Clone()
+}
+
+// WriteSymbol writes the given Symbol using the current code, then updates
+// the model.
+func (ac *AdaptiveCoder) WriteSymbol(bw *BitWriter, symbol Symbol) error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if err := bw.WriteSymbol(&ac.e, symbol); err != nil {
+		return err
+	}
+	ac.update(symbol)
+	return nil
+}
+
+// ReadSymbol reads one Symbol using the current code, then updates the
+// model.  On error, the model is left unchanged.
+func (ac *AdaptiveCoder) ReadSymbol(br *BitReader) (Symbol, error) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	symbol, err := br.ReadSymbol(&ac.d)
+	if err != nil {
+		return symbol, err
+	}
+	ac.update(symbol)
+	return symbol, nil
+}
+
+// Snapshot returns a copy of the current state of this AdaptiveCoder.
+func (ac *AdaptiveCoder) Snapshot() AdaptiveSnapshot {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	snap := AdaptiveSnapshot{
+		counts:   make([]uint32, len(ac.counts)),
+		sizes:    ac.e.SizeBySymbol(),
+		interval: ac.interval,
+		pending:  ac.pending,
+	}
+	copy(snap.counts, ac.counts)
+	return snap
+}
+
+// Restore returns this AdaptiveCoder to the state saved in the given
+// snapshot.  Returns ErrSnapshotMismatch if the snapshot was taken from an
+// AdaptiveCoder with a different number of Symbols or a different interval.
+func (ac *AdaptiveCoder) Restore(snap AdaptiveSnapshot) error {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if len(snap.counts) != len(ac.counts) || snap.interval != ac.interval {
+		return ErrSnapshotMismatch
+	}
+	if err := ac.e.InitFromSizes(snap.sizes); err != nil {
+		return err
+	}
+	if err := ac.d.InitFromEncoder(ac.e); err != nil {
+		return err
+	}
+	copy(ac.counts, snap.counts)
+	ac.pending = snap.pending
+	return nil
+}
+
+func (ac *AdaptiveCoder) update(symbol Symbol) {
+	ac.counts[symbol]++
+	if ac.counts[symbol] >= maxAdaptiveCount {
+		for index, count := range ac.counts {
+			ac.counts[index] = (count >> 1) | 1
+		}
+	}
+	ac.pending++
+	if ac.pending >= ac.interval {
+		ac.rebuild()
+	}
+}
+
+func (ac *AdaptiveCoder) rebuild() {
+	initLimited(&ac.e, len(ac.counts), ac.counts)
+	if err := ac.d.InitFromEncoder(ac.e); err != nil {
+		panic(err)
+	}
+	ac.pending = 0
+}
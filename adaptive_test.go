@@ -0,0 +1,104 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestAdaptiveCoder(t *testing.T) {
+	rng := rand.New(rand.NewSource(146))
+	symbols := make([]Symbol, 2000)
+	for index := range symbols {
+		symbols[index] = Symbol(rng.ExpFloat64()*4) % 32
+	}
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	enc := NewAdaptiveCoder(32, 64)
+	for _, symbol := range symbols {
+		if err := enc.WriteSymbol(bw, symbol); err != nil {
+			t.Fatalf("WriteSymbol: unexpected error: %v", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		t.Fatalf("Flush: unexpected error: %v", err)
+	}
+	if flat := uint64(5 * len(symbols)); bw.BitsWritten() >= flat {
+		t.Errorf("adaptive coding did not beat a flat code: %d >= %d bits", bw.BitsWritten(), flat)
+	}
+
+	// Decode, checkpointing halfway through.
+	br := NewBitReader(bytes.NewReader(buf.Bytes()))
+	dec := NewAdaptiveCoder(32, 64)
+	var snap AdaptiveSnapshot
+	var snapBits uint64
+	const half = 1000
+	for index, expect := range symbols {
+		if index == half {
+			snap = dec.Snapshot()
+			snapBits = br.BitsRead()
+		}
+		actual, err := dec.ReadSymbol(br)
+		if err != nil || actual != expect {
+			t.Fatalf("symbol %d: expected %d, got %d (%v)", index, expect, actual, err)
+		}
+	}
+
+	// Seek back to the checkpoint and decode the rest again.
+	if err := dec.Restore(snap); err != nil {
+		t.Fatalf("Restore: unexpected error: %v", err)
+	}
+	br = NewBitReader(bytes.NewReader(buf.Bytes()[snapBits/8:]))
+	if _, err := br.ReadBits(byte(snapBits % 8)); err != nil {
+		t.Fatal(err)
+	}
+	for index := half; index < len(symbols); index++ {
+		actual, err := dec.ReadSymbol(br)
+		if err != nil || actual != symbols[index] {
+			t.Fatalf("after Restore, symbol %d: expected %d, got %d (%v)", index, symbols[index], actual, err)
+		}
+	}
+}
+
+func TestAdaptiveCoder_RestoreMismatch(t *testing.T) {
+	snap := NewAdaptiveCoder(8, 16).Snapshot()
+	if err := NewAdaptiveCoder(9, 16).Restore(snap); !errors.Is(err, ErrSnapshotMismatch) {
+		t.Errorf("numSymbols: expected ErrSnapshotMismatch, got %v", err)
+	}
+	if err := NewAdaptiveCoder(8, 32).Restore(snap); !errors.Is(err, ErrSnapshotMismatch) {
+		t.Errorf("interval: expected ErrSnapshotMismatch, got %v", err)
+	}
+}
+
+func TestAdaptiveCoder_Rollback(t *testing.T) {
+	ac := NewAdaptiveCoder(4, 1)
+	before := ac.Encoder().SizeBySymbol()
+	snap := ac.Snapshot()
+	for i := 0; i < 10; i++ {
+		ac.update(3)
+	}
+	if bytes.Equal(before, ac.Encoder().SizeBySymbol()) {
+		t.Fatalf("code did not adapt")
+	}
+	if err := ac.Restore(snap); err != nil {
+		t.Fatalf("Restore: unexpected error: %v", err)
+	}
+	if after := ac.Encoder().SizeBySymbol(); !bytes.Equal(before, after) {
+		t.Errorf("after Restore: expected sizes %v, got %v", before, after)
+	}
+}
+
+func TestAdaptiveCoder_TooManySymbols(t *testing.T) {
+	if ac := NewAdaptiveCoder(1<<16, 1000); ac.Encoder().MaxSize() != 16 {
+		t.Errorf("1<<16 symbols: expected a flat 16-bit code, got MaxSize %d", ac.Encoder().MaxSize())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewAdaptiveCoder: expected panic above 1<<16 symbols")
+		}
+	}()
+	NewAdaptiveCoder(100000, 1000)
+}
@@ -0,0 +1,147 @@
+package huffman
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// AnnotatingWriter wraps a BitWriter and writes a human-readable line to a
+// trace for everything written through it, for producing documented example
+// bitstreams such as those in a format specification.  Each line gives the
+// position of the first bit written, as a byte offset and a bit within that
+// byte, followed by what was written, e.g.:
+//
+//     offset 0x1F bit 3: symbol 42 code 10110
+//     offset 0x20 bit 0: symbol 7 code 110 extra 5 (101)
+//     offset 0x20 bit 6: symbol 300 escape 1110 raw 100101100
+//     offset 0x21 bit 7: bits 1011
+//     offset 0x22 bit 3: padding 00000
+//     # end of block
+//
+// Bits are listed in the order they appear in the stream, which for a Code
+// is the reverse of Code.String.  Symbols are named by the Encoder's
+// Options.SymbolName, if set.
+//
+type AnnotatingWriter struct {
+	bw    *BitWriter
+	trace io.Writer
+}
+
+// NewAnnotatingWriter returns a new AnnotatingWriter which writes to bw and
+// annotates to trace.
+func NewAnnotatingWriter(bw *BitWriter, trace io.Writer) *AnnotatingWriter {
+	return &AnnotatingWriter{bw: bw, trace: trace}
+}
+
+// BitWriter returns the underlying BitWriter.  Bits written directly to it
+// are not annotated.
+func (aw *AnnotatingWriter) BitWriter() *BitWriter {
+	return aw.bw
+}
+
+// annotate writes one line to the trace for the bits written from position
+// start onward.
+func (aw *AnnotatingWriter) annotate(start uint64, format string, args ...interface{}) error {
+	_, err := fmt.Fprintf(aw.trace, "offset 0x%X bit %d: "+format+"\n", append([]interface{}{start / 8, start % 8}, args...)...)
+	return err
+}
+
+// Comment writes a comment line to the trace.
+func (aw *AnnotatingWriter) Comment(text string) error {
+	_, err := fmt.Fprintf(aw.trace, "# %s\n", text)
+	return err
+}
+
+// WriteBits is like BitWriter.WriteBits, but also annotates the bits.
+func (aw *AnnotatingWriter) WriteBits(size byte, bits uint32) error {
+	start := aw.bw.BitsWritten()
+	if err := aw.bw.WriteBits(size, bits); err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	return aw.annotate(start, "bits %s", aw.bw.bitString(start, aw.bw.BitsWritten()))
+}
+
+// WriteSymbol is like BitWriter.WriteSymbol, but also annotates the Symbol
+// and its code.
+func (aw *AnnotatingWriter) WriteSymbol(e *Encoder, symbol Symbol) error {
+	start := aw.bw.BitsWritten()
+	if err := aw.bw.WriteSymbol(e, symbol); err != nil {
+		return err
+	}
+	return aw.annotate(start, "%s", aw.describeSymbol(e, symbol, start, aw.bw.BitsWritten()))
+}
+
+// WriteSymbolWithExtra is like BitWriter.WriteSymbolWithExtra, but also
+// annotates the Symbol, its code, and its extra bits.
+func (aw *AnnotatingWriter) WriteSymbolWithExtra(e *Encoder, symbol Symbol, extra uint32) error {
+	start := aw.bw.BitsWritten()
+	if err := aw.bw.WriteSymbolWithExtra(e, symbol, extra); err != nil {
+		return err
+	}
+	end := aw.bw.BitsWritten()
+	k := uint64(e.opts.extraBits(symbol))
+	str := aw.describeSymbol(e, symbol, start, end-k)
+	if k != 0 {
+		str = fmt.Sprintf("%s extra %d (%s)", str, extra, aw.bw.bitString(end-k, end))
+	}
+	return aw.annotate(start, "%s", str)
+}
+
+// describeSymbol describes the code for symbol written from start to end.
+func (aw *AnnotatingWriter) describeSymbol(e *Encoder, symbol Symbol, start, end uint64) string {
+	name := e.opts.formatSymbol(symbol)
+	if e.escapes(symbol) {
+		mid := start + uint64(e.codes[e.opts.FallbackSymbol].Size)
+		return fmt.Sprintf("symbol %s escape %s raw %s", name, aw.bw.bitString(start, mid), aw.bw.bitString(mid, end))
+	}
+	return fmt.Sprintf("symbol %s code %s", name, aw.bw.bitString(start, end))
+}
+
+// Align is like BitWriter.Align, but also annotates the padding, if any.
+func (aw *AnnotatingWriter) Align() error {
+	start := aw.bw.BitsWritten()
+	if err := aw.bw.Align(); err != nil {
+		return err
+	}
+	return aw.annotatePadding(start)
+}
+
+// Flush is like BitWriter.Flush, but also annotates the padding, if any.
+func (aw *AnnotatingWriter) Flush() error {
+	start := aw.bw.BitsWritten()
+	if err := aw.bw.Flush(); err != nil {
+		return err
+	}
+	return aw.annotatePadding(start)
+}
+
+func (aw *AnnotatingWriter) annotatePadding(start uint64) error {
+	end := aw.bw.BitsWritten()
+	if end == start {
+		return nil
+	}
+	return aw.annotate(start, "padding %s", strings.Repeat("0", int(end-start)))
+}
+
+// bitString returns the bits written from position start to end, in stream
+// order, as a string of '0' and '1'.  The bits must not yet be flushed.
+func (bw *BitWriter) bitString(start, end uint64) string {
+	base := bw.count - uint64(bw.n) - 8*uint64(len(bw.buf))
+	var sb strings.Builder
+	sb.Grow(int(end - start))
+	for pos := start; pos < end; pos++ {
+		var bit byte
+		rel := pos - base
+		if rel < 8*uint64(len(bw.buf)) {
+			bit = (bw.buf[rel/8] >> (rel % 8)) & 1
+		} else {
+			bit = byte(bw.acc>>(rel-8*uint64(len(bw.buf)))) & 1
+		}
+		sb.WriteByte('0' + bit)
+	}
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAnnotatingWriter(t *testing.T) {
+	names := []string{"A", "B", "C", "D", "E"}
+	e := NewEncoderFromSizesWithOptions([]byte{1, 2, 3, 3, 0}, Options{
+		SymbolName:     func(symbol Symbol) string { return names[symbol] },
+		ExtraBits:      []byte{0, 0, 3},
+		MissingCode:    MissingCodeEscape,
+		FallbackSymbol: 3,
+	})
+
+	var out, trace bytes.Buffer
+	aw := NewAnnotatingWriter(NewBitWriter(&out), &trace)
+	check := func(what string, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", what, err)
+		}
+	}
+	check("WriteSymbol", aw.WriteSymbol(e, 1))
+	check("WriteSymbolWithExtra", aw.WriteSymbolWithExtra(e, 2, 5))
+	check("WriteSymbol", aw.WriteSymbol(e, 4))
+	check("Comment", aw.Comment("raw bits follow"))
+	check("WriteBits", aw.WriteBits(4, 0xd))
+	check("WriteBits", aw.WriteBits(0, 0))
+	check("Align", aw.Align())
+	check("Align", aw.Align())
+	check("WriteSymbol", aw.WriteSymbol(e, 0))
+	check("Flush", aw.Flush())
+
+	expect := `offset 0x0 bit 0: symbol 1 (B) code 10
+offset 0x0 bit 2: symbol 2 (C) code 110 extra 5 (101)
+offset 0x1 bit 0: symbol 4 (E) escape 111 raw 001
+# raw bits follow
+offset 0x1 bit 6: bits 1011
+offset 0x2 bit 2: padding 000000
+offset 0x3 bit 0: symbol 0 (A) code 0
+offset 0x3 bit 1: padding 0000000
+`
+	if actual := trace.String(); expect != actual {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+	if expect := []byte{0xad, 0x67, 0x03, 0x00}; !bytes.Equal(out.Bytes(), expect) {
+		t.Errorf("expected output %x, got %x", expect, out.Bytes())
+	}
+}
@@ -0,0 +1,38 @@
+package huffman
+
+// Encoder.Init builds a code in two phases: first it assigns each Symbol a
+// code length, and then it numbers the codes of each length canonically.
+// AssignLengths and AssignCanonicalBits expose the two phases, so that the
+// lengths can be post-processed in between, for instance to apply custom
+// length limiting, to merge the lengths of several codes, or to keep them
+// stable from one block to the next.  NewEncoderFromSizes and NewDecoder
+// accept the post-processed lengths directly.
+
+// AssignLengths returns the bit length of the optimal code for each Symbol,
+// given the frequency of each, exactly as Encoder.Init would assign them.
+// Symbols with a frequency of 0 get a bit length of 0, meaning no code.
+// Unlike BuildSizes, the lengths are not limited, and may exceed the 16 bits
+// accepted by AssignCanonicalBits if the frequencies are very skewed.
+func AssignLengths(frequencies []uint32) []byte {
+	if len(frequencies) == 0 {
+		return []byte{}
+	}
+	var e Encoder
+	e.init(len(frequencies), frequencies, Options{})
+	return e.SizeBySymbol()
+}
+
+// AssignCanonicalBits returns the canonical Code for each Symbol, given the
+// bit length of each, per RFC 1951 Section 3.2.2.  Symbols with a bit length
+// of 0 get the zero Code.  Returns an error if the lengths do not describe a
+// prefix code, or if any is longer than 16 bits.
+func AssignCanonicalBits(sizes []byte) ([]Code, error) {
+	codes := make([]Code, len(sizes))
+	for symbol, size := range sizes {
+		codes[symbol].Size = size
+	}
+	if err := secondPass(codes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,55 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAssignLengths(t *testing.T) {
+	type testRow struct {
+		freqs []uint32
+		sizes []byte
+	}
+
+	testData := [...]testRow{
+		{freqs: []uint32{}, sizes: []byte{}},
+		{freqs: []uint32{0, 5}, sizes: []byte{0, 1}},
+		{freqs: []uint32{9, 5, 3, 3}, sizes: []byte{1, 2, 3, 3}},
+		{freqs: []uint32{1, 0, 1, 2, 4}, sizes: []byte{3, 0, 3, 2, 1}},
+	}
+	for _, row := range testData {
+		actual := AssignLengths(row.freqs)
+		if !bytes.Equal(row.sizes, actual) {
+			t.Errorf("AssignLengths(%v): expected %v, got %v", row.freqs, row.sizes, actual)
+		}
+		if len(row.freqs) != 0 {
+			if expect := NewEncoder(len(row.freqs), row.freqs).SizeBySymbol(); !bytes.Equal(expect, actual) {
+				t.Errorf("AssignLengths(%v): expected the same as Encoder.Init, %v, got %v", row.freqs, expect, actual)
+			}
+		}
+	}
+}
+
+func TestAssignCanonicalBits(t *testing.T) {
+	// Post-process the lengths by lengthening the shortest code, as a
+	// custom rule might, then number them.
+	sizes := AssignLengths([]uint32{9, 5, 3, 3})
+	sizes[0]++
+
+	codes, err := AssignCanonicalBits(sizes)
+	if err != nil {
+		t.Fatalf("AssignCanonicalBits(%v): unexpected error: %v", sizes, err)
+	}
+	e := NewEncoderFromSizes(sizes)
+	for symbol, hc := range codes {
+		if expect := e.Encode(Symbol(symbol)); hc != expect {
+			t.Errorf("symbol %d: expected %v, got %v", symbol, expect, hc)
+		}
+	}
+
+	for _, bad := range [][]byte{{1, 1, 1}, {17, 1}} {
+		if _, err := AssignCanonicalBits(bad); err == nil {
+			t.Errorf("AssignCanonicalBits(%v): expected an error", bad)
+		}
+	}
+}
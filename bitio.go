@@ -0,0 +1,317 @@
+package huffman
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNoCode is returned when attempting to write a Symbol which has no code
+// assigned to it, i.e. a Symbol which had a frequency of 0.
+var ErrNoCode = errors.New("symbol has no assigned Huffman code")
+
+// BitWriter writes a sequence of bits to an io.Writer.  Bits are packed into
+// bytes starting from the least significant bit, which matches both the bit
+// order of Code and the packing used by DEFLATE.
+type BitWriter struct {
+	w       io.Writer
+	buf     []byte
+	acc     uint64
+	n       byte
+	count   uint64
+	err     error
+	packing Packing
+}
+
+// NewBitWriter is a convenience function that allocates a new BitWriter and
+// calls Init on it.
+func NewBitWriter(w io.Writer) *BitWriter {
+	bw := new(BitWriter)
+	bw.Init(w)
+	return bw
+}
+
+// Init initializes this BitWriter to write to the given io.Writer.
+func (bw *BitWriter) Init(w io.Writer) {
+	*bw = BitWriter{w: w, buf: bw.buf[:0]}
+}
+
+// WriteBits writes the low size bits of bits, least significant bit first.
+// At most 32 bits may be written in a single call.
+func (bw *BitWriter) WriteBits(size byte, bits uint32) error {
+	if bw.err != nil {
+		return bw.err
+	}
+	if size > 32 {
+		panic(fmt.Errorf("WriteBits: size %d > 32", size))
+	}
+	if size == 0 {
+		return nil
+	}
+	mask := (uint64(1) << size) - 1
+	bw.acc |= (uint64(bits) & mask) << bw.n
+	bw.n += size
+	bw.count += uint64(size)
+	for bw.n >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.n -= 8
+	}
+	return nil
+}
+
+// WriteCode writes the bits of the given Code.
+func (bw *BitWriter) WriteCode(hc Code) error {
+	return bw.WriteBits(hc.Size, hc.Bits)
+}
+
+// WriteSymbol encodes the given Symbol using the given Encoder and writes the
+// resulting Code.  Returns ErrNoCode if the Symbol has no assigned code,
+// unless the Encoder's Options.MissingCode says otherwise.
+func (bw *BitWriter) WriteSymbol(e *Encoder, symbol Symbol) error {
+	if debugChecks {
+		defer debugStreamCheck("BitWriter.WriteSymbol", bw.count, &bw.count, func() error { return e.checkInvariants(false) })
+	}
+	if e.escapes(symbol) {
+		return bw.writeEscaped(e, symbol)
+	}
+	hc := e.Encode(symbol)
+	if hc.Size == 0 {
+		return fmt.Errorf("symbol %s: %w", e.opts.formatSymbol(symbol), ErrNoCode)
+	}
+	return bw.WriteCode(hc)
+}
+
+// WriteSymbolWithExtra is like WriteSymbol, but follows the code with the
+// low k bits of extra, where k is the number of extra bits registered for the
+// Symbol in the Encoder's Options.ExtraBits.  Returns an error if extra does
+// not fit in k bits.
+func (bw *BitWriter) WriteSymbolWithExtra(e *Encoder, symbol Symbol, extra uint32) error {
+	k := e.opts.extraBits(symbol)
+	if k < 32 && (extra>>k) != 0 {
+		return fmt.Errorf("symbol %s: extra value %d does not fit in %d bits", e.opts.formatSymbol(symbol), extra, k)
+	}
+	if err := bw.WriteSymbol(e, symbol); err != nil {
+		return err
+	}
+	return bw.WriteBits(k, extra)
+}
+
+// Align pads the output with zero bits until the next byte boundary.
+func (bw *BitWriter) Align() error {
+	if bw.n == 0 {
+		return bw.err
+	}
+	return bw.WriteBits(8-bw.n, 0)
+}
+
+// WriteBytes aligns the output to a byte boundary, then writes the given
+// bytes verbatim.
+func (bw *BitWriter) WriteBytes(p []byte) error {
+	if err := bw.Align(); err != nil {
+		return err
+	}
+	bw.buf = append(bw.buf, p...)
+	bw.count += 8 * uint64(len(p))
+	return nil
+}
+
+// Flush aligns the output to a byte boundary, or to a word boundary if a
+// Packing with larger words was selected, then writes all buffered bytes to
+// the underlying io.Writer.
+func (bw *BitWriter) Flush() error {
+	if err := bw.Align(); err != nil {
+		return err
+	}
+	for len(bw.buf)%bw.packing.wordSize() != 0 {
+		bw.buf = append(bw.buf, 0)
+		bw.count += 8
+	}
+	bw.packing.pack(bw.buf)
+	if len(bw.buf) != 0 {
+		_, err := bw.w.Write(bw.buf)
+		bw.buf = bw.buf[:0]
+		if err != nil {
+			bw.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// BitsWritten returns the total number of bits written so far, including any
+// padding bits added by Align.
+func (bw *BitWriter) BitsWritten() uint64 {
+	return bw.count
+}
+
+// BitReader reads a sequence of bits from an io.Reader.  See BitWriter for
+// details of the bit order.
+//
+// BitReader never reads more bytes from the underlying reader than it needs
+// to satisfy the current request, so it is safe to switch back to byte-level
+// reads after calling Align.
+//
+type BitReader struct {
+	r     io.ByteReader
+	acc   uint64
+	n     byte
+	count uint64
+}
+
+// NewBitReader is a convenience function that allocates a new BitReader and
+// calls Init on it.
+func NewBitReader(r io.Reader) *BitReader {
+	br := new(BitReader)
+	br.Init(r)
+	return br
+}
+
+// Init initializes this BitReader to read from the given io.Reader.  If r
+// does not implement io.ByteReader, it is wrapped in a bufio.Reader.
+func (br *BitReader) Init(r io.Reader) {
+	byteReader, ok := r.(io.ByteReader)
+	if !ok {
+		byteReader = bufio.NewReader(r)
+	}
+	*br = BitReader{r: byteReader}
+}
+
+func (br *BitReader) fill(size byte) error {
+	for br.n < size {
+		b, err := br.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && br.n != 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		br.acc |= uint64(b) << br.n
+		br.n += 8
+	}
+	return nil
+}
+
+// ReadBits reads size bits and returns them, with the first bit read in the
+// least significant position.  At most 32 bits may be read in a single call.
+func (br *BitReader) ReadBits(size byte) (uint32, error) {
+	if size > 32 {
+		panic(fmt.Errorf("ReadBits: size %d > 32", size))
+	}
+	if err := br.fill(size); err != nil {
+		return 0, err
+	}
+	mask := (uint64(1) << size) - 1
+	bits := uint32(br.acc & mask)
+	br.acc >>= size
+	br.n -= size
+	br.count += uint64(size)
+	return bits, nil
+}
+
+// ReadSymbol reads the bits of one code and decodes them using the given
+// Decoder.
+func (br *BitReader) ReadSymbol(d *Decoder) (Symbol, error) {
+	if debugChecks {
+		defer debugStreamCheck("BitReader.ReadSymbol", br.count, &br.count, d.checkInvariants)
+	}
+	var symbol Symbol
+	var err error
+	if d.direct != nil {
+		symbol, err = br.readSymbolDirect(d)
+	} else {
+		symbol, err = br.readSymbolTable(d)
+	}
+	if err == nil && d.opts.MissingCode == MissingCodeEscape && symbol == d.opts.FallbackSymbol {
+		return br.readEscaped(d)
+	}
+	return symbol, err
+}
+
+// readSymbolTable is ReadSymbol for a Decoder without a direct table.
+func (br *BitReader) readSymbolTable(d *Decoder) (Symbol, error) {
+	var hc Code
+	for {
+		symbol, minSize, _ := d.Decode(hc)
+		if symbol >= 0 {
+			return symbol, nil
+		}
+		if minSize <= hc.Size {
+			return InvalidSymbol, fmt.Errorf("invalid Huffman code %s", hc)
+		}
+		extra := minSize - hc.Size
+		bits, err := br.ReadBits(extra)
+		if err != nil {
+			if err == io.EOF && hc.Size != 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return InvalidSymbol, err
+		}
+		hc.Bits |= bits << hc.Size
+		hc.Size = minSize
+	}
+}
+
+// ReadSymbolWithExtra is like ReadSymbol, but also reads the extra bits which
+// follow the code, as registered for the Symbol in the Decoder's
+// Options.ExtraBits.  The extra value is 0 for Symbols without extra bits.
+func (br *BitReader) ReadSymbolWithExtra(d *Decoder) (Symbol, uint32, error) {
+	symbol, err := br.ReadSymbol(d)
+	if err != nil {
+		return symbol, 0, err
+	}
+	extra, err := br.ReadBits(d.opts.extraBits(symbol))
+	if err != nil {
+		return InvalidSymbol, 0, noEOF(err)
+	}
+	return symbol, extra, nil
+}
+
+// readSymbolDirect is ReadSymbol for a DirectTableDecoder.  Bits above br.n
+// in br.acc are always zero, so the direct table can be consulted before all
+// MaxSize() bits are available: if the entry's code fits within the bits we
+// have, those bits alone determine it.  Otherwise, one more byte is read and
+// the lookup is retried, so no byte is read unless it is needed.
+func (br *BitReader) readSymbolDirect(d *Decoder) (Symbol, error) {
+	mask := uint64(len(d.direct) - 1)
+	for {
+		de := d.direct[br.acc&mask]
+		if size := de.size(); size != 0 && size <= br.n {
+			br.acc >>= size
+			br.n -= size
+			br.count += uint64(size)
+			return de.symbol(), nil
+		}
+		if br.n >= d.maxSize {
+			hc := MakeCode(d.maxSize, uint32(br.acc&mask))
+			return InvalidSymbol, fmt.Errorf("invalid Huffman code %s", hc)
+		}
+		if err := br.fill(br.n + 1); err != nil {
+			return InvalidSymbol, err
+		}
+	}
+}
+
+// Align discards bits until the next byte boundary.
+func (br *BitReader) Align() {
+	extra := br.n % 8
+	br.acc >>= extra
+	br.n -= extra
+	br.count += uint64(extra)
+}
+
+// ReadByte aligns the input to a byte boundary, then reads one byte.
+func (br *BitReader) ReadByte() (byte, error) {
+	br.Align()
+	bits, err := br.ReadBits(8)
+	return byte(bits), err
+}
+
+// BitsRead returns the total number of bits consumed so far, including any
+// bits discarded by Align.
+func (br *BitReader) BitsRead() uint64 {
+	return br.count
+}
+
+var _ io.ByteReader = (*BitReader)(nil)
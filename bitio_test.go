@@ -0,0 +1,226 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBitWriter(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	_ = bw.WriteBits(3, 0x5)
+	_ = bw.WriteCode(MakeCode(4, 0x9))
+	_ = bw.WriteBits(12, 0xabc)
+	if err := bw.Flush(); err != nil {
+		t.Fatalf("Flush: unexpected error: %v", err)
+	}
+
+	expectBytes := []byte{0x4d, 0x5e, 0x05}
+	actualBytes := buf.Bytes()
+	if !bytes.Equal(expectBytes, actualBytes) {
+		t.Errorf("wrong bytes:\n\texpect: %#v\n\tactual: %#v", expectBytes, actualBytes)
+	}
+	if expect, actual := uint64(24), bw.BitsWritten(); expect != actual {
+		t.Errorf("wrong BitsWritten: expect %d, actual %d", expect, actual)
+	}
+}
+
+func TestBitReader(t *testing.T) {
+	br := NewBitReader(bytes.NewReader([]byte{0x4d, 0x5e, 0x05}))
+
+	type testRow struct {
+		size byte
+		bits uint32
+	}
+
+	testData := [...]testRow{
+		{size: 3, bits: 0x5},
+		{size: 4, bits: 0x9},
+		{size: 12, bits: 0xabc},
+	}
+	for _, row := range testData {
+		bits, err := br.ReadBits(row.size)
+		if err != nil {
+			t.Fatalf("ReadBits(%d): unexpected error: %v", row.size, err)
+		}
+		if bits != row.bits {
+			t.Errorf("ReadBits(%d): expected %#x, got %#x", row.size, row.bits, bits)
+		}
+	}
+
+	br.Align()
+	if _, err := br.ReadByte(); err != io.EOF {
+		t.Errorf("ReadByte: expected io.EOF, got %v", err)
+	}
+}
+
+func TestBitReader_ReadSymbol(t *testing.T) {
+	e := makeTestEncoder()
+	d := makeTestDecoder()
+
+	input := []Symbol{5, 0, 1, 2, 3, 4, 5, 5, 2}
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	for _, symbol := range input {
+		if err := bw.WriteSymbol(&e, symbol); err != nil {
+			t.Fatalf("WriteSymbol(%d): unexpected error: %v", symbol, err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		t.Fatalf("Flush: unexpected error: %v", err)
+	}
+
+	br := NewBitReader(&buf)
+	for index, expect := range input {
+		actual, err := br.ReadSymbol(&d)
+		if err != nil {
+			t.Fatalf("ReadSymbol #%d: unexpected error: %v", index, err)
+		}
+		if expect != actual {
+			t.Errorf("ReadSymbol #%d: expected %d, got %d", index, expect, actual)
+		}
+	}
+}
+
+func TestBitWriter_WriteSymbol_NoCode(t *testing.T) {
+	var e Encoder
+	e.Init(4, []uint32{1, 0, 1, 1})
+
+	bw := NewBitWriter(io.Discard)
+	err := bw.WriteSymbol(&e, 1)
+	if !errors.Is(err, ErrNoCode) {
+		t.Errorf("expected ErrNoCode, got %v", err)
+	}
+}
+
+func TestBitReader_ReadSymbol_Direct(t *testing.T) {
+	text := []byte("she sells sea shells by the sea shore, and the shells she sells are sea shells for sure")
+	e := makeByteEncoder(text, true)
+	direct := e.Decoder()
+	if direct.Kind() != DirectTableDecoder {
+		t.Fatalf("expected %v, got %v", DirectTableDecoder, direct.Kind())
+	}
+	prefixMap := *direct
+	prefixMap.direct = nil
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	if err := e.EncodeBytes(text, bw); err != nil {
+		t.Fatalf("EncodeBytes: unexpected error: %v", err)
+	}
+	_ = bw.WriteBits(5, 0x1f)
+	_ = bw.Flush()
+	buf.WriteString("tail")
+
+	for _, d := range []*Decoder{direct, &prefixMap} {
+		t.Run(d.Kind().String(), func(t *testing.T) {
+			r := bytes.NewReader(buf.Bytes())
+			br := NewBitReader(r)
+			for index, expect := range text {
+				actual, err := br.ReadSymbol(d)
+				if err != nil {
+					t.Fatalf("[%d]: unexpected error: %v", index, err)
+				}
+				if Symbol(expect) != actual {
+					t.Fatalf("[%d]: expected %d, got %d", index, expect, actual)
+				}
+			}
+			if bits, _ := br.ReadBits(5); bits != 0x1f {
+				t.Errorf("expected trailing bits 0x1f, got %#x", bits)
+			}
+
+			// The BitReader must not have read past the padded payload.
+			if expect, actual := 4, r.Len(); expect != actual {
+				t.Errorf("expected %d unread bytes, got %d", expect, actual)
+			}
+		})
+	}
+}
+
+func BenchmarkBitReader_ReadSymbol(b *testing.B) {
+	text := bytes.Repeat([]byte("she sells sea shells by the sea shore. "), 1000)
+	e := makeByteEncoder(text, true)
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	_ = e.EncodeBytes(text, bw)
+	_ = bw.Flush()
+
+	direct := e.Decoder()
+	prefixMap := *direct
+	prefixMap.direct = nil
+
+	for _, d := range []*Decoder{direct, &prefixMap} {
+		b.Run(d.Kind().String(), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				br := NewBitReader(bytes.NewReader(buf.Bytes()))
+				for range text {
+					if _, err := br.ReadSymbol(d); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBitWriter_WriteSymbolWithExtra(t *testing.T) {
+	// A toy length alphabet: Symbols 0..3 are literal lengths, Symbol 4
+	// carries 2 extra bits, and Symbol 5 carries 13.
+	opts := Options{ExtraBits: []byte{0, 0, 0, 0, 2, 13}}
+	var e Encoder
+	if err := e.InitWithOptions(6, []uint32{5, 4, 3, 2, 2, 1}, opts); err != nil {
+		t.Fatalf("Encoder.InitWithOptions: unexpected error: %v", err)
+	}
+	var d Decoder
+	if err := d.InitWithOptions(e.SizeBySymbol(), opts); err != nil {
+		t.Fatalf("Decoder.InitWithOptions: unexpected error: %v", err)
+	}
+
+	type pair struct {
+		symbol Symbol
+		extra  uint32
+	}
+	input := []pair{{0, 0}, {4, 3}, {5, 8191}, {1, 0}, {4, 0}, {5, 1234}, {3, 0}}
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	for _, p := range input {
+		if err := bw.WriteSymbolWithExtra(&e, p.symbol, p.extra); err != nil {
+			t.Fatalf("WriteSymbolWithExtra(%d, %d): unexpected error: %v", p.symbol, p.extra, err)
+		}
+	}
+	_ = bw.Flush()
+
+	br := NewBitReader(&buf)
+	for index, p := range input {
+		symbol, extra, err := br.ReadSymbolWithExtra(&d)
+		if err != nil {
+			t.Fatalf("ReadSymbolWithExtra: index %d: unexpected error: %v", index, err)
+		}
+		if symbol != p.symbol || extra != p.extra {
+			t.Errorf("ReadSymbolWithExtra: index %d: expected (%d, %d), got (%d, %d)", index, p.symbol, p.extra, symbol, extra)
+		}
+	}
+
+	if err := NewBitWriter(io.Discard).WriteSymbolWithExtra(&e, 4, 4); err == nil {
+		t.Errorf("WriteSymbolWithExtra: expected error for oversized extra value, got nil")
+	}
+	if err := NewBitWriter(io.Discard).WriteSymbolWithExtra(&e, 0, 1); err == nil {
+		t.Errorf("WriteSymbolWithExtra: expected error for extra value on Symbol without extra bits, got nil")
+	}
+	if err := e.InitWithOptions(2, []uint32{1, 1}, Options{ExtraBits: []byte{33}}); err == nil {
+		t.Errorf("InitWithOptions: expected error for more than 32 extra bits, got nil")
+	}
+
+	buf.Reset()
+	bw = NewBitWriter(&buf)
+	_ = bw.WriteSymbol(&e, 5)
+	_ = bw.Flush()
+	if _, _, err := NewBitReader(&buf).ReadSymbolWithExtra(&d); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadSymbolWithExtra: expected io.ErrUnexpectedEOF for missing extra bits, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package huffman
+
+import (
+	"fmt"
+	"math"
+	mathbits "math/bits"
+	"sync"
+	"time"
+)
+
+// BuildPath reports how BuildWithBudget built its code.
+type BuildPath byte
+
+const (
+	// BuildExact means that the code was built by the usual algorithm,
+	// exactly as BuildSizes would build it.
+	BuildExact BuildPath = iota
+
+	// BuildApprox means that the exact code was expected to take too long,
+	// so an approximate code was built instead.  See BuildWithBudget.
+	BuildApprox
+)
+
+var buildPathNames = [...]string{
+	"BuildExact",
+	"BuildApprox",
+}
+
+// String returns the name of this BuildPath.
+func (path BuildPath) String() string {
+	if uint(path) < uint(len(buildPathNames)) {
+		return buildPathNames[path]
+	}
+	return fmt.Sprintf("BuildPath(%d)", uint(path))
+}
+
+// calibrationSymbols is the size of the alphabet timed to calibrate the cost
+// model used by BuildWithBudget.
+const calibrationSymbols = 4096
+
+var (
+	calibrateOnce sync.Once
+	nsPerStep     float64
+)
+
+// calibrate times the exact construction of one code, keeping the faster of
+// two runs, and from it derives the cost of each of the n*log2(n) steps it
+// takes for an alphabet of n Symbols.
+func calibrate() {
+	frequencies := make([]uint32, calibrationSymbols)
+	x := uint32(0x9e3779b9)
+	for index := range frequencies {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		frequencies[index] = 1 + x&0xffff>>uint(index&15)
+	}
+
+	var e Encoder
+	var elapsed time.Duration
+	for run := 0; run < 2; run++ {
+		start := time.Now()
+		initLimited(&e, len(frequencies), frequencies)
+		if d := time.Since(start); run == 0 || d < elapsed {
+			elapsed = d
+		}
+	}
+
+	nsPerStep = float64(elapsed.Nanoseconds()) / buildSteps(calibrationSymbols)
+}
+
+// buildSteps returns the number of steps in the exact construction of a code
+// over n Symbols.
+func buildSteps(n int) float64 {
+	if n < 2 {
+		return 1
+	}
+	return float64(n) * math.Log2(float64(n))
+}
+
+// EstimateBuildTime returns the time that the exact construction of a code
+// over numSymbols Symbols is expected to take on this machine.  The first call
+// to EstimateBuildTime or BuildWithBudget builds a code of 4096 Symbols to
+// calibrate the estimate.
+func EstimateBuildTime(numSymbols int) time.Duration {
+	calibrateOnce.Do(calibrate)
+	return time.Duration(nsPerStep * buildSteps(numSymbols))
+}
+
+// BuildWithBudget returns an Encoder for the given frequencies, with no code
+// longer than 16 bits, for encoders which must not stall on very large
+// alphabets.
+//
+// If EstimateBuildTime says that the exact code can be built within budget,
+// it is built just as BuildSizes would build it, and BuildExact is returned.
+// Otherwise, each Symbol is given a code length of about -log2 of its
+// probability, rounding down its frequency to a power of 2, which takes time
+// linear in the size of the alphabet, and BuildApprox is returned.  Unless
+// the 16-bit limit comes into play, the approximate code costs less than 2
+// bits per Symbol more than the exact one.  It can be incomplete.
+//
+// Returns an error if there are more than 65536 frequencies.
+//
+func BuildWithBudget(frequencies []uint32, budget time.Duration) (*Encoder, BuildPath, error) {
+	numSymbols := len(frequencies)
+	if numSymbols > 1<<maxBitsPerCode {
+		return nil, BuildExact, fmt.Errorf("%d symbols cannot fit in codes of at most %d bits", numSymbols, maxBitsPerCode)
+	}
+	if numSymbols == 0 {
+		return &Encoder{}, BuildExact, nil
+	}
+
+	e := new(Encoder)
+	if EstimateBuildTime(numSymbols) <= budget {
+		initLimited(e, numSymbols, frequencies)
+		return e, BuildExact, nil
+	}
+
+	if err := e.InitFromSizes(approxSizes(frequencies)); err != nil {
+		panic(fmt.Errorf("BUG: approximate code is not a prefix code: %w", err))
+	}
+	return e, BuildApprox, nil
+}
+
+// approxSizes returns code lengths for the given frequencies by logarithmic
+// bucketing: each Symbol of frequency f gets log2(t/f') bits, where t is the
+// smallest power of 2 above the total and f' is f rounded down to a power of
+// 2, clamped to at most maxBitsPerCode bits.  If clamping overfills the code
+// space, Symbols are moved from the longest bucket which can be lengthened to
+// the next one until it fits again.
+func approxSizes(frequencies []uint32) []byte {
+	var total uint64
+	for _, freq := range frequencies {
+		total += uint64(freq)
+	}
+
+	sizes := make([]byte, len(frequencies))
+	if total == 0 {
+		return sizes
+	}
+
+	totalBits := mathbits.Len64(total)
+	var countBySize [maxBitsPerCode + 1]int
+	for symbol, freq := range frequencies {
+		if freq == 0 {
+			continue
+		}
+		size := totalBits - mathbits.Len32(freq) + 1
+		if size > maxBitsPerCode {
+			size = maxBitsPerCode
+		}
+		sizes[symbol] = byte(size)
+		countBySize[size]++
+	}
+
+	var used int64
+	for size := 1; size <= maxBitsPerCode; size++ {
+		used += int64(countBySize[size]) * kraftUnits(byte(size))
+	}
+
+	var demote [maxBitsPerCode + 1]int
+	for used > 1<<maxBitsPerCode {
+		size := maxBitsPerCode - 1
+		for countBySize[size] == 0 {
+			size--
+		}
+		countBySize[size]--
+		countBySize[size+1]++
+		demote[size]++
+		used -= kraftUnits(byte(size)) - kraftUnits(byte(size+1))
+	}
+
+	// Within a bucket, frequencies differ by less than a factor of 2, so
+	// the choice of which Symbols to demote matters little.  Buckets are
+	// visited from shortest to longest, because a Symbol demoted into a
+	// bucket may need to be demoted again.
+
+	for size := 1; size < maxBitsPerCode; size++ {
+		for symbol := len(sizes) - 1; symbol >= 0 && demote[size] > 0; symbol-- {
+			if sizes[symbol] == byte(size) {
+				sizes[symbol]++
+				demote[size]--
+			}
+		}
+	}
+	return sizes
+}
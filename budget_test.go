@@ -0,0 +1,84 @@
+package huffman
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBuildWithBudget(t *testing.T) {
+	rng := rand.New(rand.NewSource(163))
+	skewed := make([]uint32, 1<<maxBitsPerCode)
+	for index := range skewed {
+		skewed[index] = uint32(rng.ExpFloat64() * 1000)
+	}
+	uniform := make([]uint32, 1<<maxBitsPerCode)
+	for index := range uniform {
+		uniform[index] = 7
+	}
+
+	type testRow struct {
+		name   string
+		freqs  []uint32
+		budget time.Duration
+		path   BuildPath
+	}
+
+	testData := [...]testRow{
+		{name: "small-exact", freqs: []uint32{5, 1, 1, 3}, budget: time.Hour, path: BuildExact},
+		{name: "small-approx", freqs: []uint32{5, 1, 1, 3}, budget: 0, path: BuildApprox},
+		{name: "single-approx", freqs: []uint32{0, 9, 0}, budget: 0, path: BuildApprox},
+		{name: "zeros-approx", freqs: []uint32{0, 0, 0}, budget: 0, path: BuildApprox},
+		{name: "skewed-exact", freqs: skewed, budget: time.Hour, path: BuildExact},
+		{name: "skewed-approx", freqs: skewed, budget: 0, path: BuildApprox},
+		{name: "uniform-approx", freqs: uniform, budget: 0, path: BuildApprox},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			e, path, err := BuildWithBudget(row.freqs, row.budget)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if path != row.path {
+				t.Errorf("path: expected %v, got %v", row.path, path)
+			}
+			if e.MaxSize() > maxBitsPerCode {
+				t.Errorf("MaxSize: %d > %d", e.MaxSize(), maxBitsPerCode)
+			}
+
+			exact, err := BuildSizes(row.freqs, maxBitsPerCode)
+			if err != nil {
+				t.Fatalf("BuildSizes: unexpected error: %v", err)
+			}
+			var count, exactCost, actualCost uint64
+			for symbol, freq := range row.freqs {
+				size := e.Encode(Symbol(symbol)).Size
+				if (freq == 0) != (size == 0) {
+					t.Errorf("symbol %d: frequency %d but size %d", symbol, freq, size)
+				}
+				count += uint64(freq)
+				exactCost += uint64(freq) * uint64(exact[symbol])
+				actualCost += uint64(freq) * uint64(size)
+			}
+			if actualCost < exactCost || actualCost > exactCost+2*count {
+				t.Errorf("cost: got %d bits, exact code costs %d bits", actualCost, exactCost)
+			}
+		})
+	}
+
+	if _, _, err := BuildWithBudget(make([]uint32, 1<<maxBitsPerCode+1), time.Hour); err == nil {
+		t.Errorf("expected error for oversized alphabet, got nil")
+	}
+}
+
+func TestBuildPath_String(t *testing.T) {
+	for path, expect := range map[BuildPath]string{
+		BuildExact:     "BuildExact",
+		BuildApprox:    "BuildApprox",
+		BuildPath(255): "BuildPath(255)",
+	} {
+		if actual := path.String(); actual != expect {
+			t.Errorf("expected %q, got %q", expect, actual)
+		}
+	}
+}
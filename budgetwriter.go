@@ -0,0 +1,163 @@
+package huffman
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBudgetExceeded is matched by every *BudgetError under errors.Is.
+var ErrBudgetExceeded = errors.New("Huffman output budget exceeded")
+
+// BudgetError reports a write which a BudgetedWriter refused because it
+// would have exceeded the budget.
+type BudgetError struct {
+	// Budget is the budget, in bits.
+	Budget uint64
+
+	// Used is the number of bits written within the budget before the
+	// refused write.
+	Used uint64
+
+	// Need is the number of bits the refused write would have taken.
+	Need uint64
+}
+
+// Error fulfills the error interface.
+func (err *BudgetError) Error() string {
+	return fmt.Sprintf("writing %d bits would exceed the output budget: %d of %d bits used", err.Need, err.Used, err.Budget)
+}
+
+// Is returns true if target is ErrBudgetExceeded.
+func (err *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// BudgetedWriter wraps a BitWriter and limits the number of bits written
+// through it, for output which must fit a fixed-size packet or frame.  Each
+// write is checked before any of its bits are written, so a write which
+// would not fit writes nothing and fails with a *BudgetError; the bits
+// already written are untouched, and the caller can abandon them and fall
+// back to an uncompressed ("stored") encoding instead.  The padding added by
+// Align and Flush counts against the budget.
+//
+// If a callback has been set with OnExceeded, a write which would not fit
+// calls it with the *BudgetError.  The write is refused all the same, and
+// fails with the callback's error, or with the *BudgetError if the callback
+// returns nil.
+//
+type BudgetedWriter struct {
+	bw         *BitWriter
+	start      uint64
+	budget     uint64
+	onExceeded func(*BudgetError) error
+}
+
+// NewBudgetedWriter returns a BudgetedWriter which allows at most budget more
+// bits to be written to bw, counting from its current position.
+func NewBudgetedWriter(bw *BitWriter, budget uint64) *BudgetedWriter {
+	return &BudgetedWriter{bw: bw, start: bw.BitsWritten(), budget: budget}
+}
+
+// OnExceeded sets the callback for writes which would exceed the budget, or
+// clears it if fn is nil.
+func (w *BudgetedWriter) OnExceeded(fn func(*BudgetError) error) {
+	w.onExceeded = fn
+}
+
+// Used returns the number of bits written within the budget so far.
+func (w *BudgetedWriter) Used() uint64 {
+	return w.bw.BitsWritten() - w.start
+}
+
+// Remaining returns the number of bits which can still be written.  It is 0
+// if the budget has been overrun by writing to the underlying BitWriter
+// directly.
+func (w *BudgetedWriter) Remaining() uint64 {
+	used := w.Used()
+	if used >= w.budget {
+		return 0
+	}
+	return w.budget - used
+}
+
+// check returns nil if need more bits fit within the budget.
+func (w *BudgetedWriter) check(need uint64) error {
+	if need <= w.Remaining() {
+		return nil
+	}
+	err := &BudgetError{Budget: w.budget, Used: w.Used(), Need: need}
+	if w.onExceeded != nil {
+		if cbErr := w.onExceeded(err); cbErr != nil {
+			return cbErr
+		}
+	}
+	return err
+}
+
+// WriteBits is like BitWriter.WriteBits, but checks the budget first.
+func (w *BudgetedWriter) WriteBits(size byte, bits uint32) error {
+	if err := w.check(uint64(size)); err != nil {
+		return err
+	}
+	return w.bw.WriteBits(size, bits)
+}
+
+// WriteCode is like BitWriter.WriteCode, but checks the budget first.
+func (w *BudgetedWriter) WriteCode(hc Code) error {
+	return w.WriteBits(hc.Size, hc.Bits)
+}
+
+// WriteSymbol is like BitWriter.WriteSymbol, but checks the budget first.
+func (w *BudgetedWriter) WriteSymbol(e *Encoder, symbol Symbol) error {
+	if err := w.check(uint64(e.symbolBits(symbol))); err != nil {
+		return err
+	}
+	return w.bw.WriteSymbol(e, symbol)
+}
+
+// WriteSymbolWithExtra is like BitWriter.WriteSymbolWithExtra, but checks
+// the budget first.
+func (w *BudgetedWriter) WriteSymbolWithExtra(e *Encoder, symbol Symbol, extra uint32) error {
+	if err := w.check(uint64(e.symbolBits(symbol)) + uint64(e.opts.extraBits(symbol))); err != nil {
+		return err
+	}
+	return w.bw.WriteSymbolWithExtra(e, symbol, extra)
+}
+
+// padding returns the number of bits Align would write.
+func (w *BudgetedWriter) padding() uint64 {
+	return (8 - w.bw.BitsWritten()%8) % 8
+}
+
+// Align is like BitWriter.Align, but checks the budget first.
+func (w *BudgetedWriter) Align() error {
+	if err := w.check(w.padding()); err != nil {
+		return err
+	}
+	return w.bw.Align()
+}
+
+// WriteBytes is like BitWriter.WriteBytes, but checks the budget first.
+func (w *BudgetedWriter) WriteBytes(p []byte) error {
+	if err := w.check(w.padding() + 8*uint64(len(p))); err != nil {
+		return err
+	}
+	return w.bw.WriteBytes(p)
+}
+
+// Flush is like BitWriter.Flush, but checks the budget first.
+func (w *BudgetedWriter) Flush() error {
+	if err := w.check(w.padding()); err != nil {
+		return err
+	}
+	return w.bw.Flush()
+}
+
+// symbolBits returns the number of bits BitWriter.WriteSymbol writes for the
+// given Symbol, not counting extra bits, or 0 if it has no code.
+func (e *Encoder) symbolBits(symbol Symbol) byte {
+	if e.escapes(symbol) {
+		return e.codes[e.opts.FallbackSymbol].Size + rawSymbolBits(len(e.codes))
+	}
+	return e.Encode(symbol).Size
+}
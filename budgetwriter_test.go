@@ -0,0 +1,100 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestBudgetedWriter(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{1, 2, 3, 3})
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	_ = bw.WriteBits(3, 0x5)
+	w := NewBudgetedWriter(bw, 16)
+
+	// 3 + 3 + 2 + 1 + 3 + 3 = 15 bits
+	for _, symbol := range []Symbol{2, 3, 1, 0, 2, 3} {
+		if err := w.WriteSymbol(e, symbol); err != nil {
+			t.Fatalf("WriteSymbol(%d): unexpected error: %v", symbol, err)
+		}
+	}
+	if used, remaining := w.Used(), w.Remaining(); used != 15 || remaining != 1 {
+		t.Errorf("expected 15 used and 1 remaining, got %d and %d", used, remaining)
+	}
+
+	err := w.WriteSymbol(e, 1)
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("WriteSymbol: expected *BudgetError, got %v", err)
+	}
+	if budgetErr.Budget != 16 || budgetErr.Used != 15 || budgetErr.Need != 2 {
+		t.Errorf("wrong BudgetError: %+v", budgetErr)
+	}
+	if actual := bw.BitsWritten(); actual != 18 {
+		t.Errorf("refused write wrote bits: expected 18 total, got %d", actual)
+	}
+
+	// The stream is at bit 18, so Flush needs 6 bits of padding.
+	if err := w.Flush(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Flush: expected ErrBudgetExceeded, got %v", err)
+	}
+	if err := w.WriteSymbol(e, 0); err != nil {
+		t.Errorf("WriteSymbol(0): unexpected error: %v", err)
+	}
+	if err := w.WriteBits(1, 0); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("WriteBits: expected ErrBudgetExceeded, got %v", err)
+	}
+}
+
+func TestBudgetedWriter_OnExceeded(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{1, 1})
+	errStored := errors.New("switch to stored mode")
+
+	var buf bytes.Buffer
+	w := NewBudgetedWriter(NewBitWriter(&buf), 8)
+	var seen *BudgetError
+	w.OnExceeded(func(err *BudgetError) error {
+		seen = err
+		return errStored
+	})
+
+	if err := w.WriteBytes([]byte{0xff}); err != nil {
+		t.Fatalf("WriteBytes: unexpected error: %v", err)
+	}
+	if err := w.WriteSymbol(e, 1); err != errStored {
+		t.Errorf("WriteSymbol: expected the callback's error, got %v", err)
+	}
+	if seen == nil || seen.Need != 1 || seen.Used != 8 {
+		t.Errorf("callback: wrong BudgetError: %+v", seen)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("Flush: unexpected error: %v", err)
+	}
+	if expect := []byte{0xff}; !bytes.Equal(buf.Bytes(), expect) {
+		t.Errorf("expected %x, got %x", expect, buf.Bytes())
+	}
+}
+
+func TestBudgetedWriter_OnExceededNil(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{1, 1})
+
+	var buf bytes.Buffer
+	w := NewBudgetedWriter(NewBitWriter(&buf), 8)
+	w.OnExceeded(func(err *BudgetError) error { return nil })
+
+	if err := w.WriteBytes([]byte{0xff}); err != nil {
+		t.Fatalf("WriteBytes: unexpected error: %v", err)
+	}
+	var budgetErr *BudgetError
+	if err := w.WriteSymbol(e, 1); !errors.As(err, &budgetErr) {
+		t.Errorf("WriteSymbol: expected a *BudgetError, got %v", err)
+	}
+	if used := w.Used(); used != 8 {
+		t.Errorf("Used: expected 8, got %d", used)
+	}
+	if remaining := w.Remaining(); remaining != 0 {
+		t.Errorf("Remaining: expected 0, got %d", remaining)
+	}
+}
@@ -0,0 +1,138 @@
+package huffman
+
+import (
+	"fmt"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+// Builder builds Encoders, keeping the scratch storage used during
+// construction so that it can be reused by the next build.  Compressors which
+// build a fresh code for every small block should keep one Builder per
+// goroutine and call Build in place of Encoder.Init.
+//
+// The zero value is a Builder with the default Options.  A Builder is not
+// safe for concurrent use.
+//
+type Builder struct {
+	opts      Options
+	nodes     []symbolAndFreq
+	synthetic []syntheticSymbol
+	heights   []uint32
+	stack     []stackItem
+	heap      []uint32
+}
+
+// syntheticSymbol records the children of one merged subtree.  See
+// firstPass.
+type syntheticSymbol struct {
+	left  Symbol
+	right Symbol
+}
+
+// stackItem is one level of firstPass's walk of the tree.
+type stackItem struct {
+	s Symbol
+	x byte
+}
+
+// NewBuilder returns a new Builder which applies the given Options to every
+// Encoder it builds.
+func NewBuilder(opts Options) *Builder {
+	if err := opts.validate(); err != nil {
+		panic(fmt.Errorf("NewBuilder: %w", err))
+	}
+	return &Builder{opts: opts}
+}
+
+// Options returns the Options applied by this Builder.
+func (b *Builder) Options() Options {
+	return b.opts
+}
+
+// Build is like BuildE, but panics if the alphabet is larger than
+// Options.MaxAlphabet allows.  It is meant for callers whose alphabet size
+// is fixed, such as a compressor with 257 Symbols per block.
+func (b *Builder) Build(frequencies []uint32, e *Encoder) {
+	if err := b.BuildE(frequencies, e); err != nil {
+		panic(fmt.Errorf("Build: %w", err))
+	}
+}
+
+// BuildE initializes e with the code that Encoder.InitWithOptions would give
+// it for the given frequencies, one per Symbol of the alphabet.  There must
+// be at least one frequency.
+//
+// If the alphabet is larger than Options.MaxAlphabet allows, BuildE returns
+// an *AlphabetError and leaves e unchanged.  Callers who build from
+// untrusted frequencies should use BuildE rather than Build.
+//
+// BuildE reuses the storage of e's previous code where it can, so any copy
+// of e made by plain assignment (rather than Clone) sees its codes change.
+//
+func (b *Builder) BuildE(frequencies []uint32, e *Encoder) error {
+	numSymbols := len(frequencies)
+	assert.Assertf(numSymbols >= 1, "len(frequencies) %d < 1", numSymbols)
+	if err := b.opts.CheckAlphabet(numSymbols); err != nil {
+		return err
+	}
+
+	codes := e.codes
+	if cap(codes) < numSymbols {
+		codes = make([]Code, numSymbols)
+	} else {
+		codes = codes[:numSymbols]
+		for index := range codes {
+			codes[index] = Code{}
+		}
+	}
+	b.build(e, numSymbols, frequencies, codes)
+	return nil
+}
+
+// build initializes e from frequencies, using codes (which must be zeroed
+// and of length numSymbols) as the storage for the new code.
+func (b *Builder) build(e *Encoder, numSymbols int, frequencies []uint32, codes []Code) {
+	opts := &b.opts
+	nodes := b.nodes[:0]
+	for symbol := Symbol(0); symbol < Symbol(len(frequencies)); symbol++ {
+		if freq := frequencies[symbol]; freq != 0 {
+			nodes = append(nodes, symbolAndFreq{symbol, freq})
+		}
+	}
+	b.nodes = nodes[:0]
+	b.quantize(nodes)
+	clampFrequencyRatio(nodes, opts.MaxFrequencyRatio)
+
+	var minSize, maxSize byte
+	nodeLen := uint32(len(nodes))
+	if nodeLen <= 2 {
+		minSize, maxSize = 1, 1
+		for index := uint32(0); index < nodeLen; index++ {
+			node := nodes[index]
+			codes[node.symbol] = MakeCode(1, index)
+		}
+	} else if opts.TieBreak == TieBreakShallow && isFlat(nodes) {
+		start := opts.traceStart()
+		flatPass(codes, nodes, &minSize, &maxSize)
+		opts.tracePhase(PhaseSizes, start)
+		start = opts.traceStart()
+		_ = secondPass(codes)
+		opts.tracePhase(PhaseCanonical, start)
+	} else {
+		firstPass(codes, nodes, b, &minSize, &maxSize)
+		start := opts.traceStart()
+		_ = secondPass(codes)
+		opts.tracePhase(PhaseCanonical, start)
+	}
+
+	*e = Encoder{
+		codes:   codes,
+		minSize: minSize,
+		maxSize: maxSize,
+		opts:    *opts,
+	}
+	if debugChecks {
+		debugCheck("Builder.Build", e.checkInvariants(true))
+	}
+}
@@ -0,0 +1,97 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	rng := rand.New(rand.NewSource(135))
+	b := NewBuilder(Options{})
+	var actual Encoder
+	for trial := 0; trial < 200; trial++ {
+		// Vary the alphabet size in both directions, so that Build must
+		// both grow and shrink its storage.
+		frequencies := make([]uint32, 1+rng.Intn(300))
+		for index := range frequencies {
+			if rng.Intn(4) != 0 {
+				frequencies[index] = uint32(rng.Intn(1000))
+			}
+		}
+
+		var expect Encoder
+		expect.Init(len(frequencies), frequencies)
+		b.Build(frequencies, &actual)
+		if !bytes.Equal(expect.SizeBySymbol(), actual.SizeBySymbol()) {
+			t.Fatalf("trial %d: expected sizes %v, got %v", trial, expect.SizeBySymbol(), actual.SizeBySymbol())
+		}
+		for symbol := Symbol(0); symbol < Symbol(len(frequencies)); symbol++ {
+			if x, y := expect.Encode(symbol), actual.Encode(symbol); x != y {
+				t.Fatalf("trial %d: symbol %d: expected %v, got %v", trial, symbol, x, y)
+			}
+		}
+		if expect.MinSize() != actual.MinSize() || expect.MaxSize() != actual.MaxSize() {
+			t.Fatalf("trial %d: expected sizes %d..%d, got %d..%d", trial, expect.MinSize(), expect.MaxSize(), actual.MinSize(), actual.MaxSize())
+		}
+	}
+}
+
+func TestBuilder_Options(t *testing.T) {
+	b := NewBuilder(Options{TieBreak: TieBreakDeep})
+	var e Encoder
+	b.Build([]uint32{1, 1, 2, 2}, &e)
+	if expect, actual := []byte{3, 3, 2, 1}, e.SizeBySymbol(); !bytes.Equal(expect, actual) {
+		t.Errorf("expected sizes %v, got %v", expect, actual)
+	}
+	if expect, actual := TieBreakDeep, e.Options().TieBreak; expect != actual {
+		t.Errorf("Options: expected %v, got %v", expect, actual)
+	}
+}
+
+func benchmarkFrequencies(n int) []uint32 {
+	rng := rand.New(rand.NewSource(1))
+	frequencies := make([]uint32, n)
+	for index := range frequencies {
+		frequencies[index] = uint32(rng.Intn(1000))
+	}
+	return frequencies
+}
+
+func BenchmarkEncoder_Init(b *testing.B) {
+	frequencies := benchmarkFrequencies(286)
+	b.ReportAllocs()
+	var e Encoder
+	for i := 0; i < b.N; i++ {
+		e.Init(len(frequencies), frequencies)
+	}
+}
+
+func BenchmarkBuilder_Build(b *testing.B) {
+	frequencies := benchmarkFrequencies(286)
+	builder := NewBuilder(Options{})
+	b.ReportAllocs()
+	var e Encoder
+	for i := 0; i < b.N; i++ {
+		builder.Build(frequencies, &e)
+	}
+}
+
+func BenchmarkEncoder_Init_64K(b *testing.B) {
+	frequencies := benchmarkFrequencies(1 << 16)
+	b.ReportAllocs()
+	var e Encoder
+	for i := 0; i < b.N; i++ {
+		e.Init(len(frequencies), frequencies)
+	}
+}
+
+func BenchmarkBuilder_Build_64K(b *testing.B) {
+	frequencies := benchmarkFrequencies(1 << 16)
+	builder := NewBuilder(Options{})
+	b.ReportAllocs()
+	var e Encoder
+	for i := 0; i < b.N; i++ {
+		builder.Build(frequencies, &e)
+	}
+}
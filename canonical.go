@@ -0,0 +1,57 @@
+package huffman
+
+import (
+	"fmt"
+)
+
+// CanonicalizeCodes takes an arbitrary prefix code, given as the Code for
+// each Symbol (with a zero-size Code for Symbols not in the code), and
+// returns the canonical code with the same bit lengths, as Encoder and
+// Decoder would assign them.  The returned map gives the canonical Code for
+// each original Code; since both codes are prefix-free, it is a bijection and
+// can be inverted to translate in the other direction.
+//
+// Only the bit lengths of a canonical code need be transmitted, so this is
+// useful for importing tables from legacy formats.  An error is returned if
+// codes is not prefix-free or if any Code is longer than 16 bits.
+//
+func CanonicalizeCodes(codes []Code) ([]Code, map[Code]Code, error) {
+	var set CodeSet
+	seen := make(map[Code]Symbol, len(codes))
+	for symbol, hc := range codes {
+		if hc.Size == 0 {
+			continue
+		}
+		if hc.Size > maxBitsPerCode {
+			return nil, nil, fmt.Errorf("symbol %d: code %s is longer than %d bits", symbol, hc, maxBitsPerCode)
+		}
+		if !hc.IsValid() {
+			return nil, nil, fmt.Errorf("symbol %d: code %s has bits set beyond its size", symbol, hc)
+		}
+		if other, found := seen[hc]; found {
+			return nil, nil, fmt.Errorf("symbols %d and %d both have code %s", other, symbol, hc)
+		}
+		seen[hc] = Symbol(symbol)
+		_ = set.Add(hc)
+	}
+
+	if prefix, hc, found := set.Conflict(); found {
+		return nil, nil, fmt.Errorf("%w: code %s for symbol %d is a prefix of code %s for symbol %d", ErrNotPrefixFree, prefix, seen[prefix], hc, seen[hc])
+	}
+
+	canonical := make([]Code, len(codes))
+	for symbol, hc := range codes {
+		canonical[symbol].Size = hc.Size
+	}
+	if err := secondPass(canonical); err != nil {
+		return nil, nil, err
+	}
+
+	mapping := make(map[Code]Code, len(seen))
+	for symbol, hc := range codes {
+		if hc.Size != 0 {
+			mapping[hc] = canonical[symbol]
+		}
+	}
+	return canonical, mapping, nil
+}
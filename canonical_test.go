@@ -0,0 +1,52 @@
+package huffman
+
+import (
+	"testing"
+)
+
+func TestCanonicalizeCodes(t *testing.T) {
+	// A valid but non-canonical code: the same lengths as makeTestEncoder,
+	// with the bit patterns shuffled around.
+	legacy := []Code{
+		MakeReversedCode(4, 0x0), // 0000
+		MakeReversedCode(4, 0x1), // 0001
+		MakeReversedCode(3, 0x1), // 001
+		MakeReversedCode(3, 0x2), // 010
+		MakeReversedCode(3, 0x3), // 011
+		MakeReversedCode(1, 0x1), // 1
+		{},
+	}
+
+	canonical, mapping, err := CanonicalizeCodes(legacy)
+	if err != nil {
+		t.Fatalf("CanonicalizeCodes: unexpected error: %v", err)
+	}
+
+	e := NewEncoderFromSizes([]byte{4, 4, 3, 3, 3, 1, 0})
+	for symbol, hc := range canonical {
+		if expect := e.Encode(Symbol(symbol)); expect != hc {
+			t.Errorf("symbol %d: expected %v, got %v", symbol, expect, hc)
+		}
+		if legacy[symbol].Size == 0 {
+			continue
+		}
+		if actual := mapping[legacy[symbol]]; actual != hc {
+			t.Errorf("mapping[%v]: expected %v, got %v", legacy[symbol], hc, actual)
+		}
+	}
+	if expect, actual := 6, len(mapping); expect != actual {
+		t.Errorf("expected %d mapping entries, got %d", expect, actual)
+	}
+
+	badData := [][]Code{
+		{MakeCode(1, 0), MakeCode(2, 0)},
+		{MakeCode(2, 1), MakeCode(2, 1)},
+		{MakeCode(1, 2)},
+		{MakeCode(17, 0)},
+	}
+	for _, bad := range badData {
+		if _, _, err := CanonicalizeCodes(bad); err == nil {
+			t.Errorf("%v: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// The CBOR methods below follow the Marshaler and Unmarshaler interfaces of
+// github.com/fxamacker/cbor, which can be implemented without importing it.
+// A coder is rendered as a CBOR array (major type 4) of unsigned integers
+// (major type 0), one bit length per Symbol, mirroring the JSON form.  A
+// byte string (major type 2) holding one bit length per byte is also
+// accepted when unmarshalling.
+
+const (
+	cborMajorUint   = 0
+	cborMajorBytes  = 2
+	cborMajorArray  = 4
+	cborMaxDataSize = maxDictSymbols
+)
+
+var errCBOR = errors.New("invalid CBOR data for Huffman code")
+
+// MarshalCBOR renders this Encoder as a CBOR array of bit lengths.
+func (e Encoder) MarshalCBOR() ([]byte, error) {
+	return appendCBORSizes(nil, e.SizeBySymbol()), nil
+}
+
+// UnmarshalCBOR initializes this Encoder from CBOR data.
+func (e *Encoder) UnmarshalCBOR(data []byte) error {
+	sizes, err := parseCBORSizes(data)
+	if err != nil {
+		return err
+	}
+	return e.InitFromSizes(sizes)
+}
+
+// MarshalCBOR renders this Decoder as a CBOR array of bit lengths.
+func (d Decoder) MarshalCBOR() ([]byte, error) {
+	return appendCBORSizes(nil, d.sizes), nil
+}
+
+// UnmarshalCBOR initializes this Decoder from CBOR data.
+func (d *Decoder) UnmarshalCBOR(data []byte) error {
+	sizes, err := parseCBORSizes(data)
+	if err != nil {
+		return err
+	}
+	return d.init(sizes)
+}
+
+func appendCBORHead(dst []byte, major byte, value uint64) []byte {
+	major <<= 5
+	switch {
+	case value < 24:
+		return append(dst, major|byte(value))
+	case value <= 0xff:
+		return append(dst, major|24, byte(value))
+	case value <= 0xffff:
+		return append(dst, major|25, byte(value>>8), byte(value))
+	case value <= 0xffffffff:
+		var tmp [4]byte
+		binary.BigEndian.PutUint32(tmp[:], uint32(value))
+		return append(append(dst, major|26), tmp[:]...)
+	default:
+		var tmp [8]byte
+		binary.BigEndian.PutUint64(tmp[:], value)
+		return append(append(dst, major|27), tmp[:]...)
+	}
+}
+
+func appendCBORSizes(dst []byte, sizes []byte) []byte {
+	dst = appendCBORHead(dst, cborMajorArray, uint64(len(sizes)))
+	for _, size := range sizes {
+		dst = appendCBORHead(dst, cborMajorUint, uint64(size))
+	}
+	return dst
+}
+
+// readCBORHead parses one data item head, returning its major type, its
+// argument, and the remaining data.  Indefinite lengths are not supported.
+func readCBORHead(data []byte) (byte, uint64, []byte, error) {
+	if len(data) == 0 {
+		return 0, 0, nil, fmt.Errorf("%w: unexpected end of data", errCBOR)
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+	var n int
+	switch {
+	case info < 24:
+		return major, uint64(info), data, nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	default:
+		return 0, 0, nil, fmt.Errorf("%w: unsupported additional information %d", errCBOR, info)
+	}
+	if len(data) < n {
+		return 0, 0, nil, fmt.Errorf("%w: unexpected end of data", errCBOR)
+	}
+	var value uint64
+	for _, b := range data[:n] {
+		value = (value << 8) | uint64(b)
+	}
+	return major, value, data[n:], nil
+}
+
+func parseCBORSizes(data []byte) ([]byte, error) {
+	major, length, data, err := readCBORHead(data)
+	if err != nil {
+		return nil, err
+	}
+	if length > cborMaxDataSize || length > uint64(len(data)) {
+		return nil, fmt.Errorf("%w: length %d is too large", errCBOR, length)
+	}
+
+	var sizes []byte
+	switch major {
+	case cborMajorBytes:
+		sizes = make([]byte, length)
+		copy(sizes, data)
+		data = data[length:]
+
+	case cborMajorArray:
+		sizes = make([]byte, length)
+		for index := range sizes {
+			var itemMajor byte
+			var value uint64
+			itemMajor, value, data, err = readCBORHead(data)
+			if err != nil {
+				return nil, err
+			}
+			if itemMajor != cborMajorUint {
+				return nil, fmt.Errorf("%w: expected unsigned integer, got major type %d", errCBOR, itemMajor)
+			}
+			if value > maxBitsPerCode {
+				return nil, fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", value, maxBitsPerCode)
+			}
+			sizes[index] = byte(value)
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: expected array or byte string, got major type %d", errCBOR, major)
+	}
+
+	if len(data) != 0 {
+		return nil, fmt.Errorf("%w: %d bytes of trailing data", errCBOR, len(data))
+	}
+	for _, size := range sizes {
+		if size > maxBitsPerCode {
+			return nil, fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
+		}
+	}
+	return sizes, nil
+}
@@ -0,0 +1,56 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoder_MarshalCBOR(t *testing.T) {
+	e := makeTestEncoder()
+	data, err := e.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR: unexpected error: %v", err)
+	}
+	if expect := []byte{0x86, 4, 4, 3, 3, 3, 1}; !bytes.Equal(expect, data) {
+		t.Errorf("MarshalCBOR: expected %x, got %x", expect, data)
+	}
+
+	var e2 Encoder
+	if err := e2.UnmarshalCBOR(data); err != nil {
+		t.Fatalf("UnmarshalCBOR: unexpected error: %v", err)
+	}
+	if expect, actual := e.DebugString(), e2.DebugString(); expect != actual {
+		t.Errorf("UnmarshalCBOR: wrong Encoder:\n\texpect: %s\n\tactual: %s", expect, actual)
+	}
+
+	// Byte string form, and a long array needing a 2-byte length.
+	var d Decoder
+	if err := d.UnmarshalCBOR([]byte{0x46, 4, 4, 3, 3, 3, 1}); err != nil {
+		t.Fatalf("UnmarshalCBOR: unexpected error: %v", err)
+	}
+	if !bytes.Equal([]byte{4, 4, 3, 3, 3, 1}, d.SizeBySymbol()) {
+		t.Errorf("UnmarshalCBOR: wrong sizes %v", d.SizeBySymbol())
+	}
+	big := NewDecoder(fixedDeflateLitLenSizes())
+	data, _ = big.MarshalCBOR()
+	if expect := []byte{0x99, 0x01, 0x20}; !bytes.Equal(expect, data[:3]) {
+		t.Errorf("MarshalCBOR: expected header %x, got %x", expect, data[:3])
+	}
+	if err := d.UnmarshalCBOR(data); err != nil {
+		t.Fatalf("UnmarshalCBOR: unexpected error: %v", err)
+	}
+
+	for _, bad := range [][]byte{
+		nil,
+		{0x86, 4, 4, 3, 3, 3},
+		{0x82, 1, 1, 0},
+		{0x82, 1, 0x61},
+		{0x81, 17},
+		{0xa0},
+		{0x83, 1, 1, 1},
+	} {
+		if err := e2.UnmarshalCBOR(bad); err == nil {
+			t.Errorf("%x: UnmarshalCBOR: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+)
+
+// Checksum selects the per-block checksum algorithm used by the stream
+// format.
+type Checksum byte
+
+const (
+	// NoChecksum disables per-block checksums.
+	NoChecksum Checksum = iota
+
+	// ChecksumCRC32C selects CRC-32 with the Castagnoli polynomial,
+	// stored as 4 bytes.
+	ChecksumCRC32C
+
+	// ChecksumXXH64 selects 64-bit xxHash with a seed of 0, stored as 8
+	// bytes.
+	ChecksumXXH64
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	// ErrChecksum is returned when a block's checksum does not match its
+	// decompressed contents.
+	ErrChecksum = errors.New("Huffman stream checksum mismatch")
+
+	// ErrLength is returned when the total decompressed length of a stream
+	// does not match its length trailer.
+	ErrLength = errors.New("Huffman stream length mismatch")
+)
+
+// BlockError reports corruption within a specific block of a stream.
+type BlockError struct {
+	// Block is the zero-based index of the failing block.
+	Block int
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error fulfills the error interface.
+func (err *BlockError) Error() string {
+	return fmt.Sprintf("block %d: %v", err.Block, err.Err)
+}
+
+// Unwrap returns the underlying error.
+func (err *BlockError) Unwrap() error {
+	return err.Err
+}
+
+// Is returns true if target is ErrCorrupt.
+func (err *BlockError) Is(target error) bool {
+	return target == ErrCorrupt
+}
+
+// String returns the name of this checksum algorithm.
+func (c Checksum) String() string {
+	switch c {
+	case NoChecksum:
+		return "none"
+	case ChecksumCRC32C:
+		return "crc32c"
+	case ChecksumXXH64:
+		return "xxh64"
+	default:
+		return fmt.Sprintf("Checksum(%d)", byte(c))
+	}
+}
+
+// Size returns the number of bytes occupied by this checksum in the stream.
+func (c Checksum) Size() int {
+	switch c {
+	case ChecksumCRC32C:
+		return 4
+	case ChecksumXXH64:
+		return 8
+	default:
+		return 0
+	}
+}
+
+func (c Checksum) isValid() bool {
+	return c <= ChecksumXXH64
+}
+
+func (c Checksum) newHash() hash.Hash64 {
+	switch c {
+	case ChecksumCRC32C:
+		return crc32As64{crc32.New(crc32cTable)}
+	case ChecksumXXH64:
+		return newXXH64()
+	default:
+		return nil
+	}
+}
+
+func (c Checksum) appendSum(dst []byte, h hash.Hash64) []byte {
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], h.Sum64())
+	return append(dst, tmp[:c.Size()]...)
+}
+
+type crc32As64 struct {
+	hash.Hash32
+}
+
+func (h crc32As64) Sum64() uint64 {
+	return uint64(h.Sum32())
+}
+
+var (
+	_ fmt.Stringer = Checksum(0)
+	_ error        = (*BlockError)(nil)
+)
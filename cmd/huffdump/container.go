@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// detectFormat guesses the container format of raw from its first bytes.
+func detectFormat(raw []byte) string {
+	switch {
+	case bytes.HasPrefix(raw, pngSignature):
+		return "png"
+	case len(raw) >= 2 && raw[0] == 0x1f && raw[1] == 0x8b:
+		return "gzip"
+	case len(raw) >= 2 && raw[0]&0x0f == 8 && (uint(raw[0])<<8|uint(raw[1]))%31 == 0:
+		return "zlib"
+	default:
+		return "raw"
+	}
+}
+
+// unwrap strips the container of the given format from raw, returning the
+// DEFLATE stream inside.
+func unwrap(format string, raw []byte) ([]byte, error) {
+	switch format {
+	case "raw":
+		return raw, nil
+	case "zlib":
+		return unwrapZlib(raw)
+	case "gzip":
+		return unwrapGzip(raw)
+	case "png":
+		idat, err := unwrapPNG(raw)
+		if err != nil {
+			return nil, err
+		}
+		return unwrapZlib(idat)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// unwrapZlib strips an RFC 1950 header.  The trailing checksum is left in
+// place, as it follows the final block.
+func unwrapZlib(raw []byte) ([]byte, error) {
+	if len(raw) < 2 {
+		return nil, fmt.Errorf("zlib: truncated header")
+	}
+	if raw[0]&0x0f != 8 || (uint(raw[0])<<8|uint(raw[1]))%31 != 0 {
+		return nil, fmt.Errorf("zlib: invalid header %02x %02x", raw[0], raw[1])
+	}
+	if raw[1]&0x20 != 0 {
+		return nil, fmt.Errorf("zlib: preset dictionaries are not supported")
+	}
+	return raw[2:], nil
+}
+
+// unwrapGzip strips an RFC 1952 member header.
+func unwrapGzip(raw []byte) ([]byte, error) {
+	const (
+		flagHCRC    = 0x02
+		flagExtra   = 0x04
+		flagName    = 0x08
+		flagComment = 0x10
+	)
+
+	if len(raw) < 10 || raw[0] != 0x1f || raw[1] != 0x8b || raw[2] != 8 {
+		return nil, fmt.Errorf("gzip: invalid header")
+	}
+	flags := raw[3]
+	rest := raw[10:]
+	if flags&flagExtra != 0 {
+		if len(rest) < 2 {
+			return nil, fmt.Errorf("gzip: truncated header")
+		}
+		n := int(binary.LittleEndian.Uint16(rest))
+		if len(rest) < 2+n {
+			return nil, fmt.Errorf("gzip: truncated header")
+		}
+		rest = rest[2+n:]
+	}
+	for _, flag := range []byte{flagName, flagComment} {
+		if flags&flag == 0 {
+			continue
+		}
+		end := bytes.IndexByte(rest, 0)
+		if end < 0 {
+			return nil, fmt.Errorf("gzip: truncated header")
+		}
+		rest = rest[end+1:]
+	}
+	if flags&flagHCRC != 0 {
+		if len(rest) < 2 {
+			return nil, fmt.Errorf("gzip: truncated header")
+		}
+		rest = rest[2:]
+	}
+	return rest, nil
+}
+
+// unwrapPNG returns the concatenated contents of the IDAT chunks of a PNG
+// file.
+func unwrapPNG(raw []byte) ([]byte, error) {
+	if !bytes.HasPrefix(raw, pngSignature) {
+		return nil, fmt.Errorf("png: invalid signature")
+	}
+	var idat []byte
+	rest := raw[len(pngSignature):]
+	for len(rest) != 0 {
+		if len(rest) < 12 {
+			return nil, fmt.Errorf("png: truncated chunk")
+		}
+		n := binary.BigEndian.Uint32(rest)
+		chunkType := string(rest[4:8])
+		if uint64(len(rest)) < 12+uint64(n) {
+			return nil, fmt.Errorf("png: truncated %s chunk", chunkType)
+		}
+		if chunkType == "IDAT" {
+			idat = append(idat, rest[8:8+n]...)
+		}
+		rest = rest[12+n:]
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	if idat == nil {
+		return nil, fmt.Errorf("png: no IDAT chunks")
+	}
+	return idat, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+// dumper walks a DEFLATE stream block by block, printing what it finds.
+type dumper struct {
+	w      io.Writer
+	tables bool
+	trace  bool
+	br     *huffman.BitReader
+	out    int64
+}
+
+func (d *dumper) dump(body []byte) error {
+	d.br = huffman.NewBitReader(bytes.NewReader(body))
+	for block := 0; ; block++ {
+		offset := d.br.BitsRead()
+		header, err := d.br.ReadBits(3)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", block, noEOF(err))
+		}
+		final := header&1 != 0
+		btype := header >> 1
+		fmt.Fprintf(d.w, "block %d at bit %d: final=%t type=%s\n", block, offset, final, blockTypeName(btype))
+
+		switch btype {
+		case 0:
+			err = d.stored()
+		case 1:
+			err = d.fixed()
+		case 2:
+			err = d.dynamic()
+		default:
+			err = fmt.Errorf("reserved block type 3")
+		}
+		if err != nil {
+			return fmt.Errorf("block %d: %w", block, err)
+		}
+		if final {
+			break
+		}
+	}
+	bits := d.br.BitsRead()
+	fmt.Fprintf(d.w, "end at bit %d (byte %d), %d bytes decompressed\n", bits, (bits+7)/8, d.out)
+	return nil
+}
+
+func blockTypeName(btype uint32) string {
+	switch btype {
+	case 0:
+		return "stored"
+	case 1:
+		return "fixed"
+	case 2:
+		return "dynamic"
+	default:
+		return "reserved"
+	}
+}
+
+func (d *dumper) stored() error {
+	d.br.Align()
+	var header [4]byte
+	for index := range header {
+		b, err := d.br.ReadByte()
+		if err != nil {
+			return noEOF(err)
+		}
+		header[index] = b
+	}
+	n := binary.LittleEndian.Uint16(header[0:2])
+	if ^n != binary.LittleEndian.Uint16(header[2:4]) {
+		return fmt.Errorf("stored block length %d does not match its complement", n)
+	}
+	fmt.Fprintf(d.w, "  %d stored bytes\n", n)
+	for i := uint16(0); i < n; i++ {
+		if _, err := d.br.ReadByte(); err != nil {
+			return noEOF(err)
+		}
+	}
+	d.out += int64(n)
+	return nil
+}
+
+func (d *dumper) fixed() error {
+	litSizes := make([]byte, huffman.DeflateLitLenAlphabetSize)
+	for symbol := range litSizes {
+		switch {
+		case symbol < 144:
+			litSizes[symbol] = 8
+		case symbol < 256:
+			litSizes[symbol] = 9
+		case symbol < 280:
+			litSizes[symbol] = 7
+		default:
+			litSizes[symbol] = 8
+		}
+	}
+	distSizes := make([]byte, huffman.DeflateDistAlphabetSize)
+	for symbol := range distSizes {
+		distSizes[symbol] = 5
+	}
+	return d.codes(huffman.NewDecoder(litSizes), huffman.NewDecoder(distSizes), false)
+}
+
+func (d *dumper) dynamic() error {
+	litlen, dist, err := huffman.ParseDeflateDynamicHeader(d.br)
+	if err != nil {
+		return err
+	}
+	return d.codes(litlen, dist, d.tables)
+}
+
+// codes prints the tables of a Huffman-coded block if tables is true, then
+// decodes its Symbols.
+func (d *dumper) codes(litlen *huffman.Decoder, dist *huffman.Decoder, tables bool) error {
+	if tables {
+		fmt.Fprintln(d.w, "  literal/length code:")
+		_, _ = litlen.DumpWith(d.w, huffman.DebugOptions{SymbolName: litLenName})
+		fmt.Fprintln(d.w, "  distance code:")
+		_, _ = dist.DumpWith(d.w, huffman.DebugOptions{SymbolName: distName})
+	}
+
+	litEnc, distEnc := litlen.Encoder(), dist.Encoder()
+	var literals, matches int
+	for {
+		offset := d.br.BitsRead()
+		symbol, err := d.br.ReadSymbol(litlen)
+		if err != nil {
+			return noEOF(err)
+		}
+		switch {
+		case symbol < huffman.EndOfBlock:
+			literals++
+			d.out++
+			if d.trace {
+				fmt.Fprintf(d.w, "  bit %d: %s literal %s\n", offset, litEnc.Encode(symbol), strconv.QuoteRune(rune(symbol)))
+			}
+			continue
+
+		case symbol == huffman.EndOfBlock:
+			if d.trace {
+				fmt.Fprintf(d.w, "  bit %d: %s end of block\n", offset, litEnc.Encode(symbol))
+			}
+			fmt.Fprintf(d.w, "  %d literals, %d matches\n", literals, matches)
+			return nil
+		}
+
+		lengthBase, lengthExtra, ok := huffman.DeflateLengthBase(symbol)
+		if !ok {
+			return fmt.Errorf("bit %d: invalid length symbol %d", offset, symbol)
+		}
+		extra, err := d.br.ReadBits(lengthExtra)
+		if err != nil {
+			return noEOF(err)
+		}
+		length := int(lengthBase) + int(extra)
+
+		distSymbol, err := d.br.ReadSymbol(dist)
+		if err != nil {
+			return noEOF(err)
+		}
+		distBase, distExtra, ok := huffman.DeflateDistBase(distSymbol)
+		if !ok {
+			return fmt.Errorf("bit %d: invalid distance symbol %d", offset, distSymbol)
+		}
+		extra, err = d.br.ReadBits(distExtra)
+		if err != nil {
+			return noEOF(err)
+		}
+		distance := int(distBase) + int(extra)
+		if int64(distance) > d.out {
+			return fmt.Errorf("bit %d: distance %d exceeds output length %d", offset, distance, d.out)
+		}
+
+		matches++
+		d.out += int64(length)
+		if d.trace {
+			fmt.Fprintf(d.w, "  bit %d: %s %s match length %d distance %d\n", offset, litEnc.Encode(symbol), distEnc.Encode(distSymbol), length, distance)
+		}
+	}
+}
+
+func litLenName(symbol huffman.Symbol) string {
+	switch {
+	case symbol < huffman.EndOfBlock:
+		return "literal " + strconv.QuoteRune(rune(symbol))
+	case symbol == huffman.EndOfBlock:
+		return "end of block"
+	}
+	if base, extra, ok := huffman.DeflateLengthBase(symbol); ok {
+		return fmt.Sprintf("length %d+%d bits", base, extra)
+	}
+	return "invalid"
+}
+
+func distName(symbol huffman.Symbol) string {
+	if base, extra, ok := huffman.DeflateDistBase(symbol); ok {
+		return fmt.Sprintf("distance %d+%d bits", base, extra)
+	}
+	return "invalid"
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+// Command huffdump prints the structure of a DEFLATE stream: each block's
+// type and bit offset, its Huffman tables, and optionally every Symbol
+// decoded from it.
+//
+// Usage:
+//
+//     huffdump [-format auto|raw|zlib|gzip|png] [-tables=false] [-trace] [file]
+//
+// The input is read from file, or from standard input if no file is given.
+// With -format auto (the default), zlib, gzip and PNG inputs are recognized
+// by their headers; anything else is treated as raw DEFLATE.  For PNG input,
+// the contents of the IDAT chunks are concatenated and dumped as one zlib
+// stream.
+//
+// Bit offsets are counted from the start of the DEFLATE stream, after any
+// container header.  The fixed codes of BTYPE=01 blocks are not printed.
+//
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "huffdump: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run implements the command, so that it can be tested.
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("huffdump", flag.ContinueOnError)
+	format := fs.String("format", "auto", "input format: auto, raw, zlib, gzip, or png")
+	tables := fs.Bool("tables", true, "print each block's Huffman tables")
+	trace := fs.Bool("trace", false, "print each decoded symbol")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var input io.Reader = stdin
+	switch fs.NArg() {
+	case 0:
+		// pass
+	case 1:
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	default:
+		return fmt.Errorf("too many arguments")
+	}
+
+	raw, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	if *format == "auto" {
+		*format = detectFormat(raw)
+	}
+	body, err := unwrap(*format, raw)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(stdout)
+	fmt.Fprintf(w, "format: %s\n", *format)
+	d := &dumper{w: w, tables: *tables, trace: *trace}
+	err = d.dump(body)
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func testData() []byte {
+	rng := rand.New(rand.NewSource(141))
+	data := make([]byte, 20000)
+	for index := range data {
+		data[index] = "aaaaaaaabbbbccdefghij  "[rng.Intn(23)]
+	}
+	return data
+}
+
+func TestRun(t *testing.T) {
+	data := testData()
+
+	var rawBuf bytes.Buffer
+	fw, _ := flate.NewWriter(&rawBuf, flate.BestCompression)
+	_, _ = fw.Write(data)
+	_ = fw.Close()
+
+	var zlibBuf bytes.Buffer
+	zw := zlib.NewWriter(&zlibBuf)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+
+	var gzipBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzipBuf)
+	gw.Name = "test.txt"
+	gw.Comment = "a comment"
+	_, _ = gw.Write(data)
+	_ = gw.Close()
+
+	var storedBuf bytes.Buffer
+	sw, _ := flate.NewWriter(&storedBuf, flate.NoCompression)
+	_, _ = sw.Write(data[:100])
+	_ = sw.Close()
+
+	img := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetGray(x, y, color.Gray{Y: byte(x * y)})
+		}
+	}
+	var pngBuf bytes.Buffer
+	_ = png.Encode(&pngBuf, img)
+
+	type testRow struct {
+		name   string
+		args   []string
+		input  []byte
+		expect []string
+	}
+
+	testData := [...]testRow{
+		{
+			name:   "raw",
+			args:   []string{"-format", "raw"},
+			input:  rawBuf.Bytes(),
+			expect: []string{"format: raw\n", "type=dynamic", "literal/length code:", "20000 bytes decompressed"},
+		},
+		{
+			name:   "zlib",
+			input:  zlibBuf.Bytes(),
+			expect: []string{"format: zlib\n", "type=dynamic", "20000 bytes decompressed"},
+		},
+		{
+			name:   "gzip",
+			input:  gzipBuf.Bytes(),
+			expect: []string{"format: gzip\n", "type=dynamic", "20000 bytes decompressed"},
+		},
+		{
+			name:   "stored",
+			args:   []string{"-format", "raw"},
+			input:  storedBuf.Bytes(),
+			expect: []string{"type=stored", "100 stored bytes", "100 bytes decompressed"},
+		},
+		{
+			name:   "png",
+			args:   []string{"-tables=false"},
+			input:  pngBuf.Bytes(),
+			expect: []string{"format: png\n", "bytes decompressed"},
+		},
+		{
+			name:   "trace",
+			args:   []string{"-trace", "-tables=false"},
+			input:  rawBuf.Bytes(),
+			expect: []string{"literal 'a'", "match length", "end of block"},
+		},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(row.args, bytes.NewReader(row.input), &out); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range row.expect {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected %q in output:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	type testRow struct {
+		name  string
+		args  []string
+		input []byte
+	}
+
+	testData := [...]testRow{
+		{name: "empty", input: nil},
+		{name: "bad-format", args: []string{"-format", "bzip2"}, input: []byte{0}},
+		{name: "truncated-gzip", input: []byte{0x1f, 0x8b, 8}},
+		{name: "reserved-btype", args: []string{"-format", "raw"}, input: []byte{0x07}},
+		{name: "png-no-idat", input: append(append([]byte(nil), pngSignature...), 0, 0, 0, 0, 'I', 'E', 'N', 'D', 0, 0, 0, 0)},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(row.args, bytes.NewReader(row.input), &out); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
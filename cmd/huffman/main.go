@@ -0,0 +1,43 @@
+// Command huffman works with the files of the huffman package.
+//
+// Usage:
+//
+//     huffman train -o out.huffdict [-holdout 0.1] [-sample 1.0] [-seed 1] file...
+//
+// The train subcommand builds a shared dictionary for the stream format (see
+// huffman.TrainStreamDict) from the byte frequencies of the given files, and
+// writes it to the -o file.  A fraction of the files, chosen by -holdout, is
+// kept out of training and used to evaluate the dictionary; with -sample, only
+// that fraction of the remaining data is used for training, in 4 KiB chunks.
+// Both choices are random, but repeatable for a given -seed.
+//
+// The evaluation reports the average cost in bits per byte of coding the
+// training and held-out data with the dictionary.
+//
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "huffman: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run implements the command, so that it can be tested.
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand; try \"huffman train\"")
+	}
+	switch args[0] {
+	case "train":
+		return runTrain(args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown subcommand %q", args[0])
+	}
+}
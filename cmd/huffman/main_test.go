@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+func TestRunTrain(t *testing.T) {
+	dir := t.TempDir()
+	var names []string
+	for i := 0; i < 10; i++ {
+		name := filepath.Join(dir, string(rune('a'+i))+".txt")
+		data := strings.Repeat("the quick brown fox jumps over the lazy dog\n", 50*(i+1))
+		if err := os.WriteFile(name, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	output := filepath.Join(dir, "out.huffdict")
+
+	var stdout bytes.Buffer
+	args := append([]string{"train", "-o", output, "-holdout", "0.3", "-sample", "0.5", "-seed", "158"}, names...)
+	if err := run(args, &stdout); err != nil {
+		t.Fatalf("run: unexpected error: %v", err)
+	}
+	for _, expect := range []string{"wrote ", "training: ", "held out: ", "bits/byte"} {
+		if !strings.Contains(stdout.String(), expect) {
+			t.Errorf("output lacks %q:\n%s", expect, stdout.String())
+		}
+	}
+
+	f, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dict, err := huffman.LoadDict(f)
+	if err != nil {
+		t.Fatalf("LoadDict: unexpected error: %v", err)
+	}
+	if expect, actual := huffman.StreamAlphabetSize, len(dict.Sizes); expect != actual {
+		t.Errorf("expected %d sizes, got %d", expect, actual)
+	}
+	if e, _ := dict.Encoder(); e.Encode('q').Size <= e.Encode(' ').Size {
+		t.Errorf("expected ' ' to have a shorter code than 'q'")
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"bogus"},
+		{"train", "x"},
+		{"train", "-o", "out", "-holdout", "1", "x"},
+		{"train", "-o", "out", "-sample", "0", "x"},
+		{"train", "-o", "out"},
+		{"train", "-o", filepath.Join(t.TempDir(), "out"), "/nonexistent/file"},
+	} {
+		if err := run(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+}
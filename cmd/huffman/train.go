@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+// sampleChunkSize is the granularity at which -sample selects training data.
+const sampleChunkSize = 4096
+
+// corpusStats accumulates the cost of coding some data with a dictionary.
+type corpusStats struct {
+	files int
+	bytes uint64
+	bits  uint64
+}
+
+func (stats *corpusStats) add(e *huffman.Encoder, data []byte) {
+	stats.files++
+	stats.bytes += uint64(len(data))
+	for _, b := range data {
+		stats.bits += uint64(e.Encode(huffman.Symbol(b)).Size)
+	}
+}
+
+func (stats corpusStats) String() string {
+	if stats.bytes == 0 {
+		return fmt.Sprintf("%d files, 0 bytes", stats.files)
+	}
+	bitsPerByte := float64(stats.bits) / float64(stats.bytes)
+	return fmt.Sprintf("%d files, %d bytes, %.3f bits/byte (%.1f%%)", stats.files, stats.bytes, bitsPerByte, 100*bitsPerByte/8)
+}
+
+func runTrain(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("huffman train", flag.ContinueOnError)
+	output := fs.String("o", "", "output .huffdict file")
+	holdout := fs.Float64("holdout", 0.1, "fraction of files to hold out for evaluation")
+	sample := fs.Float64("sample", 1.0, "fraction of the training data to use")
+	seed := fs.Int64("seed", 1, "random seed for -holdout and -sample")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return fmt.Errorf("missing -o")
+	}
+	if *holdout < 0 || *holdout >= 1 {
+		return fmt.Errorf("-holdout %g not in range [0, 1)", *holdout)
+	}
+	if *sample <= 0 || *sample > 1 {
+		return fmt.Errorf("-sample %g not in range (0, 1]", *sample)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+
+	rng := rand.New(rand.NewSource(*seed))
+	var training, heldOut [][]byte
+	var samples [][]byte
+	for _, name := range fs.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if rng.Float64() < *holdout {
+			heldOut = append(heldOut, data)
+			continue
+		}
+		training = append(training, data)
+		for start := 0; start < len(data); start += sampleChunkSize {
+			end := start + sampleChunkSize
+			if end > len(data) {
+				end = len(data)
+			}
+			if *sample >= 1 || rng.Float64() < *sample {
+				samples = append(samples, data[start:end])
+			}
+		}
+	}
+	if len(training) == 0 {
+		return fmt.Errorf("every input file was held out; lower -holdout or add files")
+	}
+
+	dict := huffman.TrainStreamDict(samples...)
+	e, err := dict.Encoder()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := huffman.SaveDict(f, dict); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	var trainStats, heldOutStats corpusStats
+	for _, data := range training {
+		trainStats.add(e, data)
+	}
+	for _, data := range heldOut {
+		heldOutStats.add(e, data)
+	}
+
+	w := bufio.NewWriter(stdout)
+	fmt.Fprintf(w, "wrote %s (fingerprint %016x)\n", *output, dict.Fingerprint())
+	fmt.Fprintf(w, "training: %v\n", trainStats)
+	fmt.Fprintf(w, "held out: %v\n", heldOutStats)
+	return w.Flush()
+}
@@ -33,6 +33,43 @@ func (hc Code) Reversed() Code {
 	return MakeReversedCode(hc.Size, hc.Bits)
 }
 
+// IsZero returns true if this is the zero Code, with no bits.  Encoder.Encode
+// returns the zero Code for a Symbol with no code assigned, and never for a
+// Symbol with one, since every assigned code has at least 1 bit.
+func (hc Code) IsZero() bool {
+	return hc.Size == 0 && hc.Bits == 0
+}
+
+// IsValid returns true if this Code could be assigned to a Symbol: it has
+// between 1 and 32 bits, and no bits set in Bits beyond the first Size.
+func (hc Code) IsValid() bool {
+	if hc.Size == 0 || hc.Size > 32 {
+		return false
+	}
+	return hc.Size == 32 || (hc.Bits>>hc.Size) == 0
+}
+
+// Packed returns this Code packed into a uint64, with Size in the high 32 bits
+// and Bits in the low 32 bits.  Two Codes are equal if and only if their packed
+// forms are equal, and comparing packed forms orders Codes the same way the
+// package sorts them internally: shorter Codes first, then by Bits as an
+// integer.  This makes the packed form suitable as a compact map key or sort
+// key.
+//
+// Because Bits holds the first bit in the least significant position, this is
+// not the canonical order of RFC 1951 among Codes of the same Size, which
+// reads the first bit as most significant: the canonical order of the 2-bit
+// Codes is Bits 0, 2, 1, 3.
+func (hc Code) Packed() uint64 {
+	return uint64(hc.Size)<<32 | uint64(hc.Bits)
+}
+
+// CodeFromPacked is the inverse of Code.Packed.  Bits of packed above the
+// low 40 are ignored.
+func CodeFromPacked(packed uint64) Code {
+	return MakeCode(byte(packed>>32), uint32(packed))
+}
+
 // String returns the string representation of this Code.
 func (hc Code) String() string {
 	if hc.Size == 0 {
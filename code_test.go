@@ -0,0 +1,104 @@
+package huffman
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCode_IsValid(t *testing.T) {
+	type testRow struct {
+		code    Code
+		isValid bool
+		isZero  bool
+	}
+
+	testData := [...]testRow{
+		{code: Code{}, isValid: false, isZero: true},
+		{code: MakeCode(0, 1), isValid: false, isZero: false},
+		{code: MakeCode(1, 0), isValid: true, isZero: false},
+		{code: MakeCode(1, 1), isValid: true, isZero: false},
+		{code: MakeCode(1, 2), isValid: false, isZero: false},
+		{code: MakeCode(16, 0xffff), isValid: true, isZero: false},
+		{code: MakeCode(16, 0x10000), isValid: false, isZero: false},
+		{code: MakeCode(32, 0xffffffff), isValid: true, isZero: false},
+		{code: MakeCode(33, 0), isValid: false, isZero: false},
+	}
+	for _, row := range testData {
+		t.Run(row.code.String(), func(t *testing.T) {
+			if actual := row.code.IsValid(); row.isValid != actual {
+				t.Errorf("IsValid(%#v): expected %t, got %t", row.code, row.isValid, actual)
+			}
+			if actual := row.code.IsZero(); row.isZero != actual {
+				t.Errorf("IsZero(%#v): expected %t, got %t", row.code, row.isZero, actual)
+			}
+		})
+	}
+}
+
+func TestEncoder_Assigned(t *testing.T) {
+	type testRow struct {
+		name  string
+		freqs []uint32
+	}
+
+	testData := [...]testRow{
+		{name: "one", freqs: []uint32{0, 7, 0}},
+		{name: "two", freqs: []uint32{3, 0, 1}},
+		{name: "many", freqs: []uint32{5, 0, 3, 3, 0, 1, 1, 1, 9}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var e Encoder
+			e.Init(len(row.freqs), row.freqs)
+			for symbol, freq := range row.freqs {
+				hc := e.Encode(Symbol(symbol))
+				if assigned := e.Assigned(Symbol(symbol)); assigned != (freq != 0) {
+					t.Errorf("Assigned(%d): expected %t, got %t", symbol, freq != 0, assigned)
+				}
+				if freq != 0 && !hc.IsValid() {
+					t.Errorf("Encode(%d): expected a valid Code, got %#v", symbol, hc)
+				}
+				if freq == 0 && !hc.IsZero() {
+					t.Errorf("Encode(%d): expected the zero Code, got %#v", symbol, hc)
+				}
+			}
+			if e.Assigned(-1) || e.Assigned(Symbol(len(row.freqs))) {
+				t.Errorf("Assigned: expected false outside the alphabet")
+			}
+		})
+	}
+}
+
+func TestCode_Packed(t *testing.T) {
+	codes := []Code{
+		MakeCode(3, 0x5),
+		MakeCode(1, 0x1),
+		MakeCode(16, 0xffff),
+		MakeCode(3, 0x2),
+		MakeCode(2, 0x3),
+		MakeCode(1, 0x0),
+		MakeCode(32, 0xffffffff),
+		MakeCode(16, 0x0001),
+		{},
+	}
+	for _, hc := range codes {
+		if actual := CodeFromPacked(hc.Packed()); actual != hc {
+			t.Errorf("CodeFromPacked(%#v.Packed()): got %#v", hc, actual)
+		}
+	}
+
+	expected := append([]Code(nil), codes...)
+	sort.Slice(expected, func(i, j int) bool {
+		a, b := expected[i], expected[j]
+		if a.Size != b.Size {
+			return a.Size < b.Size
+		}
+		return a.Bits < b.Bits
+	})
+	actual := append([]Code(nil), codes...)
+	sortCodes(actual)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("sort by Packed: expected %v, got %v", expected, actual)
+	}
+}
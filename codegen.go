@@ -0,0 +1,173 @@
+package huffman
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteCHeader writes a C header declaring this Encoder's code table, so
+// that firmware which decodes (or encodes) the same streams can be generated
+// from the same source as the Go side.  All identifiers are prefixed with
+// name, which must be a valid C identifier.  The header declares:
+//
+//     #define name_NUM_SYMBOLS ...
+//     #define name_MAX_BITS ...
+//     static const uint16_t name_lens[name_NUM_SYMBOLS];
+//     static const uint16_t name_codes[name_NUM_SYMBOLS];
+//
+// where name_lens[s] is the length in bits of the code for Symbol s, or 0 if
+// it has none, and name_codes[s] holds its bits with the first bit in the
+// least significant bit, as in Code.  With MissingCodeEscape, the header
+// also defines name_ESCAPE_SYMBOL and name_RAW_BITS.
+//
+// Returns an error if any code is longer than 16 bits.  See also
+// WriteRustModule and WritePythonModule.
+//
+func (e Encoder) WriteCHeader(w io.Writer, name string) error {
+	lens, codes, err := e.codegenTables("WriteCHeader", name)
+	if err != nil {
+		return err
+	}
+
+	guard := strings.ToUpper(name) + "_H"
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "/* Code generated by huffman.Encoder.WriteCHeader. DO NOT EDIT. */\n\n")
+	fmt.Fprintf(bw, "#ifndef %s\n#define %s\n\n#include <stdint.h>\n\n", guard, guard)
+	fmt.Fprintf(bw, "#define %s_NUM_SYMBOLS %d\n", name, len(e.codes))
+	fmt.Fprintf(bw, "#define %s_MAX_BITS %d\n", name, e.maxSize)
+	if e.opts.MissingCode == MissingCodeEscape {
+		fmt.Fprintf(bw, "#define %s_ESCAPE_SYMBOL %d\n", name, e.opts.FallbackSymbol)
+		fmt.Fprintf(bw, "#define %s_RAW_BITS %d\n", name, rawSymbolBits(len(e.codes)))
+	}
+	fmt.Fprintf(bw, "\n/* Code length in bits by symbol; 0 if the symbol has no code. */\n")
+	fmt.Fprintf(bw, "static const uint16_t %s_lens[%s_NUM_SYMBOLS] = {\n", name, name)
+	writeArray(bw, "\t", lens, "%d", 16)
+	fmt.Fprintf(bw, "};\n\n/* Code bits by symbol; the first bit is the least significant. */\n")
+	fmt.Fprintf(bw, "static const uint16_t %s_codes[%s_NUM_SYMBOLS] = {\n", name, name)
+	writeArray(bw, "\t", codes, "0x%04x", 8)
+	fmt.Fprintf(bw, "};\n\n#endif /* %s */\n", guard)
+	return bw.Flush()
+}
+
+// WriteRustModule is like WriteCHeader, but writes Rust source for a module
+// declaring the same tables as constants.  The identifiers are prefixed with
+// name in upper case, as in name_LENS, which holds u8 values, and
+// name_CODES, which holds u16 values.
+func (e Encoder) WriteRustModule(w io.Writer, name string) error {
+	lens, codes, err := e.codegenTables("WriteRustModule", name)
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.ToUpper(name)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "// Code generated by huffman.Encoder.WriteRustModule. DO NOT EDIT.\n\n")
+	fmt.Fprintf(bw, "pub const %s_NUM_SYMBOLS: usize = %d;\n", prefix, len(e.codes))
+	fmt.Fprintf(bw, "pub const %s_MAX_BITS: u32 = %d;\n", prefix, e.maxSize)
+	if e.opts.MissingCode == MissingCodeEscape {
+		fmt.Fprintf(bw, "pub const %s_ESCAPE_SYMBOL: usize = %d;\n", prefix, e.opts.FallbackSymbol)
+		fmt.Fprintf(bw, "pub const %s_RAW_BITS: u32 = %d;\n", prefix, rawSymbolBits(len(e.codes)))
+	}
+	fmt.Fprintf(bw, "\n/// Code length in bits by symbol; 0 if the symbol has no code.\n")
+	fmt.Fprintf(bw, "pub const %s_LENS: [u8; %s_NUM_SYMBOLS] = [\n", prefix, prefix)
+	writeArray(bw, "    ", lens, "%d", 16)
+	fmt.Fprintf(bw, "];\n\n/// Code bits by symbol; the first bit is the least significant.\n")
+	fmt.Fprintf(bw, "pub const %s_CODES: [u16; %s_NUM_SYMBOLS] = [\n", prefix, prefix)
+	writeArray(bw, "    ", codes, "0x%04x", 8)
+	fmt.Fprintf(bw, "];\n")
+	return bw.Flush()
+}
+
+// WritePythonModule is like WriteCHeader, but writes a Python module
+// declaring the same tables as tuples, with identifiers prefixed with name in
+// upper case.  It also declares name_TABLE, a dict which maps each Symbol
+// with a code to a (length, bits) tuple.
+func (e Encoder) WritePythonModule(w io.Writer, name string) error {
+	lens, codes, err := e.codegenTables("WritePythonModule", name)
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.ToUpper(name)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Code generated by huffman.Encoder.WritePythonModule. DO NOT EDIT.\n\n")
+	fmt.Fprintf(bw, "%s_NUM_SYMBOLS = %d\n", prefix, len(e.codes))
+	fmt.Fprintf(bw, "%s_MAX_BITS = %d\n", prefix, e.maxSize)
+	if e.opts.MissingCode == MissingCodeEscape {
+		fmt.Fprintf(bw, "%s_ESCAPE_SYMBOL = %d\n", prefix, e.opts.FallbackSymbol)
+		fmt.Fprintf(bw, "%s_RAW_BITS = %d\n", prefix, rawSymbolBits(len(e.codes)))
+	}
+	fmt.Fprintf(bw, "\n# Code length in bits by symbol; 0 if the symbol has no code.\n")
+	fmt.Fprintf(bw, "%s_LENS = (\n", prefix)
+	writeArray(bw, "    ", lens, "%d", 16)
+	fmt.Fprintf(bw, ")\n\n# Code bits by symbol; the first bit is the least significant.\n")
+	fmt.Fprintf(bw, "%s_CODES = (\n", prefix)
+	writeArray(bw, "    ", codes, "0x%04x", 8)
+	fmt.Fprintf(bw, ")\n\n# (length, bits) by symbol, for each symbol with a code.\n")
+	fmt.Fprintf(bw, "%s_TABLE = {\n", prefix)
+	for symbol, hc := range e.codes {
+		if hc.Size != 0 {
+			fmt.Fprintf(bw, "    %d: (%d, 0x%04x),\n", symbol, hc.Size, hc.Bits)
+		}
+	}
+	fmt.Fprintf(bw, "}\n")
+	return bw.Flush()
+}
+
+// codegenTables checks that this Encoder and name are suitable for code
+// generation, and returns the lengths and bits of the codes by Symbol.
+func (e Encoder) codegenTables(fn string, name string) (lens []uint32, codes []uint32, err error) {
+	if !isIdentifier(name) {
+		return nil, nil, fmt.Errorf("%s: %q is not a valid identifier", fn, name)
+	}
+	if e.maxSize > maxBitsPerCode {
+		return nil, nil, fmt.Errorf("%s: code of %d bits does not fit in 16 bits", fn, e.maxSize)
+	}
+	lens = make([]uint32, len(e.codes))
+	codes = make([]uint32, len(e.codes))
+	for index, hc := range e.codes {
+		lens[index] = uint32(hc.Size)
+		codes[index] = hc.Bits
+	}
+	return lens, codes, nil
+}
+
+// writeArray writes the elements of an array literal, perLine to a line,
+// each line starting with indent and each element followed by a comma.
+func writeArray(bw *bufio.Writer, indent string, values []uint32, format string, perLine int) {
+	for index, value := range values {
+		if index%perLine == 0 {
+			bw.WriteString(indent)
+		} else {
+			bw.WriteString(" ")
+		}
+		fmt.Fprintf(bw, format, value)
+		bw.WriteString(",")
+		if index%perLine == perLine-1 || index == len(values)-1 {
+			bw.WriteString("\n")
+		}
+	}
+}
+
+// isIdentifier reports whether str is a valid identifier in C, Rust, and
+// Python: an ASCII letter or underscore, followed by any number of ASCII
+// letters, digits, or underscores.
+func isIdentifier(str string) bool {
+	if str == "" {
+		return false
+	}
+	for index := 0; index < len(str); index++ {
+		ch := str[index]
+		switch {
+		case ch == '_':
+		case ch >= 'a' && ch <= 'z':
+		case ch >= 'A' && ch <= 'Z':
+		case ch >= '0' && ch <= '9' && index != 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
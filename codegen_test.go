@@ -0,0 +1,123 @@
+package huffman
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEncoder_WriteCHeader(t *testing.T) {
+	sizes := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 9, 0}
+	e := NewEncoderFromSizesWithOptions(sizes, Options{MissingCode: MissingCodeEscape, FallbackSymbol: 9})
+
+	var buf bytes.Buffer
+	if err := e.WriteCHeader(&buf, "demo"); err != nil {
+		t.Fatalf("WriteCHeader: unexpected error: %v", err)
+	}
+	expect := `/* Code generated by huffman.Encoder.WriteCHeader. DO NOT EDIT. */
+
+#ifndef DEMO_H
+#define DEMO_H
+
+#include <stdint.h>
+
+#define demo_NUM_SYMBOLS 11
+#define demo_MAX_BITS 9
+#define demo_ESCAPE_SYMBOL 9
+#define demo_RAW_BITS 4
+
+/* Code length in bits by symbol; 0 if the symbol has no code. */
+static const uint16_t demo_lens[demo_NUM_SYMBOLS] = {
+	1, 2, 3, 4, 5, 6, 7, 8, 9, 9, 0,
+};
+
+/* Code bits by symbol; the first bit is the least significant. */
+static const uint16_t demo_codes[demo_NUM_SYMBOLS] = {
+	0x0000, 0x0001, 0x0003, 0x0007, 0x000f, 0x001f, 0x003f, 0x007f,
+	0x00ff, 0x01ff, 0x0000,
+};
+
+#endif /* DEMO_H */
+`
+	if actual := buf.String(); expect != actual {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+
+	for _, name := range []string{"", "9lives", "my-table", "tab le"} {
+		if err := e.WriteCHeader(&buf, name); err == nil {
+			t.Errorf("WriteCHeader(%q): expected an error", name)
+		}
+	}
+
+	long := Encoder{codes: []Code{{Size: 17}}, minSize: 17, maxSize: 17}
+	if err := long.WriteCHeader(&buf, "long"); err == nil || !strings.Contains(err.Error(), "17 bits") {
+		t.Errorf("WriteCHeader of a 17-bit code: expected an error, got %v", err)
+	}
+}
+
+func TestEncoder_WriteRustModule(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{1, 2, 0, 2})
+
+	var buf bytes.Buffer
+	if err := e.WriteRustModule(&buf, "demo"); err != nil {
+		t.Fatalf("WriteRustModule: unexpected error: %v", err)
+	}
+	expect := `// Code generated by huffman.Encoder.WriteRustModule. DO NOT EDIT.
+
+pub const DEMO_NUM_SYMBOLS: usize = 4;
+pub const DEMO_MAX_BITS: u32 = 2;
+
+/// Code length in bits by symbol; 0 if the symbol has no code.
+pub const DEMO_LENS: [u8; DEMO_NUM_SYMBOLS] = [
+    1, 2, 0, 2,
+];
+
+/// Code bits by symbol; the first bit is the least significant.
+pub const DEMO_CODES: [u16; DEMO_NUM_SYMBOLS] = [
+    0x0000, 0x0001, 0x0000, 0x0003,
+];
+`
+	if actual := buf.String(); expect != actual {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+	if err := e.WriteRustModule(&buf, "my-table"); err == nil {
+		t.Errorf("WriteRustModule(%q): expected an error", "my-table")
+	}
+}
+
+func TestEncoder_WritePythonModule(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{1, 2, 0, 2})
+
+	var buf bytes.Buffer
+	if err := e.WritePythonModule(&buf, "demo"); err != nil {
+		t.Fatalf("WritePythonModule: unexpected error: %v", err)
+	}
+	expect := `# Code generated by huffman.Encoder.WritePythonModule. DO NOT EDIT.
+
+DEMO_NUM_SYMBOLS = 4
+DEMO_MAX_BITS = 2
+
+# Code length in bits by symbol; 0 if the symbol has no code.
+DEMO_LENS = (
+    1, 2, 0, 2,
+)
+
+# Code bits by symbol; the first bit is the least significant.
+DEMO_CODES = (
+    0x0000, 0x0001, 0x0000, 0x0003,
+)
+
+# (length, bits) by symbol, for each symbol with a code.
+DEMO_TABLE = {
+    0: (1, 0x0000),
+    1: (2, 0x0001),
+    3: (2, 0x0003),
+}
+`
+	if actual := buf.String(); expect != actual {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+	if err := e.WritePythonModule(&buf, "my-table"); err == nil {
+		t.Errorf("WritePythonModule(%q): expected an error", "my-table")
+	}
+}
@@ -0,0 +1,222 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CoderSet manages the codes of a format which uses several alphabets
+// together, such as the literal/length and distance codes of DEFLATE.  Each
+// alphabet is declared once with a name, a size, and Options; after that,
+// the codes for a block can be built, validated, serialized, and parsed as a
+// unit, and Reset clears them all for the next block.
+//
+// The serialized form is the size table of each alphabet in declaration
+// order, as written by AppendSizes.  Names are not serialized, so the
+// reading side must declare the same alphabets in the same order.
+//
+// A CoderSet is not safe for concurrent modification.
+//
+type CoderSet struct {
+	entries []*coderSetEntry
+	byName  map[string]*coderSetEntry
+}
+
+type coderSetEntry struct {
+	name       string
+	numSymbols int
+	opts       Options
+	hasCode    bool
+	e          Encoder
+	d          Decoder
+}
+
+// NewCoderSet returns a new CoderSet with no alphabets.
+func NewCoderSet() *CoderSet {
+	return &CoderSet{byName: make(map[string]*coderSetEntry)}
+}
+
+// Declare adds an alphabet of numSymbols Symbols to this CoderSet.  Its code
+// is built and checked with the given Options.  Returns an error if the name
+// is already in use or the Options are invalid.
+func (cs *CoderSet) Declare(name string, numSymbols int, opts Options) error {
+	if _, found := cs.byName[name]; found {
+		return fmt.Errorf("alphabet %q already declared", name)
+	}
+	if numSymbols < 0 {
+		return fmt.Errorf("alphabet %q: numSymbols %d < 0", name, numSymbols)
+	}
+	if err := opts.validate(); err != nil {
+		return fmt.Errorf("alphabet %q: %w", name, err)
+	}
+	if err := opts.CheckAlphabet(numSymbols); err != nil {
+		return fmt.Errorf("alphabet %q: %w", name, err)
+	}
+	entry := &coderSetEntry{name: name, numSymbols: numSymbols, opts: opts}
+	if cs.byName == nil {
+		cs.byName = make(map[string]*coderSetEntry)
+	}
+	cs.entries = append(cs.entries, entry)
+	cs.byName[name] = entry
+	return nil
+}
+
+// Names returns the names of the alphabets, in declaration order.
+func (cs *CoderSet) Names() []string {
+	out := make([]string, len(cs.entries))
+	for index, entry := range cs.entries {
+		out[index] = entry.name
+	}
+	return out
+}
+
+func (cs *CoderSet) lookup(name string) (*coderSetEntry, error) {
+	entry, found := cs.byName[name]
+	if !found {
+		return nil, fmt.Errorf("no alphabet named %q", name)
+	}
+	return entry, nil
+}
+
+// Build builds the code for the named alphabet from the frequency of each of
+// its Symbols, as by Encoder.InitWithOptions.
+func (cs *CoderSet) Build(name string, frequencies []uint32) error {
+	entry, err := cs.lookup(name)
+	if err != nil {
+		return err
+	}
+	var e Encoder
+	if err := e.InitWithOptions(entry.numSymbols, frequencies, entry.opts); err != nil {
+		return fmt.Errorf("alphabet %q: %w", name, err)
+	}
+	return entry.set(e.SizeBySymbol())
+}
+
+// SetSizes sets the code for the named alphabet from the bit length of each
+// of its Symbols, as by Decoder.InitWithOptions.
+func (cs *CoderSet) SetSizes(name string, sizes []byte) error {
+	entry, err := cs.lookup(name)
+	if err != nil {
+		return err
+	}
+	tmp := make([]byte, len(sizes))
+	copy(tmp, sizes)
+	return entry.set(tmp)
+}
+
+func (entry *coderSetEntry) set(sizes []byte) error {
+	if len(sizes) != entry.numSymbols {
+		return fmt.Errorf("alphabet %q: expected %d Symbols, got %d", entry.name, entry.numSymbols, len(sizes))
+	}
+	var d Decoder
+	if err := d.InitWithOptions(sizes, entry.opts); err != nil {
+		return fmt.Errorf("alphabet %q: %w", entry.name, err)
+	}
+	var e Encoder
+	if err := e.InitFromSizesWithOptions(sizes, entry.opts); err != nil {
+		return fmt.Errorf("alphabet %q: %w", entry.name, err)
+	}
+	entry.e = e
+	entry.d = d
+	entry.hasCode = true
+	return nil
+}
+
+// Encoder returns the Encoder for the named alphabet, or nil if there is no
+// such alphabet or its code has not been set.
+func (cs *CoderSet) Encoder(name string) *Encoder {
+	if entry, found := cs.byName[name]; found && entry.hasCode {
+		return &entry.e
+	}
+	return nil
+}
+
+// Decoder returns the Decoder for the named alphabet, or nil if there is no
+// such alphabet or its code has not been set.
+func (cs *CoderSet) Decoder(name string) *Decoder {
+	if entry, found := cs.byName[name]; found && entry.hasCode {
+		return &entry.d
+	}
+	return nil
+}
+
+// Validate returns an error naming every alphabet whose code has not been
+// set, or nil if all of them have one.  The codes themselves were checked
+// against their Options when they were set.
+func (cs *CoderSet) Validate() error {
+	var errs []error
+	for _, entry := range cs.entries {
+		if !entry.hasCode {
+			errs = append(errs, fmt.Errorf("alphabet %q: no code", entry.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reset clears the code of every alphabet, keeping the declarations.
+func (cs *CoderSet) Reset() {
+	for _, entry := range cs.entries {
+		entry.hasCode = false
+		entry.e = Encoder{}
+		entry.d = Decoder{}
+	}
+}
+
+// AppendSizes appends the size table of every alphabet, in declaration
+// order, to dst and returns the extended slice.  Returns an error if
+// Validate does.
+func (cs *CoderSet) AppendSizes(dst []byte) ([]byte, error) {
+	if err := cs.Validate(); err != nil {
+		return dst, err
+	}
+	for _, entry := range cs.entries {
+		dst = AppendSizes(dst, entry.d.SizeBySymbol())
+	}
+	return dst, nil
+}
+
+// ReadSizes parses the size tables written by AppendSizes and sets the code
+// of every alphabet from them.  If it returns an error, the codes are left
+// as they were.
+func (cs *CoderSet) ReadSizes(r io.ByteReader) error {
+	list := make([][]byte, len(cs.entries))
+	for index, entry := range cs.entries {
+		sizes, err := ReadSizes(r, uint(entry.numSymbols))
+		if err != nil {
+			return fmt.Errorf("alphabet %q: %w", entry.name, err)
+		}
+		list[index] = sizes
+	}
+
+	tmp := make([]coderSetEntry, len(cs.entries))
+	for index, entry := range cs.entries {
+		tmp[index] = *entry
+		if err := tmp[index].set(list[index]); err != nil {
+			return err
+		}
+	}
+	for index, entry := range cs.entries {
+		*entry = tmp[index]
+	}
+	return nil
+}
+
+// MarshalBinary returns the output of AppendSizes.
+func (cs *CoderSet) MarshalBinary() ([]byte, error) {
+	return cs.AppendSizes(nil)
+}
+
+// UnmarshalBinary sets the codes from the output of MarshalBinary, as by
+// ReadSizes, and rejects trailing bytes.
+func (cs *CoderSet) UnmarshalBinary(raw []byte) error {
+	r := bytes.NewReader(raw)
+	if err := cs.ReadSizes(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d bytes of trailing garbage after Huffman size tables", r.Len())
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func makeTestCoderSet(t *testing.T) *CoderSet {
+	t.Helper()
+	cs := NewCoderSet()
+	if err := cs.Declare("literals", 8, Options{}); err != nil {
+		t.Fatalf("Declare: unexpected error: %v", err)
+	}
+	if err := cs.Declare("distances", 4, Options{Profile: ProfileRFC1951}); err != nil {
+		t.Fatalf("Declare: unexpected error: %v", err)
+	}
+	return cs
+}
+
+func TestCoderSet(t *testing.T) {
+	cs := makeTestCoderSet(t)
+	if expect, actual := []string{"literals", "distances"}, cs.Names(); !reflect.DeepEqual(expect, actual) {
+		t.Errorf("Names: expected %v, got %v", expect, actual)
+	}
+	if err := cs.Declare("literals", 3, Options{}); err == nil {
+		t.Errorf("Declare: expected error for a duplicate name")
+	}
+
+	err := cs.Validate()
+	if err == nil || !strings.Contains(err.Error(), `"literals"`) || !strings.Contains(err.Error(), `"distances"`) {
+		t.Errorf("Validate: expected errors for both alphabets, got %v", err)
+	}
+	if _, err := cs.MarshalBinary(); err == nil {
+		t.Errorf("MarshalBinary: expected error before the codes are set")
+	}
+
+	if err := cs.Build("literals", []uint32{10, 5, 5, 1, 1, 1, 0, 7}); err != nil {
+		t.Fatalf("Build: unexpected error: %v", err)
+	}
+	if err := cs.SetSizes("distances", []byte{1, 2, 3, 3}); err != nil {
+		t.Fatalf("SetSizes: unexpected error: %v", err)
+	}
+	if err := cs.SetSizes("distances", []byte{1, 2, 3}); err == nil {
+		t.Errorf("SetSizes: expected error for the wrong alphabet size")
+	}
+	if err := cs.SetSizes("lengths", []byte{1, 1}); err == nil {
+		t.Errorf("SetSizes: expected error for an unknown alphabet")
+	}
+	if err := cs.Validate(); err != nil {
+		t.Errorf("Validate: unexpected error: %v", err)
+	}
+
+	raw, err := cs.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: unexpected error: %v", err)
+	}
+	other := makeTestCoderSet(t)
+	if err := other.UnmarshalBinary(raw); err != nil {
+		t.Fatalf("UnmarshalBinary: unexpected error: %v", err)
+	}
+	for _, name := range cs.Names() {
+		if expect, actual := cs.Decoder(name).SizeBySymbol(), other.Decoder(name).SizeBySymbol(); !bytes.Equal(expect, actual) {
+			t.Errorf("%s: expected %v, got %v", name, expect, actual)
+		}
+		if expect, actual := cs.Encoder(name).SizeBySymbol(), other.Encoder(name).SizeBySymbol(); !bytes.Equal(expect, actual) {
+			t.Errorf("%s: expected %v, got %v", name, expect, actual)
+		}
+	}
+	if err := other.UnmarshalBinary(append(raw, 0)); err == nil {
+		t.Errorf("UnmarshalBinary: expected error for trailing garbage")
+	}
+
+	cs.Reset()
+	if cs.Encoder("literals") != nil || cs.Decoder("distances") != nil {
+		t.Errorf("Reset: expected no codes")
+	}
+	if cs.Validate() == nil {
+		t.Errorf("Validate after Reset: expected error")
+	}
+}
+
+func TestCoderSet_ReadSizesAtomic(t *testing.T) {
+	cs := makeTestCoderSet(t)
+	_ = cs.SetSizes("literals", []byte{3, 3, 3, 3, 3, 3, 3, 3})
+	_ = cs.SetSizes("distances", []byte{2, 2, 2, 2})
+
+	// The literal code is fine, but the distance code is incomplete,
+	// which ProfileRFC1951 forbids, so the whole read fails.
+	raw := AppendSizes(nil, []byte{1, 1, 0, 0, 0, 0, 0, 0})
+	raw = AppendSizes(raw, []byte{0, 2, 0, 0})
+	err := cs.UnmarshalBinary(raw)
+	if err == nil || !strings.Contains(err.Error(), `"distances"`) {
+		t.Fatalf("UnmarshalBinary: expected error for distances, got %v", err)
+	}
+	if expect, actual := []byte{3, 3, 3, 3, 3, 3, 3, 3}, cs.Decoder("literals").SizeBySymbol(); !bytes.Equal(expect, actual) {
+		t.Errorf("literals changed by a failed read: got %v", actual)
+	}
+
+	var zero CoderSet
+	if err := zero.Declare("only", 2, Options{MaxAlphabet: 1}); !errors.Is(err, ErrAlphabetTooLarge) {
+		t.Errorf("Declare: expected ErrAlphabetTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package huffman
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotPrefixFree is returned when a set of codes which must be prefix-free
+// has a code which is a prefix of another.
+var ErrNotPrefixFree = errors.New("Huffman code is not prefix-free")
+
+// CodeSet is a set of Codes, for designing and checking prefix codes which
+// were not built by this package, such as codes imported from a legacy
+// format or laid out by hand.  Every Code in a CodeSet is valid as defined by
+// Code.IsValid.  A CodeSet is not required to be prefix-free; use Check to
+// find out whether it is.
+//
+// Encoder and Decoder validate their code lengths directly and never build a
+// CodeSet; within this package, only CanonicalizeCodes uses one.
+//
+// The zero value is an empty CodeSet, ready to use.
+//
+type CodeSet struct {
+	codes map[Code]struct{}
+}
+
+// NewCodeSet is a convenience function that allocates a new CodeSet and adds
+// the given Codes to it.  If any Code is invalid, NewCodeSet panics.
+func NewCodeSet(codes ...Code) *CodeSet {
+	cs := new(CodeSet)
+	for _, hc := range codes {
+		if err := cs.Add(hc); err != nil {
+			panic(err)
+		}
+	}
+	return cs
+}
+
+// Add adds the given Code to this CodeSet.  Adding a Code which is already
+// present does nothing.  Returns an error if the Code is not valid.
+func (cs *CodeSet) Add(hc Code) error {
+	if !hc.IsValid() {
+		return fmt.Errorf("invalid code %#v", hc)
+	}
+	if cs.codes == nil {
+		cs.codes = make(map[Code]struct{})
+	}
+	cs.codes[hc] = struct{}{}
+	return nil
+}
+
+// Remove removes the given Code from this CodeSet.  Returns true if the Code
+// was present.
+func (cs *CodeSet) Remove(hc Code) bool {
+	if _, found := cs.codes[hc]; !found {
+		return false
+	}
+	delete(cs.codes, hc)
+	return true
+}
+
+// Contains returns true if the given Code is in this CodeSet.
+func (cs CodeSet) Contains(hc Code) bool {
+	_, found := cs.codes[hc]
+	return found
+}
+
+// Len returns the number of Codes in this CodeSet.
+func (cs CodeSet) Len() int {
+	return len(cs.codes)
+}
+
+// Codes returns the Codes in this CodeSet, ordered by Code.Packed.
+func (cs CodeSet) Codes() []Code {
+	out := make([]Code, 0, len(cs.codes))
+	for hc := range cs.codes {
+		out = append(out, hc)
+	}
+	sortCodes(out)
+	return out
+}
+
+// Conflict finds two Codes in this CodeSet such that prefix is a proper
+// prefix of hc.  Returns false if there are none, i.e. if the CodeSet is
+// prefix-free.  If there are several such pairs, the first by the
+// Code.Packed order of hc is returned.
+func (cs CodeSet) Conflict() (prefix Code, hc Code, found bool) {
+	for _, hc := range cs.Codes() {
+		for size := byte(1); size < hc.Size; size++ {
+			prefix := MakeCode(size, hc.Bits&lowBits(size))
+			if cs.Contains(prefix) {
+				return prefix, hc, true
+			}
+		}
+	}
+	return Code{}, Code{}, false
+}
+
+// Check returns an error wrapping ErrNotPrefixFree if this CodeSet is not
+// prefix-free, or nil if it is.
+func (cs CodeSet) Check() error {
+	if prefix, hc, found := cs.Conflict(); found {
+		return fmt.Errorf("%w: code %s is a prefix of code %s", ErrNotPrefixFree, prefix, hc)
+	}
+	return nil
+}
+
+// KraftSum returns the sum of 2**-Size over the Codes in this CodeSet.  A
+// prefix-free CodeSet has a sum of at most 1, and a sum of exactly 1 if it
+// is complete, i.e. if every sequence of bits begins with one of its Codes.
+func (cs CodeSet) KraftSum() float64 {
+	var sum float64
+	for hc := range cs.codes {
+		sum += 1 / float64(uint64(1)<<hc.Size)
+	}
+	return sum
+}
+
+// IsComplete returns true if this CodeSet is prefix-free and complete, i.e.
+// if every sequence of bits begins with exactly one of its Codes.
+func (cs CodeSet) IsComplete() bool {
+	if cs.Check() != nil {
+		return false
+	}
+	var used uint64
+	for hc := range cs.codes {
+		used += uint64(1) << (32 - hc.Size)
+	}
+	return used == uint64(1)<<32
+}
+
+// Completion returns the fewest Codes which, added to this CodeSet, would
+// make it complete, ordered by Code.Packed.  These are the unused branches of
+// the code tree.  A complete CodeSet needs none, and an empty CodeSet needs
+// the two 1-bit Codes.  Returns an error wrapping ErrNotPrefixFree if this
+// CodeSet is not prefix-free.
+func (cs CodeSet) Completion() ([]Code, error) {
+	if err := cs.Check(); err != nil {
+		return nil, err
+	}
+
+	// The internal nodes of the code tree are the proper prefixes of
+	// every Code, plus the root.  Each child of an internal node which
+	// is neither a Code nor an internal node itself is unused.
+	internal := map[Code]struct{}{{}: {}}
+	for hc := range cs.codes {
+		for size := byte(1); size < hc.Size; size++ {
+			internal[MakeCode(size, hc.Bits&lowBits(size))] = struct{}{}
+		}
+	}
+
+	var out []Code
+	for node := range internal {
+		for bit := uint32(0); bit < 2; bit++ {
+			child := MakeCode(node.Size+1, node.Bits|bit<<node.Size)
+			if _, found := internal[child]; found {
+				continue
+			}
+			if !cs.Contains(child) {
+				out = append(out, child)
+			}
+		}
+	}
+	sortCodes(out)
+	return out, nil
+}
+
+// lowBits returns a mask of the low size bits.
+func lowBits(size byte) uint32 {
+	return uint32(uint64(1)<<size - 1)
+}
@@ -0,0 +1,114 @@
+package huffman
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCodeSet(t *testing.T) {
+	type testRow struct {
+		name       string
+		codes      []Code
+		prefixFree bool
+		complete   bool
+		kraftSum   float64
+		completion []Code
+	}
+
+	testData := [...]testRow{
+		{
+			name:       "empty",
+			prefixFree: true,
+			completion: []Code{MakeCode(1, 0), MakeCode(1, 1)},
+		},
+		{
+			name:       "complete",
+			codes:      []Code{MakeCode(1, 0), MakeCode(2, 1), MakeCode(2, 3)},
+			prefixFree: true,
+			complete:   true,
+			kraftSum:   1,
+		},
+		{
+			name:       "one-short",
+			codes:      []Code{MakeCode(1, 0)},
+			prefixFree: true,
+			kraftSum:   0.5,
+			completion: []Code{MakeCode(1, 1)},
+		},
+		{
+			name:       "deep",
+			codes:      []Code{MakeCode(3, 0x5), MakeCode(2, 0x0)},
+			prefixFree: true,
+			kraftSum:   0.375,
+			completion: []Code{MakeCode(2, 0x2), MakeCode(2, 0x3), MakeCode(3, 0x1)},
+		},
+		{
+			name:     "not-prefix-free",
+			codes:    []Code{MakeCode(1, 1), MakeCode(3, 0x5)},
+			kraftSum: 0.625,
+		},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			cs := NewCodeSet(row.codes...)
+			if actual := cs.Len(); actual != len(row.codes) {
+				t.Errorf("Len: expected %d, got %d", len(row.codes), actual)
+			}
+			if err := cs.Check(); (err == nil) != row.prefixFree {
+				t.Errorf("Check: expected prefix-free %t, got %v", row.prefixFree, err)
+			} else if err != nil && !errors.Is(err, ErrNotPrefixFree) {
+				t.Errorf("Check: expected ErrNotPrefixFree, got %v", err)
+			}
+			if actual := cs.IsComplete(); actual != row.complete {
+				t.Errorf("IsComplete: expected %t, got %t", row.complete, actual)
+			}
+			if actual := cs.KraftSum(); actual != row.kraftSum {
+				t.Errorf("KraftSum: expected %g, got %g", row.kraftSum, actual)
+			}
+			completion, err := cs.Completion()
+			if !row.prefixFree {
+				if !errors.Is(err, ErrNotPrefixFree) {
+					t.Errorf("Completion: expected ErrNotPrefixFree, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Completion: unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(completion, row.completion) {
+				t.Errorf("Completion: expected %v, got %v", row.completion, completion)
+			}
+			for _, hc := range completion {
+				_ = cs.Add(hc)
+			}
+			if !cs.IsComplete() {
+				t.Errorf("IsComplete after adding the completion: expected true, got false")
+			}
+		})
+	}
+}
+
+func TestCodeSet_AddRemove(t *testing.T) {
+	var cs CodeSet
+	if err := cs.Add(MakeCode(2, 4)); err == nil {
+		t.Errorf("Add: expected error for an invalid Code")
+	}
+	if err := cs.Add(MakeCode(2, 3)); err != nil {
+		t.Errorf("Add: unexpected error: %v", err)
+	}
+	_ = cs.Add(MakeCode(2, 3))
+	_ = cs.Add(MakeCode(1, 0))
+	if expected, actual := []Code{MakeCode(1, 0), MakeCode(2, 3)}, cs.Codes(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Codes: expected %v, got %v", expected, actual)
+	}
+	if !cs.Contains(MakeCode(2, 3)) || cs.Contains(MakeCode(2, 1)) {
+		t.Errorf("Contains: wrong result")
+	}
+	if !cs.Remove(MakeCode(2, 3)) || cs.Remove(MakeCode(2, 3)) {
+		t.Errorf("Remove: wrong result")
+	}
+	if actual := cs.Len(); actual != 1 {
+		t.Errorf("Len: expected 1, got %d", actual)
+	}
+}
@@ -0,0 +1,142 @@
+package huffman
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CodeSpec is a flag.Value which selects a Huffman code from the command
+// line.  It accepts any of:
+//
+//     fixed-deflate-litlen    a preset; see CodeSpecPresets
+//     file:dict.huffdict      a dictionary file written by SaveDict
+//     [4,4,3,3,3,1]           an inline list of bit lengths
+//     4,4,3,3,3,1             the same, without brackets
+//
+// CodeSpec also has the Type method required by github.com/spf13/pflag, so
+// it can be used with either flag package.
+//
+type CodeSpec struct {
+	// Dict holds the selected code.
+	Dict Dict
+
+	spec string
+}
+
+// codeSpecPresets maps preset names to functions returning their sizes.
+var codeSpecPresets = map[string]func() []byte{
+	"fixed-deflate-litlen": fixedDeflateLitLenSizes,
+	"fixed-deflate-dist":   fixedDeflateDistSizes,
+}
+
+// CodeSpecPresets returns the names of the presets accepted by CodeSpec.Set,
+// in sorted order.
+func CodeSpecPresets() []string {
+	names := make([]string, 0, len(codeSpecPresets))
+	for name := range codeSpecPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fixedDeflateLitLenSizes returns the bit lengths of DEFLATE's fixed
+// literal/length code, per RFC 1951 Section 3.2.6.
+func fixedDeflateLitLenSizes() []byte {
+	sizes := make([]byte, DeflateLitLenAlphabetSize)
+	for symbol := range sizes {
+		switch {
+		case symbol < 144:
+			sizes[symbol] = 8
+		case symbol < 256:
+			sizes[symbol] = 9
+		case symbol < 280:
+			sizes[symbol] = 7
+		default:
+			sizes[symbol] = 8
+		}
+	}
+	return sizes
+}
+
+// fixedDeflateDistSizes returns the bit lengths of DEFLATE's fixed distance
+// code, per RFC 1951 Section 3.2.6.
+func fixedDeflateDistSizes() []byte {
+	sizes := make([]byte, DeflateDistAlphabetSize)
+	for symbol := range sizes {
+		sizes[symbol] = 5
+	}
+	return sizes
+}
+
+// Set parses spec and selects the code it describes.
+func (cs *CodeSpec) Set(spec string) error {
+	var dict Dict
+	switch {
+	case codeSpecPresets[spec] != nil:
+		dict.Sizes = codeSpecPresets[spec]()
+
+	case strings.HasPrefix(spec, "file:"):
+		f, err := os.Open(strings.TrimPrefix(spec, "file:"))
+		if err != nil {
+			return err
+		}
+		dict, err = LoadDict(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+	default:
+		text := strings.TrimSpace(spec)
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			text = text[1 : len(text)-1]
+		}
+		sizes, err := parseSizes([]byte(text))
+		if err != nil {
+			return fmt.Errorf("invalid code %q: expected a preset (%s), file:<path>, or a list of bit lengths: %w", spec, strings.Join(CodeSpecPresets(), ", "), err)
+		}
+		dict.Sizes = sizes
+	}
+
+	if _, err := dict.Decoder(); err != nil {
+		return fmt.Errorf("invalid code %q: %w", spec, err)
+	}
+	*cs = CodeSpec{Dict: dict, spec: spec}
+	return nil
+}
+
+// String returns the spec passed to Set or, if Dict was filled in directly,
+// an inline list of its bit lengths.
+func (cs *CodeSpec) String() string {
+	if cs == nil {
+		return ""
+	}
+	if cs.spec != "" {
+		return cs.spec
+	}
+	if cs.Dict.Sizes == nil {
+		return ""
+	}
+	return "[" + string(formatSizes(cs.Dict.Sizes)) + "]"
+}
+
+// Type returns the name of the flag's type, for github.com/spf13/pflag.
+func (cs *CodeSpec) Type() string {
+	return "huffman-code"
+}
+
+// Encoder returns a new Encoder for the selected code.
+func (cs *CodeSpec) Encoder() (*Encoder, error) {
+	return cs.Dict.Encoder()
+}
+
+// Decoder returns a new Decoder for the selected code.
+func (cs *CodeSpec) Decoder() (*Decoder, error) {
+	return cs.Dict.Decoder()
+}
+
+var _ flag.Value = (*CodeSpec)(nil)
@@ -0,0 +1,72 @@
+package huffman
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodeSpec(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.huffdict")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveDict(f, Dict{Sizes: []byte{2, 2, 2, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	type testRow struct {
+		spec    string
+		sizes   []byte
+		numSyms int
+	}
+
+	testData := [...]testRow{
+		{spec: "[4,4,3,3,3,1]", sizes: []byte{4, 4, 3, 3, 3, 1}},
+		{spec: "4, 4, 3, 3, 3, 1", sizes: []byte{4, 4, 3, 3, 3, 1}},
+		{spec: "file:" + path, sizes: []byte{2, 2, 2, 2}},
+		{spec: "fixed-deflate-litlen", numSyms: 288},
+		{spec: "fixed-deflate-dist", numSyms: 32},
+	}
+	for _, row := range testData {
+		t.Run(row.spec, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			var cs CodeSpec
+			fs.Var(&cs, "code", "Huffman code")
+			if err := fs.Parse([]string{"-code", row.spec}); err != nil {
+				t.Fatalf("Parse: unexpected error: %v", err)
+			}
+			if row.sizes != nil && !bytes.Equal(row.sizes, cs.Dict.Sizes) {
+				t.Errorf("wrong sizes:\n\texpect: %v\n\tactual: %v", row.sizes, cs.Dict.Sizes)
+			}
+			if row.numSyms != 0 && row.numSyms != len(cs.Dict.Sizes) {
+				t.Errorf("expected %d symbols, got %d", row.numSyms, len(cs.Dict.Sizes))
+			}
+			if cs.String() != row.spec {
+				t.Errorf("String: expected %q, got %q", row.spec, cs.String())
+			}
+			if _, err := cs.Encoder(); err != nil {
+				t.Errorf("Encoder: unexpected error: %v", err)
+			}
+		})
+	}
+
+	var cs CodeSpec
+	for _, bad := range []string{"no-such-preset", "[1,1,1]", "file:" + filepath.Join(dir, "missing")} {
+		if err := cs.Set(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+
+	cs = CodeSpec{Dict: Dict{Sizes: []byte{1, 1}}}
+	if expect, actual := "[1,1]", cs.String(); expect != actual {
+		t.Errorf("String: expected %q, got %q", expect, actual)
+	}
+}
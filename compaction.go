@@ -0,0 +1,86 @@
+package huffman
+
+import (
+	"slices"
+)
+
+// UsedSymbols returns the number of Symbols which have a code.
+func (e Encoder) UsedSymbols() int {
+	n := 0
+	for _, hc := range e.codes {
+		if hc.Size != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// CompactionPlan is a renumbering of an alphabet which drops the Symbols that
+// have no code, so that a caller who passed an oversized alphabet can shrink
+// its tables and the headers which transmit them.  The compacted alphabet
+// keeps the used Symbols in their original order, and the compacted code
+// gives each of them the same bit length as before, so the cost of coding
+// any message is unchanged.
+//
+// The mapping is serialized by storing Dict() in the dictionary file format,
+// whose Remap gives the original Symbol for each compacted one.
+//
+type CompactionPlan struct {
+	// NumSymbols is the number of Symbols in the original alphabet.
+	NumSymbols int
+
+	// Remap holds the original Symbol for each Symbol of the compacted
+	// alphabet, in ascending order.
+	Remap []Symbol
+
+	// Sizes holds the bit length of each Symbol of the compacted alphabet.
+	Sizes []byte
+}
+
+// CompactionPlan returns the plan for dropping the Symbols of this Encoder's
+// alphabet which have no code.
+func (e Encoder) CompactionPlan() CompactionPlan {
+	n := e.UsedSymbols()
+	plan := CompactionPlan{
+		NumSymbols: len(e.codes),
+		Remap:      make([]Symbol, 0, n),
+		Sizes:      make([]byte, 0, n),
+	}
+	for symbol, hc := range e.codes {
+		if hc.Size != 0 {
+			plan.Remap = append(plan.Remap, Symbol(symbol))
+			plan.Sizes = append(plan.Sizes, hc.Size)
+		}
+	}
+	return plan
+}
+
+// Dropped returns the number of Symbols which the plan drops.
+func (plan CompactionPlan) Dropped() int {
+	return plan.NumSymbols - len(plan.Remap)
+}
+
+// Compact returns the compacted Symbol which stands for the given original
+// Symbol, or false if the plan drops it.
+func (plan CompactionPlan) Compact(symbol Symbol) (Symbol, bool) {
+	index, found := slices.BinarySearch(plan.Remap, symbol)
+	if !found {
+		return InvalidSymbol, false
+	}
+	return Symbol(index), true
+}
+
+// Original returns the original Symbol for the given compacted Symbol, or
+// InvalidSymbol if it is out of range.
+func (plan CompactionPlan) Original(symbol Symbol) Symbol {
+	if symbol < 0 || int(symbol) >= len(plan.Remap) {
+		return InvalidSymbol
+	}
+	return plan.Remap[symbol]
+}
+
+// Dict returns the compacted code together with its mapping to the original
+// alphabet.
+func (plan CompactionPlan) Dict() Dict {
+	return Dict{Sizes: plan.Sizes, Remap: plan.Remap}
+}
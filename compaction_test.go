@@ -0,0 +1,68 @@
+package huffman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncoder_CompactionPlan(t *testing.T) {
+	type testRow struct {
+		name  string
+		freqs []uint32
+		remap []Symbol
+	}
+
+	testData := [...]testRow{
+		{name: "empty", freqs: []uint32{0, 0, 0}, remap: []Symbol{}},
+		{name: "dense", freqs: []uint32{1, 2, 3}, remap: []Symbol{0, 1, 2}},
+		{name: "sparse", freqs: []uint32{0, 5, 0, 0, 1, 1, 0, 9, 0}, remap: []Symbol{1, 4, 5, 7}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var e Encoder
+			e.Init(len(row.freqs), row.freqs)
+			if actual := e.UsedSymbols(); actual != len(row.remap) {
+				t.Errorf("UsedSymbols: expected %d, got %d", len(row.remap), actual)
+			}
+
+			plan := e.CompactionPlan()
+			if !reflect.DeepEqual(plan.Remap, row.remap) {
+				t.Errorf("Remap: expected %v, got %v", row.remap, plan.Remap)
+			}
+			if expect, actual := len(row.freqs)-len(row.remap), plan.Dropped(); expect != actual {
+				t.Errorf("Dropped: expected %d, got %d", expect, actual)
+			}
+			for symbol := range row.freqs {
+				compact, ok := plan.Compact(Symbol(symbol))
+				if ok != (row.freqs[symbol] != 0) {
+					t.Errorf("Compact(%d): expected %t, got %t", symbol, row.freqs[symbol] != 0, ok)
+					continue
+				}
+				if !ok {
+					continue
+				}
+				if actual := plan.Original(compact); actual != Symbol(symbol) {
+					t.Errorf("Original(Compact(%d)): got %d", symbol, actual)
+				}
+				if expect, actual := e.Encode(Symbol(symbol)).Size, plan.Sizes[compact]; expect != actual {
+					t.Errorf("Sizes[%d]: expected %d, got %d", compact, expect, actual)
+				}
+			}
+			if actual := plan.Original(Symbol(len(row.remap))); actual != InvalidSymbol {
+				t.Errorf("Original out of range: expected InvalidSymbol, got %d", actual)
+			}
+
+			raw, err := plan.Dict().MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary: unexpected error: %v", err)
+			}
+			var dict Dict
+			if err := dict.UnmarshalBinary(raw); err != nil {
+				t.Fatalf("UnmarshalBinary: unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(dict.Remap, plan.Remap) || !reflect.DeepEqual(dict.Sizes, plan.Sizes) {
+				t.Errorf("Dict round trip: expected %v, got %v", plan.Dict(), dict)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package huffman
+
+import (
+	"math"
+	"slices"
+)
+
+// SampleReport describes the coding of one sample by EvaluateOnCorpus.
+type SampleReport struct {
+	// InputBytes is the size of the sample.
+	InputBytes int
+
+	// CompressedBytes is the size of the coded sample, rounded up to a
+	// whole byte.  It is 0 if Uncodable.
+	CompressedBytes int
+
+	// Expanded is true if CompressedBytes exceeds InputBytes.
+	Expanded bool
+
+	// Uncodable is true if the sample holds a byte which the Encoder cannot
+	// code, either because it has no code or because the Encoder has too
+	// few Symbols.
+	Uncodable bool
+}
+
+// CorpusReport summarizes the compressed sizes of a corpus of samples coded
+// with one Encoder.  See EvaluateOnCorpus.  Uncodable samples are excluded
+// from every statistic except Uncodable itself.
+type CorpusReport struct {
+	// Samples describes each sample, in order.
+	Samples []SampleReport
+
+	// Count is the number of codable samples.
+	Count int
+
+	// Expanded is the number of samples which expanded when coded.
+	Expanded int
+
+	// Uncodable is the number of samples which could not be coded.
+	Uncodable int
+
+	// InputBytes is the total size of the codable samples.
+	InputBytes uint64
+
+	// CompressedBytes is the total size of the coded samples.
+	CompressedBytes uint64
+
+	// MeanBytes is the mean compressed size of a sample.
+	MeanBytes float64
+
+	// P50, P90, P99, and MaxBytes are percentiles of the compressed size of
+	// a sample, as returned by Percentile.
+	P50      int
+	P90      int
+	P99      int
+	MaxBytes int
+
+	sorted []int
+}
+
+// Ratio returns CompressedBytes divided by InputBytes, or 0 if InputBytes is
+// 0.
+func (r CorpusReport) Ratio() float64 {
+	if r.InputBytes == 0 {
+		return 0
+	}
+	return float64(r.CompressedBytes) / float64(r.InputBytes)
+}
+
+// Percentile returns the compressed size which no more than p percent of the
+// codable samples exceed, by the nearest-rank method, or 0 if there are no
+// codable samples.  For instance, Percentile(99) is the size to plan for in
+// a 99% service level objective.
+func (r CorpusReport) Percentile(p float64) int {
+	if len(r.sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(r.sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(r.sorted) {
+		rank = len(r.sorted)
+	}
+	return r.sorted[rank-1]
+}
+
+// EvaluateOnCorpus codes each sample, a sequence of byte Symbols, with e as
+// by BitWriter.WriteSymbol, and reports the distribution of the compressed
+// sizes.  It is meant for checking a trained Encoder, such as that of a Dict
+// from TrainStreamDict, against representative inputs before deploying it:
+// the percentiles and the number of Expanded samples show what size limits
+// it can promise.  No data is actually written.
+func EvaluateOnCorpus(e *Encoder, samples [][]byte) CorpusReport {
+	var r CorpusReport
+	r.Samples = make([]SampleReport, len(samples))
+	r.sorted = make([]int, 0, len(samples))
+	for index, sample := range samples {
+		sr := SampleReport{InputBytes: len(sample)}
+		var bits uint64
+		for _, b := range sample {
+			var size byte
+			if int(b) < len(e.codes) {
+				size = e.symbolBits(Symbol(b))
+			}
+			if size == 0 {
+				sr.Uncodable = true
+				break
+			}
+			bits += uint64(size)
+		}
+		if sr.Uncodable {
+			r.Samples[index] = sr
+			r.Uncodable++
+			continue
+		}
+		sr.CompressedBytes = int((bits + 7) / 8)
+		sr.Expanded = sr.CompressedBytes > sr.InputBytes
+		r.Samples[index] = sr
+
+		r.Count++
+		if sr.Expanded {
+			r.Expanded++
+		}
+		r.InputBytes += uint64(sr.InputBytes)
+		r.CompressedBytes += uint64(sr.CompressedBytes)
+		r.sorted = append(r.sorted, sr.CompressedBytes)
+	}
+
+	slices.Sort(r.sorted)
+	if r.Count != 0 {
+		r.MeanBytes = float64(r.CompressedBytes) / float64(r.Count)
+		r.MaxBytes = r.sorted[len(r.sorted)-1]
+	}
+	r.P50 = r.Percentile(50)
+	r.P90 = r.Percentile(90)
+	r.P99 = r.Percentile(99)
+	return r
+}
@@ -0,0 +1,71 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEvaluateOnCorpus(t *testing.T) {
+	// Symbol 0 has a 1-bit code, 1 has 2 bits, 2 and 3 have 3 bits, and 4
+	// has none.
+	e := NewEncoderFromSizes([]byte{1, 2, 3, 3, 0})
+
+	samples := [][]byte{
+		bytes.Repeat([]byte{0}, 16), // 16 bits
+		bytes.Repeat([]byte{1}, 8),  // 16 bits
+		{2, 3, 2},                   // 9 bits
+		{0, 4},                      // no code for 4
+		{7},                         // too few Symbols
+		{},                          // empty
+		bytes.Repeat([]byte{3}, 40), // 120 bits
+	}
+	r := EvaluateOnCorpus(e, samples)
+
+	expectSamples := []SampleReport{
+		{InputBytes: 16, CompressedBytes: 2},
+		{InputBytes: 8, CompressedBytes: 2},
+		{InputBytes: 3, CompressedBytes: 2},
+		{InputBytes: 2, Uncodable: true},
+		{InputBytes: 1, Uncodable: true},
+		{InputBytes: 0, CompressedBytes: 0},
+		{InputBytes: 40, CompressedBytes: 15},
+	}
+	for index, expect := range expectSamples {
+		if actual := r.Samples[index]; actual != expect {
+			t.Errorf("sample %d: expected %+v, got %+v", index, expect, actual)
+		}
+	}
+
+	if r.Count != 5 || r.Uncodable != 2 || r.Expanded != 0 {
+		t.Errorf("expected 5 codable, 2 uncodable, 0 expanded; got %d, %d, %d", r.Count, r.Uncodable, r.Expanded)
+	}
+	if r.InputBytes != 67 || r.CompressedBytes != 21 {
+		t.Errorf("expected 67 bytes coded to 21, got %d to %d", r.InputBytes, r.CompressedBytes)
+	}
+	if r.MeanBytes != 4.2 {
+		t.Errorf("MeanBytes: expected 4.2, got %v", r.MeanBytes)
+	}
+	if r.P50 != 2 || r.P90 != 15 || r.P99 != 15 || r.MaxBytes != 15 {
+		t.Errorf("expected percentiles 2/15/15/15, got %d/%d/%d/%d", r.P50, r.P90, r.P99, r.MaxBytes)
+	}
+	if actual := r.Percentile(0); actual != 0 {
+		t.Errorf("Percentile(0): expected 0, got %d", actual)
+	}
+	if actual := r.Percentile(20); actual != 0 {
+		t.Errorf("Percentile(20): expected 0, got %d", actual)
+	}
+	if actual := r.Percentile(21); actual != 2 {
+		t.Errorf("Percentile(21): expected 2, got %d", actual)
+	}
+
+	skewed := NewEncoderFromSizes([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 9})
+	r = EvaluateOnCorpus(skewed, [][]byte{{9}, {0, 9}})
+	if r.Expanded != 1 || !r.Samples[0].Expanded || r.Samples[1].Expanded {
+		t.Errorf("expected a 1-byte sample to expand, got %+v", r)
+	}
+
+	r = EvaluateOnCorpus(e, nil)
+	if r.Count != 0 || r.P99 != 0 || r.MeanBytes != 0 || r.Ratio() != 0 {
+		t.Errorf("expected an empty report, got %+v", r)
+	}
+}
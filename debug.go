@@ -0,0 +1,196 @@
+package huffman
+
+import (
+	"fmt"
+	"cmp"
+	"io"
+	"slices"
+	"strings"
+)
+
+// DebugOrder selects the order in which DebugStringWith lists Symbols.
+type DebugOrder byte
+
+const (
+	// OrderBySymbol lists Symbols in ascending order.
+	OrderBySymbol DebugOrder = iota
+
+	// OrderBySize lists the Symbols with the shortest codes first.
+	// Symbols with codes of equal length are listed in ascending order,
+	// and Symbols without codes are listed last.
+	OrderBySize
+
+	// OrderByFrequency lists the Symbols with the highest frequencies
+	// first, per DebugOptions.Frequencies.  Symbols with equal frequencies
+	// are listed in ascending order.
+	OrderByFrequency
+)
+
+var debugOrderNames = []string{
+	"OrderBySymbol",
+	"OrderBySize",
+	"OrderByFrequency",
+}
+
+// String returns the name of this DebugOrder.
+func (order DebugOrder) String() string {
+	if uint(order) < uint(len(debugOrderNames)) {
+		return debugOrderNames[order]
+	}
+	return fmt.Sprintf("DebugOrder(%d)", uint(order))
+}
+
+// DebugOptions controls the output of DebugStringWith and DumpWith.  For an
+// Encoder, the zero value gives the same output as DebugString.
+type DebugOptions struct {
+	// Order selects the order of the listing.  Ties are broken by Symbol.
+	Order DebugOrder
+
+	// AssignedOnly, if true, omits Symbols which have no code.
+	AssignedOnly bool
+
+	// MaxRows, if positive, limits the listing to that many Symbols.  A
+	// final line reports how many were left out.
+	MaxRows int
+
+	// SymbolName, if non-nil, returns a name for each Symbol, which is
+	// shown alongside its code.  If nil, Options.SymbolName is used.
+	SymbolName func(Symbol) string
+
+	// Frequencies, if non-nil, gives the frequency of each Symbol, which
+	// is shown alongside its code and is used by OrderByFrequency.
+	Frequencies []uint32
+}
+
+type debugRow struct {
+	symbol Symbol
+	hc     Code
+	freq   uint32
+}
+
+// debugRows returns the rows to list for the given codes, filtered, sorted,
+// and truncated per opts, along with the number of rows omitted by MaxRows.
+func debugRows(codes []Code, opts DebugOptions) ([]debugRow, int) {
+	rows := make([]debugRow, 0, len(codes))
+	for index, hc := range codes {
+		if opts.AssignedOnly && hc.Size == 0 {
+			continue
+		}
+		row := debugRow{symbol: Symbol(index), hc: hc}
+		if index < len(opts.Frequencies) {
+			row.freq = opts.Frequencies[index]
+		}
+		rows = append(rows, row)
+	}
+
+	switch opts.Order {
+	case OrderBySize:
+		slices.SortFunc(rows, func(a, b debugRow) int {
+			if (a.hc.Size == 0) != (b.hc.Size == 0) {
+				if a.hc.Size == 0 {
+					return 1
+				}
+				return -1
+			}
+			if c := cmp.Compare(a.hc.Size, b.hc.Size); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.symbol, b.symbol)
+		})
+	case OrderByFrequency:
+		slices.SortFunc(rows, func(a, b debugRow) int {
+			if c := cmp.Compare(b.freq, a.freq); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.symbol, b.symbol)
+		})
+	}
+
+	var omitted int
+	if opts.MaxRows > 0 && len(rows) > opts.MaxRows {
+		omitted = len(rows) - opts.MaxRows
+		rows = rows[:opts.MaxRows]
+	}
+	return rows, omitted
+}
+
+func writeDebugRows(buf *strings.Builder, codes []Code, opts DebugOptions, format func(Symbol, Code) string) {
+	rows, omitted := debugRows(codes, opts)
+	for _, row := range rows {
+		buf.WriteByte('\t')
+		buf.WriteString(format(row.symbol, row.hc))
+
+		var notes []string
+		if opts.SymbolName != nil {
+			notes = append(notes, opts.SymbolName(row.symbol))
+		}
+		if opts.Frequencies != nil {
+			notes = append(notes, fmt.Sprintf("freq=%d", row.freq))
+		}
+		if len(notes) != 0 {
+			buf.WriteString("  // ")
+			buf.WriteString(strings.Join(notes, ", "))
+		}
+		buf.WriteByte('\n')
+	}
+	if omitted != 0 {
+		fmt.Fprintf(buf, "\t// ... %d more\n", omitted)
+	}
+}
+
+// DebugStringWith is like DebugString, but with the listing of codes
+// controlled by opts.
+func (e Encoder) DebugStringWith(opts DebugOptions) string {
+	if opts.SymbolName == nil {
+		opts.SymbolName = e.opts.SymbolName
+	}
+	var buf strings.Builder
+	buf.WriteString("Encoder{\n")
+	fmt.Fprintf(&buf, "\tMinSize() = %d\n", e.minSize)
+	fmt.Fprintf(&buf, "\tMaxSize() = %d\n", e.maxSize)
+	writeDebugRows(&buf, e.codes, opts, func(symbol Symbol, hc Code) string {
+		if hc.Size == 0 {
+			return fmt.Sprintf("Encode(%d) = nil", symbol)
+		}
+		return fmt.Sprintf("Encode(%d) = %s", symbol, hc)
+	})
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// DumpWith writes DebugStringWith(opts) to the given writer.
+func (e Encoder) DumpWith(w io.Writer, opts DebugOptions) (int64, error) {
+	r := strings.NewReader(e.DebugStringWith(opts))
+	return r.WriteTo(w)
+}
+
+// DebugStringWith returns a programmer-readable debugging string listing
+// each complete code of this Decoder, controlled by opts.  Unlike
+// DebugString, it omits the entries for partial codes.  Symbols without
+// codes are always omitted.
+func (d Decoder) DebugStringWith(opts DebugOptions) string {
+	var codes []Code
+	if d.maxSize != 0 {
+		codes = d.Encoder().codes
+	}
+	opts.AssignedOnly = true
+	if opts.SymbolName == nil {
+		opts.SymbolName = d.opts.SymbolName
+	}
+
+	var buf strings.Builder
+	buf.WriteString("Decoder{\n")
+	fmt.Fprintf(&buf, "\tMinSize() = %d\n", d.minSize)
+	fmt.Fprintf(&buf, "\tMaxSize() = %d\n", d.maxSize)
+	writeDebugRows(&buf, codes, opts, func(symbol Symbol, hc Code) string {
+		return fmt.Sprintf("Decode(%s) = %d", hc, symbol)
+	})
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// DumpWith writes DebugStringWith(opts) to the given writer.
+func (d Decoder) DumpWith(w io.Writer, opts DebugOptions) (int64, error) {
+	r := strings.NewReader(d.DebugStringWith(opts))
+	return r.WriteTo(w)
+}
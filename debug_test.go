@@ -0,0 +1,76 @@
+package huffman
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncoder_DebugStringWith(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{4, 4, 3, 0, 3, 3, 1})
+	freqs := []uint32{5, 9, 12, 0, 13, 16, 45}
+	names := func(symbol Symbol) string {
+		return string(rune('a' + symbol))
+	}
+
+	type testRow struct {
+		name   string
+		opts   DebugOptions
+		expect []string
+	}
+
+	testData := [...]testRow{
+		{
+			name: "by-size",
+			opts: DebugOptions{Order: OrderBySize, SymbolName: names},
+			expect: []string{
+				"\tEncode(6) = \"0\"  // g\n",
+				"\tEncode(2) = \"001\"  // c\n",
+				"\tEncode(4) = \"101\"  // e\n",
+				"\tEncode(5) = \"011\"  // f\n",
+				"\tEncode(0) = \"0111\"  // a\n",
+				"\tEncode(1) = \"1111\"  // b\n",
+				"\tEncode(3) = nil  // d\n",
+			},
+		},
+		{
+			name: "by-frequency",
+			opts: DebugOptions{Order: OrderByFrequency, AssignedOnly: true, MaxRows: 3, Frequencies: freqs},
+			expect: []string{
+				"\tEncode(6) = \"0\"  // freq=45\n",
+				"\tEncode(5) = \"011\"  // freq=16\n",
+				"\tEncode(4) = \"101\"  // freq=13\n",
+				"\t// ... 3 more\n",
+			},
+		},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			expect := "Encoder{\n\tMinSize() = 1\n\tMaxSize() = 4\n" + strings.Join(row.expect, "") + "}\n"
+			actual := e.DebugStringWith(row.opts)
+			if expect != actual {
+				t.Errorf("wrong output:\n\texpect: %s\n\tactual: %s", expect, actual)
+			}
+		})
+	}
+}
+
+func TestDecoder_DebugStringWith(t *testing.T) {
+	d := NewDecoder([]byte{4, 4, 3, 0, 3, 3, 1})
+	expect := strings.Join([]string{
+		"Decoder{\n",
+		"\tMinSize() = 1\n",
+		"\tMaxSize() = 4\n",
+		"\tDecode(\"0\") = 6\n",
+		"\tDecode(\"001\") = 2\n",
+		"\t// ... 4 more\n",
+		"}\n",
+	}, "")
+	actual := d.DebugStringWith(DebugOptions{Order: OrderBySize, MaxRows: 2})
+	if expect != actual {
+		t.Errorf("wrong output:\n\texpect: %s\n\tactual: %s", expect, actual)
+	}
+
+	if expect, actual := "Decoder{\n\tMinSize() = 0\n\tMaxSize() = 0\n}\n", NewDecoder(nil).DebugStringWith(DebugOptions{}); expect != actual {
+		t.Errorf("wrong output for empty Decoder:\n\texpect: %s\n\tactual: %s", expect, actual)
+	}
+}
@@ -1,20 +1,75 @@
 package huffman
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
 	"io"
-	"sort"
-	"strconv"
+	"slices"
 	"strings"
 )
 
 // Decoder implements a decoder for canonical Huffman codes.
 type Decoder struct {
-	table   map[Code]decoderData
-	sizes   []byte
-	minSize byte
-	maxSize byte
+	table       map[Code]decoderData
+	sorted      []uint64
+	direct      []directEntry
+	sizes       []byte
+	opts        Options
+	countBySize [maxBitsPerCode + 1]uint32
+	minSize     byte
+	maxSize     byte
+}
+
+// DecoderKind identifies the lookup strategy used by a Decoder.
+type DecoderKind byte
+
+const (
+	// PrefixMapDecoder looks up codes one prefix at a time in a map from
+	// each legal code and code prefix to its decoding.
+	PrefixMapDecoder DecoderKind = iota
+
+	// DirectTableDecoder additionally has a dense table with one entry for
+	// every possible MaxSize()-bit input, so that a BitReader can decode
+	// any code in a single lookup.  It is used when MaxSize() is no more
+	// than 11 bits.
+	DirectTableDecoder
+
+	// SortedTableDecoder looks up codes one prefix at a time by binary
+	// search in a sorted array, as selected by the SortedTable
+	// TableStrategy.
+	SortedTableDecoder
+)
+
+var decoderKindNames = []string{
+	"PrefixMapDecoder",
+	"DirectTableDecoder",
+	"SortedTableDecoder",
+}
+
+// String returns the name of this DecoderKind.
+func (kind DecoderKind) String() string {
+	if uint(kind) < uint(len(decoderKindNames)) {
+		return decoderKindNames[kind]
+	}
+	return fmt.Sprintf("DecoderKind(%d)", uint(kind))
+}
+
+// maxDirectBits is the largest MaxSize() for which Init builds a direct
+// table.  At 11 bits, the table is 2048 entries, or 8 KiB.
+const maxDirectBits = 11
+
+// directEntry holds the decoding of one MaxSize()-bit input: the Symbol in
+// the upper 24 bits and the code's size in the lower 8 bits.  A size of 0
+// indicates that the input does not begin with any legal code.
+type directEntry uint32
+
+func (de directEntry) symbol() Symbol {
+	return Symbol(de >> 8)
+}
+
+func (de directEntry) size() byte {
+	return byte(de)
 }
 
 // NewDecoder is a convenience function that allocates a new Decoder and calls
@@ -39,15 +94,47 @@ func NewDecoder(sizes []byte) *Decoder {
 // non-degenerate Huffman code for such cases.
 //
 func (d *Decoder) Init(sizes []byte) error {
-	numSymbols := Symbol(len(sizes))
-
-	tmp := make([]byte, numSymbols)
+	tmp := make([]byte, len(sizes))
 	copy(tmp, sizes)
-	sizes = tmp
+	return d.init(tmp)
+}
+
+// init is Init without the defensive copy; the Decoder takes ownership of
+// sizes.
+func (d *Decoder) init(sizes []byte) error {
+	return d.initWith(sizes, make([]Code, len(sizes)), nil, nil)
+}
+
+// reinit is init, but reuses the storage of this Decoder's previous tables,
+// and *codes as scratch space.  It must only be used on a Decoder which has
+// never been copied, since the copies would see their tables change.  If
+// reinit fails, the Decoder is left empty.
+func (d *Decoder) reinit(sizes []byte, codes *[]Code) error {
+	if cap(*codes) < len(sizes) {
+		*codes = make([]Code, len(sizes))
+	} else {
+		*codes = (*codes)[:len(sizes)]
+		for index := range *codes {
+			(*codes)[index] = Code{}
+		}
+	}
+	table, direct := d.table, d.direct
+	if err := d.initWith(sizes, *codes, table, direct); err != nil {
+		*d = Decoder{}
+		return err
+	}
+	return nil
+}
+
+// initWith is init using codes (which must be zeroed and of length
+// len(sizes)) as scratch space.  If table or direct is non-nil, its storage is
+// reused for the new tables.
+func (d *Decoder) initWith(sizes []byte, codes []Code, table map[Code]decoderData, direct []directEntry) error {
+	numSymbols := Symbol(len(sizes))
 
-	codes := make([]Code, numSymbols)
 	var numSymbolsWithNonZeroSizes uint32
 	var minSize, maxSize byte
+	var countBySize [maxBitsPerCode + 1]uint32
 	for symbol := Symbol(0); symbol < numSymbols; symbol++ {
 		size := sizes[symbol]
 		if size == 0 {
@@ -63,6 +150,9 @@ func (d *Decoder) Init(sizes []byte) error {
 			maxSize = size
 		}
 		numSymbolsWithNonZeroSizes++
+		if size <= maxBitsPerCode {
+			countBySize[size]++
+		}
 		codes[symbol].Size = sizes[symbol]
 	}
 
@@ -75,18 +165,22 @@ func (d *Decoder) Init(sizes []byte) error {
 		return err
 	}
 
-	// len(table) is approximately n×log2(n) when filled.
-	numTableSlots := numSymbolsWithNonZeroSizes * log2uint32(numSymbolsWithNonZeroSizes)
+	if table == nil {
+		table = make(map[Code]decoderData, countTableSlots(countBySize[:maxSize+1]))
+	} else {
+		for hc := range table {
+			delete(table, hc)
+		}
+	}
 
 	*d = Decoder{
-		table:   make(map[Code]decoderData, numTableSlots),
-		sizes:   sizes,
-		minSize: minSize,
-		maxSize: maxSize,
+		table:       table,
+		sizes:       sizes,
+		countBySize: countBySize,
+		minSize:     minSize,
+		maxSize:     maxSize,
 	}
 
-	copy(d.sizes, sizes)
-
 	for symbol := Symbol(0); symbol < numSymbols; symbol++ {
 		hc := codes[symbol]
 		if hc.Size == 0 {
@@ -96,15 +190,89 @@ func (d *Decoder) Init(sizes []byte) error {
 		fillTable(d.table, symbol, hc)
 	}
 
+	if maxSize <= maxDirectBits && numSymbols <= (1<<24) {
+		if cap(direct) < 1<<maxSize {
+			direct = make([]directEntry, 1<<maxSize)
+		} else {
+			direct = direct[:1<<maxSize]
+			for index := range direct {
+				direct[index] = 0
+			}
+		}
+		d.direct = direct
+		for symbol := Symbol(0); symbol < numSymbols; symbol++ {
+			hc := codes[symbol]
+			if hc.Size == 0 {
+				continue
+			}
+			de := directEntry(uint32(symbol)<<8 | uint32(hc.Size))
+			step := uint32(1) << hc.Size
+			for index := hc.Bits; index < uint32(len(d.direct)); index += step {
+				d.direct[index] = de
+			}
+		}
+	}
+
+	if debugChecks {
+		debugCheck("Decoder.Init", d.checkInvariants())
+	}
 	return nil
 }
 
+// DecoderView is a Decoder which borrows its bit length array from the
+// caller, rather than making its own copy as Decoder.Init does.  It is meant
+// for callers which build many decoders from size tables they already own,
+// such as a memory-mapped file, where the copy is a measurable cost.
+//
+// The caller must not modify the borrowed array for as long as the
+// DecoderView, or anything derived from it, is in use: SizeBySymbol returns
+// the borrowed array itself, and the lookup tables are not rebuilt if it
+// changes.
+//
+type DecoderView struct {
+	Decoder
+}
+
+// NewDecoderView is a convenience function that allocates a new DecoderView
+// and calls Init on it.  If Init returns an error, NewDecoderView panics.
+func NewDecoderView(sizes []byte) *DecoderView {
+	v := new(DecoderView)
+	if err := v.Init(sizes); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Init initializes this DecoderView to borrow the given bit length array.
+// See Decoder.Init for the meaning of sizes.
+func (v *DecoderView) Init(sizes []byte) error {
+	return v.Decoder.init(sizes)
+}
+
 // InitFromEncoder initializes this Decoder to be the mirror of the given
 // Encoder.
 func (d *Decoder) InitFromEncoder(e Encoder) error {
 	return d.Init(e.SizeBySymbol())
 }
 
+// TableSize returns the number of entries in this Decoder's lookup table: one
+// for each legal code, plus one for each proper prefix of a legal code.  The
+// direct table of a DirectTableDecoder, if any, is not included.
+func (d Decoder) TableSize() int {
+	return len(d.table) + len(d.sorted)
+}
+
+// Kind reports which lookup strategy this Decoder uses.
+func (d Decoder) Kind() DecoderKind {
+	if d.direct != nil {
+		return DirectTableDecoder
+	}
+	if d.sorted != nil {
+		return SortedTableDecoder
+	}
+	return PrefixMapDecoder
+}
+
 // Decode attempts to decode a Huffman code into a Symbol.
 //
 // If the Decode is completely successful, symbol >= 0 and minSize == maxSize.
@@ -116,14 +284,61 @@ func (d *Decoder) InitFromEncoder(e Encoder) error {
 // If the Decode fails due to unreasonable input, symbol == InvalidSymbol and
 // minSize == maxSize == 0.
 //
+// See Remaining for the number of Symbols which hc might still decode to.
+//
 func (d Decoder) Decode(hc Code) (symbol Symbol, minSize byte, maxSize byte) {
-	dd, found := d.table[hc]
+	dd, found := d.lookup(hc)
 	if !found {
 		return InvalidSymbol, 0, 0
 	}
 	return dd.symbol, dd.minSize, dd.maxSize
 }
 
+// Remaining returns the number of Symbols whose codes begin with hc: 1 if hc
+// is itself a code, 0 if hc is not a prefix of any code, and otherwise the
+// number of codes beneath the prefix.  The empty Code gives the number of
+// Symbols with codes.
+//
+// Together with the minSize returned by Decode, this lets a stream decoder
+// detect errors early: if Remaining(hc) is 0, or if fewer than minSize -
+// hc.Size bits of input remain, the input cannot be valid.
+//
+// Remaining takes constant time, using the structure of canonical codes:
+// the codes of each size are consecutive numbers, so the codes beneath a
+// prefix are found by intersecting two ranges per size.
+//
+func (d Decoder) Remaining(hc Code) int {
+	if hc.Size > d.maxSize || (!hc.IsValid() && !hc.IsZero()) {
+		return 0
+	}
+
+	// Codes are numbered with the first bit most significant, as in
+	// RFC 1951 Section 3.2.2.
+	prefix := uint64(reverseBits(hc.Size, hc.Bits))
+	var total uint64
+	var first uint64
+	for size := byte(1); size <= d.maxSize; size++ {
+		if size > 1 {
+			first = (first + uint64(d.countBySize[size-1])) << 1
+		}
+		if size < hc.Size {
+			continue
+		}
+		shift := size - hc.Size
+		lo, hi := prefix<<shift, (prefix+1)<<shift
+		if start := first; start > lo {
+			lo = start
+		}
+		if end := first + uint64(d.countBySize[size]); end < hi {
+			hi = end
+		}
+		if hi > lo {
+			total += hi - lo
+		}
+	}
+	return int(total)
+}
+
 // MinSize is the bit length of the shortest legal code.
 func (d Decoder) MinSize() byte {
 	return d.minSize
@@ -147,12 +362,98 @@ func (d Decoder) MaxSymbol() Symbol {
 	return Symbol(len(d.sizes)) - 1
 }
 
+// IsDegenerate returns true if fewer than 2 Symbols have codes, in which case
+// the code carries no information.  See Init.
+func (d Decoder) IsDegenerate() bool {
+	_, n := d.soleSymbol()
+	return n < 2
+}
+
+// SoleSymbol returns the only Symbol with a code, and true, if exactly one
+// Symbol has a code.  Otherwise, it returns (InvalidSymbol, false).
+func (d Decoder) SoleSymbol() (Symbol, bool) {
+	symbol, n := d.soleSymbol()
+	if n != 1 {
+		return InvalidSymbol, false
+	}
+	return symbol, true
+}
+
+// soleSymbol returns the first Symbol with a code and the number of Symbols
+// with codes, counting no further than 2.
+func (d Decoder) soleSymbol() (Symbol, int) {
+	first, n := InvalidSymbol, 0
+	for symbol, size := range d.sizes {
+		if size == 0 {
+			continue
+		}
+		if n == 0 {
+			first = Symbol(symbol)
+		}
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	return first, n
+}
+
 // SizeBySymbol returns a copy of the original bit length array used to
 // initialize this Decoder.
 func (d Decoder) SizeBySymbol() []byte {
 	return d.sizes
 }
 
+// CodeLengthHistogram returns the number of codes of each size, indexed by
+// size, from 0 through MaxSize().  The entry at index 0 counts the Symbols
+// which have no code.
+func (d Decoder) CodeLengthHistogram() []uint32 {
+	out := make([]uint32, int(d.maxSize)+1)
+	for _, size := range d.sizes {
+		out[size]++
+	}
+	return out
+}
+
+// SymbolsWithPrefix returns, in ascending order, every Symbol whose code
+// begins with the bits of prefix, which are in the same order as Code.Bits
+// (use MakeReversedCode for a prefix written most significant bit first).
+// A code shorter than prefix never matches, and an empty prefix matches
+// every Symbol with a code.
+func (d Decoder) SymbolsWithPrefix(prefix Code) []Symbol {
+	codes := make([]Code, len(d.sizes))
+	for symbol, size := range d.sizes {
+		codes[symbol].Size = size
+	}
+	if err := secondPass(codes); err != nil {
+		return nil
+	}
+
+	mask := uint32(1)<<prefix.Size - 1
+	want := prefix.Bits & mask
+	var out []Symbol
+	for symbol, hc := range codes {
+		if hc.Size != 0 && hc.Size >= prefix.Size && hc.Bits&mask == want {
+			out = append(out, Symbol(symbol))
+		}
+	}
+	return out
+}
+
+// SortedCodes returns the code of every Symbol which has one, ordered by
+// Code.Packed: shorter codes first, then by Bits as an integer.  Use Decode
+// to find the Symbol for each.
+func (d Decoder) SortedCodes() []Code {
+	out := make([]Code, 0, len(d.sizes))
+	d.forEachEntry(func(hc Code, dd decoderData) {
+		if dd.symbol >= 0 {
+			out = append(out, hc)
+		}
+	})
+	sortCodes(out)
+	return out
+}
+
 // Encoder returns a new Encoder which mirrors this Decoder.
 func (d Decoder) Encoder() *Encoder {
 	e := new(Encoder)
@@ -162,6 +463,46 @@ func (d Decoder) Encoder() *Encoder {
 	return e
 }
 
+// Clone returns a new Decoder which is an independent copy of this Decoder.
+// The lookup tables are copied directly, which is much cheaper than building
+// them again with Init.  Cloning a DecoderView's Decoder yields a Decoder
+// with its own copy of the bit length array.
+func (d Decoder) Clone() *Decoder {
+	var table map[Code]decoderData
+	if d.table != nil {
+		table = make(map[Code]decoderData, len(d.table))
+		for hc, dd := range d.table {
+			table[hc] = dd
+		}
+	}
+
+	var sorted []uint64
+	if d.sorted != nil {
+		sorted = make([]uint64, len(d.sorted))
+		copy(sorted, d.sorted)
+	}
+
+	var direct []directEntry
+	if d.direct != nil {
+		direct = make([]directEntry, len(d.direct))
+		copy(direct, d.direct)
+	}
+
+	sizes := make([]byte, len(d.sizes))
+	copy(sizes, d.sizes)
+
+	return &Decoder{
+		table:       table,
+		sorted:      sorted,
+		direct:      direct,
+		sizes:       sizes,
+		opts:        d.opts,
+		countBySize: d.countBySize,
+		minSize:     d.minSize,
+		maxSize:     d.maxSize,
+	}
+}
+
 // Dump writes DebugString() to the given writer.
 func (d Decoder) Dump(w io.Writer) (int64, error) {
 	r := strings.NewReader(d.DebugString())
@@ -175,30 +516,39 @@ func (d Decoder) DebugString() string {
 	buf.WriteString("Decoder{\n")
 	fmt.Fprintf(&buf, "\tMinSize() = %d\n", d.minSize)
 	fmt.Fprintf(&buf, "\tMaxSize() = %d\n", d.maxSize)
-	keys := make(byCode, 0, len(d.table))
-	for hc := range d.table {
+	keys := make([]Code, 0, d.TableSize())
+	d.forEachEntry(func(hc Code, _ decoderData) {
 		keys = append(keys, hc)
-	}
-	keys.Sort()
+	})
+	sortCodes(keys)
 	for _, hc := range keys {
-		dd := d.table[hc]
-		fmt.Fprintf(&buf, "\tDecode(%s) = {%d, %d, %d}\n", hc, dd.symbol, dd.minSize, dd.maxSize)
+		dd, _ := d.lookup(hc)
+		fmt.Fprintf(&buf, "\tDecode(%s) = {%d, %d, %d}", hc, dd.symbol, dd.minSize, dd.maxSize)
+		if dd.symbol >= 0 && d.opts.SymbolName != nil {
+			fmt.Fprintf(&buf, "  // %s", d.opts.SymbolName(dd.symbol))
+		}
+		buf.WriteByte('\n')
 	}
 	buf.WriteString("}\n")
 	return buf.String()
 }
 
-// GoString returns a Go expression that would reconstruct this Decoder.
+// GoString returns a Go expression that would reconstruct this Decoder.  If
+// the Decoder has Options, the expression calls NewDecoderWithOptions with
+// the Options literal given by Options.GoString.
 func (d Decoder) GoString() string {
 	var buf strings.Builder
-	buf.WriteString("NewDecoder([]byte{")
-	for index, size := range d.SizeBySymbol() {
-		if index != 0 {
-			buf.WriteByte(',')
-		}
-		buf.WriteString(strconv.FormatUint(uint64(size), 10))
+	if d.opts.isZeroGo() {
+		buf.WriteString("NewDecoder(")
+	} else {
+		buf.WriteString("NewDecoderWithOptions(")
 	}
-	buf.WriteString("})")
+	writeBytesGo(&buf, d.sizes)
+	if !d.opts.isZeroGo() {
+		buf.WriteByte(',')
+		buf.WriteString(d.opts.GoString())
+	}
+	buf.WriteString(")")
 	return buf.String()
 }
 
@@ -222,24 +572,32 @@ func (d Decoder) MarshalJSON() ([]byte, error) {
 	return json.Marshal(arr)
 }
 
-// UnmarshalJSON initializes this Decoder from JSON data.
+// UnmarshalJSON initializes this Decoder from JSON data, in either the form
+// written by MarshalJSON or the one written by MarshalJSONV2.  The Decoder's
+// current Options are kept, and an alphabet larger than Options.MaxAlphabet
+// allows is rejected with an *AlphabetError before anything is allocated.
 func (d *Decoder) UnmarshalJSON(raw []byte) error {
-	var arr []uint
-	if err := json.Unmarshal(raw, &arr); err != nil {
+	sizes, err := unmarshalJSONSizes(raw, d.opts)
+	if err != nil {
 		return err
 	}
+	return d.InitWithOptions(sizes, d.opts)
+}
 
-	length := uint(len(arr))
-	sizes := make([]byte, length)
-	for i := uint(0); i < length; i++ {
-		size := arr[i]
-		if size > maxBitsPerCode {
-			return fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
-		}
-		sizes[i] = byte(size)
+// countTableSlots returns the exact number of entries that fillTable will
+// create for a canonical code with countBySize[n] codes of length n.
+//
+// In a canonical code, the codes of each length begin on a boundary that is
+// aligned for the next shorter length, so the nodes at depth n are the codes
+// of length n plus the parents of the nodes at depth n+1, rounded up.
+//
+func countTableSlots(countBySize []uint32) int {
+	var total, nodes uint32
+	for size := len(countBySize) - 1; size >= 0; size-- {
+		nodes = countBySize[size] + (nodes+1)/2
+		total += nodes
 	}
-
-	return d.Init(sizes)
+	return int(total)
 }
 
 type decoderData struct {
@@ -289,32 +647,10 @@ func fillTable(table map[Code]decoderData, symbol Symbol, hc Code) {
 	}
 }
 
-// type byCode {{{
-
-type byCode []Code
-
-func (list byCode) Sort() {
-	sort.Sort(list)
-}
-
-func (list byCode) Len() int {
-	return len(list)
-}
-
-func (list byCode) Swap(i, j int) {
-	list[i], list[j] = list[j], list[i]
-}
-
-func (list byCode) Less(i, j int) bool {
-	a, b := list[i], list[j]
-	as, ab := a.Size, a.Bits
-	bs, bb := b.Size, b.Bits
-	if as != bs {
-		return as < bs
-	}
-	return ab < bb
+// sortCodes sorts codes by Code.Packed: shorter codes first, then by Bits as
+// an integer.
+func sortCodes(codes []Code) {
+	slices.SortFunc(codes, func(a, b Code) int {
+		return cmp.Compare(a.Packed(), b.Packed())
+	})
 }
-
-var _ sort.Interface = byCode(nil)
-
-// }}}
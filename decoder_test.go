@@ -3,6 +3,8 @@ package huffman
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -158,3 +160,309 @@ func TestDecoder_UnmarshalJSON(t *testing.T) {
 		t.Errorf("wrong output:\n\texpect: %s\n\tactual: %s", expectDebug, actualDebug)
 	}
 }
+
+func TestDecoder_TableSize(t *testing.T) {
+	type testRow struct {
+		name  string
+		sizes []byte
+	}
+
+	deflateLitLen := make([]byte, 288)
+	for i := range deflateLitLen {
+		switch {
+		case i < 144:
+			deflateLitLen[i] = 8
+		case i < 256:
+			deflateLitLen[i] = 9
+		case i < 280:
+			deflateLitLen[i] = 7
+		default:
+			deflateLitLen[i] = 8
+		}
+	}
+
+	skewed := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 15}
+
+	testData := [...]testRow{
+		{"test", []byte{4, 4, 3, 3, 3, 1}},
+		{"single", []byte{0, 1}},
+		{"incomplete", []byte{2, 2, 2}},
+		{"deflate-fixed", deflateLitLen},
+		{"skewed", skewed},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			d := NewDecoder(row.sizes)
+
+			// Count the distinct prefixes of every code, the slow way.
+			prefixes := make(map[Code]struct{})
+			e := d.Encoder()
+			for symbol := Symbol(0); symbol <= e.MaxSymbol(); symbol++ {
+				hc := e.Encode(symbol)
+				if hc.Size == 0 {
+					continue
+				}
+				for size := byte(0); size <= hc.Size; size++ {
+					prefixes[MakeCode(size, hc.Bits&((1<<size)-1))] = struct{}{}
+				}
+			}
+			expect := len(prefixes)
+			if actual := d.TableSize(); expect != actual {
+				t.Errorf("expected %d table entries, got %d", expect, actual)
+			}
+		})
+	}
+	if expect, actual := 11, makeTestDecoder().TableSize(); expect != actual {
+		t.Errorf("expected %d table entries, got %d", expect, actual)
+	}
+}
+
+func BenchmarkDecoder_Init(b *testing.B) {
+	frequencies := make([]uint32, 286)
+	for i := range frequencies {
+		frequencies[i] = uint32(1 + (i*7919)%1000)
+	}
+	frequencies[256] = 1
+	var e Encoder
+	initLimited(&e, len(frequencies), frequencies)
+	sizes := e.SizeBySymbol()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var d Decoder
+		if err := d.Init(sizes); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecoder_Kind(t *testing.T) {
+	type testRow struct {
+		name  string
+		sizes []byte
+		kind  DecoderKind
+	}
+
+	testData := [...]testRow{
+		{"empty", nil, PrefixMapDecoder},
+		{"test", []byte{4, 4, 3, 3, 3, 1}, DirectTableDecoder},
+		{"single", []byte{0, 1}, DirectTableDecoder},
+		{"11-bit", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 11}, DirectTableDecoder},
+		{"12-bit", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 12}, PrefixMapDecoder},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var d Decoder
+			if err := d.Init(row.sizes); err != nil {
+				t.Fatalf("Init: unexpected error: %v", err)
+			}
+			if actual := d.Kind(); row.kind != actual {
+				t.Errorf("expected %v, got %v", row.kind, actual)
+			}
+		})
+	}
+}
+
+func TestDecoder_SortedCodes(t *testing.T) {
+	d := NewDecoder([]byte{4, 0, 4, 3, 3, 3, 1})
+	expected := []Code{
+		MakeReversedCode(1, 0x0),
+		MakeReversedCode(3, 0x4),
+		MakeReversedCode(3, 0x5),
+		MakeReversedCode(3, 0x6),
+		MakeReversedCode(4, 0xe),
+		MakeReversedCode(4, 0xf),
+	}
+	sortCodes(expected)
+	if actual := d.SortedCodes(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("SortedCodes: expected %v, got %v", expected, actual)
+	}
+	for _, hc := range d.SortedCodes() {
+		if symbol, _, _ := d.Decode(hc); symbol < 0 {
+			t.Errorf("Decode(%v): expected a Symbol, got %d", hc, symbol)
+		}
+	}
+
+	var empty Decoder
+	if actual := empty.SortedCodes(); len(actual) != 0 {
+		t.Errorf("SortedCodes of an empty Decoder: expected none, got %v", actual)
+	}
+}
+
+func TestDecoder_Remaining(t *testing.T) {
+	type testRow struct {
+		hc     Code
+		expect int
+	}
+
+	// Codes: 0, 100, 101, 110, 1110, 1111 (first bit leftmost).
+	d := NewDecoder([]byte{4, 0, 4, 3, 3, 3, 1})
+	testData := [...]testRow{
+		{hc: Code{}, expect: 6},
+		{hc: MakeReversedCode(1, 0x0), expect: 1},
+		{hc: MakeReversedCode(1, 0x1), expect: 5},
+		{hc: MakeReversedCode(2, 0x2), expect: 2},
+		{hc: MakeReversedCode(2, 0x3), expect: 3},
+		{hc: MakeReversedCode(3, 0x7), expect: 2},
+		{hc: MakeReversedCode(4, 0xf), expect: 1},
+		{hc: MakeReversedCode(2, 0x0), expect: 0},
+		{hc: MakeReversedCode(5, 0x1f), expect: 0},
+		{hc: Code{Size: 2, Bits: 0x7}, expect: 0},
+	}
+	for _, row := range testData {
+		if actual := d.Remaining(row.hc); actual != row.expect {
+			t.Errorf("Remaining(%v): expected %d, got %d", row.hc, row.expect, actual)
+		}
+	}
+
+	// Check every prefix of some incomplete codes against a brute-force
+	// count.
+	for _, sizes := range [][]byte{{2, 2, 2, 0}, {3, 3, 2, 0, 4, 4, 2}, {0, 1}, {}} {
+		d := NewDecoder(sizes)
+		codes := d.SortedCodes()
+		for size := byte(0); size <= d.MaxSize(); size++ {
+			for bits := uint32(0); bits < 1<<size; bits++ {
+				expect := 0
+				for _, hc := range codes {
+					if hc.Size >= size && hc.Bits&(1<<size-1) == bits {
+						expect++
+					}
+				}
+				hc := MakeCode(size, bits)
+				if actual := d.Remaining(hc); actual != expect {
+					t.Errorf("sizes %v: Remaining(%v): expected %d, got %d", sizes, hc, expect, actual)
+				}
+			}
+		}
+	}
+}
+
+func TestDecoderView(t *testing.T) {
+	sizes := []byte{4, 4, 3, 3, 3, 1}
+	v := NewDecoderView(sizes)
+
+	if actual := v.SizeBySymbol(); &actual[0] != &sizes[0] {
+		t.Errorf("SizeBySymbol: expected the borrowed array")
+	}
+
+	d := makeTestDecoder()
+	if expect, actual := d.DebugString(), v.DebugString(); expect != actual {
+		t.Errorf("DebugString: wrong output:\n\texpect: %q\n\tactual: %q", expect, actual)
+	}
+
+	if err := v.Init([]byte{1, 1, 1}); err == nil {
+		t.Errorf("Init: expected error for an over-full code")
+	}
+}
+
+func BenchmarkDecoderView_Init(b *testing.B) {
+	sizes := make([]byte, 256)
+	for i := range sizes {
+		sizes[i] = 8
+	}
+
+	b.Run("Decoder", func(b *testing.B) {
+		b.ReportAllocs()
+		var d Decoder
+		for i := 0; i < b.N; i++ {
+			_ = d.Init(sizes)
+		}
+	})
+	b.Run("DecoderView", func(b *testing.B) {
+		b.ReportAllocs()
+		var v DecoderView
+		for i := 0; i < b.N; i++ {
+			_ = v.Init(sizes)
+		}
+	})
+}
+
+func TestDecoder_Clone(t *testing.T) {
+	for _, sizes := range [][]byte{nil, {4, 4, 3, 3, 3, 1}, {1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 12}} {
+		d := NewDecoder(sizes)
+		clone := d.Clone()
+		if expect, actual := d.DebugString(), clone.DebugString(); expect != actual {
+			t.Errorf("wrong clone:\n\texpect: %q\n\tactual: %q", expect, actual)
+		}
+		if expect, actual := d.Kind(), clone.Kind(); expect != actual {
+			t.Errorf("Kind: expected %v, got %v", expect, actual)
+		}
+	}
+
+	sizes := []byte{4, 4, 3, 3, 3, 1}
+	v := NewDecoderView(sizes)
+	clone := v.Clone()
+	sizes[0] = 0
+	if expect, actual := byte(4), clone.SizeBySymbol()[0]; expect != actual {
+		t.Errorf("clone of DecoderView shares the borrowed array")
+	}
+	clone.table[Code{}] = decoderData{}
+	if _, minSize, _ := v.Decode(Code{}); minSize == 0 {
+		t.Errorf("modifying the clone modified the original")
+	}
+}
+
+func TestIsDegenerate(t *testing.T) {
+	type testRow struct {
+		sizes      []byte
+		degenerate bool
+		sole       Symbol
+		ok         bool
+	}
+
+	testData := [...]testRow{
+		{sizes: []byte{}, degenerate: true, sole: InvalidSymbol},
+		{sizes: []byte{0, 0, 0}, degenerate: true, sole: InvalidSymbol},
+		{sizes: []byte{0, 0, 1}, degenerate: true, sole: 2, ok: true},
+		{sizes: []byte{0, 3, 0}, degenerate: true, sole: 1, ok: true},
+		{sizes: []byte{1, 0, 1}, sole: InvalidSymbol},
+		{sizes: []byte{1, 2, 2}, sole: InvalidSymbol},
+	}
+	for _, row := range testData {
+		t.Run(fmt.Sprint(row.sizes), func(t *testing.T) {
+			d := NewDecoder(row.sizes)
+			e := d.Encoder()
+			if actual := d.IsDegenerate(); actual != row.degenerate {
+				t.Errorf("Decoder.IsDegenerate: expected %v, got %v", row.degenerate, actual)
+			}
+			if actual := e.IsDegenerate(); actual != row.degenerate {
+				t.Errorf("Encoder.IsDegenerate: expected %v, got %v", row.degenerate, actual)
+			}
+			if sole, ok := d.SoleSymbol(); sole != row.sole || ok != row.ok {
+				t.Errorf("Decoder.SoleSymbol: expected (%d, %v), got (%d, %v)", row.sole, row.ok, sole, ok)
+			}
+			if sole, ok := e.SoleSymbol(); sole != row.sole || ok != row.ok {
+				t.Errorf("Encoder.SoleSymbol: expected (%d, %v), got (%d, %v)", row.sole, row.ok, sole, ok)
+			}
+		})
+	}
+}
+
+func TestDecoder_SymbolsWithPrefix(t *testing.T) {
+	// Codes: 0 = "0", 1 = "10", 2 = "110", 3 = "111", 4 = none.
+	var d Decoder
+	if err := d.Init([]byte{1, 2, 3, 3, 0}); err != nil {
+		t.Fatalf("Init: unexpected error: %v", err)
+	}
+
+	type testRow struct {
+		prefix Code
+		expect []Symbol
+	}
+
+	testData := [...]testRow{
+		{prefix: Code{}, expect: []Symbol{0, 1, 2, 3}},
+		{prefix: MakeReversedCode(1, 0b0), expect: []Symbol{0}},
+		{prefix: MakeReversedCode(1, 0b1), expect: []Symbol{1, 2, 3}},
+		{prefix: MakeReversedCode(2, 0b10), expect: []Symbol{1}},
+		{prefix: MakeReversedCode(2, 0b11), expect: []Symbol{2, 3}},
+		{prefix: MakeReversedCode(3, 0b111), expect: []Symbol{3}},
+		{prefix: MakeReversedCode(4, 0b1101), expect: nil},
+		{prefix: MakeReversedCode(2, 0b01), expect: nil},
+	}
+	for _, row := range testData {
+		if actual := d.SymbolsWithPrefix(row.prefix); !reflect.DeepEqual(row.expect, actual) {
+			t.Errorf("%v: expected %v, got %v", row.prefix, row.expect, actual)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package huffman
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The binary trace format written by WriteDecodeTrace is:
+//
+//     trace  := "HUFT" version:byte count:uvarint record*
+//     record := bitOffset:uvarint size:byte bits:uvarint symbol:uvarint
+//
+// Each bitOffset is stored as the difference from the previous record's
+// bitOffset (or from 0, for the first record).
+//
+
+const decodeTraceVersion = 1
+
+var decodeTraceMagic = [4]byte{'H', 'U', 'F', 'T'}
+
+// ErrDecodeTrace is returned when reading an invalid binary decode trace.
+var ErrDecodeTrace = errors.New("invalid decode trace")
+
+// DecodeRecord describes one Symbol decoded by a TracingReader.
+type DecodeRecord struct {
+	// BitOffset is the value of BitReader.BitsRead before the Symbol's
+	// code was read.
+	BitOffset uint64 `json:"bit"`
+
+	// Code is the code that was read.
+	Code Code `json:"code"`
+
+	// Symbol is the Symbol it decoded to.
+	Symbol Symbol `json:"symbol"`
+}
+
+// TracingReader wraps a BitReader and records every Symbol decoded through
+// it, for comparing the behavior of two implementations of the same format
+// symbol by symbol.
+type TracingReader struct {
+	br      *BitReader
+	records []DecodeRecord
+	codes   map[*Decoder][]Code
+}
+
+// NewTracingReader returns a new TracingReader which reads from br.
+func NewTracingReader(br *BitReader) *TracingReader {
+	return &TracingReader{br: br, codes: make(map[*Decoder][]Code)}
+}
+
+// BitReader returns the underlying BitReader, for reading raw bits between
+// codes.  Bits read directly from it are not recorded.
+func (tr *TracingReader) BitReader() *BitReader {
+	return tr.br
+}
+
+// ReadSymbol is like BitReader.ReadSymbol, but also records the Symbol.
+func (tr *TracingReader) ReadSymbol(d *Decoder) (Symbol, error) {
+	offset := tr.br.BitsRead()
+	symbol, err := tr.br.ReadSymbol(d)
+	if err != nil {
+		return symbol, err
+	}
+
+	codes, found := tr.codes[d]
+	if !found {
+		codes = d.Encoder().codes
+		tr.codes[d] = codes
+	}
+	tr.records = append(tr.records, DecodeRecord{BitOffset: offset, Code: codes[symbol], Symbol: symbol})
+	return symbol, nil
+}
+
+// Records returns the records of all Symbols decoded so far.
+func (tr *TracingReader) Records() []DecodeRecord {
+	return tr.records
+}
+
+// Reset discards all records.  Call it also if any Decoder which has been
+// used with this TracingReader is re-initialized.
+func (tr *TracingReader) Reset() {
+	tr.records = nil
+	tr.codes = make(map[*Decoder][]Code)
+}
+
+// FirstDifference returns the index of the first record at which a and b
+// differ, or -1 if they are identical.  If one is a prefix of the other, the
+// length of the shorter is returned.
+func FirstDifference(a []DecodeRecord, b []DecodeRecord) int {
+	for index := range a {
+		if index >= len(b) || a[index] != b[index] {
+			return index
+		}
+	}
+	if len(a) != len(b) {
+		return len(a)
+	}
+	return -1
+}
+
+// WriteDecodeTraceJSON writes records to w as a JSON array.
+func WriteDecodeTraceJSON(w io.Writer, records []DecodeRecord) error {
+	if records == nil {
+		records = []DecodeRecord{}
+	}
+	return json.NewEncoder(w).Encode(records)
+}
+
+// WriteDecodeTrace writes records to w in a compact binary format.
+func WriteDecodeTrace(w io.Writer, records []DecodeRecord) error {
+	var tmp [binary.MaxVarintLen64]byte
+	out := append([]byte(nil), decodeTraceMagic[:]...)
+	out = append(out, decodeTraceVersion)
+	n := binary.PutUvarint(tmp[:], uint64(len(records)))
+	out = append(out, tmp[:n]...)
+
+	var prev uint64
+	for _, record := range records {
+		n = binary.PutUvarint(tmp[:], record.BitOffset-prev)
+		out = append(out, tmp[:n]...)
+		prev = record.BitOffset
+		out = append(out, record.Code.Size)
+		n = binary.PutUvarint(tmp[:], uint64(record.Code.Bits))
+		out = append(out, tmp[:n]...)
+		n = binary.PutUvarint(tmp[:], uint64(record.Symbol))
+		out = append(out, tmp[:n]...)
+	}
+	_, err := w.Write(out)
+	return err
+}
+
+// ReadDecodeTrace reads records written by WriteDecodeTrace from r.  At most
+// maxRecords records will be accepted.
+func ReadDecodeTrace(r io.Reader, maxRecords int) ([]DecodeRecord, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+
+	var header [5]byte
+	for index := range header {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecodeTrace, noEOF(err))
+		}
+		header[index] = b
+	}
+	if [4]byte{header[0], header[1], header[2], header[3]} != decodeTraceMagic || header[4] != decodeTraceVersion {
+		return nil, fmt.Errorf("%w: bad header", ErrDecodeTrace)
+	}
+
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecodeTrace, noEOF(err))
+	}
+	if count > uint64(maxRecords) {
+		return nil, fmt.Errorf("%w: too many records: got %d, max %d", ErrDecodeTrace, count, maxRecords)
+	}
+
+	records := make([]DecodeRecord, count)
+	var prev uint64
+	for index := range records {
+		delta, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecodeTrace, noEOF(err))
+		}
+		size, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecodeTrace, noEOF(err))
+		}
+		bits, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecodeTrace, noEOF(err))
+		}
+		symbol, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecodeTrace, noEOF(err))
+		}
+		if size > 32 || bits > uint64(^uint32(0)) || symbol > uint64(MaxSymbol) {
+			return nil, fmt.Errorf("%w: record %d out of range", ErrDecodeTrace, index)
+		}
+		prev += delta
+		records[index] = DecodeRecord{
+			BitOffset: prev,
+			Code:      MakeCode(size, uint32(bits)),
+			Symbol:    Symbol(symbol),
+		}
+	}
+	return records, nil
+}
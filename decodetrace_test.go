@@ -0,0 +1,101 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTracingReader(t *testing.T) {
+	e := NewEncoder(4, []uint32{1, 1, 2, 4})
+	d := e.Decoder()
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	for _, symbol := range []Symbol{3, 2, 0, 3, 1} {
+		_ = bw.WriteSymbol(e, symbol)
+	}
+	_ = bw.WriteBits(5, 0x1f)
+	_ = bw.WriteSymbol(e, 2)
+	_ = bw.Flush()
+
+	tr := NewTracingReader(NewBitReader(&buf))
+	for i := 0; i < 5; i++ {
+		if _, err := tr.ReadSymbol(d); err != nil {
+			t.Fatalf("ReadSymbol: unexpected error: %v", err)
+		}
+	}
+	if _, err := tr.BitReader().ReadBits(5); err != nil {
+		t.Fatalf("ReadBits: unexpected error: %v", err)
+	}
+	if _, err := tr.ReadSymbol(d); err != nil {
+		t.Fatalf("ReadSymbol: unexpected error: %v", err)
+	}
+
+	expect := []DecodeRecord{
+		{BitOffset: 0, Code: e.Encode(3), Symbol: 3},
+		{BitOffset: 1, Code: e.Encode(2), Symbol: 2},
+		{BitOffset: 3, Code: e.Encode(0), Symbol: 0},
+		{BitOffset: 6, Code: e.Encode(3), Symbol: 3},
+		{BitOffset: 7, Code: e.Encode(1), Symbol: 1},
+		{BitOffset: 15, Code: e.Encode(2), Symbol: 2},
+	}
+	records := tr.Records()
+	if index := FirstDifference(expect, records); index >= 0 {
+		t.Fatalf("records differ at index %d: expected %v, got %v", index, expect, records)
+	}
+
+	var jsonBuf bytes.Buffer
+	if err := WriteDecodeTraceJSON(&jsonBuf, records[:2]); err != nil {
+		t.Fatalf("WriteDecodeTraceJSON: unexpected error: %v", err)
+	}
+	expectJSON := `[{"bit":0,"code":"0","symbol":3},{"bit":1,"code":"01","symbol":2}]` + "\n"
+	if actual := jsonBuf.String(); expectJSON != actual {
+		t.Errorf("WriteDecodeTraceJSON: expected %q, got %q", expectJSON, actual)
+	}
+
+	var binBuf bytes.Buffer
+	if err := WriteDecodeTrace(&binBuf, records); err != nil {
+		t.Fatalf("WriteDecodeTrace: unexpected error: %v", err)
+	}
+	raw := binBuf.Bytes()
+	decoded, err := ReadDecodeTrace(bytes.NewReader(raw), len(records))
+	if err != nil {
+		t.Fatalf("ReadDecodeTrace: unexpected error: %v", err)
+	}
+	if index := FirstDifference(records, decoded); index >= 0 {
+		t.Errorf("ReadDecodeTrace: records differ at index %d", index)
+	}
+	if _, err := ReadDecodeTrace(bytes.NewReader(raw), len(records)-1); !errors.Is(err, ErrDecodeTrace) {
+		t.Errorf("ReadDecodeTrace: expected ErrDecodeTrace for too many records, got %v", err)
+	}
+	if _, err := ReadDecodeTrace(bytes.NewReader(raw[:len(raw)-1]), len(records)); !errors.Is(err, ErrDecodeTrace) {
+		t.Errorf("ReadDecodeTrace: expected ErrDecodeTrace for truncated input, got %v", err)
+	}
+	if _, err := ReadDecodeTrace(strings.NewReader("HUFX\x01\x00"), 1); !errors.Is(err, ErrDecodeTrace) {
+		t.Errorf("ReadDecodeTrace: expected ErrDecodeTrace for bad magic, got %v", err)
+	}
+
+	tr.Reset()
+	if n := len(tr.Records()); n != 0 {
+		t.Errorf("Reset: expected no records, got %d", n)
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	a := []DecodeRecord{{BitOffset: 0, Symbol: 1}, {BitOffset: 2, Symbol: 2}}
+	b := []DecodeRecord{{BitOffset: 0, Symbol: 1}, {BitOffset: 2, Symbol: 3}}
+	if expect, actual := 1, FirstDifference(a, b); expect != actual {
+		t.Errorf("expected %d, got %d", expect, actual)
+	}
+	if expect, actual := 1, FirstDifference(a[:1], a); expect != actual {
+		t.Errorf("prefix: expected %d, got %d", expect, actual)
+	}
+	if expect, actual := 1, FirstDifference(a, a[:1]); expect != actual {
+		t.Errorf("prefix: expected %d, got %d", expect, actual)
+	}
+	if expect, actual := -1, FirstDifference(a, a); expect != actual {
+		t.Errorf("identical: expected %d, got %d", expect, actual)
+	}
+}
@@ -0,0 +1,472 @@
+package huffman
+
+import (
+	"fmt"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+// DEFLATE (RFC 1951) alphabets and limits.
+const (
+	// DeflateLitLenAlphabetSize is the size of the literal/length
+	// alphabet, including Symbols 286 and 287, which take part in the fixed
+	// code but never appear in compressed data.
+	DeflateLitLenAlphabetSize = 288
+
+	// DeflateMinLitLenSymbols and DeflateMaxLitLenSymbols are the fewest
+	// and most literal/length code lengths a dynamic block header can
+	// carry.
+	DeflateMinLitLenSymbols = 257
+	DeflateMaxLitLenSymbols = 286
+
+	// DeflateDistAlphabetSize is the size of the distance alphabet,
+	// including Symbols 30 and 31, which take part in the fixed code but
+	// never appear in compressed data.
+	DeflateDistAlphabetSize = 32
+
+	// DeflateMaxDistSymbols is the most distance code lengths a dynamic
+	// block header can carry.
+	DeflateMaxDistSymbols = 30
+
+	// DeflateCLenAlphabetSize is the size of the code length alphabet
+	// used to describe the codes of a dynamic block.
+	DeflateCLenAlphabetSize = 19
+
+	// DeflateMaxCodeSize and DeflateMaxCLenCodeSize are the longest codes
+	// allowed in the literal/length and distance codes, and in the code
+	// length code, respectively.
+	DeflateMaxCodeSize     = 15
+	DeflateMaxCLenCodeSize = 7
+
+	// DeflateMinMatch and DeflateMaxMatch are the shortest and longest
+	// back-references.
+	DeflateMinMatch = 3
+	DeflateMaxMatch = 258
+
+	// DeflateWindowSize is the longest distance of a back-reference.
+	DeflateWindowSize = 1 << 15
+
+	// DeflateFirstLengthSymbol is the literal/length Symbol for the
+	// shortest back-reference.
+	DeflateFirstLengthSymbol Symbol = 257
+)
+
+// deflateCLenOrder is the order in which the code length code's sizes are
+// written, per RFC 1951 Section 3.2.7.
+var deflateCLenOrder = [DeflateCLenAlphabetSize]byte{
+	16, 17, 18, 0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15,
+}
+
+// DeflateCLenOrder returns the order in which the sizes of the code length
+// code are written in a dynamic block header, per RFC 1951 Section 3.2.7.
+func DeflateCLenOrder() [DeflateCLenAlphabetSize]byte {
+	return deflateCLenOrder
+}
+
+// deflateLengthBase and deflateLengthExtra describe literal/length Symbols
+// 257 to 285, per RFC 1951 Section 3.2.5.
+var (
+	deflateLengthBase = [...]uint16{
+		3, 4, 5, 6, 7, 8, 9, 10, 11, 13,
+		15, 17, 19, 23, 27, 31, 35, 43, 51, 59,
+		67, 83, 99, 115, 131, 163, 195, 227, 258,
+	}
+	deflateLengthExtra = [...]byte{
+		0, 0, 0, 0, 0, 0, 0, 0, 1, 1,
+		1, 1, 2, 2, 2, 2, 3, 3, 3, 3,
+		4, 4, 4, 4, 5, 5, 5, 5, 0,
+	}
+)
+
+// deflateDistBase and deflateDistExtra describe distance Symbols 0 to 29,
+// per RFC 1951 Section 3.2.5.
+var (
+	deflateDistBase = [...]uint16{
+		1, 2, 3, 4, 5, 7, 9, 13, 17, 25,
+		33, 49, 65, 97, 129, 193, 257, 385, 513, 769,
+		1025, 1537, 2049, 3073, 4097, 6145, 8193, 12289, 16385, 24577,
+	}
+	deflateDistExtra = [...]byte{
+		0, 0, 0, 0, 1, 1, 2, 2, 3, 3,
+		4, 4, 5, 5, 6, 6, 7, 7, 8, 8,
+		9, 9, 10, 10, 11, 11, 12, 12, 13, 13,
+	}
+)
+
+// DeflateToken is one literal or back-reference in a DEFLATE block.
+type DeflateToken struct {
+	// Length is the length of the back-reference, from 3 to 258, or 0
+	// for a literal.
+	Length uint16
+
+	// Distance is the distance of the back-reference, from 1 to 32768.
+	Distance uint16
+
+	// Literal is the literal byte, if Length is 0.
+	Literal byte
+}
+
+// IsLiteral returns true iff this DeflateToken is a literal byte.
+func (tok DeflateToken) IsLiteral() bool {
+	return tok.Length == 0
+}
+
+// DeflateLengthSymbol returns the literal/length Symbol, the number of extra
+// bits, and the value of the extra bits for a back-reference of the given
+// length, which must be from DeflateMinMatch to DeflateMaxMatch.
+func DeflateLengthSymbol(length uint16) (Symbol, byte, uint32) {
+	assert.Assertf(length >= DeflateMinMatch && length <= DeflateMaxMatch, "length %d not in range [%d..%d]", length, DeflateMinMatch, DeflateMaxMatch)
+	if length == DeflateMaxMatch {
+		return 285, 0, 0
+	}
+	index := len(deflateLengthBase) - 2
+	for deflateLengthBase[index] > length {
+		index--
+	}
+	return DeflateFirstLengthSymbol + Symbol(index), deflateLengthExtra[index], uint32(length - deflateLengthBase[index])
+}
+
+// DeflateDistSymbol returns the distance Symbol, the number of extra bits,
+// and the value of the extra bits for a back-reference at the given distance,
+// which must be from 1 to DeflateWindowSize.
+func DeflateDistSymbol(distance uint16) (Symbol, byte, uint32) {
+	assert.Assertf(distance >= 1 && distance <= DeflateWindowSize, "distance %d not in range [1..%d]", distance, DeflateWindowSize)
+	index := len(deflateDistBase) - 1
+	for deflateDistBase[index] > distance {
+		index--
+	}
+	return Symbol(index), deflateDistExtra[index], uint32(distance - deflateDistBase[index])
+}
+
+// DeflateLengthBase returns the shortest back-reference length and the number
+// of extra bits for the given literal/length Symbol.  The length is the base
+// plus the value of the extra bits.  Returns false if the Symbol is not a
+// length Symbol.
+func DeflateLengthBase(symbol Symbol) (base uint16, extraBits byte, ok bool) {
+	index := symbol - DeflateFirstLengthSymbol
+	if symbol < DeflateFirstLengthSymbol || int(index) >= len(deflateLengthBase) {
+		return 0, 0, false
+	}
+	return deflateLengthBase[index], deflateLengthExtra[index], true
+}
+
+// DeflateDistBase returns the shortest back-reference distance and the number
+// of extra bits for the given distance Symbol.  The distance is the base plus
+// the value of the extra bits.  Returns false if the Symbol is out of range.
+func DeflateDistBase(symbol Symbol) (base uint16, extraBits byte, ok bool) {
+	if symbol < 0 || int(symbol) >= len(deflateDistBase) {
+		return 0, 0, false
+	}
+	return deflateDistBase[symbol], deflateDistExtra[symbol], true
+}
+
+// DeflateEncoders returns literal/length and distance Encoders suited to
+// coding the given tokens with DeflateBlockWriter: every Symbol used by the
+// tokens, plus EndOfBlock, has a code, and no code is longer than the 15 bits
+// that DEFLATE allows.
+func DeflateEncoders(tokens []DeflateToken) (litlen *Encoder, dist *Encoder) {
+	litFreqs := make([]uint32, DeflateMaxLitLenSymbols)
+	distFreqs := make([]uint32, DeflateMaxDistSymbols)
+	litFreqs[EndOfBlock] = 1
+	for _, tok := range tokens {
+		if tok.IsLiteral() {
+			litFreqs[tok.Literal]++
+			continue
+		}
+		lengthSymbol, _, _ := DeflateLengthSymbol(tok.Length)
+		distSymbol, _, _ := DeflateDistSymbol(tok.Distance)
+		litFreqs[lengthSymbol]++
+		distFreqs[distSymbol]++
+	}
+
+	litlen, dist = new(Encoder), new(Encoder)
+	opts := Options{Profile: ProfileRFC1951}
+	if err := litlen.InitWithOptions(len(litFreqs), litFreqs, opts); err != nil {
+		panic(err)
+	}
+	if err := dist.InitWithOptions(len(distFreqs), distFreqs, opts); err != nil {
+		panic(err)
+	}
+	return litlen, dist
+}
+
+// DeflateBlockWriter writes raw DEFLATE (RFC 1951) blocks with
+// dynamic Huffman codes chosen by the caller.  The output can be read by any
+// DEFLATE decoder, such as compress/flate.
+//
+// The caller is responsible for the LZ77 parse, for setting final on the last
+// block, and for flushing the BitWriter afterward.
+//
+type DeflateBlockWriter struct {
+	bw *BitWriter
+}
+
+// NewDeflateBlockWriter returns a new DeflateBlockWriter which writes to the
+// given BitWriter.
+func NewDeflateBlockWriter(bw *BitWriter) *DeflateBlockWriter {
+	return &DeflateBlockWriter{bw: bw}
+}
+
+// WriteBlock writes one dynamic-Huffman block holding the given tokens,
+// coded with the given literal/length and distance Encoders.
+//
+// litlen must have at most 286 Symbols and a code for EndOfBlock, dist must
+// have at most 30 Symbols, and no code may be longer than 15 bits.  Every
+// Symbol used by the tokens must have a code.  See DeflateEncoders.
+//
+func (w *DeflateBlockWriter) WriteBlock(litlen *Encoder, dist *Encoder, tokens []DeflateToken, final bool) error {
+	if n := litlen.NumSymbols(); n > DeflateMaxLitLenSymbols {
+		return fmt.Errorf("literal/length code has %d symbols, max %d", n, DeflateMaxLitLenSymbols)
+	}
+	if n := dist.NumSymbols(); n > DeflateMaxDistSymbols {
+		return fmt.Errorf("distance code has %d symbols, max %d", n, DeflateMaxDistSymbols)
+	}
+	if litlen.MaxSize() > DeflateMaxCodeSize || dist.MaxSize() > DeflateMaxCodeSize {
+		return fmt.Errorf("code size %d exceeds DEFLATE maximum of %d", maxByte(litlen.MaxSize(), dist.MaxSize()), DeflateMaxCodeSize)
+	}
+	if litlen.NumSymbols() <= uint(EndOfBlock) || litlen.Encode(EndOfBlock).Size == 0 {
+		return fmt.Errorf("literal/length code has no code for EndOfBlock")
+	}
+	for index, tok := range tokens {
+		if tok.IsLiteral() {
+			continue
+		}
+		if tok.Length < DeflateMinMatch || tok.Length > DeflateMaxMatch {
+			return fmt.Errorf("token %d: invalid length %d", index, tok.Length)
+		}
+		if tok.Distance < 1 || tok.Distance > DeflateWindowSize {
+			return fmt.Errorf("token %d: invalid distance %d", index, tok.Distance)
+		}
+	}
+
+	litSizes := padSizes(trimSizes(litlen.SizeBySymbol()), DeflateMinLitLenSymbols)
+	distSizes := padSizes(trimSizes(dist.SizeBySymbol()), 1)
+	clens := deflateCLenSymbols(nil, append(append([]byte(nil), litSizes...), distSizes...))
+
+	clenFreqs := make([]uint32, DeflateCLenAlphabetSize)
+	for _, item := range clens {
+		clenFreqs[item.symbol]++
+	}
+	var clen Encoder
+	initLimitedTo(&clen, DeflateCLenAlphabetSize, clenFreqs, DeflateMaxCLenCodeSize)
+	numCLen := DeflateCLenAlphabetSize
+	for numCLen > 4 && clen.Encode(Symbol(deflateCLenOrder[numCLen-1])).Size == 0 {
+		numCLen--
+	}
+
+	bw := w.bw
+	var finalBit uint32
+	if final {
+		finalBit = 1
+	}
+	_ = bw.WriteBits(1, finalBit)
+	_ = bw.WriteBits(2, 2)
+	_ = bw.WriteBits(5, uint32(len(litSizes)-DeflateMinLitLenSymbols))
+	_ = bw.WriteBits(5, uint32(len(distSizes)-1))
+	_ = bw.WriteBits(4, uint32(numCLen-4))
+	for _, symbol := range deflateCLenOrder[:numCLen] {
+		_ = bw.WriteBits(3, uint32(clen.Encode(Symbol(symbol)).Size))
+	}
+	for _, item := range clens {
+		_ = bw.WriteSymbol(&clen, item.symbol)
+		_ = bw.WriteBits(item.extraSize, item.extra)
+	}
+
+	for _, tok := range tokens {
+		if tok.IsLiteral() {
+			if err := bw.WriteSymbol(litlen, Symbol(tok.Literal)); err != nil {
+				return err
+			}
+			continue
+		}
+		lengthSymbol, lengthExtraSize, lengthExtra := DeflateLengthSymbol(tok.Length)
+		if err := bw.WriteSymbol(litlen, lengthSymbol); err != nil {
+			return err
+		}
+		_ = bw.WriteBits(lengthExtraSize, lengthExtra)
+		distSymbol, distExtraSize, distExtra := DeflateDistSymbol(tok.Distance)
+		if err := bw.WriteSymbol(dist, distSymbol); err != nil {
+			return err
+		}
+		if err := bw.WriteBits(distExtraSize, distExtra); err != nil {
+			return err
+		}
+	}
+	return bw.WriteSymbol(litlen, EndOfBlock)
+}
+
+// deflateCLen is one Symbol of the code length alphabet, with its extra
+// bits.
+type deflateCLen struct {
+	symbol    Symbol
+	extraSize byte
+	extra     uint32
+}
+
+// deflateCLenSymbols appends the code length alphabet encoding of sizes to
+// out, using Symbols 16 to 18 for runs, per RFC 1951 Section 3.2.7.
+func deflateCLenSymbols(out []deflateCLen, sizes []byte) []deflateCLen {
+	for i := 0; i < len(sizes); {
+		size := sizes[i]
+		run := 1
+		for i+run < len(sizes) && sizes[i+run] == size {
+			run++
+		}
+		i += run
+
+		if size == 0 {
+			for run >= 11 {
+				n := minInt(run, 138)
+				out = append(out, deflateCLen{18, 7, uint32(n - 11)})
+				run -= n
+			}
+			if run >= 3 {
+				out = append(out, deflateCLen{17, 3, uint32(run - 3)})
+				run = 0
+			}
+		} else {
+			out = append(out, deflateCLen{Symbol(size), 0, 0})
+			run--
+			for run >= 3 {
+				n := minInt(run, 6)
+				out = append(out, deflateCLen{16, 2, uint32(n - 3)})
+				run -= n
+			}
+		}
+		for ; run > 0; run-- {
+			out = append(out, deflateCLen{Symbol(size), 0, 0})
+		}
+	}
+	return out
+}
+
+// padSizes extends sizes with zeroes to at least min entries.
+func padSizes(sizes []byte, min int) []byte {
+	for len(sizes) < min {
+		sizes = append(sizes, 0)
+	}
+	return sizes
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxByte(a, b byte) byte {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// DeflateLengthExtraBits returns the number of extra bits for each
+// literal/length Symbol, for use as Options.ExtraBits.
+func DeflateLengthExtraBits() []byte {
+	out := make([]byte, DeflateMaxLitLenSymbols)
+	copy(out[DeflateFirstLengthSymbol:], deflateLengthExtra[:])
+	return out
+}
+
+// DeflateDistExtraBits returns the number of extra bits for each distance
+// Symbol, for use as Options.ExtraBits.
+func DeflateDistExtraBits() []byte {
+	out := make([]byte, DeflateMaxDistSymbols)
+	copy(out, deflateDistExtra[:])
+	return out
+}
+
+// ParseDeflateDynamicHeader reads the code tables at the start of a DEFLATE
+// block with dynamic Huffman codes (BTYPE=10), per RFC 1951 Section 3.2.7,
+// and returns Decoders for the block's literal/length and distance codes.
+// br must be positioned just after the block's 3-bit BFINAL/BTYPE header.
+//
+// The Decoders' Options.ExtraBits hold DEFLATE's extra bit counts, so the
+// block's codes can be read with BitReader.ReadSymbolWithExtra.  The base
+// length or distance must still be added to the extra value.
+//
+func ParseDeflateDynamicHeader(br *BitReader) (litlen *Decoder, dist *Decoder, err error) {
+	hlit, err := br.ReadBits(5)
+	if err != nil {
+		return nil, nil, noEOF(err)
+	}
+	hdist, err := br.ReadBits(5)
+	if err != nil {
+		return nil, nil, noEOF(err)
+	}
+	hclen, err := br.ReadBits(4)
+	if err != nil {
+		return nil, nil, noEOF(err)
+	}
+	numLit := int(hlit) + DeflateMinLitLenSymbols
+	numDist := int(hdist) + 1
+	numCLen := int(hclen) + 4
+	if numLit > DeflateMaxLitLenSymbols || numDist > DeflateMaxDistSymbols {
+		return nil, nil, fmt.Errorf("invalid DEFLATE header: %d literal/length codes, %d distance codes", numLit, numDist)
+	}
+
+	clenSizes := make([]byte, DeflateCLenAlphabetSize)
+	for _, symbol := range deflateCLenOrder[:numCLen] {
+		size, err := br.ReadBits(3)
+		if err != nil {
+			return nil, nil, noEOF(err)
+		}
+		clenSizes[symbol] = byte(size)
+	}
+	var clen Decoder
+	if err := clen.Init(clenSizes); err != nil {
+		return nil, nil, fmt.Errorf("invalid DEFLATE code length code: %w", err)
+	}
+
+	sizes := make([]byte, 0, numLit+numDist)
+	for len(sizes) < numLit+numDist {
+		symbol, err := br.ReadSymbol(&clen)
+		if err != nil {
+			return nil, nil, noEOF(err)
+		}
+
+		var value byte
+		var repeat uint32
+		switch {
+		case symbol < 16:
+			sizes = append(sizes, byte(symbol))
+			continue
+		case symbol == 16:
+			if len(sizes) == 0 {
+				return nil, nil, fmt.Errorf("invalid DEFLATE header: repeat with no previous code length")
+			}
+			value = sizes[len(sizes)-1]
+			repeat, err = br.ReadBits(2)
+			repeat += 3
+		case symbol == 17:
+			repeat, err = br.ReadBits(3)
+			repeat += 3
+		default:
+			repeat, err = br.ReadBits(7)
+			repeat += 11
+		}
+		if err != nil {
+			return nil, nil, noEOF(err)
+		}
+		if len(sizes)+int(repeat) > numLit+numDist {
+			return nil, nil, fmt.Errorf("invalid DEFLATE header: code lengths overflow table")
+		}
+		for ; repeat > 0; repeat-- {
+			sizes = append(sizes, value)
+		}
+	}
+	if sizes[EndOfBlock] == 0 {
+		return nil, nil, fmt.Errorf("invalid DEFLATE header: no code for end of block")
+	}
+
+	litlen, dist = new(Decoder), new(Decoder)
+	if err := litlen.InitWithOptions(sizes[:numLit], Options{ExtraBits: DeflateLengthExtraBits(), Profile: ProfileRFC1951}); err != nil {
+		return nil, nil, fmt.Errorf("invalid DEFLATE literal/length code: %w", err)
+	}
+	if err := dist.InitWithOptions(sizes[numLit:], Options{ExtraBits: DeflateDistExtraBits(), Profile: ProfileRFC1951}); err != nil {
+		return nil, nil, fmt.Errorf("invalid DEFLATE distance code: %w", err)
+	}
+	return litlen, dist, nil
+}
@@ -0,0 +1,224 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// The dictionary file format (conventionally stored with a ".huffdict"
+// extension) is:
+//
+//     dict   := "HUFD" version:byte flags:byte sizes:RLE remap? crc:uint32le
+//     remap  := symbol:uvarint*
+//
+// The sizes table uses the same run-length encoding as the stream format.
+// If bit 0 of flags is set, it is followed by one remap entry per symbol.
+// The trailing crc is the CRC-32C of all preceding bytes.  All other bits of
+// flags must be zero.
+//
+
+const (
+	dictVersion = 1
+
+	dictFlagRemap  = 0x01
+	dictFlagsKnown = dictFlagRemap
+
+	maxDictSymbols = 1 << 24
+)
+
+var dictMagic = [4]byte{'H', 'U', 'F', 'D'}
+
+// ErrDict is returned when loading an invalid dictionary.
+var ErrDict = errors.New("invalid Huffman dictionary")
+
+// Dict is a persistable Huffman code, suitable for sharing a trained code
+// between the producers and consumers of compressed data.
+type Dict struct {
+	// Sizes holds the bit length of each symbol in the code.  See
+	// Decoder.Init for details.
+	Sizes []byte
+
+	// Remap optionally maps each symbol in the code to a symbol in the
+	// application's alphabet.  If non-nil, it must have the same length as
+	// Sizes and must not contain duplicate or negative symbols.
+	Remap []Symbol
+}
+
+// NewDict returns a Dict holding the code used by the given Encoder.
+func NewDict(e *Encoder) Dict {
+	return Dict{Sizes: e.SizeBySymbol()}
+}
+
+// TrainStreamDict returns a Dict suitable for use with the stream format (see
+// DictRegistry), trained on the byte frequencies of the given samples.  Every
+// byte value is assigned a code, so the Dict can code any input.
+func TrainStreamDict(samples ...[]byte) Dict {
+	frequencies := make([]uint32, StreamAlphabetSize)
+	for index := range frequencies {
+		frequencies[index] = 1
+	}
+	for _, sample := range samples {
+		for _, b := range sample {
+			if frequencies[b] < math.MaxUint32 {
+				frequencies[b]++
+			}
+		}
+	}
+
+	var e Encoder
+	initLimited(&e, StreamAlphabetSize, frequencies)
+	return NewDict(&e)
+}
+
+// Encoder returns a new Encoder for this dictionary's code.
+func (dict Dict) Encoder() (*Encoder, error) {
+	e := new(Encoder)
+	if err := e.InitFromSizes(dict.Sizes); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Decoder returns a new Decoder for this dictionary's code.
+func (dict Dict) Decoder() (*Decoder, error) {
+	d := new(Decoder)
+	if err := d.Init(dict.Sizes); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Validate checks that this Dict is well-formed.
+func (dict Dict) Validate() error {
+	if len(dict.Sizes) > maxDictSymbols {
+		return fmt.Errorf("%w: too many symbols: got %d, max %d", ErrDict, len(dict.Sizes), maxDictSymbols)
+	}
+	for _, size := range dict.Sizes {
+		if size > maxBitsPerCode {
+			return fmt.Errorf("%w: invalid bit length: got %d, max %d", ErrDict, size, maxBitsPerCode)
+		}
+	}
+	if dict.Remap != nil {
+		if len(dict.Remap) != len(dict.Sizes) {
+			return fmt.Errorf("%w: remap has %d entries, expected %d", ErrDict, len(dict.Remap), len(dict.Sizes))
+		}
+		seen := make(map[Symbol]struct{}, len(dict.Remap))
+		for _, symbol := range dict.Remap {
+			if symbol < 0 {
+				return fmt.Errorf("%w: remap contains invalid symbol %d", ErrDict, symbol)
+			}
+			if _, found := seen[symbol]; found {
+				return fmt.Errorf("%w: remap contains duplicate symbol %d", ErrDict, symbol)
+			}
+			seen[symbol] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// MarshalBinary renders this Dict in the dictionary file format.
+func (dict Dict) MarshalBinary() ([]byte, error) {
+	if err := dict.Validate(); err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	if dict.Remap != nil {
+		flags |= dictFlagRemap
+	}
+
+	out := make([]byte, 0, 16+len(dict.Sizes))
+	out = append(out, dictMagic[:]...)
+	out = append(out, dictVersion, flags)
+	out = appendSizesRLE(out, dict.Sizes)
+
+	var tmp [binary.MaxVarintLen64]byte
+	for _, symbol := range dict.Remap {
+		n := binary.PutUvarint(tmp[:], uint64(symbol))
+		out = append(out, tmp[:n]...)
+	}
+
+	var crc [4]byte
+	binary.LittleEndian.PutUint32(crc[:], crc32.Checksum(out, crc32cTable))
+	return append(out, crc[:]...), nil
+}
+
+// UnmarshalBinary initializes this Dict from the dictionary file format.
+func (dict *Dict) UnmarshalBinary(raw []byte) error {
+	if len(raw) < 10 {
+		return fmt.Errorf("%w: too short", ErrDict)
+	}
+	body, crc := raw[:len(raw)-4], raw[len(raw)-4:]
+	if binary.LittleEndian.Uint32(crc) != crc32.Checksum(body, crc32cTable) {
+		return fmt.Errorf("%w: checksum mismatch", ErrDict)
+	}
+
+	if !bytes.Equal(body[0:4], dictMagic[:]) {
+		return fmt.Errorf("%w: bad magic", ErrDict)
+	}
+	if body[4] != dictVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrDict, body[4])
+	}
+	flags := body[5]
+	if (flags &^ dictFlagsKnown) != 0 {
+		return fmt.Errorf("%w: unknown flags 0x%02x", ErrDict, flags)
+	}
+
+	r := bytes.NewReader(body[6:])
+	sizes, err := readSizesRLE(r, maxDictSymbols)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDict, err)
+	}
+
+	var remap []Symbol
+	if (flags & dictFlagRemap) != 0 {
+		remap = make([]Symbol, len(sizes))
+		for i := range remap {
+			value, err := binary.ReadUvarint(r)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrDict, noEOF(err))
+			}
+			if value > uint64(MaxSymbol) {
+				return fmt.Errorf("%w: remap contains invalid symbol %d", ErrDict, value)
+			}
+			remap[i] = Symbol(value)
+		}
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: %d bytes of trailing garbage", ErrDict, r.Len())
+	}
+
+	tmp := Dict{Sizes: sizes, Remap: remap}
+	if err := tmp.Validate(); err != nil {
+		return err
+	}
+	*dict = tmp
+	return nil
+}
+
+// SaveDict writes the given Dict to w in the dictionary file format.
+func SaveDict(w io.Writer, dict Dict) error {
+	raw, err := dict.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(raw)
+	return err
+}
+
+// LoadDict reads a Dict in the dictionary file format from r.  It consumes
+// all remaining input.
+func LoadDict(r io.Reader) (Dict, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return Dict{}, err
+	}
+	var dict Dict
+	err = dict.UnmarshalBinary(raw)
+	return dict, err
+}
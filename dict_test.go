@@ -0,0 +1,83 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDict_RoundTrip(t *testing.T) {
+	type testRow struct {
+		name string
+		dict Dict
+	}
+
+	testData := [...]testRow{
+		{name: "empty", dict: Dict{Sizes: []byte{}}},
+		{name: "plain", dict: Dict{Sizes: []byte{4, 4, 3, 3, 3, 1}}},
+		{name: "remap", dict: Dict{Sizes: []byte{2, 2, 1}, Remap: []Symbol{65, 1000, 7}}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := SaveDict(&buf, row.dict); err != nil {
+				t.Fatalf("SaveDict: unexpected error: %v", err)
+			}
+			dict, err := LoadDict(&buf)
+			if err != nil {
+				t.Fatalf("LoadDict: unexpected error: %v", err)
+			}
+			if !bytes.Equal(row.dict.Sizes, dict.Sizes) {
+				t.Errorf("wrong sizes:\n\texpect: %#v\n\tactual: %#v", row.dict.Sizes, dict.Sizes)
+			}
+			if len(row.dict.Remap) != len(dict.Remap) {
+				t.Fatalf("wrong remap:\n\texpect: %#v\n\tactual: %#v", row.dict.Remap, dict.Remap)
+			}
+			for index := range dict.Remap {
+				if row.dict.Remap[index] != dict.Remap[index] {
+					t.Errorf("wrong remap:\n\texpect: %#v\n\tactual: %#v", row.dict.Remap, dict.Remap)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestDict_Encoder(t *testing.T) {
+	e := makeTestEncoder()
+	dict := NewDict(&e)
+
+	e2, err := dict.Encoder()
+	if err != nil {
+		t.Fatalf("Encoder: unexpected error: %v", err)
+	}
+	if expect, actual := e.GoString(), e2.GoString(); expect != actual {
+		t.Errorf("wrong Encoder:\n\texpect: %s\n\tactual: %s", expect, actual)
+	}
+
+	d, err := dict.Decoder()
+	if err != nil {
+		t.Fatalf("Decoder: unexpected error: %v", err)
+	}
+	if expect, actual := makeTestDecoder().GoString(), d.GoString(); expect != actual {
+		t.Errorf("wrong Decoder:\n\texpect: %s\n\tactual: %s", expect, actual)
+	}
+}
+
+func TestDict_Invalid(t *testing.T) {
+	raw, err := Dict{Sizes: []byte{1, 1}}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: unexpected error: %v", err)
+	}
+
+	corrupt := append([]byte(nil), raw...)
+	corrupt[6] ^= 0x01
+	var dict Dict
+	if err := dict.UnmarshalBinary(corrupt); !errors.Is(err, ErrDict) {
+		t.Errorf("UnmarshalBinary: expected ErrDict, got %v", err)
+	}
+
+	if _, err := (Dict{Sizes: []byte{1, 1}, Remap: []Symbol{3, 3}}).MarshalBinary(); !errors.Is(err, ErrDict) {
+		t.Errorf("MarshalBinary: expected ErrDict, got %v", err)
+	}
+}
@@ -1,6 +1,11 @@
 // Package huffman implements canonical Huffman codes.  These are useful for
 // DEFLATE and other compression algorithms.
 //
+// Building with the "huffmandebug" tag enables exhaustive checks of the
+// internal consistency of every Encoder and Decoder after Init, and
+// periodically while streaming, which panic on failure.  They are slow, and
+// meant only for tracking down bugs such as memory corruption.
+//
 // References:
 //
 //     <https://www.rfc-editor.org/rfc/rfc1951.html>, Section 3.2.2
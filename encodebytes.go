@@ -0,0 +1,140 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// EncodeBytes encodes each byte of src as a Symbol using this Encoder and
+// writes the resulting codes to w.  It produces exactly the same output as
+// calling w.WriteSymbol for each byte, including the handling of bytes with
+// no code per Options.MissingCode, but is considerably faster for the common
+// 256-symbol byte alphabet.
+//
+// Returns ErrNoCode if any byte in src has no assigned code and the policy is
+// MissingCodeError, or if any byte lies outside the alphabet.
+//
+func (e *Encoder) EncodeBytes(src []byte, w *BitWriter) error {
+	if w.err != nil {
+		return w.err
+	}
+	if e.maxSize > 32 {
+		for _, b := range src {
+			if err := e.writeByteSymbol(b, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Each entry holds the code's bits in the low 32 bits and its size in
+	// the high 32 bits.  Entries for Symbols which the kernel cannot write
+	// directly have size 0, and are passed to BitWriter.WriteSymbol.
+	var table [256]uint64
+	for symbol := range e.codes {
+		if symbol >= len(table) {
+			break
+		}
+		if e.escapes(Symbol(symbol)) {
+			continue
+		}
+		hc := e.codes[symbol]
+		if hc.Size == 0 && e.opts.MissingCode == MissingCodeSubstitute {
+			hc = e.encodeMissing(Symbol(symbol))
+		}
+		table[symbol] = uint64(hc.Bits) | uint64(hc.Size)<<32
+	}
+
+	for len(src) != 0 {
+		src = src[encodeBytesRun(&table, e.maxSize, src, w):]
+		if len(src) == 0 {
+			break
+		}
+		if err := e.writeByteSymbol(src[0], w); err != nil {
+			return err
+		}
+		src = src[1:]
+	}
+	return nil
+}
+
+// writeByteSymbol writes b as by BitWriter.WriteSymbol, except that a byte
+// outside the alphabet is reported as ErrNoCode rather than a panic.
+func (e *Encoder) writeByteSymbol(b byte, w *BitWriter) error {
+	if int(b) >= len(e.codes) {
+		return fmt.Errorf("symbol %s: %w", e.opts.formatSymbol(Symbol(b)), ErrNoCode)
+	}
+	return w.WriteSymbol(e, Symbol(b))
+}
+
+// encodeBytesRun writes the codes for a prefix of src from table, stopping
+// at the first byte whose entry has size 0.  maxSize is the longest code in
+// table.  Returns the number of bytes written.
+func encodeBytesRun(table *[256]uint64, maxSize byte, src []byte, w *BitWriter) int {
+	// Grow the buffer once, up front, so the loops below can store whole
+	// 32-bit words without bounds checks against capacity.
+	maxBytes := (len(src)*int(maxSize)+int(w.n))/8 + 8
+	buf := w.buf
+	if cap(buf)-len(buf) < maxBytes {
+		grown := make([]byte, len(buf), len(buf)+maxBytes)
+		copy(grown, buf)
+		buf = grown
+	}
+	pos := len(buf)
+	buf = buf[:cap(buf)]
+
+	acc := w.acc
+	n := uint(w.n)
+	var total uint64
+
+	// Invariant at the top of each loop: n < 8.  After adding codes to the
+	// reservoir, all 8 bytes of it are stored unconditionally and the
+	// output position advances by the number of complete bytes, which
+	// avoids a hard-to-predict branch.  When no code exceeds 16 bits, three
+	// codes fit in the reservoir between stores.
+	i := 0
+	if maxSize <= 16 {
+		for ; i+2 < len(src); i += 3 {
+			x0 := table[src[i]]
+			x1 := table[src[i+1]]
+			x2 := table[src[i+2]]
+			s0 := uint(x0 >> 32)
+			s1 := uint(x1 >> 32)
+			s2 := uint(x2 >> 32)
+			if s0 == 0 || s1 == 0 || s2 == 0 {
+				break
+			}
+			acc |= (x0 & 0xffffffff) << n
+			n += s0
+			acc |= (x1 & 0xffffffff) << n
+			n += s1
+			acc |= (x2 & 0xffffffff) << n
+			n += s2
+			total += uint64(s0 + s1 + s2)
+			binary.LittleEndian.PutUint64(buf[pos:], acc)
+			pos += int(n >> 3)
+			acc >>= n &^ 7
+			n &= 7
+		}
+	}
+	for ; i < len(src); i++ {
+		x := table[src[i]]
+		s := uint(x >> 32)
+		if s == 0 {
+			break
+		}
+		acc |= (x & 0xffffffff) << n
+		n += s
+		total += uint64(s)
+		binary.LittleEndian.PutUint64(buf[pos:], acc)
+		pos += int(n >> 3)
+		acc >>= n &^ 7
+		n &= 7
+	}
+
+	w.acc = acc
+	w.n = byte(n)
+	w.buf = buf[:pos]
+	w.count += total
+	return i
+}
@@ -0,0 +1,109 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func makeByteEncoder(data []byte, limited bool) *Encoder {
+	frequencies := make([]uint32, 256)
+	for _, b := range data {
+		frequencies[b]++
+	}
+	e := new(Encoder)
+	if limited {
+		initLimited(e, 256, frequencies)
+	} else {
+		e.Init(256, frequencies)
+	}
+	return e
+}
+
+func TestEncoder_EncodeBytes(t *testing.T) {
+	type testRow struct {
+		name string
+		e    *Encoder
+		data []byte
+	}
+
+	text := []byte("It was the best of times, it was the worst of times, it was the age of wisdom, it was the age of foolishness")
+	testData := [...]testRow{
+		{"text", makeByteEncoder(text, true), text},
+		{"all", makeByteEncoder(allBytes(1), true), allBytes(3)},
+		{"skewed-limited", makeByteEncoder(skewedBytes(), true), skewedBytes()},
+		{"skewed-unlimited", makeByteEncoder(skewedBytes(), false), skewedBytes()},
+		{"empty", makeByteEncoder(text, true), nil},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			// Start unaligned, to exercise the reservoir hand-off.
+			for _, prefix := range []byte{0, 3, 7} {
+				var expect, actual bytes.Buffer
+				bw := NewBitWriter(&expect)
+				_ = bw.WriteBits(prefix, 0x55)
+				for _, b := range row.data {
+					if err := bw.WriteSymbol(row.e, Symbol(b)); err != nil {
+						t.Fatalf("WriteSymbol: unexpected error: %v", err)
+					}
+				}
+				expectBits := bw.BitsWritten()
+				_ = bw.Flush()
+
+				bw = NewBitWriter(&actual)
+				_ = bw.WriteBits(prefix, 0x55)
+				if err := row.e.EncodeBytes(row.data, bw); err != nil {
+					t.Fatalf("EncodeBytes: unexpected error: %v", err)
+				}
+				if actualBits := bw.BitsWritten(); expectBits != actualBits {
+					t.Errorf("prefix %d: expected %d bits, got %d", prefix, expectBits, actualBits)
+				}
+				_ = bw.Flush()
+
+				if !bytes.Equal(expect.Bytes(), actual.Bytes()) {
+					t.Errorf("prefix %d: output mismatch:\n\texpect: %x\n\tactual: %x", prefix, expect.Bytes(), actual.Bytes())
+				}
+			}
+		})
+	}
+}
+
+func TestEncoder_EncodeBytes_NoCode(t *testing.T) {
+	e := NewEncoder(256, []uint32{'a': 5, 'b': 3, 'c': 1})
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	err := e.EncodeBytes([]byte("abcabcz"), bw)
+	if !errors.Is(err, ErrNoCode) {
+		t.Errorf("expected ErrNoCode, got %v", err)
+	}
+}
+
+func BenchmarkEncoder_EncodeBytes(b *testing.B) {
+	data := skewedBytes()
+	for len(data) < 1<<20 {
+		data = append(data, data...)
+	}
+	e := makeByteEncoder(data, true)
+
+	b.Run("WriteSymbol", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		var bw BitWriter
+		for i := 0; i < b.N; i++ {
+			bw.Init(io.Discard)
+			for _, c := range data {
+				_ = bw.WriteSymbol(e, Symbol(c))
+			}
+			_ = bw.Flush()
+		}
+	})
+	b.Run("EncodeBytes", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		var bw BitWriter
+		for i := 0; i < b.N; i++ {
+			bw.Init(io.Discard)
+			_ = e.EncodeBytes(data, &bw)
+			_ = bw.Flush()
+		}
+	})
+}
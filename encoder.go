@@ -1,13 +1,10 @@
 package huffman
 
 import (
-	"container/heap"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math"
-	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/chronos-tachyon/assert"
@@ -16,6 +13,7 @@ import (
 // Encoder implements an encoder for canonical Huffman codes.
 type Encoder struct {
 	codes   []Code
+	opts    Options
 	minSize byte
 	maxSize byte
 }
@@ -46,36 +44,28 @@ func NewEncoderFromSizes(sizes []byte) *Encoder {
 // have a frequency of 0.
 //
 func (e *Encoder) Init(numSymbols int, frequencies []uint32) {
+	e.init(numSymbols, frequencies, Options{})
+}
+
+func (e *Encoder) init(numSymbols int, frequencies []uint32, opts Options) {
 	assert.Assertf(numSymbols >= 1, "numSymbols %d < 1", numSymbols)
 	assert.Assertf(numSymbols <= int(MaxSymbol), "numSymbols %d > MaxSymbol %d", numSymbols, int(MaxSymbol))
 	assert.Assertf(numSymbols >= len(frequencies), "numSymbols %d < len(frequencies) %d", numSymbols, len(frequencies))
 
-	codes := make([]Code, numSymbols)
-	nodes := make([]symbolAndFreq, 0, numSymbols)
-	for symbol := Symbol(0); symbol < Symbol(len(frequencies)); symbol++ {
-		if freq := frequencies[symbol]; freq != 0 {
-			nodes = append(nodes, symbolAndFreq{symbol, freq})
-		}
-	}
+	b := Builder{opts: opts}
+	b.build(e, numSymbols, frequencies, make([]Code, numSymbols))
+}
 
-	var minSize, maxSize byte
-	nodeLen := uint32(len(nodes))
-	if nodeLen <= 2 {
-		minSize, maxSize = 1, 1
-		for index := uint32(0); index < nodeLen; index++ {
-			node := nodes[index]
-			codes[node.symbol] = MakeCode(1, index)
-		}
-	} else {
-		firstPass(codes, nodes, &minSize, &maxSize)
-		_ = secondPass(codes)
-	}
-
-	*e = Encoder{
-		codes:   codes,
-		minSize: minSize,
-		maxSize: maxSize,
+// NewFlatEncoder returns a new Encoder in which all numSymbols Symbols are
+// equally likely.  Each code is either floor(log2(numSymbols)) bits long or
+// one bit longer; if numSymbols is a power of 2, all codes are the same
+// length.
+func NewFlatEncoder(numSymbols int) *Encoder {
+	frequencies := make([]uint32, numSymbols)
+	for index := range frequencies {
+		frequencies[index] = 1
 	}
+	return NewEncoder(numSymbols, frequencies)
 }
 
 // InitFromSizes initializes this Encoder from a list of bit lengths, one for
@@ -115,6 +105,9 @@ func (e *Encoder) InitFromSizes(sizes []byte) error {
 		minSize: minSize,
 		maxSize: maxSize,
 	}
+	if debugChecks {
+		debugCheck("Encoder.InitFromSizes", e.checkInvariants(false))
+	}
 	return nil
 }
 
@@ -124,9 +117,115 @@ func (e *Encoder) InitFromDecoder(d Decoder) error {
 	return e.InitFromSizes(d.SizeBySymbol())
 }
 
-// Encode encodes a Symbol into a Huffman-coded bit string.
+// InitFromMerged initializes this Encoder with a code for a mixture of the
+// sources modeled by a and b, in proportion to weightA and weightB.
+//
+// An Encoder does not retain the frequencies it was built from, so each code
+// is taken as a model in which a Symbol with an n-bit code has probability
+// 2**-n.  The models are blended, and a new code is built for the blend.  No
+// code in the result is longer than 16 bits.  Every Symbol with a code in
+// either a or b has a code in the result, unless its weight is 0.
+//
+func (e *Encoder) InitFromMerged(a, b *Encoder, weightA, weightB float64) error {
+	if math.IsNaN(weightA) || math.IsInf(weightA, 0) || weightA < 0 {
+		return fmt.Errorf("invalid weight %v for first Encoder", weightA)
+	}
+	if math.IsNaN(weightB) || math.IsInf(weightB, 0) || weightB < 0 {
+		return fmt.Errorf("invalid weight %v for second Encoder", weightB)
+	}
+	sum := weightA + weightB
+	if sum == 0 {
+		return fmt.Errorf("weights must not both be zero")
+	}
+	weightA /= sum
+	weightB /= sum
+
+	numSymbols := len(a.codes)
+	if numSymbols < len(b.codes) {
+		numSymbols = len(b.codes)
+	}
+	if numSymbols == 0 {
+		return fmt.Errorf("cannot merge two empty Encoders")
+	}
+
+	const scale = 1 << 24
+	frequencies := make([]uint32, numSymbols)
+	for symbol := range frequencies {
+		var p float64
+		if symbol < len(a.codes) && a.codes[symbol].Size != 0 {
+			p += weightA * math.Ldexp(1, -int(a.codes[symbol].Size))
+		}
+		if symbol < len(b.codes) && b.codes[symbol].Size != 0 {
+			p += weightB * math.Ldexp(1, -int(b.codes[symbol].Size))
+		}
+		if p > 0 {
+			frequencies[symbol] = uint32(math.Round(p * scale))
+			if frequencies[symbol] == 0 {
+				frequencies[symbol] = 1
+			}
+		}
+	}
+
+	initLimited(e, numSymbols, frequencies)
+	return nil
+}
+
+// Assigned returns true if the given Symbol has a code.  It returns false
+// for Symbols outside the alphabet, rather than panicking as Encode does.
+func (e Encoder) Assigned(symbol Symbol) bool {
+	return uint(symbol) < uint(len(e.codes)) && e.codes[symbol].Size != 0
+}
+
+// Encode encodes a Symbol into a Huffman-coded bit string.  The result for a
+// Symbol with a code is always a valid Code (see Code.IsValid), even if it is
+// the only Symbol with a code.  The result for a Symbol with no code depends
+// on Options.MissingCode; by default it is the zero Code (see Code.IsZero).
 func (e Encoder) Encode(symbol Symbol) Code {
-	return e.codes[symbol]
+	hc := e.codes[symbol]
+	if hc.Size == 0 && e.opts.MissingCode != MissingCodeError {
+		return e.encodeMissing(symbol)
+	}
+	return hc
+}
+
+// EncodeToUint64 encodes a Symbol, returning the bits which
+// BitWriter.WriteSymbol would write for it, with the first bit in the least
+// significant position, and the number of bits.  A Symbol with no code is
+// handled according to Options.MissingCode: under MissingCodeEscape the
+// result is the escape code followed by the raw Symbol, and under
+// MissingCodeError it is (0, 0).
+func (e Encoder) EncodeToUint64(symbol Symbol) (bits uint64, n byte) {
+	if e.escapes(symbol) {
+		hc := e.codes[e.opts.FallbackSymbol]
+		return uint64(hc.Bits) | uint64(symbol)<<hc.Size, hc.Size + rawSymbolBits(len(e.codes))
+	}
+	hc := e.Encode(symbol)
+	return uint64(hc.Bits), hc.Size
+}
+
+// EncodeAppendBits encodes a Symbol and writes the bits returned by
+// EncodeToUint64 into dst starting at bit offset bitPos, least significant
+// bit first, as BitWriter would.  dst is extended as needed, and any bits
+// already in dst at or after bitPos are assumed to be zero.  Returns the
+// extended slice and the bit offset just past the code.  If the Symbol has no
+// code under MissingCodeError, dst and bitPos are returned unchanged.
+//
+func (e Encoder) EncodeAppendBits(dst []byte, bitPos uint, symbol Symbol) ([]byte, uint) {
+	bits, n := e.EncodeToUint64(symbol)
+	if n == 0 {
+		return dst, bitPos
+	}
+	end := bitPos + uint(n)
+	for uint(len(dst)) < (end+7)/8 {
+		dst = append(dst, 0)
+	}
+	for bitPos < end {
+		shift := bitPos % 8
+		dst[bitPos/8] |= byte(bits << shift)
+		bits >>= 8 - shift
+		bitPos += 8 - shift
+	}
+	return dst, end
 }
 
 // MinSize is the bit length of the shortest legal code.
@@ -152,6 +251,42 @@ func (e Encoder) MaxSymbol() Symbol {
 	return Symbol(len(e.codes)) - 1
 }
 
+// IsDegenerate returns true if fewer than 2 Symbols have codes, in which case
+// no bits are needed to tell the Symbols apart.  See Decoder.Init.
+func (e Encoder) IsDegenerate() bool {
+	_, n := e.soleSymbol()
+	return n < 2
+}
+
+// SoleSymbol returns the only Symbol with a code, and true, if exactly one
+// Symbol has a code.  Otherwise, it returns (InvalidSymbol, false).
+func (e Encoder) SoleSymbol() (Symbol, bool) {
+	symbol, n := e.soleSymbol()
+	if n != 1 {
+		return InvalidSymbol, false
+	}
+	return symbol, true
+}
+
+// soleSymbol returns the first Symbol with a code and the number of Symbols
+// with codes, counting no further than 2.
+func (e Encoder) soleSymbol() (Symbol, int) {
+	first, n := InvalidSymbol, 0
+	for symbol, hc := range e.codes {
+		if hc.Size == 0 {
+			continue
+		}
+		if n == 0 {
+			first = Symbol(symbol)
+		}
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	return first, n
+}
+
 // SizeBySymbol returns an array containing the bit length for each Symbol in
 // the alphabet.  This array can be transmitted to another party and used by
 // Decoder to reconstruct this Huffman code on the receiving end.
@@ -166,6 +301,17 @@ func (e Encoder) SizeBySymbol() []byte {
 	return out
 }
 
+// CodeLengthHistogram returns the number of codes of each size, indexed by
+// size, from 0 through MaxSize().  The entry at index 0 counts the Symbols
+// which have no code.
+func (e Encoder) CodeLengthHistogram() []uint32 {
+	out := make([]uint32, int(e.maxSize)+1)
+	for _, hc := range e.codes {
+		out[hc.Size]++
+	}
+	return out
+}
+
 // Decoder returns a new Decoder which mirrors this Encoder.
 func (e Encoder) Decoder() *Decoder {
 	d := new(Decoder)
@@ -175,6 +321,79 @@ func (e Encoder) Decoder() *Decoder {
 	return d
 }
 
+// IsOptimalFor reports whether this Encoder's code is a minimum-redundancy
+// code for the given Symbol frequencies, i.e. whether coding them takes no
+// more bits than with a code freshly built by Init.  It returns false if any
+// Symbol with a non-zero frequency has no code.
+//
+// Many different codes can be optimal for the same frequencies, so this is
+// not the same as comparing against the bit lengths that Init would assign.
+//
+func (e Encoder) IsOptimalFor(frequencies []uint32) bool {
+	actual, ok := codedBits(&e, frequencies)
+	if !ok {
+		return false
+	}
+
+	numSymbols := len(frequencies)
+	for numSymbols > 0 && frequencies[numSymbols-1] == 0 {
+		numSymbols--
+	}
+	if numSymbols == 0 {
+		return true
+	}
+
+	var optimal Encoder
+	optimal.Init(numSymbols, frequencies[:numSymbols])
+	expect, _ := codedBits(&optimal, frequencies[:numSymbols])
+	return actual <= expect
+}
+
+// SizeVariance returns the variance of the code length per Symbol coded, for
+// a message with the given Symbol frequencies.  Symbols with no code are
+// ignored.  A lower variance means more uniform per-Symbol decoding times;
+// see TieBreakMinVariance.
+func (e Encoder) SizeVariance(frequencies []uint32) float64 {
+	var count, sum, sumSquares float64
+	for index, freq := range frequencies {
+		if freq == 0 || index >= len(e.codes) {
+			continue
+		}
+		size := float64(e.codes[index].Size)
+		if size == 0 {
+			continue
+		}
+		count += float64(freq)
+		sum += float64(freq) * size
+		sumSquares += float64(freq) * size * size
+	}
+	if count == 0 {
+		return 0
+	}
+	mean := sum / count
+	return sumSquares/count - mean*mean
+}
+
+// Clone returns a new Encoder which is an independent copy of this Encoder.
+func (e Encoder) Clone() *Encoder {
+	codes := make([]Code, len(e.codes))
+	copy(codes, e.codes)
+	return &Encoder{
+		codes:   codes,
+		opts:    e.opts,
+		minSize: e.minSize,
+		maxSize: e.maxSize,
+	}
+}
+
+// copyFrom makes e a copy of src, as Clone does, but reuses the storage of
+// e's previous code.
+func (e *Encoder) copyFrom(src *Encoder) {
+	codes := append(e.codes[:0], src.codes...)
+	*e = *src
+	e.codes = codes
+}
+
 // Dump writes DebugString() to the given writer.
 func (e Encoder) Dump(w io.Writer) (int64, error) {
 	r := strings.NewReader(e.DebugString())
@@ -184,34 +403,26 @@ func (e Encoder) Dump(w io.Writer) (int64, error) {
 // DebugString returns a programmer-readable debugging string of the Encoder's
 // current state.
 func (e Encoder) DebugString() string {
-	var buf strings.Builder
-	buf.WriteString("Encoder{\n")
-	fmt.Fprintf(&buf, "\tMinSize() = %d\n", e.minSize)
-	fmt.Fprintf(&buf, "\tMaxSize() = %d\n", e.maxSize)
-	numSymbols := Symbol(len(e.codes))
-	for symbol := Symbol(0); symbol < numSymbols; symbol++ {
-		hc := e.codes[symbol]
-		if hc.Size == 0 {
-			fmt.Fprintf(&buf, "\tEncode(%d) = nil\n", symbol)
-		} else {
-			fmt.Fprintf(&buf, "\tEncode(%d) = %s\n", symbol, hc)
-		}
-	}
-	buf.WriteString("}\n")
-	return buf.String()
+	return e.DebugStringWith(DebugOptions{})
 }
 
-// GoString returns a Go expression that would reconstruct this Encoder.
+// GoString returns a Go expression that would reconstruct this Encoder.  If
+// the Encoder has Options, the expression calls
+// NewEncoderFromSizesWithOptions with the Options literal given by
+// Options.GoString.
 func (e Encoder) GoString() string {
 	var buf strings.Builder
-	buf.WriteString("NewEncoderFromSizes([]byte{")
-	for index, hc := range e.codes {
-		if index != 0 {
-			buf.WriteByte(',')
-		}
-		buf.WriteString(strconv.FormatUint(uint64(hc.Size), 10))
+	if e.opts.isZeroGo() {
+		buf.WriteString("NewEncoderFromSizes(")
+	} else {
+		buf.WriteString("NewEncoderFromSizesWithOptions(")
 	}
-	buf.WriteString("})")
+	writeBytesGo(&buf, e.SizeBySymbol())
+	if !e.opts.isZeroGo() {
+		buf.WriteByte(',')
+		buf.WriteString(e.opts.GoString())
+	}
+	buf.WriteString(")")
 	return buf.String()
 }
 
@@ -235,38 +446,37 @@ func (e Encoder) MarshalJSON() ([]byte, error) {
 	return json.Marshal(arr)
 }
 
-// UnmarshalJSON initializes this Encoder from JSON data.
+// UnmarshalJSON initializes this Encoder from JSON data, in either the form
+// written by MarshalJSON or the one written by MarshalJSONV2.  The Encoder's
+// current Options are kept, and an alphabet larger than Options.MaxAlphabet
+// allows is rejected with an *AlphabetError before anything is allocated.
 func (e *Encoder) UnmarshalJSON(raw []byte) error {
-	var arr []uint
-	if err := json.Unmarshal(raw, &arr); err != nil {
+	sizes, err := unmarshalJSONSizes(raw, e.opts)
+	if err != nil {
 		return err
 	}
-
-	length := uint(len(arr))
-	sizes := make([]byte, length)
-	for i := uint(0); i < length; i++ {
-		size := arr[i]
-		if size > maxBitsPerCode {
-			return fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
-		}
-		sizes[i] = byte(size)
-	}
-
-	return e.InitFromSizes(sizes)
+	return e.InitFromSizesWithOptions(sizes, e.opts)
 }
 
 // firstPass computes the "first pass" of Huffman code assignment, which is to
 // determine and populate codes[Symbol].Size.  We also compute minSize and
-// maxSize while we're here.
+// maxSize while we're here.  b.opts.TieBreak chooses between nodes of equal
+// frequency, each step is reported to b.opts.Trace, and b provides the
+// scratch storage.
 //
-func firstPass(codes []Code, nodes []symbolAndFreq, minSize *byte, maxSize *byte) {
-	nodeLen := uint32(len(nodes))
-	nodeLog := log2uint32(nodeLen)
+func firstPass(codes []Code, nodes []symbolAndFreq, b *Builder, minSize *byte, maxSize *byte) {
+	opts := &b.opts
 
 	// Step 1: build a minheap.
 
-	h := freqHeap{nodes}
+	start := opts.traceStart()
+	h := freqHeap{list: nodes, deep: opts.TieBreak == TieBreakDeep}
+	if opts.TieBreak == TieBreakMinVariance {
+		h.minVariance = true
+		h.heights = b.heights[:0]
+	}
 	h.Init()
+	opts.tracePhase(PhaseHeap, start)
 
 	// Step 2: process the minheap by popping two symbols, combining them
 	// into a new synthetic symbol, and pushing the new symbol back onto
@@ -280,17 +490,13 @@ func firstPass(codes []Code, nodes []symbolAndFreq, minSize *byte, maxSize *byte
 	//
 	// We probably need only log2(len(nodes)) synthetic symbols.
 
-	type syntheticSymbol struct {
-		left  Symbol
-		right Symbol
-	}
-
-	syntheticSymbols := make([]syntheticSymbol, 0, nodeLog)
+	syntheticSymbols := b.synthetic[:0]
 	nextSyntheticSymbol := Symbol(math.MinInt32)
 
+	start = opts.traceStart()
 	for h.Len() > 1 {
-		a := heap.Pop(&h).(symbolAndFreq)
-		b := heap.Pop(&h).(symbolAndFreq)
+		a := h.Pop()
+		b := h.Top()
 
 		// Compute freqSum using saturating addition
 		freqSum := a.freq + b.freq
@@ -299,15 +505,24 @@ func firstPass(codes []Code, nodes []symbolAndFreq, minSize *byte, maxSize *byte
 		}
 
 		syntheticSymbols = append(syntheticSymbols, syntheticSymbol{a.symbol, b.symbol})
-		heap.Push(&h, symbolAndFreq{nextSyntheticSymbol, freqSum})
+		if h.minVariance {
+			h.heights = append(h.heights, 1+max(h.height(a.symbol), h.height(b.symbol)))
+		}
+		h.ReplaceTop(symbolAndFreq{nextSyntheticSymbol, freqSum})
+		opts.traceMerge(a.symbol, b.symbol, freqSum)
 		nextSyntheticSymbol++
 	}
+	opts.tracePhase(PhaseMerge, start)
+	b.synthetic = syntheticSymbols
+	if h.minVariance {
+		b.heights = h.heights
+	}
 
 	// root is the root of our tree.  This is not the *actual* Huffman code
 	// tree that we'll be using, because it's not necessarily canonical,
 	// but it's good enough to tell us the bit length for each natural
 	// symbol's canonical code.
-	root := heap.Pop(&h).(symbolAndFreq)
+	root := h.Pop()
 
 	// Step 3: use a stack to walk the tree.
 	//
@@ -326,12 +541,7 @@ func firstPass(codes []Code, nodes []symbolAndFreq, minSize *byte, maxSize *byte
 	// First we define the needed stack operations as closures, and then
 	// the final tree-walking loop will be fairly trivial.
 
-	type stackItem struct {
-		s Symbol
-		x byte
-	}
-
-	stack := make([]stackItem, 0, nodeLog)
+	stack := b.stack[:0]
 	var stackLen uint
 	var hasMinMax bool
 
@@ -380,6 +590,7 @@ func firstPass(codes []Code, nodes []symbolAndFreq, minSize *byte, maxSize *byte
 	}
 
 	// And now the tree-walking loop.
+	start = opts.traceStart()
 	stackPush(root.symbol)
 	for stackLen != 0 {
 		top := stackTop()
@@ -394,51 +605,99 @@ func firstPass(codes []Code, nodes []symbolAndFreq, minSize *byte, maxSize *byte
 			stackPop()
 		}
 	}
+	opts.tracePhase(PhaseSizes, start)
+	b.stack = stack
+}
+
+// isFlat returns true iff all nodes have the same frequency.
+func isFlat(nodes []symbolAndFreq) bool {
+	for _, node := range nodes[1:] {
+		if node.freq != nodes[0].freq {
+			return false
+		}
+	}
+	return true
+}
+
+// flatPass is firstPass for the case where every node has the same
+// frequency, for which the Huffman tree is as balanced as possible and can be
+// sized directly, without the heap.  With n nodes and k = floor(log2(n)),
+// the first 2×(n - 2**k) nodes are given k+1 bits and the rest are given k
+// bits, which is the same assignment that firstPass makes.
+func flatPass(codes []Code, nodes []symbolAndFreq, minSize *byte, maxSize *byte) {
+	n := uint32(len(nodes))
+	k := byte(log2uint32(n) - 1)
+	long := 2 * (n - (uint32(1) << k))
+	for index, node := range nodes {
+		size := k
+		if uint32(index) < long {
+			size = k + 1
+		}
+		codes[node.symbol].Size = size
+	}
+	*minSize = k
+	*maxSize = k
+	if long != 0 {
+		*maxSize = k + 1
+	}
 }
 
 // secondPass computes the "second pass" of Huffman code assignment, which
 // involves transforming the (Symbol, codes[Symbol].Size) assignments from
 // phase one into a canonical Huffman code written back to codes[Symbol].Bits.
+//
+// Rather than sorting the Symbols by (size, Symbol), which is what canonical
+// numbering calls for, secondPass counts the Symbols of each size and then
+// numbers them in Symbol order, as in RFC 1951 Section 3.2.2.  This is a
+// counting sort in all but name, and takes O(n) time with no allocations.
+//
 func secondPass(codes []Code) error {
-	// Step 1: sort the symbols by (codes[Symbol].Size, Symbol) ascending.
+	// Step 1: count the number of codes of each size.
 
-	numSymbols := Symbol(len(codes))
-	sorted := make(bySize, 0, numSymbols)
-	for symbol := Symbol(0); symbol < numSymbols; symbol++ {
-		size := codes[symbol].Size
-		if size == 0 {
-			continue
-		}
+	countBySize, err := codeLengthHistogram(codes)
+	if err != nil {
+		return err
+	}
 
-		// forbid codes with sizes greater than maxBitsPerCode
-		if size > maxBitsPerCode {
-			return fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
-		}
+	// Step 2: find the numerically smallest code of each size.
 
-		sorted = append(sorted, symbolAndSize{symbol, size})
+	var nextCode [maxBitsPerCode + 1]uint32
+	code := uint32(0)
+	for size := byte(1); size <= maxBitsPerCode; size++ {
+		if size > 1 {
+			code = (code + countBySize[size-1]) << 1
+		}
+		if code+countBySize[size] > (uint32(1) << size) {
+			return fmt.Errorf("too many symbols have a code length of %d", size)
+		}
+		nextCode[size] = code
 	}
-	sorted.Sort()
 
-	// Step 2: assign the codes sequentially, per the algorithm detailed at
-	// <https://en.wikipedia.org/w/index.php?title=Canonical_Huffman_code&oldid=999983137>.
+	// Step 3: assign the codes sequentially, in Symbol order.
 
-	lastSize := sorted[0].size
-	nextCode := uint32(0)
-	for _, item := range sorted {
-		if item.size > lastSize {
-			nextCode <<= (item.size - lastSize)
-			lastSize = item.size
+	for symbol, hc := range codes {
+		if hc.Size == 0 {
+			continue
 		}
+		codes[symbol].Bits = reverseBits(hc.Size, nextCode[hc.Size])
+		nextCode[hc.Size]++
+	}
+	return nil
+}
 
-		mask := (uint32(1) << item.size) - 1
-		if (nextCode &^ mask) != 0 {
-			return fmt.Errorf("too many symbols have a code length of %d", item.size)
+// codeLengthHistogram returns the number of codes of each size, with the
+// number of Symbols without codes at index 0.  Returns an error if any code
+// is longer than maxBitsPerCode bits.
+func codeLengthHistogram(codes []Code) ([maxBitsPerCode + 1]uint32, error) {
+	var countBySize [maxBitsPerCode + 1]uint32
+	for _, hc := range codes {
+		// forbid codes with sizes greater than maxBitsPerCode
+		if hc.Size > maxBitsPerCode {
+			return countBySize, fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", hc.Size, maxBitsPerCode)
 		}
-
-		codes[item.symbol].Bits = reverseBits(item.size, nextCode)
-		nextCode++
+		countBySize[hc.Size]++
 	}
-	return nil
+	return countBySize, nil
 }
 
 // type symbolAndFreq + type freqHeap {{{
@@ -448,80 +707,98 @@ type symbolAndFreq struct {
 	freq   uint32
 }
 
+// freqHeap is a minheap of symbolAndFreq.  It does the same job as
+// container/heap, but without boxing each item in an interface{}, which
+// allocated on every Push and Pop.
+//
+// If minVariance is set, heights holds the height of each synthetic symbol,
+// indexed as syntheticSymbols is, and the caller appends to it before
+// pushing each new synthetic symbol.
+//
 type freqHeap struct {
-	list []symbolAndFreq
+	list        []symbolAndFreq
+	heights     []uint32
+	deep        bool
+	minVariance bool
 }
 
 func (h *freqHeap) Init() {
-	heap.Init(h)
-}
-
-func (h *freqHeap) Fix(i int) {
-	heap.Fix(h, i)
+	n := len(h.list)
+	for i := n/2 - 1; i >= 0; i-- {
+		h.down(i)
+	}
 }
 
 func (h *freqHeap) Len() int {
 	return len(h.list)
 }
 
-func (h *freqHeap) Swap(i, j int) {
-	h.list[i], h.list[j] = h.list[j], h.list[i]
-}
-
 func (h *freqHeap) Less(i, j int) bool {
 	a, b := h.list[i], h.list[j]
 	if a.freq != b.freq {
 		return a.freq < b.freq
 	}
+	if h.minVariance {
+		if ha, hb := h.height(a.symbol), h.height(b.symbol); ha != hb {
+			return ha < hb
+		}
+	}
+	if h.deep && (a.symbol < 0 || b.symbol < 0) {
+		// Synthetic symbols win ties, newest first.
+		return a.symbol < 0 && (b.symbol >= 0 || a.symbol > b.symbol)
+	}
 	return uint32(a.symbol) < uint32(b.symbol)
 }
 
-func (h *freqHeap) Push(x interface{}) {
-	h.list = append(h.list, x.(symbolAndFreq))
-}
-
-func (h *freqHeap) Pop() interface{} {
-	last := uint(len(h.list)) - 1
-	x := h.list[last]
-	h.list = h.list[:last]
-	return x
+// height returns the height of the subtree rooted at symbol, which is 0 for a
+// natural symbol.  Only valid if minVariance is set.
+func (h *freqHeap) height(symbol Symbol) uint32 {
+	if symbol >= 0 {
+		return 0
+	}
+	return h.heights[int32(symbol)-math.MinInt32]
 }
 
-var _ heap.Interface = (*freqHeap)(nil)
-
-// }}}
-
-// type symbolAndSize + type bySize {{{
-
-type symbolAndSize struct {
-	symbol Symbol
-	size   byte
+// Top returns the smallest item without removing it.
+func (h *freqHeap) Top() symbolAndFreq {
+	return h.list[0]
 }
 
-type bySize []symbolAndSize
-
-func (list bySize) Len() int {
-	return len(list)
+// Pop removes and returns the smallest item.
+func (h *freqHeap) Pop() symbolAndFreq {
+	last := len(h.list) - 1
+	x := h.list[0]
+	h.list[0] = h.list[last]
+	h.list = h.list[:last]
+	if last > 0 {
+		h.down(0)
+	}
+	return x
 }
 
-func (list bySize) Swap(i, j int) {
-	list[i], list[j] = list[j], list[i]
+// ReplaceTop replaces the smallest item with x.  This is equivalent to Pop
+// followed by Push, but restores the heap order only once.
+func (h *freqHeap) ReplaceTop(x symbolAndFreq) {
+	h.list[0] = x
+	h.down(0)
 }
 
-func (list bySize) Less(i, j int) bool {
-	a, b := list[i], list[j]
-	ay, ai := a.symbol, a.size
-	by, bi := b.symbol, b.size
-	if ai != bi {
-		return ai < bi
+func (h *freqHeap) down(i int) {
+	n := len(h.list)
+	for {
+		j := 2*i + 1
+		if j >= n {
+			return
+		}
+		if k := j + 1; k < n && h.Less(k, j) {
+			j = k
+		}
+		if !h.Less(j, i) {
+			return
+		}
+		h.list[i], h.list[j] = h.list[j], h.list[i]
+		i = j
 	}
-	return ay < by
 }
 
-func (list bySize) Sort() {
-	sort.Sort(list)
-}
-
-var _ sort.Interface = bySize(nil)
-
 // }}}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/rand"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -136,3 +139,294 @@ func TestEncoder_UnmarshalJSON(t *testing.T) {
 		t.Errorf("wrong output:\n\texpect: %s\n\tactual: %s", expectDebug, actualDebug)
 	}
 }
+
+func TestEncoder_Clone(t *testing.T) {
+	e := makeTestEncoder()
+	clone := e.Clone()
+	if expect, actual := e.DebugString(), clone.DebugString(); expect != actual {
+		t.Errorf("wrong clone:\n\texpect: %q\n\tactual: %q", expect, actual)
+	}
+
+	clone.codes[0] = Code{}
+	if e.Encode(0).Size == 0 {
+		t.Errorf("modifying the clone modified the original")
+	}
+}
+
+func TestEncoder_InitFromMerged(t *testing.T) {
+	a := NewEncoderFromSizes([]byte{1, 2, 3, 3, 0})
+	b := NewEncoderFromSizes([]byte{3, 3, 2, 1})
+
+	type testRow struct {
+		name    string
+		weightA float64
+		weightB float64
+		sizes   []byte
+	}
+
+	testData := [...]testRow{
+		{"all-a", 1, 0, []byte{1, 2, 3, 3, 0}},
+		{"all-b", 0, 1, []byte{3, 3, 2, 1, 0}},
+		{"even", 1, 1, []byte{2, 2, 2, 2, 0}},
+		{"mostly-a", 9, 1, []byte{1, 2, 3, 3, 0}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var e Encoder
+			if err := e.InitFromMerged(a, b, row.weightA, row.weightB); err != nil {
+				t.Fatalf("InitFromMerged: unexpected error: %v", err)
+			}
+			if actual := e.SizeBySymbol(); !bytes.Equal(row.sizes, actual) {
+				t.Errorf("wrong sizes:\n\texpect: %v\n\tactual: %v", row.sizes, actual)
+			}
+		})
+	}
+
+	var e Encoder
+	if err := e.InitFromMerged(a, b, 0, 0); err == nil {
+		t.Errorf("expected error for zero weights")
+	}
+	if err := e.InitFromMerged(a, b, -1, 2); err == nil {
+		t.Errorf("expected error for negative weight")
+	}
+	if err := e.InitFromMerged(a, b, math.NaN(), 2); err == nil {
+		t.Errorf("expected error for NaN weight")
+	}
+
+	// A symbol known only to the longer Encoder still gets a code.
+	c := NewEncoderFromSizes([]byte{1, 2, 3, 3, 0, 0})
+	d := NewEncoderFromSizes([]byte{0, 0, 0, 0, 1, 1})
+	if err := e.InitFromMerged(c, d, 0.99, 0.01); err != nil {
+		t.Fatalf("InitFromMerged: unexpected error: %v", err)
+	}
+	if e.Encode(5).Size == 0 {
+		t.Errorf("expected symbol 5 to have a code: %v", e.SizeBySymbol())
+	}
+}
+
+func TestEncoder_IsOptimalFor(t *testing.T) {
+	type testRow struct {
+		name  string
+		sizes []byte
+		freqs []uint32
+		ok    bool
+	}
+
+	testData := [...]testRow{
+		{"test", []byte{4, 4, 3, 3, 3, 1}, []uint32{5, 9, 12, 13, 16, 45}, true},
+		{"balanced", []byte{2, 2, 3, 3, 3, 3}, []uint32{5, 9, 12, 13, 16, 45}, false},
+		{"tie", []byte{2, 2, 2, 2}, []uint32{1, 1, 2, 2}, true},
+		{"stale", []byte{1, 2, 3, 3}, []uint32{1, 1, 1, 10}, false},
+		{"missing", []byte{1, 1, 0}, []uint32{1, 1, 1}, false},
+		{"beyond", []byte{1, 1}, []uint32{1, 1, 1}, false},
+		{"trailing-zeros", []byte{1, 1}, []uint32{3, 1, 0, 0}, true},
+		{"empty", []byte{1, 1}, nil, true},
+		{"two", []byte{1, 1}, []uint32{7, 0}, true},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			e := NewEncoderFromSizes(row.sizes)
+			if actual := e.IsOptimalFor(row.freqs); row.ok != actual {
+				t.Errorf("expected %v, got %v", row.ok, actual)
+			}
+		})
+	}
+}
+
+func TestEncoder_Init_Flat(t *testing.T) {
+	for n := 3; n <= 300; n++ {
+		// Leave some gaps, so that node order and symbol order differ.
+		var nodes []symbolAndFreq
+		for symbol := 0; symbol < n; symbol++ {
+			if symbol%7 != 3 {
+				nodes = append(nodes, symbolAndFreq{Symbol(symbol), 42})
+			}
+		}
+
+		expect := make([]Code, n)
+		var expectMin, expectMax byte
+		firstPass(expect, append([]symbolAndFreq(nil), nodes...), &Builder{}, &expectMin, &expectMax)
+
+		actual := make([]Code, n)
+		var actualMin, actualMax byte
+		flatPass(actual, nodes, &actualMin, &actualMax)
+
+		for symbol := range expect {
+			if expect[symbol].Size != actual[symbol].Size {
+				t.Errorf("n=%d: symbol %d: expected size %d, got %d", n, symbol, expect[symbol].Size, actual[symbol].Size)
+			}
+		}
+		if expectMin != actualMin || expectMax != actualMax {
+			t.Errorf("n=%d: expected sizes %d..%d, got %d..%d", n, expectMin, expectMax, actualMin, actualMax)
+		}
+	}
+}
+
+func TestNewFlatEncoder(t *testing.T) {
+	type testRow struct {
+		n     int
+		sizes []byte
+	}
+	testData := [...]testRow{
+		{1, []byte{1}},
+		{2, []byte{1, 1}},
+		{4, []byte{2, 2, 2, 2}},
+		{5, []byte{3, 3, 2, 2, 2}},
+		{8, []byte{3, 3, 3, 3, 3, 3, 3, 3}},
+	}
+	for _, row := range testData {
+		e := NewFlatEncoder(row.n)
+		if actual := e.SizeBySymbol(); !bytes.Equal(row.sizes, actual) {
+			t.Errorf("n=%d: expected %v, got %v", row.n, row.sizes, actual)
+		}
+	}
+}
+
+func BenchmarkEncoder_Init_Flat(b *testing.B) {
+	frequencies := make([]uint32, 4096)
+	for i := range frequencies {
+		frequencies[i] = 1
+	}
+	b.ReportAllocs()
+	var e Encoder
+	for i := 0; i < b.N; i++ {
+		e.Init(len(frequencies), frequencies)
+	}
+}
+
+func TestEncoder_EncodeAppendBits(t *testing.T) {
+	e := NewEncoder(6, []uint32{1, 1, 2, 3, 3, 5})
+	symbols := []Symbol{5, 0, 3, 1, 2, 5, 5, 4, 0, 0, 1}
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	var dst []byte
+	var bitPos uint
+	for _, symbol := range symbols {
+		_ = bw.WriteSymbol(e, symbol)
+		dst, bitPos = e.EncodeAppendBits(dst, bitPos, symbol)
+
+		bits, n := e.EncodeToUint64(symbol)
+		if hc := e.Encode(symbol); uint64(hc.Bits) != bits || hc.Size != n {
+			t.Errorf("EncodeToUint64(%d): expected (%d, %d), got (%d, %d)", symbol, hc.Bits, hc.Size, bits, n)
+		}
+	}
+	if expect := uint(bw.BitsWritten()); expect != bitPos {
+		t.Errorf("expected bit position %d, got %d", expect, bitPos)
+	}
+	_ = bw.Flush()
+	if expect := buf.Bytes(); !bytes.Equal(expect, dst) {
+		t.Errorf("expected %x, got %x", expect, dst)
+	}
+
+	var none Encoder
+	none.Init(3, []uint32{1, 0, 1})
+	if out, pos := none.EncodeAppendBits([]byte{0x01}, 3, 1); len(out) != 1 || pos != 3 {
+		t.Errorf("EncodeAppendBits: expected no change for Symbol with no code, got %x at %d", out, pos)
+	}
+	if bits, n := none.EncodeToUint64(1); bits != 0 || n != 0 {
+		t.Errorf("EncodeToUint64: expected (0, 0) for Symbol with no code, got (%d, %d)", bits, n)
+	}
+}
+
+func TestEncoder_CodeLengthHistogram(t *testing.T) {
+	e := makeTestEncoder()
+	expect := []uint32{0, 1, 0, 3, 2}
+	if actual := e.CodeLengthHistogram(); !equalUint32s(expect, actual) {
+		t.Errorf("Encoder: expected %v, got %v", expect, actual)
+	}
+
+	d := NewDecoder([]byte{0, 2, 0, 1, 2})
+	expect = []uint32{2, 1, 2}
+	if actual := d.CodeLengthHistogram(); !equalUint32s(expect, actual) {
+		t.Errorf("Decoder: expected %v, got %v", expect, actual)
+	}
+}
+
+func equalUint32s(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSecondPass(t *testing.T) {
+	type testRow struct {
+		sizes  []byte
+		expect []string
+		err    bool
+	}
+
+	testData := [...]testRow{
+		{sizes: []byte{}, expect: []string{}},
+		{sizes: []byte{0, 1}, expect: []string{"", "0"}},
+		{sizes: []byte{3, 3, 3, 3, 3, 2, 4, 4}, expect: []string{"010", "110", "001", "101", "011", "00", "0111", "1111"}},
+		{sizes: []byte{2, 1, 3, 3}, expect: []string{"01", "0", "011", "111"}},
+		{sizes: []byte{1, 1, 1}, err: true},
+		{sizes: []byte{1, 1, 2}, err: true},
+		{sizes: []byte{1, 17}, err: true},
+	}
+	for _, row := range testData {
+		t.Run(fmt.Sprint(row.sizes), func(t *testing.T) {
+			codes := make([]Code, len(row.sizes))
+			for index, size := range row.sizes {
+				codes[index].Size = size
+			}
+			err := secondPass(codes)
+			if row.err {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for index, hc := range codes {
+				actual := ""
+				if hc.Size != 0 {
+					actual = fmt.Sprintf("%0*b", hc.Size, hc.Bits)
+				}
+				if actual != row.expect[index] {
+					t.Errorf("symbol %d: expected %q, got %q", index, row.expect[index], actual)
+				}
+			}
+		})
+	}
+}
+
+func TestFreqHeap(t *testing.T) {
+	rng := rand.New(rand.NewSource(149))
+	for _, deep := range []bool{false, true} {
+		list := make([]symbolAndFreq, 500)
+		for index := range list {
+			symbol := Symbol(index)
+			if rng.Intn(3) == 0 {
+				symbol = math.MinInt32 + Symbol(index)
+			}
+			list[index] = symbolAndFreq{symbol, uint32(rng.Intn(50))}
+		}
+		rng.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
+
+		expect := make([]symbolAndFreq, len(list))
+		copy(expect, list)
+		sorted := freqHeap{list: expect, deep: deep}
+		sort.Slice(expect, sorted.Less)
+
+		h := freqHeap{list: list, deep: deep}
+		h.Init()
+		for index := range expect {
+			if actual := h.Pop(); actual != expect[index] {
+				t.Fatalf("deep=%v: pop %d: expected %v, got %v", deep, index, expect[index], actual)
+			}
+		}
+		if h.Len() != 0 {
+			t.Errorf("deep=%v: expected empty heap, got %d items", deep, h.Len())
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package huffman
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// EncodeRecord describes one Symbol written through an NDJSONTraceWriter.
+type EncodeRecord struct {
+	// Symbol is the Symbol that was written.
+	Symbol Symbol `json:"symbol"`
+
+	// Size is the number of bits written for it.
+	Size uint64 `json:"size"`
+
+	// TotalBits is the value of BitWriter.BitsWritten after it was written.
+	TotalBits uint64 `json:"total_bits"`
+}
+
+// NDJSONTraceWriter wraps a BitWriter and emits one JSON object per Symbol
+// written through it, one per line (NDJSON), for analyzing the cost of real
+// data Symbol by Symbol.  Each line holds an EncodeRecord, e.g.:
+//
+//     {"symbol":65,"size":3,"total_bits":1234}
+//
+// Each record is written to the trace as a separate Write call, so the trace
+// should usually be a bufio.Writer.
+//
+type NDJSONTraceWriter struct {
+	bw  *BitWriter
+	enc *json.Encoder
+}
+
+// NewNDJSONTraceWriter returns a new NDJSONTraceWriter which writes Symbols
+// to bw and their records to trace.
+func NewNDJSONTraceWriter(bw *BitWriter, trace io.Writer) *NDJSONTraceWriter {
+	return &NDJSONTraceWriter{bw: bw, enc: json.NewEncoder(trace)}
+}
+
+// BitWriter returns the underlying BitWriter, for writing raw bits between
+// codes.  Bits written directly to it are not recorded, but are included in
+// the TotalBits of later records.
+func (tw *NDJSONTraceWriter) BitWriter() *BitWriter {
+	return tw.bw
+}
+
+// WriteSymbol is like BitWriter.WriteSymbol, but also records the Symbol.
+// Errors writing the trace are returned as well.
+func (tw *NDJSONTraceWriter) WriteSymbol(e *Encoder, symbol Symbol) error {
+	before := tw.bw.BitsWritten()
+	if err := tw.bw.WriteSymbol(e, symbol); err != nil {
+		return err
+	}
+	after := tw.bw.BitsWritten()
+	return tw.enc.Encode(EncodeRecord{Symbol: symbol, Size: after - before, TotalBits: after})
+}
@@ -0,0 +1,36 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNDJSONTraceWriter(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{1, 2, 2})
+	var out, trace bytes.Buffer
+	tw := NewNDJSONTraceWriter(NewBitWriter(&out), &trace)
+	for _, symbol := range []Symbol{0, 2, 1} {
+		if err := tw.WriteSymbol(e, symbol); err != nil {
+			t.Fatalf("WriteSymbol: unexpected error: %v", err)
+		}
+	}
+	_ = tw.BitWriter().WriteBits(3, 0)
+	_ = tw.WriteSymbol(e, 0)
+
+	expect := `{"symbol":0,"size":1,"total_bits":1}
+{"symbol":2,"size":2,"total_bits":3}
+{"symbol":1,"size":2,"total_bits":5}
+{"symbol":0,"size":1,"total_bits":9}
+`
+	if actual := trace.String(); expect != actual {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, actual)
+	}
+
+	none := NewEncoder(2, []uint32{1, 0})
+	if err := tw.WriteSymbol(none, 1); err == nil {
+		t.Errorf("expected an error for a Symbol with no code")
+	}
+	if n := bytes.Count(trace.Bytes(), []byte("\n")); n != 4 {
+		t.Errorf("expected no record for a failed write, got %d lines", n)
+	}
+}
@@ -0,0 +1,145 @@
+package huffman
+
+import (
+	"github.com/chronos-tachyon/assert"
+)
+
+// ErrorPropagationReport describes the effect of a single flipped bit on the
+// decoding of a Huffman-coded payload.  See AnalyzeErrorPropagation.
+type ErrorPropagationReport struct {
+	// FlipBit is the offset of the flipped bit from the start of the
+	// payload.
+	FlipBit int
+
+	// FirstSymbol is the index within the payload of the Symbol whose code
+	// holds the flipped bit.  Every Symbol before it decodes correctly.
+	FirstSymbol int
+
+	// Resynced is true if decoding returned to the original code
+	// boundaries, after which every Symbol decodes correctly.  It is false
+	// if an invalid code was found or the payload ran out mid-code first.
+	Resynced bool
+
+	// ResyncSymbol is the index within the payload of the first Symbol
+	// decoded correctly after the flip, or len(payload) if decoding
+	// resynchronized exactly at the end.  It is -1 if !Resynced.
+	ResyncSymbol int
+
+	// ResyncBit is the offset of the code boundary at which decoding
+	// resynchronized, or -1 if !Resynced.
+	ResyncBit int
+
+	// Lost is the number of original Symbols which were not decoded
+	// correctly: those from FirstSymbol up to ResyncSymbol, or to the end
+	// if !Resynced.
+	Lost int
+
+	// Decoded holds the Symbols which were decoded in place of the Lost
+	// ones.  It may be shorter or longer than Lost, in which case every
+	// later Symbol is shifted in position.
+	Decoded []Symbol
+
+	// Misdecoded is the number of Decoded Symbols which differ from the
+	// original Symbol at the same index, plus the difference in length
+	// between Decoded and the Lost Symbols.
+	Misdecoded int
+}
+
+// AnalyzeErrorPropagation simulates flipping bit flipBit of the encoding of
+// payload with the code of d, then decodes the damaged bits and reports how
+// far the damage spreads before decoding returns to the original code
+// boundaries.  Prefix codes usually resynchronize within a few Symbols,
+// which makes them tolerable on lossy channels where other variable-length
+// codes are not.
+//
+// Every Symbol in payload must have a code, and flipBit must be less than the
+// length of the encoded payload in bits.
+//
+func AnalyzeErrorPropagation(d *Decoder, payload []Symbol, flipBit int) ErrorPropagationReport {
+	e := d.Encoder()
+
+	// Encode the payload into a bit vector, recording the boundaries.
+
+	starts := make([]int, len(payload)+1)
+	var data []byte
+	pos := 0
+	for index, symbol := range payload {
+		hc := e.Encode(symbol)
+		assert.Assertf(hc.Size != 0, "symbol %d at index %d has no code", symbol, index)
+		starts[index] = pos
+		for i := byte(0); i < hc.Size; i++ {
+			if pos>>3 >= len(data) {
+				data = append(data, 0)
+			}
+			data[pos>>3] |= byte((hc.Bits>>i)&1) << uint(pos&7)
+			pos++
+		}
+	}
+	starts[len(payload)] = pos
+	totalBits := pos
+	assert.Assertf(flipBit >= 0 && flipBit < totalBits, "flipBit %d not in range [0..%d)", flipBit, totalBits)
+
+	data[flipBit>>3] ^= 1 << uint(flipBit&7)
+
+	first := 0
+	for starts[first+1] <= flipBit {
+		first++
+	}
+	report := ErrorPropagationReport{
+		FlipBit:      flipBit,
+		FirstSymbol:  first,
+		ResyncSymbol: -1,
+		ResyncBit:    -1,
+	}
+
+	// Decode one bit at a time from the start of the damaged Symbol, until
+	// a decoded code ends on an original boundary past the flipped bit.
+
+	pos = starts[first]
+	next := first
+	for pos < totalBits {
+		var hc Code
+		symbol := InvalidSymbol
+		for pos < totalBits {
+			bit := uint32(data[pos>>3]>>uint(pos&7)) & 1
+			hc.Bits |= bit << hc.Size
+			hc.Size++
+			pos++
+			var minSize byte
+			symbol, minSize, _ = d.Decode(hc)
+			if symbol >= 0 || minSize == 0 {
+				break
+			}
+		}
+		if symbol < 0 {
+			break
+		}
+		report.Decoded = append(report.Decoded, symbol)
+
+		for next < len(payload) && starts[next] < pos {
+			next++
+		}
+		if pos > flipBit && starts[next] == pos {
+			report.Resynced = true
+			report.ResyncSymbol = next
+			report.ResyncBit = pos
+			break
+		}
+	}
+
+	end := len(payload)
+	if report.Resynced {
+		end = report.ResyncSymbol
+	}
+	report.Lost = end - first
+	lost := payload[first:end]
+	for index, symbol := range report.Decoded {
+		if index >= len(lost) || lost[index] != symbol {
+			report.Misdecoded++
+		}
+	}
+	if len(lost) > len(report.Decoded) {
+		report.Misdecoded += len(lost) - len(report.Decoded)
+	}
+	return report
+}
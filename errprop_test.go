@@ -0,0 +1,103 @@
+package huffman
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeErrorPropagation(t *testing.T) {
+	// Codes: 0 = "0", 1 = "10", 2 = "110", 3 = "111".
+	var d Decoder
+	if err := d.Init([]byte{1, 2, 3, 3}); err != nil {
+		t.Fatalf("Init: unexpected error: %v", err)
+	}
+	payload := []Symbol{0, 1, 2, 3, 0}
+
+	type testRow struct {
+		flipBit int
+		expect  ErrorPropagationReport
+	}
+
+	testData := [...]testRow{
+		{
+			flipBit: 0,
+			expect: ErrorPropagationReport{
+				FlipBit:      0,
+				FirstSymbol:  0,
+				Resynced:     true,
+				ResyncSymbol: 2,
+				ResyncBit:    3,
+				Lost:         2,
+				Decoded:      []Symbol{2},
+				Misdecoded:   2,
+			},
+		},
+		{
+			flipBit: 5,
+			expect: ErrorPropagationReport{
+				FlipBit:      5,
+				FirstSymbol:  2,
+				Resynced:     true,
+				ResyncSymbol: 3,
+				ResyncBit:    6,
+				Lost:         1,
+				Decoded:      []Symbol{3},
+				Misdecoded:   1,
+			},
+		},
+		{
+			flipBit: 9,
+			expect: ErrorPropagationReport{
+				FlipBit:      9,
+				FirstSymbol:  4,
+				Resynced:     false,
+				ResyncSymbol: -1,
+				ResyncBit:    -1,
+				Lost:         1,
+				Decoded:      nil,
+				Misdecoded:   1,
+			},
+		},
+	}
+	for _, row := range testData {
+		if actual := AnalyzeErrorPropagation(&d, payload, row.flipBit); !reflect.DeepEqual(row.expect, actual) {
+			t.Errorf("bit %d: expected %+v, got %+v", row.flipBit, row.expect, actual)
+		}
+	}
+}
+
+func TestAnalyzeErrorPropagationRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(172))
+	freqs := make([]uint32, 40)
+	for index := range freqs {
+		freqs[index] = 1 + uint32(rng.ExpFloat64()*100)
+	}
+	e := NewEncoder(len(freqs), freqs)
+	d := e.Decoder()
+
+	payload := make([]Symbol, 500)
+	totalBits := 0
+	for index := range payload {
+		payload[index] = Symbol(rng.Intn(len(freqs)))
+		totalBits += int(e.Encode(payload[index]).Size)
+	}
+
+	var resynced int
+	for trial := 0; trial < 200; trial++ {
+		flipBit := rng.Intn(totalBits)
+		report := AnalyzeErrorPropagation(d, payload, flipBit)
+		if report.Lost < 1 || report.Misdecoded < 1 {
+			t.Fatalf("bit %d: flip had no effect: %+v", flipBit, report)
+		}
+		if report.Resynced {
+			resynced++
+			if report.ResyncBit <= flipBit || report.ResyncSymbol <= report.FirstSymbol {
+				t.Fatalf("bit %d: bad resync point: %+v", flipBit, report)
+			}
+		}
+	}
+	if resynced < 150 {
+		t.Errorf("expected most flips to resynchronize, got %d of 200", resynced)
+	}
+}
@@ -0,0 +1,249 @@
+// Package pngish is a worked example of the huffman package: a toy lossless
+// codec for grayscale images which filters each row as PNG does and then
+// Huffman codes the filtered bytes.
+//
+// Each step of a typical pipeline is a separate function, so that the code
+// can be read top to bottom:
+//
+//     Filter     predicts each pixel from its neighbors (PNG filters 0-4)
+//     Histogram  counts each byte of the filtered image
+//     BuildCode  turns the counts into a length-limited canonical code
+//     Encode     writes the header (size and code) and the coded bytes
+//     Decode     reads them back and undoes the filters
+//
+// The format is:
+//
+//     image   := "PNGH" width:uvarint height:uvarint sizes payload
+//     sizes   := (see huffman.AppendSizes)
+//     payload := code(row)*, least significant bit first, padded to a byte
+//     row     := filter:byte delta:byte*width
+//
+package pngish
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+const (
+	// MaxPixels is the largest image that Decode will accept.
+	MaxPixels = 1 << 26
+
+	// maxCodeSize is the longest code used, as in DEFLATE.
+	maxCodeSize = 15
+)
+
+var magic = [4]byte{'P', 'N', 'G', 'H'}
+
+// ErrFormat is returned by Decode for data which is not a valid image.
+var ErrFormat = errors.New("pngish: invalid format")
+
+// FilterType is one of the five PNG filter types.
+type FilterType byte
+
+const (
+	FilterNone FilterType = iota
+	FilterSub
+	FilterUp
+	FilterAverage
+	FilterPaeth
+
+	numFilters
+)
+
+// Filter returns the filtered rows of img, each prefixed by its FilterType.
+// The filter for each row is the one which minimizes the sum of the absolute
+// values of its deltas, which is the heuristic recommended by the PNG
+// specification.
+func Filter(img *image.Gray) []byte {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	out := make([]byte, 0, (width+1)*height)
+	prev := make([]byte, width)
+	best := make([]byte, width)
+	scratch := make([]byte, width)
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+width]
+
+		bestType, bestScore := FilterNone, -1
+		for ft := FilterNone; ft < numFilters; ft++ {
+			filterRow(scratch, row, prev, ft)
+			score := 0
+			for _, b := range scratch {
+				score += absDelta(b)
+			}
+			if bestScore < 0 || score < bestScore {
+				bestType, bestScore = ft, score
+				best, scratch = scratch, best
+			}
+		}
+		out = append(out, byte(bestType))
+		out = append(out, best...)
+		copy(prev, row)
+	}
+	return out
+}
+
+// Histogram returns the number of times each byte value occurs in data.
+func Histogram(data []byte) []uint32 {
+	freqs := make([]uint32, 256)
+	for _, b := range data {
+		freqs[b]++
+	}
+	return freqs
+}
+
+// BuildCode returns the canonical code for the given byte frequencies, with
+// no code longer than 15 bits.
+func BuildCode(freqs []uint32) (*huffman.Encoder, error) {
+	sizes, err := huffman.BuildSizes(freqs, maxCodeSize)
+	if err != nil {
+		return nil, err
+	}
+	return huffman.NewEncoderFromSizes(sizes), nil
+}
+
+// Encode compresses img.
+func Encode(img *image.Gray) ([]byte, error) {
+	filtered := Filter(img)
+	e, err := BuildCode(Histogram(filtered))
+	if err != nil {
+		return nil, err
+	}
+
+	var tmp [binary.MaxVarintLen64]byte
+	out := append([]byte(nil), magic[:]...)
+	n := binary.PutUvarint(tmp[:], uint64(img.Rect.Dx()))
+	out = append(out, tmp[:n]...)
+	n = binary.PutUvarint(tmp[:], uint64(img.Rect.Dy()))
+	out = append(out, tmp[:n]...)
+	out = huffman.AppendSizes(out, e.SizeBySymbol())
+
+	buf := bytes.NewBuffer(out)
+	bw := huffman.NewBitWriter(buf)
+	for _, b := range filtered {
+		if err := bw.WriteSymbol(e, huffman.SymbolFromByte(b)); err != nil {
+			return nil, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode decompresses data written by Encode.
+func Decode(data []byte) (*image.Gray, error) {
+	if len(data) < len(magic) || !bytes.Equal(data[:len(magic)], magic[:]) {
+		return nil, fmt.Errorf("%w: bad magic", ErrFormat)
+	}
+	r := bytes.NewReader(data[len(magic):])
+
+	width, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	height, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	if width > MaxPixels || height > MaxPixels || width*height > MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d image too large", ErrFormat, width, height)
+	}
+
+	sizes, err := huffman.ReadSizes(r, 256)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	var d huffman.Decoder
+	if err := d.Init(sizes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, int(width), int(height)))
+	br := huffman.NewBitReader(r)
+	filtered := make([]byte, width)
+	prev := make([]byte, width)
+	for y := 0; y < int(height); y++ {
+		ft, err := br.ReadSymbol(&d)
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrFormat, y, err)
+		}
+		if ft >= huffman.Symbol(numFilters) {
+			return nil, fmt.Errorf("%w: row %d: invalid filter type %d", ErrFormat, y, ft)
+		}
+		for x := range filtered {
+			symbol, err := br.ReadSymbol(&d)
+			if err != nil {
+				return nil, fmt.Errorf("%w: row %d: %v", ErrFormat, y, err)
+			}
+			filtered[x] = byte(symbol)
+		}
+		row := img.Pix[y*img.Stride : y*img.Stride+int(width)]
+		unfilterRow(row, filtered, prev, FilterType(ft))
+		prev = row
+	}
+	return img, nil
+}
+
+// filterRow writes the deltas of row, given the row above it, to out.
+func filterRow(out, row, prev []byte, ft FilterType) {
+	for x := range row {
+		out[x] = row[x] - predict(row, prev, x, ft)
+	}
+}
+
+// unfilterRow reverses filterRow, writing the pixels to row.
+func unfilterRow(row, deltas, prev []byte, ft FilterType) {
+	for x := range row {
+		row[x] = deltas[x] + predict(row, prev, x, ft)
+	}
+}
+
+// predict returns the predicted value of pixel x, which depends only on the
+// pixels to its left in row and on the row above it.
+func predict(row, prev []byte, x int, ft FilterType) byte {
+	var a, b, c byte
+	if x > 0 {
+		a, c = row[x-1], prev[x-1]
+	}
+	b = prev[x]
+
+	switch ft {
+	case FilterSub:
+		return a
+	case FilterUp:
+		return b
+	case FilterAverage:
+		return byte((int(a) + int(b)) / 2)
+	case FilterPaeth:
+		p := int(a) + int(b) - int(c)
+		pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+		switch {
+		case pa <= pb && pa <= pc:
+			return a
+		case pb <= pc:
+			return b
+		default:
+			return c
+		}
+	default:
+		return 0
+	}
+}
+
+// absDelta returns the magnitude of b, taken as a signed delta.
+func absDelta(b byte) int {
+	return abs(int(int8(b)))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,115 @@
+package pngish
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"math/rand"
+	"testing"
+)
+
+func testImage(width, height int, seed int64) *image.Gray {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Pix[y*img.Stride+x] = byte(x*3+y*2) + byte(rng.Intn(4))
+		}
+	}
+	return img
+}
+
+func TestRoundTrip(t *testing.T) {
+	type testRow struct {
+		name string
+		img  *image.Gray
+	}
+
+	testData := [...]testRow{
+		{name: "empty", img: image.NewGray(image.Rect(0, 0, 0, 0))},
+		{name: "flat", img: image.NewGray(image.Rect(0, 0, 16, 16))},
+		{name: "one-pixel", img: testImage(1, 1, 1)},
+		{name: "gradient", img: testImage(64, 48, 2)},
+		{name: "wide", img: testImage(500, 3, 3)},
+		{name: "sub-image", img: testImage(40, 40, 4).SubImage(image.Rect(5, 5, 30, 20)).(*image.Gray)},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			data, err := Encode(row.img)
+			if err != nil {
+				t.Fatalf("Encode: unexpected error: %v", err)
+			}
+			img, err := Decode(data)
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v", err)
+			}
+			width, height := row.img.Rect.Dx(), row.img.Rect.Dy()
+			if img.Rect.Dx() != width || img.Rect.Dy() != height {
+				t.Fatalf("expected %dx%d, got %dx%d", width, height, img.Rect.Dx(), img.Rect.Dy())
+			}
+			for y := 0; y < height; y++ {
+				expect := row.img.Pix[y*row.img.Stride : y*row.img.Stride+width]
+				actual := img.Pix[y*img.Stride : y*img.Stride+width]
+				if !bytes.Equal(expect, actual) {
+					t.Fatalf("row %d: expected %v, got %v", y, expect, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	img := testImage(64, 48, 5)
+	filtered := Filter(img)
+	if len(filtered) != 65*48 {
+		t.Fatalf("expected %d bytes, got %d", 65*48, len(filtered))
+	}
+	var small int
+	for _, b := range filtered {
+		if absDelta(b) < 8 {
+			small++
+		}
+	}
+	if small < len(filtered)*9/10 {
+		t.Errorf("expected mostly small deltas, got %d of %d", small, len(filtered))
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	good, _ := Encode(testImage(8, 8, 6))
+
+	testData := map[string][]byte{
+		"empty":     nil,
+		"magic":     []byte("PNGX\x01\x01"),
+		"too-large": []byte("PNGH\xff\xff\xff\xff\x0f\xff\xff\xff\xff\x0f"),
+		"truncated": good[:len(good)-4],
+	}
+	for name, input := range testData {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(input); !errors.Is(err, ErrFormat) {
+				t.Errorf("expected ErrFormat, got %v", err)
+			}
+		})
+	}
+}
+
+func Example() {
+	img := image.NewGray(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.Pix[y*img.Stride+x] = byte(4*x + 2*y)
+		}
+	}
+
+	data, err := Encode(img)
+	if err != nil {
+		panic(err)
+	}
+	out, err := Decode(data)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%d pixels -> %d bytes, round trip ok: %v\n", len(img.Pix), len(data), bytes.Equal(img.Pix, out.Pix))
+	// Output: 1024 pixels -> 151 bytes, round trip ok: true
+}
@@ -0,0 +1,44 @@
+package huffman
+
+// Fingerprint returns a 64-bit hash of this Encoder's code, suitable for
+// recognizing identical tables across many blocks or files.  The hash is the
+// XXH64 of the bit length of each Symbol, ignoring trailing Symbols without
+// codes, so it depends only on which codes are assigned: it is the same for
+// an Encoder and its Decoder, and it is stable across releases.
+func (e Encoder) Fingerprint() uint64 {
+	return fingerprintSizes(e.SizeBySymbol())
+}
+
+// Fingerprint returns a 64-bit hash of this Decoder's code.  See
+// Encoder.Fingerprint.
+func (d Decoder) Fingerprint() uint64 {
+	return fingerprintSizes(d.sizes)
+}
+
+// Fingerprint returns a 64-bit hash of this Dict's code.  See
+// Encoder.Fingerprint.  The Remap table, if any, is not included.
+func (dict Dict) Fingerprint() uint64 {
+	return fingerprintSizes(dict.Sizes)
+}
+
+func fingerprintSizes(sizes []byte) uint64 {
+	h := newXXH64()
+	_, _ = h.Write(trimSizes(sizes))
+	return h.Sum64()
+}
+
+// LookupFingerprint returns the registered dictionary whose code has the
+// given fingerprint, as returned by Dict.Fingerprint.  If several do, the one
+// with the lowest ID is returned.
+func (reg *DictRegistry) LookupFingerprint(fingerprint uint64) (*DictEntry, bool) {
+	var best *DictEntry
+	for _, entry := range reg.load().entries {
+		if entry.Fingerprint != fingerprint {
+			continue
+		}
+		if best == nil || entry.ID < best.ID {
+			best = entry
+		}
+	}
+	return best, best != nil
+}
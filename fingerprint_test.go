@@ -0,0 +1,61 @@
+package huffman
+
+import (
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	e := NewEncoder(6, []uint32{1, 1, 2, 3, 5, 0})
+	d := e.Decoder()
+	dict := NewDict(e)
+
+	fp := e.Fingerprint()
+	if actual := d.Fingerprint(); fp != actual {
+		t.Errorf("Decoder.Fingerprint: expected %#x, got %#x", fp, actual)
+	}
+	if actual := dict.Fingerprint(); fp != actual {
+		t.Errorf("Dict.Fingerprint: expected %#x, got %#x", fp, actual)
+	}
+
+	// Trailing Symbols without codes don't matter.
+	trimmed := NewEncoder(5, []uint32{1, 1, 2, 3, 5})
+	if actual := trimmed.Fingerprint(); fp != actual {
+		t.Errorf("trimmed: expected %#x, got %#x", fp, actual)
+	}
+
+	// A different code does.
+	other := NewEncoder(6, []uint32{5, 3, 2, 1, 1})
+	if actual := other.Fingerprint(); fp == actual {
+		t.Errorf("different codes have the same fingerprint %#x", fp)
+	}
+
+	// The hash must not change between releases.
+	if expect, actual := uint64(0x9014111c448f4479), fp; expect != actual {
+		t.Errorf("expected %#x, got %#x", expect, actual)
+	}
+	if expect, actual := uint64(0xef46db3751d8e999), fingerprintSizes(nil); expect != actual {
+		t.Errorf("empty: expected %#x, got %#x", expect, actual)
+	}
+}
+
+func TestDictRegistry_LookupFingerprint(t *testing.T) {
+	a := TrainStreamDict([]byte("aaaaaaaabbbbcccd"))
+	b := TrainStreamDict([]byte("the quick brown fox"))
+
+	reg := NewDictRegistry()
+	if err := reg.Reload(map[byte]Dict{9: a, 3: b, 5: a}, 3); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+
+	entry, found := reg.LookupFingerprint(a.Fingerprint())
+	if !found || entry.ID != 5 {
+		t.Errorf("LookupFingerprint(a): expected ID 5, got %v, %v", entry, found)
+	}
+	entry, found = reg.LookupFingerprint(b.Fingerprint())
+	if !found || entry.ID != 3 {
+		t.Errorf("LookupFingerprint(b): expected ID 3, got %v, %v", entry, found)
+	}
+	if entry, found := reg.LookupFingerprint(42); found {
+		t.Errorf("LookupFingerprint(42): expected not found, got ID %d", entry.ID)
+	}
+}
@@ -0,0 +1,53 @@
+package huffman
+
+import (
+	"unsafe"
+)
+
+// mapBucketSize is the number of entries in one bucket of a Go map, and
+// mapLoadFactorNum/mapLoadFactorDenom is the average number of entries per
+// bucket at which the runtime grows the map.
+const (
+	mapBucketSize      = 8
+	mapLoadFactorNum   = 13
+	mapLoadFactorDenom = 2
+)
+
+// mapFootprint estimates the heap bytes used by a Go map holding n entries of
+// the given key and value sizes.  It mirrors the runtime's layout: 2**B
+// buckets, each holding 8 hash bytes, 8 keys, 8 values, and an overflow
+// pointer, with B the smallest value keeping the load factor under 6.5.
+// Overflow buckets are not counted.
+func mapFootprint(n int, keySize uintptr, valueSize uintptr) uint64 {
+	if n == 0 {
+		return 0
+	}
+	numBuckets := uint64(1)
+	for n > mapBucketSize && uint64(n)*mapLoadFactorDenom > numBuckets*mapLoadFactorNum {
+		numBuckets <<= 1
+	}
+	bucketSize := uint64(mapBucketSize) * (1 + uint64(keySize) + uint64(valueSize))
+	bucketSize += uint64(unsafe.Sizeof(uintptr(0)))
+	return numBuckets * bucketSize
+}
+
+// MemoryFootprint returns an estimate of the heap bytes used by this Encoder,
+// not counting the Encoder struct itself.
+func (e Encoder) MemoryFootprint() uint64 {
+	total := uint64(cap(e.codes)) * uint64(unsafe.Sizeof(Code{}))
+	total += uint64(cap(e.opts.ExtraBits))
+	return total
+}
+
+// MemoryFootprint returns an estimate of the heap bytes used by this Decoder,
+// not counting the Decoder struct itself.  It includes the lookup table, the
+// direct table of a DirectTableDecoder, and the bit length array, even if
+// the latter is borrowed as by DecoderView.
+func (d Decoder) MemoryFootprint() uint64 {
+	total := mapFootprint(len(d.table), unsafe.Sizeof(Code{}), unsafe.Sizeof(decoderData{}))
+	total += uint64(cap(d.sorted)) * uint64(unsafe.Sizeof(uint64(0)))
+	total += uint64(cap(d.direct)) * uint64(unsafe.Sizeof(directEntry(0)))
+	total += uint64(cap(d.sizes))
+	total += uint64(cap(d.opts.ExtraBits))
+	return total
+}
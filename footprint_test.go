@@ -0,0 +1,65 @@
+package huffman
+
+import (
+	"testing"
+)
+
+func TestMapFootprint(t *testing.T) {
+	type testRow struct {
+		n      int
+		expect uint64
+	}
+
+	// Buckets of 8 entries with 8-byte keys and values are 8 + 64 + 64 + 8
+	// = 144 bytes on 64-bit platforms.
+	testData := [...]testRow{
+		{0, 0},
+		{1, 144},
+		{8, 144},
+		{9, 288},
+		{13, 288},
+		{14, 576},
+		{1000, 256 * 144},
+	}
+	if ptrSize := mapFootprint(1, 8, 8) - 136; ptrSize != 8 {
+		t.Skipf("pointer size %d", ptrSize)
+	}
+	for _, row := range testData {
+		if actual := mapFootprint(row.n, 8, 8); actual != row.expect {
+			t.Errorf("n=%d: expected %d, got %d", row.n, row.expect, actual)
+		}
+	}
+}
+
+func TestMemoryFootprint(t *testing.T) {
+	small := NewFlatEncoder(8)
+	large := NewFlatEncoder(4096)
+	if a, b := small.MemoryFootprint(), large.MemoryFootprint(); a == 0 || a >= b {
+		t.Errorf("Encoder: expected 0 < %d < %d", a, b)
+	}
+	if expect, actual := uint64(8*8), small.MemoryFootprint(); expect != actual {
+		t.Errorf("Encoder: expected %d, got %d", expect, actual)
+	}
+
+	var empty Decoder
+	if err := empty.Init([]byte{0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if expect, actual := uint64(2), empty.MemoryFootprint(); expect != actual {
+		t.Errorf("empty Decoder: expected %d, got %d", expect, actual)
+	}
+
+	// A DirectTableDecoder pays for its direct table.
+	direct := small.Decoder()
+	if direct.Kind() != DirectTableDecoder {
+		t.Fatalf("expected a DirectTableDecoder")
+	}
+	noDirect := *direct
+	noDirect.direct = nil
+	if expect, actual := uint64(4*8), direct.MemoryFootprint()-noDirect.MemoryFootprint(); expect != actual {
+		t.Errorf("direct table: expected %d bytes, got %d", expect, actual)
+	}
+	if a, b := direct.MemoryFootprint(), large.Decoder().MemoryFootprint(); a >= b {
+		t.Errorf("Decoder: expected %d < %d", a, b)
+	}
+}
@@ -0,0 +1,80 @@
+//go:build ignore
+
+// gen_uvarint.go builds the default nibble group code used by EncodeUvarint
+// and writes it to uvarint_table.go.  Run it with "go generate".
+//
+// The model assumes that a value with b significant bits is 15% less likely
+// than one with b-1 bits, and that values with the same number of bits are
+// equally likely.  This favors the small values common in telemetry, such as
+// counts and deltas.
+//
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+const numSymbols = 32
+
+func main() {
+	model := make([]float64, numSymbols)
+	weight := 1.0
+	for b := 0; b <= 64; b++ {
+		// A value with b significant bits has k nibbles, the last of
+		// which has t significant bits.
+		k := 1
+		if b > 4 {
+			k = (b + 3) / 4
+		}
+		t := b - 4*(k-1)
+
+		for nibble := 0; nibble < 16; nibble++ {
+			model[nibble] += weight * float64(k-1) / 16
+		}
+		if t == 0 {
+			model[16] += weight
+		} else {
+			lo, hi := 1<<(t-1), 1<<t
+			for nibble := lo; nibble < hi; nibble++ {
+				model[16+nibble] += weight / float64(hi-lo)
+			}
+		}
+		weight *= 0.85
+	}
+
+	frequencies := make([]uint32, numSymbols)
+	for symbol, p := range model {
+		frequencies[symbol] = uint32(p*1e6) + 1
+	}
+
+	e := huffman.NewEncoder(numSymbols, frequencies)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_uvarint.go; DO NOT EDIT.\n\n")
+	buf.WriteString("package huffman\n\n")
+	buf.WriteString("// uvarintSizes is the default nibble group code for EncodeUvarint.  Symbols\n")
+	buf.WriteString("// 0 to 15 are a nibble followed by more nibbles; Symbols 16 to 31 are the\n")
+	buf.WriteString("// final nibble, plus 16.\n")
+	buf.WriteString("var uvarintSizes = [...]byte{")
+	for index, size := range e.SizeBySymbol() {
+		if index%8 == 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "%d, ", size)
+	}
+	buf.WriteString("\n}\n")
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("uvarint_table.go", out, 0666); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,94 @@
+package huffman
+
+import (
+	"fmt"
+	"math"
+	mathbits "math/bits"
+)
+
+// GolombParameter returns the parameter m of the Golomb code which is the
+// optimal prefix code for a geometric source, in which each value n >= 0
+// occurs with probability (1-p)*p^n.  This is the smallest m for which
+// p^m + p^(m+1) <= 1, as shown by Gallager and Van Voorhis (1975).  p must be
+// in the range (0, 1).
+func GolombParameter(p float64) int {
+	if !(p > 0 && p < 1) {
+		panic(fmt.Errorf("GolombParameter: p %v not in range (0, 1)", p))
+	}
+	m := math.Ceil(math.Log(1+p) / -math.Log(p))
+	if m > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	k := int(m)
+	if k < 1 {
+		k = 1
+	}
+	// Correct any rounding error in the closed form.
+	for k > 1 && math.Pow(p, float64(k-1))+math.Pow(p, float64(k)) <= 1 {
+		k--
+	}
+	for math.Pow(p, float64(k))+math.Pow(p, float64(k+1)) > 1 {
+		k++
+	}
+	return k
+}
+
+// GeometricSizes returns the bit lengths of the optimal code for a geometric
+// source with parameter p (see GolombParameter), computed directly from the
+// Golomb code rather than by building a Huffman code from counts.  This
+// suits the residuals of audio and image predictors, which are usually close
+// to geometric once mapped to non-negative values.
+//
+// Symbol n, for n < numSymbols-1, stands for the value n, and gets the
+// length of its Golomb code: n/m+1 bits of unary quotient followed by a
+// truncated binary remainder.  The last Symbol stands for every larger
+// value, to be followed by the caller's own coding of the excess, and gets
+// the shortest length which still fits the code space left over.  The
+// lengths are optimal only while the last Symbol is rare; if numSymbols is
+// small enough that it is not, AssignLengths on the probabilities does
+// better.
+//
+// Returns an error if numSymbols < 2, or if any code would be longer than 16
+// bits.
+//
+func GeometricSizes(p float64, numSymbols int) ([]byte, error) {
+	if !(p > 0 && p < 1) {
+		return nil, fmt.Errorf("p %v not in range (0, 1)", p)
+	}
+	if numSymbols < 2 {
+		return nil, fmt.Errorf("numSymbols %d < 2", numSymbols)
+	}
+	m := GolombParameter(p)
+	if m > 1<<maxBitsPerCode {
+		return nil, fmt.Errorf("Golomb parameter %d needs codes longer than %d bits", m, maxBitsPerCode)
+	}
+
+	// The remainder takes k-1 bits if it is less than u, and k bits
+	// otherwise.
+	k := mathbits.Len(uint(m - 1))
+	u := (1 << k) - m
+
+	// Measure the code space used in units of 2^-16.
+	const space = uint32(1) << maxBitsPerCode
+	var used uint32
+
+	sizes := make([]byte, numSymbols)
+	last := numSymbols - 1
+	for n := 0; n < last; n++ {
+		size := n/m + 1 + k
+		if n%m < u {
+			size--
+		}
+		if size > maxBitsPerCode {
+			return nil, fmt.Errorf("symbol %d needs a code of %d bits, more than %d", n, size, maxBitsPerCode)
+		}
+		sizes[n] = byte(size)
+		used += space >> size
+	}
+
+	// The values from last onward occupy the rest of the Golomb code
+	// tree, so there is always room left for one more code.
+	free := space - used
+	sizes[last] = byte(maxBitsPerCode + 1 - mathbits.Len32(free))
+	return sizes, nil
+}
@@ -0,0 +1,99 @@
+package huffman
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestGolombParameter(t *testing.T) {
+	type testRow struct {
+		p float64
+		m int
+	}
+
+	testData := [...]testRow{
+		{p: 0.1, m: 1},
+		{p: 0.5, m: 1},
+		{p: 0.65, m: 2},
+		{p: 0.8, m: 3},
+		{p: 0.9, m: 7},
+		{p: 0.99, m: 69},
+	}
+	for _, row := range testData {
+		if actual := GolombParameter(row.p); actual != row.m {
+			t.Errorf("GolombParameter(%v): expected %d, got %d", row.p, row.m, actual)
+		}
+	}
+}
+
+func TestGeometricSizes(t *testing.T) {
+	type testRow struct {
+		p          float64
+		numSymbols int
+		sizes      []byte
+	}
+
+	testData := [...]testRow{
+		{p: 0.5, numSymbols: 5, sizes: []byte{1, 2, 3, 4, 4}},
+		{p: 0.8, numSymbols: 8, sizes: []byte{2, 3, 3, 3, 4, 4, 4, 3}},
+		{p: 0.8, numSymbols: 2, sizes: []byte{2, 1}},
+	}
+	for _, row := range testData {
+		sizes, err := GeometricSizes(row.p, row.numSymbols)
+		if err != nil {
+			t.Errorf("GeometricSizes(%v, %d): unexpected error: %v", row.p, row.numSymbols, err)
+			continue
+		}
+		if !bytes.Equal(row.sizes, sizes) {
+			t.Errorf("GeometricSizes(%v, %d): expected %v, got %v", row.p, row.numSymbols, row.sizes, sizes)
+		}
+	}
+
+	// Once the tail is rare, the lengths should cost about as much as a
+	// Huffman code built from the probabilities themselves.
+	for _, row := range []testRow{
+		{p: 0.3, numSymbols: 16},
+		{p: 0.7, numSymbols: 28},
+		{p: 0.9, numSymbols: 64},
+		{p: 0.97, numSymbols: 200},
+	} {
+		p, numSymbols := row.p, row.numSymbols
+		sizes, err := GeometricSizes(p, numSymbols)
+		if err != nil {
+			t.Fatalf("GeometricSizes(%v, %d): unexpected error: %v", p, numSymbols, err)
+		}
+		probs := make([]float64, numSymbols)
+		freqs := make([]uint32, numSymbols)
+		for n := range probs {
+			probs[n] = (1 - p) * math.Pow(p, float64(n))
+			if n == numSymbols-1 {
+				probs[n] = math.Pow(p, float64(n))
+			}
+			freqs[n] = uint32(1 + 1e9*probs[n])
+		}
+		huffman := AssignLengths(freqs)
+		var cost, optimal float64
+		for n, prob := range probs {
+			cost += prob * float64(sizes[n])
+			optimal += prob * float64(huffman[n])
+		}
+		if cost > optimal*1.01 {
+			t.Errorf("p %v: expected a cost near %.4f bits, got %.4f", p, optimal, cost)
+		}
+		if _, err := AssignCanonicalBits(sizes); err != nil {
+			t.Errorf("p %v: AssignCanonicalBits: unexpected error: %v", p, err)
+		}
+	}
+
+	for _, row := range []testRow{
+		{p: 0, numSymbols: 4},
+		{p: 1, numSymbols: 4},
+		{p: 0.5, numSymbols: 1},
+		{p: 0.5, numSymbols: 100},
+	} {
+		if _, err := GeometricSizes(row.p, row.numSymbols); err == nil {
+			t.Errorf("GeometricSizes(%v, %d): expected an error", row.p, row.numSymbols)
+		}
+	}
+}
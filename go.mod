@@ -1,5 +1,5 @@
 module github.com/chronos-tachyon/huffman
 
-go 1.16
+go 1.21
 
 require github.com/chronos-tachyon/assert v1.2.0
@@ -0,0 +1,100 @@
+package huffman
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ErrDecoderLimit is returned by Decoder.InitHardened when a size table
+// exceeds the given DecoderLimits.
+var ErrDecoderLimit = errors.New("Huffman code exceeds decoder limits")
+
+// DecoderLimits bounds the resources that Decoder.InitHardened will commit to
+// a code, for servers which build Decoders from size tables supplied by
+// untrusted peers.
+type DecoderLimits struct {
+	// MaxSymbols is the largest alphabet accepted.
+	MaxSymbols int
+
+	// MaxCodeSize is the longest code accepted, in bits.  It also bounds
+	// the work of decoding one Symbol: BitReader.ReadSymbol makes at most
+	// MaxCodeSize table lookups, and only one if the Decoder's Kind is
+	// DirectTableDecoder.
+	MaxCodeSize byte
+
+	// MaxTableBytes is the largest MemoryFootprint accepted.
+	MaxTableBytes uint64
+}
+
+// DefaultDecoderLimits are conservative limits suitable for codes with byte-
+// or DEFLATE-sized alphabets.  With them, a hostile size table can make a
+// Decoder hold no more than 1 MiB of tables, plus 32 KiB of scratch space
+// while building them, and spend no more than 15 lookups per Symbol.
+var DefaultDecoderLimits = DecoderLimits{
+	MaxSymbols:    4096,
+	MaxCodeSize:   15,
+	MaxTableBytes: 1 << 20,
+}
+
+// InitHardened is like Init, but first checks sizes against the given limits,
+// before allocating anything in proportion to the code.  Errors due to the
+// limits match ErrDecoderLimit under errors.Is.
+func (d *Decoder) InitHardened(sizes []byte, limits DecoderLimits) error {
+	if len(sizes) > limits.MaxSymbols {
+		return fmt.Errorf("%d symbols > limit of %d: %w", len(sizes), limits.MaxSymbols, ErrDecoderLimit)
+	}
+
+	var countBySize [maxBitsPerCode + 1]uint32
+	var maxSize byte
+	for _, size := range sizes {
+		if size > limits.MaxCodeSize {
+			return fmt.Errorf("code length %d > limit of %d: %w", size, limits.MaxCodeSize, ErrDecoderLimit)
+		}
+		if size > maxBitsPerCode {
+			return fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
+		}
+		if size > maxSize {
+			maxSize = size
+		}
+		countBySize[size]++
+	}
+
+	// Reject over-subscribed codes before sizing their tables, since
+	// countTableSlots assumes a valid code.
+	var kraft int64
+	for size := byte(1); size <= maxSize; size++ {
+		kraft += int64(countBySize[size]) * kraftUnits(size)
+	}
+	if kraft > 1<<maxBitsPerCode {
+		return fmt.Errorf("too many symbols for the given code lengths")
+	}
+
+	if footprint := decoderFootprint(len(sizes), countBySize[:maxSize+1]); footprint > limits.MaxTableBytes {
+		return fmt.Errorf("tables need %d bytes > limit of %d: %w", footprint, limits.MaxTableBytes, ErrDecoderLimit)
+	}
+	return d.Init(sizes)
+}
+
+// decoderFootprint returns the MemoryFootprint of a Decoder for a code with
+// numSymbols Symbols and countBySize[n] codes of length n, without building
+// it.  The code must be valid.
+func decoderFootprint(numSymbols int, countBySize []uint32) uint64 {
+	maxSize := byte(len(countBySize) - 1)
+	var numCodes uint32
+	for _, count := range countBySize[1:] {
+		numCodes += count
+	}
+	total := uint64(numSymbols)
+	if numCodes == 0 {
+		return total
+	}
+
+	var counts [maxBitsPerCode + 1]uint32
+	copy(counts[1:], countBySize[1:])
+	total += mapFootprint(countTableSlots(counts[:maxSize+1]), unsafe.Sizeof(Code{}), unsafe.Sizeof(decoderData{}))
+	if maxSize <= maxDirectBits && numSymbols <= (1<<24) {
+		total += uint64(unsafe.Sizeof(directEntry(0))) << maxSize
+	}
+	return total
+}
@@ -0,0 +1,80 @@
+package huffman
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestDecoder_InitHardened(t *testing.T) {
+	type testRow struct {
+		name    string
+		sizes   []byte
+		limits  DecoderLimits
+		limit   bool
+		invalid bool
+	}
+
+	long := make([]byte, 16)
+	for index := range long {
+		long[index] = byte(index + 1)
+	}
+	long[15] = 15
+
+	testData := [...]testRow{
+		{name: "ok", sizes: []byte{1, 2, 2}, limits: DefaultDecoderLimits},
+		{name: "empty", sizes: []byte{0, 0}, limits: DefaultDecoderLimits},
+		{name: "too-many-symbols", sizes: make([]byte, 5000), limits: DefaultDecoderLimits, limit: true},
+		{name: "too-long", sizes: long, limits: DecoderLimits{MaxSymbols: 100, MaxCodeSize: 12, MaxTableBytes: 1 << 20}, limit: true},
+		{name: "long-ok", sizes: long, limits: DefaultDecoderLimits},
+		{name: "too-big", sizes: long, limits: DecoderLimits{MaxSymbols: 100, MaxCodeSize: 15, MaxTableBytes: 100}, limit: true},
+		{name: "over-subscribed", sizes: []byte{1, 1, 1}, limits: DefaultDecoderLimits, invalid: true},
+		{name: "over-16", sizes: []byte{1, 17}, limits: DecoderLimits{MaxSymbols: 100, MaxCodeSize: 20, MaxTableBytes: 1 << 20}, invalid: true},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var d Decoder
+			err := d.InitHardened(row.sizes, row.limits)
+			switch {
+			case row.limit:
+				if !errors.Is(err, ErrDecoderLimit) {
+					t.Errorf("expected ErrDecoderLimit, got %v", err)
+				}
+			case row.invalid:
+				if err == nil || errors.Is(err, ErrDecoderLimit) {
+					t.Errorf("expected an invalid code error, got %v", err)
+				}
+			default:
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				} else if footprint := d.MemoryFootprint(); footprint > row.limits.MaxTableBytes {
+					t.Errorf("MemoryFootprint %d exceeds limit", footprint)
+				}
+			}
+		})
+	}
+}
+
+func TestDecoderFootprint(t *testing.T) {
+	rng := rand.New(rand.NewSource(159))
+	for trial := 0; trial < 200; trial++ {
+		frequencies := make([]uint32, 1+rng.Intn(500))
+		for index := range frequencies {
+			if rng.Intn(3) != 0 {
+				frequencies[index] = uint32(rng.ExpFloat64() * 100)
+			}
+		}
+		var e Encoder
+		initLimited(&e, len(frequencies), frequencies)
+		sizes := e.SizeBySymbol()
+
+		var countBySize [maxBitsPerCode + 1]uint32
+		for _, size := range sizes {
+			countBySize[size]++
+		}
+		d := NewDecoder(sizes)
+		if expect, actual := d.MemoryFootprint(), decoderFootprint(len(sizes), countBySize[:d.MaxSize()+1]); expect != actual {
+			t.Fatalf("trial %d: expected %d, got %d", trial, expect, actual)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Package huffmanbench measures the throughput of the huffman package against
+// other Go implementations of Huffman coding, producing a machine-readable
+// report so that performance claims can be reproduced before and after a
+// change.
+//
+// The harness runs each Codec over each Input using testing.Benchmark, so it
+// can be driven from an ordinary program (e.g. a CI job) as well as from go
+// test.  Codecs for this package and for compress/flate's Huffman-only mode
+// are built in; other implementations can be compared by implementing Codec.
+//
+package huffmanbench
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+// Input is a named corpus sample.
+type Input struct {
+	Name string
+	Data []byte
+}
+
+// Codec is a Huffman implementation under test.
+type Codec interface {
+	// Name returns a short, unique name for the implementation.
+	Name() string
+
+	// Compress compresses src, appending the result to dst.
+	Compress(dst []byte, src []byte) ([]byte, error)
+
+	// Decompress decompresses src, appending the result to dst.
+	Decompress(dst []byte, src []byte) ([]byte, error)
+}
+
+// Builder is an optional interface implemented by a Codec which can measure
+// code construction on its own, apart from compression.
+type Builder interface {
+	// Build constructs a code from the given symbol frequencies.
+	Build(frequencies []uint32)
+}
+
+// Result is the outcome of one benchmark.
+type Result struct {
+	Codec       string  `json:"codec"`
+	Input       string  `json:"input"`
+	Op          string  `json:"op"`
+	InputBytes  int     `json:"inputBytes"`
+	OutputBytes int     `json:"outputBytes,omitempty"`
+	N           int     `json:"n"`
+	NsPerOp     int64   `json:"nsPerOp"`
+	MBPerSec    float64 `json:"mbPerSec"`
+	AllocsPerOp int64   `json:"allocsPerOp"`
+	BytesPerOp  int64   `json:"bytesPerOp"`
+}
+
+// Report is a complete set of Results along with details of the environment
+// which produced them.
+type Report struct {
+	GoVersion string   `json:"goVersion"`
+	GOOS      string   `json:"goos"`
+	GOARCH    string   `json:"goarch"`
+	NumCPU    int      `json:"numCPU"`
+	Results   []Result `json:"results"`
+}
+
+// DefaultCodecs returns the built-in Codecs.
+func DefaultCodecs() []Codec {
+	return []Codec{HuffmanCodec{}, FlateHuffmanOnlyCodec{}}
+}
+
+// Run benchmarks each Codec over each Input.  Each Codec's output is checked
+// for a correct round trip before it is timed.
+func Run(codecs []Codec, inputs []Input) (Report, error) {
+	report := Report{
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+	}
+
+	for _, codec := range codecs {
+		for _, input := range inputs {
+			compressed, err := codec.Compress(nil, input.Data)
+			if err != nil {
+				return report, fmt.Errorf("%s: %s: compress: %w", codec.Name(), input.Name, err)
+			}
+			roundTrip, err := codec.Decompress(nil, compressed)
+			if err != nil {
+				return report, fmt.Errorf("%s: %s: decompress: %w", codec.Name(), input.Name, err)
+			}
+			if !bytes.Equal(roundTrip, input.Data) {
+				return report, fmt.Errorf("%s: %s: round trip mismatch", codec.Name(), input.Name)
+			}
+
+			if builder, ok := codec.(Builder); ok {
+				frequencies := make([]uint32, 256)
+				for _, b := range input.Data {
+					frequencies[b]++
+				}
+				br := testing.Benchmark(func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						builder.Build(frequencies)
+					}
+				})
+				report.Results = append(report.Results, makeResult(codec, input, "build", 0, br))
+			}
+
+			var dst []byte
+			br := testing.Benchmark(func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(input.Data)))
+				for i := 0; i < b.N; i++ {
+					dst, _ = codec.Compress(dst[:0], input.Data)
+				}
+			})
+			report.Results = append(report.Results, makeResult(codec, input, "compress", len(compressed), br))
+
+			br = testing.Benchmark(func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(input.Data)))
+				for i := 0; i < b.N; i++ {
+					dst, _ = codec.Decompress(dst[:0], compressed)
+				}
+			})
+			report.Results = append(report.Results, makeResult(codec, input, "decompress", len(compressed), br))
+		}
+	}
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if a.Input != b.Input {
+			return a.Input < b.Input
+		}
+		if a.Op != b.Op {
+			return a.Op < b.Op
+		}
+		return a.Codec < b.Codec
+	})
+	return report, nil
+}
+
+func makeResult(codec Codec, input Input, op string, outputBytes int, br testing.BenchmarkResult) Result {
+	result := Result{
+		Codec:       codec.Name(),
+		Input:       input.Name,
+		Op:          op,
+		InputBytes:  len(input.Data),
+		OutputBytes: outputBytes,
+		N:           br.N,
+		NsPerOp:     br.NsPerOp(),
+		AllocsPerOp: br.AllocsPerOp(),
+		BytesPerOp:  br.AllocedBytesPerOp(),
+	}
+	if br.Bytes != 0 && br.T > 0 {
+		result.MBPerSec = (float64(br.Bytes) * float64(br.N) / 1e6) / br.T.Seconds()
+	}
+	return result
+}
+
+// WriteJSON writes the report as indented JSON.
+func (report Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// WriteText writes the report as an aligned, human-readable table.
+func (report Report) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# %s %s/%s, %d CPUs\n", report.GoVersion, report.GOOS, report.GOARCH, report.NumCPU)
+	if err != nil {
+		return err
+	}
+	for _, r := range report.Results {
+		_, err = fmt.Fprintf(w, "%-16s %-12s %-24s %12d ns/op %10.2f MB/s %8d allocs/op %10d B/out\n",
+			r.Input, r.Op, r.Codec, r.NsPerOp, r.MBPerSec, r.AllocsPerOp, r.OutputBytes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HuffmanCodec benchmarks this package's stream format.
+type HuffmanCodec struct{}
+
+// Name implements Codec.
+func (HuffmanCodec) Name() string {
+	return "chronos-tachyon/huffman"
+}
+
+// Compress implements Codec.
+func (HuffmanCodec) Compress(dst []byte, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	zw := huffman.NewWriter(buf)
+	if _, err := zw.Write(src); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress implements Codec.
+func (HuffmanCodec) Decompress(dst []byte, src []byte) ([]byte, error) {
+	zr, err := huffman.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(dst)
+	_, err = buf.ReadFrom(zr)
+	return buf.Bytes(), err
+}
+
+// Build implements Builder.
+func (HuffmanCodec) Build(frequencies []uint32) {
+	var e huffman.Encoder
+	e.Init(len(frequencies), frequencies)
+}
+
+// FlateHuffmanOnlyCodec benchmarks compress/flate with HuffmanOnly, which
+// performs no LZ77 matching and so is directly comparable.
+type FlateHuffmanOnlyCodec struct{}
+
+// Name implements Codec.
+func (FlateHuffmanOnlyCodec) Name() string {
+	return "compress/flate"
+}
+
+// Compress implements Codec.
+func (FlateHuffmanOnlyCodec) Compress(dst []byte, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	fw, err := flate.NewWriter(buf, flate.HuffmanOnly)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(src); err != nil {
+		return nil, err
+	}
+	if err := fw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress implements Codec.
+func (FlateHuffmanOnlyCodec) Decompress(dst []byte, src []byte) ([]byte, error) {
+	fr := flate.NewReader(bytes.NewReader(src))
+	defer fr.Close()
+	buf := bytes.NewBuffer(dst)
+	_, err := buf.ReadFrom(fr)
+	return buf.Bytes(), err
+}
+
+// IdentityCodec codes each byte with huffman.NewIdentityEncoder, i.e. as 8
+// raw bits, through the same BitWriter and BitReader paths as a real code.
+// It is the "compression off" baseline for A/B comparisons; it is not among
+// the DefaultCodecs.
+type IdentityCodec struct{}
+
+var (
+	identityEncoder = huffman.NewIdentityEncoder(256)
+	identityDecoder = huffman.NewIdentityDecoder(256)
+)
+
+// Name implements Codec.
+func (IdentityCodec) Name() string {
+	return "identity"
+}
+
+// Compress implements Codec.
+func (IdentityCodec) Compress(dst []byte, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	bw := huffman.NewBitWriter(buf)
+	for _, b := range src {
+		if err := bw.WriteSymbol(identityEncoder, huffman.Symbol(b)); err != nil {
+			return nil, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress implements Codec.
+func (IdentityCodec) Decompress(dst []byte, src []byte) ([]byte, error) {
+	br := huffman.NewBitReader(bytes.NewReader(src))
+	for range src {
+		symbol, err := br.ReadSymbol(identityDecoder)
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, byte(symbol))
+	}
+	return dst, nil
+}
+
+var (
+	_ Codec   = HuffmanCodec{}
+	_ Builder = HuffmanCodec{}
+	_ Codec   = FlateHuffmanOnlyCodec{}
+	_ Codec   = IdentityCodec{}
+)
@@ -0,0 +1,88 @@
+package huffmanbench
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testInputs() []Input {
+	return []Input{
+		{Name: "text", Data: []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 200))},
+		{Name: "binary", Data: bytes.Repeat([]byte{0, 1, 2, 3, 0, 0, 0, 255}, 1000)},
+	}
+}
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping benchmark harness in short mode")
+	}
+
+	report, err := Run(DefaultCodecs(), testInputs()[:1])
+	if err != nil {
+		t.Fatalf("Run: unexpected error: %v", err)
+	}
+
+	// huffman: build + compress + decompress; flate: compress + decompress
+	if expect, actual := 5, len(report.Results); expect != actual {
+		t.Errorf("expected %d results, got %d", expect, actual)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: unexpected error: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal: unexpected error: %v", err)
+	}
+	if len(decoded.Results) != len(report.Results) {
+		t.Errorf("JSON round trip lost results")
+	}
+}
+
+func TestIdentityCodec(t *testing.T) {
+	var codec IdentityCodec
+	for _, input := range testInputs() {
+		compressed, err := codec.Compress(nil, input.Data)
+		if err != nil {
+			t.Fatalf("%s: Compress: unexpected error: %v", input.Name, err)
+		}
+		if len(compressed) != len(input.Data) {
+			t.Errorf("%s: Compress: expected %d bytes, got %d", input.Name, len(input.Data), len(compressed))
+		}
+		roundTrip, err := codec.Decompress(nil, compressed)
+		if err != nil {
+			t.Fatalf("%s: Decompress: unexpected error: %v", input.Name, err)
+		}
+		if !bytes.Equal(roundTrip, input.Data) {
+			t.Errorf("%s: round trip mismatch", input.Name)
+		}
+	}
+}
+
+func BenchmarkCodecs(b *testing.B) {
+	for _, codec := range DefaultCodecs() {
+		for _, input := range testInputs() {
+			compressed, err := codec.Compress(nil, input.Data)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(codec.Name()+"/"+input.Name+"/compress", func(b *testing.B) {
+				b.SetBytes(int64(len(input.Data)))
+				var dst []byte
+				for i := 0; i < b.N; i++ {
+					dst, _ = codec.Compress(dst[:0], input.Data)
+				}
+			})
+			b.Run(codec.Name()+"/"+input.Name+"/decompress", func(b *testing.B) {
+				b.SetBytes(int64(len(input.Data)))
+				var dst []byte
+				for i := 0; i < b.N; i++ {
+					dst, _ = codec.Decompress(dst[:0], compressed)
+				}
+			})
+		}
+	}
+}
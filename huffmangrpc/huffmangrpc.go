@@ -0,0 +1,69 @@
+// Package huffmangrpc implements a gRPC compressor on top of the huffman
+// package's stream format.
+//
+// Compressor satisfies google.golang.org/grpc/encoding.Compressor without
+// importing gRPC, so this package adds no dependencies.  To use it, register
+// it from a package which already depends on gRPC:
+//
+//     reg := huffman.NewDictRegistry()
+//     // ... load trained dictionaries into reg ...
+//     encoding.RegisterCompressor(huffmangrpc.NewCompressor(reg))
+//
+// and then select it per call with grpc.UseCompressor(huffmangrpc.Name).
+//
+// Trained dictionaries make the biggest difference for small structured
+// messages, where a per-message table (or gzip's header and Huffman tables)
+// would dominate the payload.  Both peers must register the same
+// dictionaries under the same IDs.
+//
+package huffmangrpc
+
+import (
+	"io"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+// Name is the name under which Compressor registers itself with gRPC.
+const Name = "huffman"
+
+// Compressor implements gRPC's encoding.Compressor interface.  It is safe
+// for concurrent use.
+type Compressor struct {
+	dicts *huffman.DictRegistry
+}
+
+// NewCompressor returns a new Compressor which uses the dictionaries in reg.
+// If reg is nil, each message carries its own table.
+func NewCompressor(reg *huffman.DictRegistry) *Compressor {
+	return &Compressor{dicts: reg}
+}
+
+// Name returns the name of this compressor.
+func (c *Compressor) Name() string {
+	return Name
+}
+
+// Compress returns a writer which compresses data written to it into w.
+// The caller must Close the writer to complete the message.
+//
+// The writer uses a Snapshot of the registry taken now, so a concurrent
+// SetCurrent or Reload cannot switch dictionaries partway through the
+// message.
+func (c *Compressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	var opts huffman.WriterOptions
+	if c.dicts != nil {
+		opts.Dicts = c.dicts.Snapshot()
+	}
+	return huffman.NewWriterOptions(w, opts), nil
+}
+
+// Decompress returns a reader which decompresses data read from r.
+func (c *Compressor) Decompress(r io.Reader) (io.Reader, error) {
+	zr, err := huffman.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	zr.UseDicts(c.dicts)
+	return zr, nil
+}
@@ -0,0 +1,102 @@
+package huffmangrpc
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+// compressor mirrors google.golang.org/grpc/encoding.Compressor.
+type compressor interface {
+	Compress(w io.Writer) (io.WriteCloser, error)
+	Decompress(r io.Reader) (io.Reader, error)
+	Name() string
+}
+
+var _ compressor = (*Compressor)(nil)
+
+func TestCompressor(t *testing.T) {
+	message := []byte("\x08\x96\x01\x12\x07testing\x1a\x03abc")
+
+	reg := huffman.NewDictRegistry()
+	dict := huffman.TrainStreamDict(bytes.Repeat(message, 50))
+	if err := reg.Reload(map[byte]huffman.Dict{1: dict}, 1); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+
+	for _, c := range []*Compressor{NewCompressor(nil), NewCompressor(reg)} {
+		var buf bytes.Buffer
+		w, err := c.Compress(&buf)
+		if err != nil {
+			t.Fatalf("Compress: unexpected error: %v", err)
+		}
+		_, _ = w.Write(message)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: unexpected error: %v", err)
+		}
+
+		r, err := c.Decompress(&buf)
+		if err != nil {
+			t.Fatalf("Decompress: unexpected error: %v", err)
+		}
+		output, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll: unexpected error: %v", err)
+		}
+		if !bytes.Equal(message, output) {
+			t.Errorf("wrong output:\n\texpect: %q\n\tactual: %q", message, output)
+		}
+	}
+
+	if name := NewCompressor(nil).Name(); !strings.EqualFold(name, Name) {
+		t.Errorf("Name: expected %q, got %q", Name, name)
+	}
+}
+
+func TestCompressor_SetCurrentMidMessage(t *testing.T) {
+	message := []byte("\x08\x96\x01\x12\x07testing\x1a\x03abc")
+	dict := huffman.TrainStreamDict(bytes.Repeat(message, 50))
+
+	serverDicts := huffman.NewDictRegistry()
+	if err := serverDicts.Reload(map[byte]huffman.Dict{7: dict, 8: dict}, 7); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+	clientDicts := huffman.NewDictRegistry()
+	if err := clientDicts.Reload(map[byte]huffman.Dict{7: dict}, 7); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+
+	// The server switches to a dictionary which the client doesn't hold
+	// between two blocks of the same message.
+	var buf bytes.Buffer
+	w, err := NewCompressor(serverDicts).Compress(&buf)
+	if err != nil {
+		t.Fatalf("Compress: unexpected error: %v", err)
+	}
+	_, _ = w.Write(message)
+	if err := w.(*huffman.Writer).Flush(); err != nil {
+		t.Fatalf("Flush: unexpected error: %v", err)
+	}
+	if err := serverDicts.SetCurrent(8); err != nil {
+		t.Fatalf("SetCurrent: unexpected error: %v", err)
+	}
+	_, _ = w.Write(message)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+
+	r, err := NewCompressor(clientDicts).Decompress(&buf)
+	if err != nil {
+		t.Fatalf("Decompress: unexpected error: %v", err)
+	}
+	output, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: unexpected error: %v", err)
+	}
+	if expect := bytes.Repeat(message, 2); !bytes.Equal(expect, output) {
+		t.Errorf("wrong output:\n\texpect: %q\n\tactual: %q", expect, output)
+	}
+}
@@ -0,0 +1,255 @@
+// Package huffmanhttp implements an HTTP content coding on top of the
+// huffman package's stream format.
+//
+// Middleware compresses response bodies for clients which advertise support
+// for the coding, and Transport advertises that support and transparently
+// decompresses the responses.  Both sides may share trained dictionaries via
+// a huffman.DictRegistry; the client lists the dictionary IDs it holds, and
+// the server only uses a dictionary that the client has.
+//
+package huffmanhttp
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+// Encoding is the name of the content coding, as used in the
+// Accept-Encoding and Content-Encoding headers.
+const Encoding = "x-huffman"
+
+// DictsHeader is the request header in which the client lists the IDs of the
+// dictionaries it holds, as a comma-separated list of decimal integers.
+const DictsHeader = "X-Huffman-Dicts"
+
+// Middleware returns an http.Handler which compresses the responses of next
+// for clients which accept Encoding.  If reg is non-nil, the dictionary which
+// is current when a request arrives is used for its whole response, provided
+// that the client holds it.
+func Middleware(reg *huffman.DictRegistry, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !AcceptsEncoding(r.Header) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Pin the registry's state for this response, so that a
+		// concurrent SetCurrent or Reload cannot switch the Writer to a
+		// dictionary which the client doesn't hold.
+		var opts huffman.WriterOptions
+		if reg != nil {
+			pinned := reg.Snapshot()
+			if entry, found := pinned.Current(); found && hasDict(r.Header, entry.ID) {
+				opts.Dicts = pinned
+			}
+		}
+
+		cw := &compressWriter{w: w, opts: opts}
+		defer cw.finish()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// AcceptsEncoding returns true if the given request headers accept Encoding.
+func AcceptsEncoding(h http.Header) bool {
+	for _, value := range h.Values("Accept-Encoding") {
+		for _, item := range strings.Split(value, ",") {
+			name, params := item, ""
+			if i := strings.IndexByte(item, ';'); i >= 0 {
+				name, params = item[:i], item[i+1:]
+			}
+			if !strings.EqualFold(strings.TrimSpace(name), Encoding) {
+				continue
+			}
+			params = strings.ReplaceAll(params, " ", "")
+			if params == "q=0" || strings.HasPrefix(params, "q=0.") && strings.Trim(params[4:], "0") == "" {
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func hasDict(h http.Header, id byte) bool {
+	for _, value := range h.Values(DictsHeader) {
+		for _, item := range strings.Split(value, ",") {
+			n, err := strconv.ParseUint(strings.TrimSpace(item), 10, 8)
+			if err == nil && byte(n) == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type compressWriter struct {
+	w           http.ResponseWriter
+	opts        huffman.WriterOptions
+	zw          *huffman.Writer
+	wroteHeader bool
+}
+
+func (cw *compressWriter) Header() http.Header {
+	return cw.w.Header()
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		// Informational responses (such as 103 Early Hints) carry no
+		// body; the compression decision waits for the final status.
+		cw.w.WriteHeader(status)
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.w.Header()
+	bodyAllowed := status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+	if bodyAllowed && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", Encoding)
+		h.Del("Content-Length")
+		cw.zw = huffman.NewWriterOptions(cw.w, cw.opts)
+	}
+	cw.w.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.w.Header().Get("Content-Type") == "" {
+			cw.w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.zw == nil {
+		return cw.w.Write(p)
+	}
+	return cw.zw.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (cw *compressWriter) Flush() {
+	if cw.zw != nil {
+		_ = cw.zw.Flush()
+	}
+	if f, ok := cw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := cw.w.(http.Hijacker); ok {
+		return hj.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (cw *compressWriter) finish() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.zw != nil {
+		_ = cw.zw.Close()
+	}
+}
+
+// Transport is an http.RoundTripper which requests Encoding and
+// transparently decompresses responses which use it.
+type Transport struct {
+	// Base is the underlying RoundTripper.  If nil,
+	// http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Dicts, if non-nil, holds the dictionaries which the client can
+	// decode.  Their IDs are advertised to the server.
+	Dicts *huffman.DictRegistry
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if req.Header.Get("Accept-Encoding") != "" {
+		// The caller is negotiating on its own; stay out of the way.
+		return base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", Encoding)
+	if t.Dicts != nil {
+		ids := t.Dicts.IDs()
+		if len(ids) != 0 {
+			list := make([]string, len(ids))
+			for index, id := range ids {
+				list[index] = strconv.Itoa(int(id))
+			}
+			req.Header.Set(DictsHeader, strings.Join(list, ","))
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), Encoding) {
+		return resp, nil
+	}
+
+	resp.Body = &decompressBody{body: resp.Body, dicts: t.Dicts}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// decompressBody reads the stream header lazily, so that RoundTrip doesn't
+// block waiting for the first bytes of the body.
+type decompressBody struct {
+	body  io.ReadCloser
+	dicts *huffman.DictRegistry
+	zr    *huffman.Reader
+	err   error
+}
+
+func (db *decompressBody) Read(p []byte) (int, error) {
+	if db.err != nil {
+		return 0, db.err
+	}
+	if db.zr == nil {
+		zr, err := huffman.NewReader(db.body)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			db.err = err
+			return 0, err
+		}
+		zr.UseDicts(db.dicts)
+		db.zr = zr
+	}
+	return db.zr.Read(p)
+}
+
+func (db *decompressBody) Close() error {
+	return db.body.Close()
+}
+
+var (
+	_ http.RoundTripper = (*Transport)(nil)
+	_ http.Flusher      = (*compressWriter)(nil)
+	_ http.Hijacker     = (*compressWriter)(nil)
+)
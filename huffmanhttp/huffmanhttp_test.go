@@ -0,0 +1,186 @@
+package huffmanhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+const testBody = "{\"items\":[{\"id\":1,\"name\":\"alpha\"},{\"id\":2,\"name\":\"beta\"}]}\n"
+
+func TestRoundTrip(t *testing.T) {
+	reg := huffman.NewDictRegistry()
+	dict := huffman.TrainStreamDict([]byte(strings.Repeat(testBody, 10)))
+	if err := reg.Reload(map[byte]huffman.Dict{7: dict}, 7); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+
+	var sawEncoding, sawDicts string
+	handler := Middleware(reg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sawEncoding = r.Header.Get("Accept-Encoding")
+		sawDicts = r.Header.Get(DictsHeader)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, testBody)
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	type testRow struct {
+		name   string
+		client *http.Client
+		coded  bool
+	}
+
+	testData := [...]testRow{
+		{name: "dicts", client: &http.Client{Transport: &Transport{Dicts: reg}}, coded: true},
+		{name: "no-dicts", client: &http.Client{Transport: &Transport{}}, coded: true},
+		{name: "plain", client: &http.Client{Transport: &http.Transport{DisableCompression: true}}, coded: false},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			resp, err := row.client.Get(srv.URL)
+			if err != nil {
+				t.Fatalf("Get: unexpected error: %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("ReadAll: unexpected error: %v", err)
+			}
+			if string(body) != testBody {
+				t.Errorf("wrong body:\n\texpect: %q\n\tactual: %q", testBody, body)
+			}
+			if coded := sawEncoding == Encoding; coded != row.coded {
+				t.Errorf("expected coded=%v, got Accept-Encoding %q", row.coded, sawEncoding)
+			}
+			if row.name == "dicts" && sawDicts != "7" {
+				t.Errorf("expected %s: 7, got %q", DictsHeader, sawDicts)
+			}
+			if ce := resp.Header.Get("Content-Encoding"); ce != "" {
+				t.Errorf("expected Content-Encoding to be removed, got %q", ce)
+			}
+		})
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	type testRow struct {
+		value  string
+		expect bool
+	}
+
+	testData := [...]testRow{
+		{value: "", expect: false},
+		{value: "gzip", expect: false},
+		{value: "gzip, x-huffman", expect: true},
+		{value: "X-Huffman;q=0.5", expect: true},
+		{value: "x-huffman;q=0", expect: false},
+		{value: "x-huffman; q=0.000", expect: false},
+	}
+	for _, row := range testData {
+		t.Run(row.value, func(t *testing.T) {
+			h := make(http.Header)
+			if row.value != "" {
+				h.Set("Accept-Encoding", row.value)
+			}
+			if actual := AcceptsEncoding(h); actual != row.expect {
+				t.Errorf("expected %v, got %v", row.expect, actual)
+			}
+		})
+	}
+}
+
+func TestMiddleware_SetCurrentMidResponse(t *testing.T) {
+	dict := huffman.TrainStreamDict([]byte(strings.Repeat(testBody, 10)))
+	serverDicts := huffman.NewDictRegistry()
+	if err := serverDicts.Reload(map[byte]huffman.Dict{7: dict, 8: dict}, 7); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+	clientDicts := huffman.NewDictRegistry()
+	if err := clientDicts.Reload(map[byte]huffman.Dict{7: dict}, 7); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+
+	// The server switches to a dictionary which the client doesn't hold
+	// between two blocks of the same response.
+	handler := Middleware(serverDicts, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, testBody)
+		w.(http.Flusher).Flush()
+		if err := serverDicts.SetCurrent(8); err != nil {
+			t.Errorf("SetCurrent: unexpected error: %v", err)
+		}
+		_, _ = io.WriteString(w, testBody)
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{Dicts: clientDicts}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll: unexpected error: %v", err)
+	}
+	if expect := testBody + testBody; string(body) != expect {
+		t.Errorf("wrong body:\n\texpect: %q\n\tactual: %q", expect, body)
+	}
+}
+
+func TestMiddleware_EarlyHints(t *testing.T) {
+	handler := Middleware(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, testBody)
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	var hints []int
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			hints = append(hints, code)
+			return nil
+		},
+	}
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: unexpected error: %v", err)
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	client := &http.Client{Transport: &Transport{}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do: unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll: unexpected error: %v", err)
+	}
+	if string(body) != testBody {
+		t.Errorf("wrong body:\n\texpect: %q\n\tactual: %q", testBody, body)
+	}
+	if len(hints) != 1 || hints[0] != http.StatusEarlyHints {
+		t.Errorf("expected one 103 response, got %v", hints)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+	if !resp.Uncompressed {
+		t.Errorf("expected the final response to be compressed")
+	}
+}
@@ -0,0 +1,168 @@
+package huffmantest
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+// maxGenSize is the longest code GenSizes will produce, matching the limit of
+// huffman.Decoder.
+const maxGenSize = 16
+
+// GenSizes returns a random size table for between 1 and maxSymbols Symbols
+// (inclusive), which is always accepted by huffman.Decoder.Init.  The code
+// it describes is complete (or has a single 1-bit code), no code is longer
+// than 16 bits, and some Symbols may have no code.
+//
+// To use GenSizes with pgregory.net/rapid, draw a seed and wrap it:
+//
+//     sizes := rapid.Custom(func(t *rapid.T) []byte {
+//         seed := rapid.Int64().Draw(t, "seed")
+//         return huffmantest.GenSizes(rand.New(rand.NewSource(seed)), 300)
+//     })
+//
+func GenSizes(rng *rand.Rand, maxSymbols int) []byte {
+	numSymbols := 1 + rng.Intn(maxSymbols)
+	numCodes := 1 + rng.Intn(numSymbols)
+	if numCodes > 1<<maxGenSize {
+		numCodes = 1 << maxGenSize
+	}
+
+	depths := genDepths(rng, numCodes, maxGenSize)
+	sizes := make([]byte, numSymbols)
+	for index, symbol := range rng.Perm(numSymbols)[:numCodes] {
+		sizes[symbol] = depths[index]
+	}
+	return sizes
+}
+
+// RandomCode returns a random complete canonical code in which each of
+// numSymbols Symbols has a code of at most maxLen bits, as both an Encoder
+// and a Decoder.  The code depends only on the state of rng, so a fuzzer or
+// simulator can reproduce it from a seed.  A single Symbol gets a 1-bit
+// code.  RandomCode panics unless maxLen is between 1 and 16 and numSymbols
+// is between 0 and 2**maxLen.
+func RandomCode(rng *rand.Rand, numSymbols int, maxLen byte) (*huffman.Encoder, *huffman.Decoder) {
+	if maxLen < 1 || maxLen > maxGenSize {
+		panic("RandomCode: maxLen out of range")
+	}
+	if numSymbols < 0 || numSymbols > 1<<maxLen {
+		panic("RandomCode: too many Symbols for maxLen")
+	}
+
+	sizes := make([]byte, numSymbols)
+	if numSymbols != 0 {
+		depths := genDepths(rng, numSymbols, maxLen)
+		for index, symbol := range rng.Perm(numSymbols) {
+			sizes[symbol] = depths[index]
+		}
+	}
+	return huffman.NewEncoderFromSizes(sizes), huffman.NewDecoder(sizes)
+}
+
+// genDepths returns the depths of the leaves of a random full binary tree
+// with numLeaves leaves, none deeper than maxDepth, or a single leaf at
+// depth 1 if numLeaves is 1.  The tree is grown by repeatedly splitting a
+// random leaf which is not yet at maxDepth; there is always one while there
+// are fewer than 2**maxDepth leaves.
+func genDepths(rng *rand.Rand, numLeaves int, maxDepth byte) []byte {
+	depths := []byte{0}
+	if numLeaves == 1 {
+		depths[0] = 1
+	}
+	for len(depths) < numLeaves {
+		index := rng.Intn(len(depths))
+		for depths[index] >= maxDepth {
+			index = (index + 1) % len(depths)
+		}
+		depths[index]++
+		depths = append(depths, depths[index])
+	}
+	return depths
+}
+
+// GenFreqs returns a random frequency table for between 1 and maxSymbols
+// Symbols (inclusive), suitable for huffman.Encoder.Init.  The tables mix
+// uniform, skewed, and sparse distributions, and at least one Symbol always
+// has a non-zero frequency.
+func GenFreqs(rng *rand.Rand, maxSymbols int) []uint32 {
+	numSymbols := 1 + rng.Intn(maxSymbols)
+	freqs := make([]uint32, numSymbols)
+	switch rng.Intn(3) {
+	case 0:
+		// Uniform.
+		for index := range freqs {
+			freqs[index] = uint32(rng.Intn(1000))
+		}
+	case 1:
+		// Skewed: roughly geometric.
+		for index := range freqs {
+			freqs[index] = uint32(rng.ExpFloat64() * 100)
+		}
+	default:
+		// Sparse: most Symbols unused.
+		for index := range freqs {
+			if rng.Intn(8) == 0 {
+				freqs[index] = 1 + uint32(rng.Intn(1<<20))
+			}
+		}
+	}
+	freqs[rng.Intn(numSymbols)] |= 1
+	return freqs
+}
+
+// GenPayload returns n random Symbols drawn according to the given
+// frequencies, which must include at least one non-zero frequency.
+func GenPayload(rng *rand.Rand, freqs []uint32, n int) []huffman.Symbol {
+	cumulative := make([]uint64, len(freqs))
+	var total uint64
+	for index, freq := range freqs {
+		total += uint64(freq)
+		cumulative[index] = total
+	}
+	if total == 0 {
+		panic("GenPayload: all frequencies are zero")
+	}
+
+	out := make([]huffman.Symbol, n)
+	for index := range out {
+		x := uint64(rng.Int63n(int64(total)))
+		symbol := sort.Search(len(cumulative), func(i int) bool { return cumulative[i] > x })
+		out[index] = huffman.Symbol(symbol)
+	}
+	return out
+}
+
+// Sizes is a size table which implements testing/quick.Generator using
+// GenSizes, with the quick size parameter as the maximum number of Symbols.
+type Sizes []byte
+
+// Generate implements testing/quick.Generator.
+func (Sizes) Generate(rng *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Sizes(GenSizes(rng, size+1)))
+}
+
+// Freqs is a frequency table which implements testing/quick.Generator using
+// GenFreqs, with the quick size parameter as the maximum number of Symbols.
+type Freqs []uint32
+
+// Generate implements testing/quick.Generator.
+func (Freqs) Generate(rng *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(Freqs(GenFreqs(rng, size+1)))
+}
+
+// Payload is a frequency table plus Symbols drawn from it, which implements
+// testing/quick.Generator.
+type Payload struct {
+	Freqs   []uint32
+	Symbols []huffman.Symbol
+}
+
+// Generate implements testing/quick.Generator.
+func (Payload) Generate(rng *rand.Rand, size int) reflect.Value {
+	freqs := GenFreqs(rng, size+1)
+	return reflect.ValueOf(Payload{Freqs: freqs, Symbols: GenPayload(rng, freqs, rng.Intn(10*size+1))})
+}
@@ -0,0 +1,142 @@
+package huffmantest
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+func TestGenSizes(t *testing.T) {
+	rng := rand.New(rand.NewSource(145))
+	for trial := 0; trial < 500; trial++ {
+		sizes := GenSizes(rng, 300)
+		if len(sizes) < 1 || len(sizes) > 300 {
+			t.Fatalf("trial %d: bad length %d", trial, len(sizes))
+		}
+		var d huffman.Decoder
+		if err := d.Init(sizes); err != nil {
+			t.Fatalf("trial %d: Decoder.Init(%v): unexpected error: %v", trial, sizes, err)
+		}
+	}
+}
+
+func TestRandomCode(t *testing.T) {
+	rng := rand.New(rand.NewSource(185))
+	for trial := 0; trial < 500; trial++ {
+		maxLen := byte(1 + rng.Intn(16))
+		limit := 1 << maxLen
+		if limit > 300 {
+			limit = 300
+		}
+		numSymbols := rng.Intn(limit + 1)
+		e, d := RandomCode(rng, numSymbols, maxLen)
+		if actual := int(e.NumSymbols()); actual != numSymbols {
+			t.Fatalf("trial %d: expected %d Symbols, got %d", trial, numSymbols, actual)
+		}
+		if e.MaxSize() > maxLen {
+			t.Fatalf("trial %d: code of %d bits exceeds maxLen %d", trial, e.MaxSize(), maxLen)
+		}
+		for symbol := 0; symbol < numSymbols; symbol++ {
+			hc := e.Encode(huffman.Symbol(symbol))
+			if actual, _, _ := d.Decode(hc); actual != huffman.Symbol(symbol) {
+				t.Fatalf("trial %d: Decode(Encode(%d)) = %d", trial, symbol, actual)
+			}
+		}
+	}
+
+	e1, _ := RandomCode(rand.New(rand.NewSource(1)), 50, 8)
+	e2, _ := RandomCode(rand.New(rand.NewSource(1)), 50, 8)
+	if !bytes.Equal(e1.SizeBySymbol(), e2.SizeBySymbol()) {
+		t.Errorf("RandomCode: same seed gave different codes")
+	}
+}
+
+func TestGenFreqs(t *testing.T) {
+	rng := rand.New(rand.NewSource(145))
+	for trial := 0; trial < 500; trial++ {
+		freqs := GenFreqs(rng, 300)
+		if len(freqs) < 1 || len(freqs) > 300 {
+			t.Fatalf("trial %d: bad length %d", trial, len(freqs))
+		}
+		nonZero := false
+		for _, freq := range freqs {
+			nonZero = nonZero || freq != 0
+		}
+		if !nonZero {
+			t.Fatalf("trial %d: all frequencies are zero", trial)
+		}
+	}
+}
+
+func TestGenPayload(t *testing.T) {
+	rng := rand.New(rand.NewSource(145))
+	freqs := []uint32{0, 1, 0, 3}
+	counts := make([]int, len(freqs))
+	for _, symbol := range GenPayload(rng, freqs, 4000) {
+		counts[symbol]++
+	}
+	if counts[0] != 0 || counts[2] != 0 {
+		t.Errorf("drew Symbols with zero frequency: %v", counts)
+	}
+	if counts[3] < 2*counts[1] {
+		t.Errorf("distribution too far from 1:3: %v", counts)
+	}
+}
+
+func TestQuick(t *testing.T) {
+	roundTrip := func(p Payload) bool {
+		e := huffman.NewEncoder(len(p.Freqs), p.Freqs)
+		var buf bytes.Buffer
+		bw := huffman.NewBitWriter(&buf)
+		for _, symbol := range p.Symbols {
+			if err := bw.WriteSymbol(e, symbol); err != nil {
+				return false
+			}
+		}
+		_ = bw.Flush()
+
+		d := e.Decoder()
+		br := huffman.NewBitReader(&buf)
+		for _, symbol := range p.Symbols {
+			if actual, err := br.ReadSymbol(d); err != nil || actual != symbol {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+
+	canonical := func(sizes Sizes) bool {
+		e := huffman.NewEncoderFromSizes(sizes)
+		return bytes.Equal(sizes, e.SizeBySymbol())
+	}
+	if err := quick.Check(canonical, nil); err != nil {
+		t.Error(err)
+	}
+
+	nonEmpty := func(freqs Freqs) bool {
+		return len(freqs) != 0
+	}
+	if err := quick.Check(nonEmpty, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGenSizes_WideAlphabet(t *testing.T) {
+	rng := rand.New(rand.NewSource(145))
+	for trial := 0; trial < 8; trial++ {
+		sizes := GenSizes(rng, 1<<17)
+		if len(sizes) < 1 || len(sizes) > 1<<17 {
+			t.Fatalf("trial %d: bad length %d", trial, len(sizes))
+		}
+		var d huffman.Decoder
+		if err := d.Init(sizes); err != nil {
+			t.Fatalf("trial %d: Decoder.Init: unexpected error: %v", trial, err)
+		}
+	}
+}
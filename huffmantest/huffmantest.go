@@ -0,0 +1,147 @@
+// Package huffmantest provides golden test vectors for canonical Huffman
+// codes, so that programs embedding the huffman package (or reimplementing
+// it) can check their integration bit for bit.
+//
+// Each Vector gives a code's bit lengths and the resulting canonical code
+// for every Symbol, in both bit orders.  Most vectors also give the Symbol
+// frequencies from which huffman.Encoder.Init derives those bit lengths; the
+// rest come straight from RFC 1951.  The package's own tests check every
+// vector against the huffman package.
+//
+package huffmantest
+
+import (
+	"github.com/chronos-tachyon/huffman"
+)
+
+// Vector is one golden test vector.
+type Vector struct {
+	// Name identifies the vector.
+	Name string
+
+	// Frequencies, if non-nil, holds the frequency of each Symbol, which
+	// huffman.Encoder.Init turns into Sizes.  Vectors which start from
+	// a published size table have no Frequencies.
+	Frequencies []uint32
+
+	// Sizes holds the bit length of each Symbol's code, or 0 if the
+	// Symbol has no code.
+	Sizes []byte
+
+	// MSBFirst holds each Symbol's canonical code with the first bit in
+	// the most significant position, as codes are written in RFC 1951.
+	// Symbols without codes have 0.
+	MSBFirst []uint32
+
+	// LSBFirst holds each Symbol's canonical code with the first bit in
+	// the least significant position, as in huffman.Code.Bits and as
+	// packed by huffman.BitWriter.  Symbols without codes have 0.
+	LSBFirst []uint32
+}
+
+// Codes returns the expected huffman.Code for each Symbol.
+func (v Vector) Codes() []huffman.Code {
+	out := make([]huffman.Code, len(v.Sizes))
+	for index, size := range v.Sizes {
+		out[index] = huffman.MakeCode(size, v.LSBFirst[index])
+	}
+	return out
+}
+
+// Vectors returns all of the test vectors.  The caller may modify the
+// result.
+func Vectors() []Vector {
+	out := make([]Vector, len(vectors))
+	for index, v := range vectors {
+		out[index] = Vector{
+			Name:        v.Name,
+			Frequencies: cloneUint32s(v.Frequencies),
+			Sizes:       append([]byte(nil), v.Sizes...),
+			MSBFirst:    cloneUint32s(v.MSBFirst),
+			LSBFirst:    cloneUint32s(v.LSBFirst),
+		}
+	}
+	return out
+}
+
+func cloneUint32s(in []uint32) []uint32 {
+	if in == nil {
+		return nil
+	}
+	return append([]uint32(nil), in...)
+}
+
+var vectors = []Vector{
+	{
+		// A lone Symbol still gets a 1-bit code.
+		Name:        "single",
+		Frequencies: []uint32{5},
+		Sizes:       []byte{1},
+		MSBFirst:    []uint32{0b0},
+		LSBFirst:    []uint32{0b0},
+	},
+	{
+		Name:        "two",
+		Frequencies: []uint32{1, 1},
+		Sizes:       []byte{1, 1},
+		MSBFirst:    []uint32{0b0, 0b1},
+		LSBFirst:    []uint32{0b0, 0b1},
+	},
+	{
+		Name:        "skewed",
+		Frequencies: []uint32{1, 2, 4, 8},
+		Sizes:       []byte{3, 3, 2, 1},
+		MSBFirst:    []uint32{0b110, 0b111, 0b10, 0b0},
+		LSBFirst:    []uint32{0b011, 0b111, 0b01, 0b0},
+	},
+	{
+		Name:        "flat-5",
+		Frequencies: []uint32{1, 1, 1, 1, 1},
+		Sizes:       []byte{3, 3, 2, 2, 2},
+		MSBFirst:    []uint32{0b110, 0b111, 0b00, 0b01, 0b10},
+		LSBFirst:    []uint32{0b011, 0b111, 0b00, 0b10, 0b01},
+	},
+	{
+		// Symbols with a frequency of 0 get no code.
+		Name:        "gaps",
+		Frequencies: []uint32{0, 3, 0, 1, 1},
+		Sizes:       []byte{0, 1, 0, 2, 2},
+		MSBFirst:    []uint32{0, 0b0, 0, 0b10, 0b11},
+		LSBFirst:    []uint32{0, 0b0, 0, 0b01, 0b11},
+	},
+	{
+		// The six-letter example from Cormen et al., "Introduction to
+		// Algorithms", section 16.3.
+		Name:        "clrs-abcdef",
+		Frequencies: []uint32{45, 13, 12, 16, 9, 5},
+		Sizes:       []byte{1, 3, 3, 3, 4, 4},
+		MSBFirst:    []uint32{0b0, 0b100, 0b101, 0b110, 0b1110, 0b1111},
+		LSBFirst:    []uint32{0b0, 0b001, 0b101, 0b011, 0b0111, 0b1111},
+	},
+	{
+		// The ABCDEFGH example from RFC 1951 section 3.2.2.
+		Name:     "rfc1951-abcdefgh",
+		Sizes:    []byte{3, 3, 3, 3, 3, 2, 4, 4},
+		MSBFirst: []uint32{0b010, 0b011, 0b100, 0b101, 0b110, 0b00, 0b1110, 0b1111},
+		LSBFirst: []uint32{0b010, 0b110, 0b001, 0b101, 0b011, 0b00, 0b0111, 0b1111},
+	},
+	{
+		// The fixed distance code from RFC 1951 section 3.2.6.
+		Name: "rfc1951-fixed-dist",
+		Sizes: []byte{
+			5, 5, 5, 5, 5, 5, 5, 5, 5, 5,
+			5, 5, 5, 5, 5, 5, 5, 5, 5, 5,
+			5, 5, 5, 5, 5, 5, 5, 5, 5, 5,
+		},
+		MSBFirst: []uint32{
+			0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09,
+			0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13,
+			0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d,
+		},
+		LSBFirst: []uint32{
+			0x00, 0x10, 0x08, 0x18, 0x04, 0x14, 0x0c, 0x1c, 0x02, 0x12,
+			0x0a, 0x1a, 0x06, 0x16, 0x0e, 0x1e, 0x01, 0x11, 0x09, 0x19,
+			0x05, 0x15, 0x0d, 0x1d, 0x03, 0x13, 0x0b, 0x1b, 0x07, 0x17,
+		},
+	},
+}
@@ -0,0 +1,51 @@
+package huffmantest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+func TestVectors(t *testing.T) {
+	for _, v := range Vectors() {
+		t.Run(v.Name, func(t *testing.T) {
+			if len(v.MSBFirst) != len(v.Sizes) || len(v.LSBFirst) != len(v.Sizes) {
+				t.Fatalf("inconsistent lengths: %d sizes, %d MSB-first, %d LSB-first", len(v.Sizes), len(v.MSBFirst), len(v.LSBFirst))
+			}
+
+			if v.Frequencies != nil {
+				e := huffman.NewEncoder(len(v.Frequencies), v.Frequencies)
+				if actual := e.SizeBySymbol(); !bytes.Equal(v.Sizes, actual) {
+					t.Errorf("Encoder.Init: expected sizes %v, got %v", v.Sizes, actual)
+				}
+			}
+
+			e := huffman.NewEncoderFromSizes(v.Sizes)
+			d := huffman.NewDecoder(v.Sizes)
+			for index, hc := range v.Codes() {
+				symbol := huffman.Symbol(index)
+				if actual := e.Encode(symbol); hc != actual {
+					t.Errorf("Encode(%d): expected %v, got %v", symbol, hc, actual)
+				}
+				if hc.Size == 0 {
+					continue
+				}
+				if actual := huffman.MakeReversedCode(hc.Size, v.MSBFirst[index]); hc != actual {
+					t.Errorf("symbol %d: MSB-first %b and LSB-first %b disagree", symbol, v.MSBFirst[index], v.LSBFirst[index])
+				}
+				if actual, _, _ := d.Decode(hc); symbol != actual {
+					t.Errorf("Decode(%v): expected %d, got %d", hc, symbol, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestVectors_Copy(t *testing.T) {
+	a := Vectors()
+	a[0].Sizes[0] = 99
+	if b := Vectors(); b[0].Sizes[0] == 99 {
+		t.Errorf("Vectors returned shared storage")
+	}
+}
@@ -0,0 +1,47 @@
+package huffman
+
+import (
+	"fmt"
+	mathbits "math/bits"
+)
+
+// maxIdentitySymbols is the largest alphabet an identity code can cover
+// without exceeding maxBitsPerCode.
+const maxIdentitySymbols = 1 << maxBitsPerCode
+
+// IdentitySizes returns the size table of the identity code for an alphabet
+// of numSymbols Symbols: every Symbol gets a code of ceil(log2(numSymbols))
+// bits, or 1 bit if numSymbols is 1.  Since all codes have the same length,
+// the canonical code of Symbol s is simply s written most significant bit
+// first, so the identity code is a plain fixed-length encoding.  It is
+// incomplete unless numSymbols is a power of 2, which Decoder.Init allows
+// under ProfileLenient.  IdentitySizes panics if numSymbols is negative or
+// greater than 65536.
+func IdentitySizes(numSymbols int) []byte {
+	if numSymbols < 0 || numSymbols > maxIdentitySymbols {
+		panic(fmt.Errorf("IdentitySizes: numSymbols %d not in range [0..%d]", numSymbols, maxIdentitySymbols))
+	}
+	size := byte(1)
+	if numSymbols > 2 {
+		size = byte(mathbits.Len(uint(numSymbols - 1)))
+	}
+	sizes := make([]byte, numSymbols)
+	for index := range sizes {
+		sizes[index] = size
+	}
+	return sizes
+}
+
+// NewIdentityEncoder returns an Encoder for the identity code described by
+// IdentitySizes.  It codes every Symbol with the same number of bits
+// regardless of frequency, so substituting it for a real Encoder turns
+// compression off without changing any other code path, and comparing the
+// two measures what compression gains.
+func NewIdentityEncoder(numSymbols int) *Encoder {
+	return NewEncoderFromSizes(IdentitySizes(numSymbols))
+}
+
+// NewIdentityDecoder returns the Decoder matching NewIdentityEncoder.
+func NewIdentityDecoder(numSymbols int) *Decoder {
+	return NewDecoder(IdentitySizes(numSymbols))
+}
@@ -0,0 +1,41 @@
+package huffman
+
+import (
+	"testing"
+)
+
+func TestIdentity(t *testing.T) {
+	type testRow struct {
+		numSymbols int
+		size       byte
+	}
+
+	testData := [...]testRow{
+		{numSymbols: 1, size: 1},
+		{numSymbols: 2, size: 1},
+		{numSymbols: 3, size: 2},
+		{numSymbols: 4, size: 2},
+		{numSymbols: 5, size: 3},
+		{numSymbols: 256, size: 8},
+		{numSymbols: 257, size: 9},
+		{numSymbols: 65536, size: 16},
+	}
+	for _, row := range testData {
+		e := NewIdentityEncoder(row.numSymbols)
+		d := NewIdentityDecoder(row.numSymbols)
+		for symbol := 0; symbol < row.numSymbols; symbol++ {
+			hc := e.Encode(Symbol(symbol))
+			expect := MakeReversedCode(row.size, uint32(symbol))
+			if hc != expect {
+				t.Fatalf("%d symbols: Encode(%d): expected %v, got %v", row.numSymbols, symbol, expect, hc)
+			}
+			if actual, _, _ := d.Decode(hc); actual != Symbol(symbol) {
+				t.Fatalf("%d symbols: Decode(%v): expected %d, got %d", row.numSymbols, hc, symbol, actual)
+			}
+		}
+	}
+
+	if sizes := IdentitySizes(0); len(sizes) != 0 {
+		t.Errorf("IdentitySizes(0): expected none, got %v", sizes)
+	}
+}
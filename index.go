@@ -0,0 +1,251 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// The block index written by WriterOptions.Index is:
+//
+//     index  := entry* footer
+//     entry  := compressed:uint64le uncompressed:uint64le
+//     footer := offset:uint64le count:uint64le "HIDX"
+//
+// There is one entry for each block, giving the offset of the block's type
+// byte within the stream and the decompressed offset of its first byte,
+// followed by one final entry for the end-of-stream marker.  The footer's
+// offset field is the offset of the first entry.
+//
+
+const (
+	indexEntrySize  = 16
+	indexFooterSize = 20
+)
+
+var indexMagic = [4]byte{'H', 'I', 'D', 'X'}
+
+type indexEntry struct {
+	compressed   uint64
+	uncompressed uint64
+}
+
+func appendIndex(dst []byte, base uint64, entries []indexEntry) []byte {
+	var tmp [8]byte
+	offset := base + uint64(len(dst))
+	for _, entry := range entries {
+		binary.LittleEndian.PutUint64(tmp[:], entry.compressed)
+		dst = append(dst, tmp[:]...)
+		binary.LittleEndian.PutUint64(tmp[:], entry.uncompressed)
+		dst = append(dst, tmp[:]...)
+	}
+	binary.LittleEndian.PutUint64(tmp[:], offset)
+	dst = append(dst, tmp[:]...)
+	binary.LittleEndian.PutUint64(tmp[:], uint64(len(entries)))
+	dst = append(dst, tmp[:]...)
+	return append(dst, indexMagic[:]...)
+}
+
+// maxDecodedLen returns the largest number of bytes that a block occupying n
+// bytes of the stream can decode to.  Every symbol costs at least one bit.
+func maxDecodedLen(n uint64) uint64 {
+	if n > math.MaxUint64/8 {
+		return math.MaxUint64
+	}
+	return n * 8
+}
+
+// ErrNoIndex is returned by NewIndexedReader when the stream was not written
+// with a block index.
+var ErrNoIndex = errors.New("Huffman stream has no block index")
+
+// IndexedReader provides random access to a stream which was written with
+// WriterOptions.Index set.
+//
+// ReadBlock may be called concurrently from multiple goroutines.  Read and
+// Seek share a cursor and a one-block cache, and so must not be.
+//
+type IndexedReader struct {
+	r          io.ReaderAt
+	flags      byte
+	dicts      *DictRegistry
+	entries    []indexEntry
+	pos        int64
+	cache      []byte
+	cacheBlock int
+}
+
+// NewIndexedReader returns a new IndexedReader which reads the size-byte
+// stream stored in r.
+func NewIndexedReader(r io.ReaderAt, size int64) (*IndexedReader, error) {
+	var header [6]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, noEOF(err)
+	}
+	if !bytes.Equal(header[0:4], streamMagic[:]) || header[4] != streamVersion {
+		return nil, ErrHeader
+	}
+	flags := header[5]
+	var zr Reader
+	if err := zr.setFlags(flags); err != nil {
+		return nil, err
+	}
+	if (flags & flagIndex) == 0 {
+		return nil, ErrNoIndex
+	}
+	if (flags & (flagEncrypted | flagTransformed)) != 0 {
+		return nil, fmt.Errorf("%w: IndexedReader does not support Transforms or a Cipher", ErrMissingTransform)
+	}
+
+	if size < int64(len(header))+indexFooterSize {
+		return nil, fmt.Errorf("%w: stream too short for block index", ErrCorrupt)
+	}
+	var footer [indexFooterSize]byte
+	if _, err := r.ReadAt(footer[:], size-indexFooterSize); err != nil {
+		return nil, noEOF(err)
+	}
+	if !bytes.Equal(footer[16:20], indexMagic[:]) {
+		return nil, fmt.Errorf("%w: invalid block index", ErrCorrupt)
+	}
+	offset := binary.LittleEndian.Uint64(footer[0:8])
+	count := binary.LittleEndian.Uint64(footer[8:16])
+	if count == 0 || offset >= uint64(size) || count > (uint64(size)-offset)/indexEntrySize || offset+count*indexEntrySize+indexFooterSize != uint64(size) {
+		return nil, fmt.Errorf("%w: invalid block index", ErrCorrupt)
+	}
+
+	raw := make([]byte, count*indexEntrySize)
+	if _, err := r.ReadAt(raw, int64(offset)); err != nil {
+		return nil, noEOF(err)
+	}
+	entries := make([]indexEntry, count)
+	for i := range entries {
+		entries[i].compressed = binary.LittleEndian.Uint64(raw[16*i:])
+		entries[i].uncompressed = binary.LittleEndian.Uint64(raw[16*i+8:])
+		if i > 0 && (entries[i].compressed <= entries[i-1].compressed || entries[i].uncompressed <= entries[i-1].uncompressed) {
+			return nil, fmt.Errorf("%w: invalid block index", ErrCorrupt)
+		}
+		if i > 0 && entries[i].uncompressed-entries[i-1].uncompressed > maxDecodedLen(entries[i].compressed-entries[i-1].compressed) {
+			return nil, fmt.Errorf("%w: block %d is too large for its %d compressed bytes", ErrCorrupt, i-1, entries[i].compressed-entries[i-1].compressed)
+		}
+	}
+	// The first block starts just after the stream header, and the
+	// end-of-stream marker comes before the index.
+	if entries[0].compressed != uint64(len(header)) || entries[0].uncompressed != 0 || entries[count-1].compressed >= offset {
+		return nil, fmt.Errorf("%w: invalid block index", ErrCorrupt)
+	}
+
+	return &IndexedReader{
+		r:          r,
+		flags:      flags,
+		entries:    entries,
+		cacheBlock: -1,
+	}, nil
+}
+
+// NumBlocks returns the number of blocks in the stream.
+func (ir *IndexedReader) NumBlocks() int {
+	return len(ir.entries) - 1
+}
+
+// Size returns the total decompressed length of the stream.
+func (ir *IndexedReader) Size() int64 {
+	return int64(ir.entries[len(ir.entries)-1].uncompressed)
+}
+
+// BlockOffset returns the decompressed offset of the first byte of the
+// given block.
+func (ir *IndexedReader) BlockOffset(block int) int64 {
+	return int64(ir.entries[block].uncompressed)
+}
+
+// UseDicts supplies the registry used to look up the dictionaries referenced
+// by the stream, if any.  It must not be called concurrently with ReadBlock.
+func (ir *IndexedReader) UseDicts(reg *DictRegistry) {
+	ir.dicts = reg
+}
+
+// ReadBlock decompresses and returns the contents of the given block.
+func (ir *IndexedReader) ReadBlock(block int) ([]byte, error) {
+	if block < 0 || block >= ir.NumBlocks() {
+		return nil, fmt.Errorf("block %d out of range [0..%d)", block, ir.NumBlocks())
+	}
+
+	start, end := ir.entries[block], ir.entries[block+1]
+	section := io.NewSectionReader(ir.r, int64(start.compressed), int64(end.compressed-start.compressed))
+
+	zr := &Reader{block: block, dicts: ir.dicts}
+	_ = zr.setFlags(ir.flags)
+	zr.br.Init(section)
+
+	// Grow the buffer as the data arrives, rather than trusting the index.
+	expectLen := end.uncompressed - start.uncompressed
+	out, err := zr.decodeBlock(make([]byte, 0, minUint64(expectLen, DefaultBlockSize)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(out)) != expectLen {
+		return nil, zr.blockError(fmt.Errorf("%w: expected %d bytes, got %d", ErrLength, expectLen, len(out)))
+	}
+	return out, nil
+}
+
+// Read decompresses data at the current position into p.
+func (ir *IndexedReader) Read(p []byte) (int, error) {
+	if ir.pos >= ir.Size() {
+		return 0, io.EOF
+	}
+
+	var n int
+	for n < len(p) && ir.pos < ir.Size() {
+		block := ir.blockAt(ir.pos)
+		if ir.cacheBlock != block {
+			data, err := ir.ReadBlock(block)
+			if err != nil {
+				return n, err
+			}
+			ir.cache = data
+			ir.cacheBlock = block
+		}
+		start := ir.pos - ir.BlockOffset(block)
+		if start < 0 || start >= int64(len(ir.cache)) {
+			return n, fmt.Errorf("%w: position %d is not within block %d", ErrCorrupt, ir.pos, block)
+		}
+		copied := copy(p[n:], ir.cache[start:])
+		n += copied
+		ir.pos += int64(copied)
+	}
+	return n, nil
+}
+
+// Seek sets the decompressed offset for the next Read.
+func (ir *IndexedReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		// pass
+	case io.SeekCurrent:
+		offset += ir.pos
+	case io.SeekEnd:
+		offset += ir.Size()
+	default:
+		return ir.pos, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return ir.pos, fmt.Errorf("negative position %d", offset)
+	}
+	ir.pos = offset
+	return offset, nil
+}
+
+// blockAt returns the index of the block containing decompressed offset pos.
+func (ir *IndexedReader) blockAt(pos int64) int {
+	numBlocks := ir.NumBlocks()
+	return sort.Search(numBlocks, func(i int) bool {
+		return int64(ir.entries[i+1].uncompressed) > pos
+	})
+}
+
+var _ io.ReadSeeker = (*IndexedReader)(nil)
@@ -0,0 +1,144 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func makeIndexedStream(t *testing.T, input []byte, opts WriterOptions) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := NewWriterOptions(&buf, opts)
+	if _, err := zw.Write(input); err != nil {
+		t.Fatalf("Write: unexpected error: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestIndexedReader(t *testing.T) {
+	input := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 40))
+	stream := makeIndexedStream(t, input, WriterOptions{
+		BlockSize:     100,
+		Checksum:      ChecksumCRC32C,
+		LengthTrailer: true,
+		Index:         true,
+	})
+
+	ir, err := NewIndexedReader(bytes.NewReader(stream), int64(len(stream)))
+	if err != nil {
+		t.Fatalf("NewIndexedReader: unexpected error: %v", err)
+	}
+	if expect, actual := (len(input)+99)/100, ir.NumBlocks(); expect != actual {
+		t.Errorf("NumBlocks: expected %d, got %d", expect, actual)
+	}
+	if expect, actual := int64(len(input)), ir.Size(); expect != actual {
+		t.Errorf("Size: expected %d, got %d", expect, actual)
+	}
+
+	block, err := ir.ReadBlock(7)
+	if err != nil {
+		t.Fatalf("ReadBlock: unexpected error: %v", err)
+	}
+	if expect := input[700:800]; !bytes.Equal(expect, block) {
+		t.Errorf("ReadBlock: wrong output:\n\texpect: %q\n\tactual: %q", expect, block)
+	}
+
+	if _, err := ir.Seek(1234, io.SeekStart); err != nil {
+		t.Fatalf("Seek: unexpected error: %v", err)
+	}
+	p := make([]byte, 250)
+	if _, err := io.ReadFull(ir, p); err != nil {
+		t.Fatalf("ReadFull: unexpected error: %v", err)
+	}
+	if expect := input[1234:1484]; !bytes.Equal(expect, p) {
+		t.Errorf("Read: wrong output:\n\texpect: %q\n\tactual: %q", expect, p)
+	}
+
+	if _, err := ir.Seek(-10, io.SeekEnd); err != nil {
+		t.Fatalf("Seek: unexpected error: %v", err)
+	}
+	rest, err := io.ReadAll(ir)
+	if err != nil {
+		t.Fatalf("ReadAll: unexpected error: %v", err)
+	}
+	if expect := input[len(input)-10:]; !bytes.Equal(expect, rest) {
+		t.Errorf("Read: wrong output:\n\texpect: %q\n\tactual: %q", expect, rest)
+	}
+
+	// The streaming Reader must skip over the index.
+	zr, err := NewReader(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	output, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("ReadAll: unexpected error: %v", err)
+	}
+	if !bytes.Equal(input, output) {
+		t.Errorf("Reader: wrong output")
+	}
+}
+
+func TestIndexedReader_NoIndex(t *testing.T) {
+	stream := makeIndexedStream(t, []byte("hello, world"), WriterOptions{})
+	_, err := NewIndexedReader(bytes.NewReader(stream), int64(len(stream)))
+	if !errors.Is(err, ErrNoIndex) {
+		t.Errorf("expected ErrNoIndex, got %v", err)
+	}
+}
+
+func TestIndexedReader_CorruptIndex(t *testing.T) {
+	input := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 40))
+	clean := makeIndexedStream(t, input, WriterOptions{BlockSize: 100, Index: true})
+	count := (len(input)+99)/100 + 1
+	first := len(clean) - indexFooterSize - count*indexEntrySize
+
+	// field returns the given column (0 for compressed, 8 for
+	// uncompressed) of the given index entry.
+	field := func(stream []byte, entry int, column int) []byte {
+		return stream[first+entry*indexEntrySize+column:]
+	}
+
+	type testRow struct {
+		name   string
+		mutate func(stream []byte)
+	}
+
+	testData := [...]testRow{
+		{"huge-last-block", func(stream []byte) {
+			binary.LittleEndian.PutUint64(field(stream, count-1, 8), 1<<62)
+		}},
+		{"shifted-uncompressed", func(stream []byte) {
+			for i := 0; i < count; i++ {
+				x := field(stream, i, 8)
+				binary.LittleEndian.PutUint64(x, binary.LittleEndian.Uint64(x)+5)
+			}
+		}},
+		{"shifted-compressed", func(stream []byte) {
+			for i := 0; i < count; i++ {
+				x := field(stream, i, 0)
+				binary.LittleEndian.PutUint64(x, binary.LittleEndian.Uint64(x)+1)
+			}
+		}},
+		{"empty-block", func(stream []byte) {
+			binary.LittleEndian.PutUint64(field(stream, 2, 8), binary.LittleEndian.Uint64(field(stream, 1, 8)))
+		}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			stream := append([]byte(nil), clean...)
+			row.mutate(stream)
+			_, err := NewIndexedReader(bytes.NewReader(stream), int64(len(stream)))
+			if !errors.Is(err, ErrCorrupt) {
+				t.Errorf("expected ErrCorrupt, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// The format produced by EncodeInterleaved is:
+//
+//     interleaved := length:uvarint{streams} stream{streams}
+//
+// Byte i of the input is coded into stream (i mod streams).  Each stream is a
+// sequence of Huffman codes over the byte alphabet, least significant bit
+// first, padded with zero bits to the next byte boundary, and its length in
+// bytes is given by the corresponding length field.  The number of coded
+// bytes is not recorded; the caller must store it.
+//
+// Splitting the input this way gives the decoder several independent chains
+// of shifts and table lookups, which a superscalar CPU can execute in
+// parallel instead of waiting on each code before it can find the next.
+//
+
+// MaxInterleavedStreams is the largest number of streams accepted by
+// EncodeInterleaved and DecodeInterleaved.
+const MaxInterleavedStreams = 8
+
+// EncodeInterleaved codes each byte of src as a Symbol using e, splitting the
+// codes round-robin across the given number of streams, appends the result to
+// dst, and returns the extended slice.  Returns ErrNoCode if any byte in src
+// has no assigned code.
+func EncodeInterleaved(streams int, e *Encoder, dst []byte, src []byte) ([]byte, error) {
+	checkInterleavedStreams(streams)
+
+	var tmp [binary.MaxVarintLen64]byte
+	parts := make([][]byte, streams)
+	strided := make([]byte, 0, (len(src)+streams-1)/streams)
+	for s := range parts {
+		strided = strided[:0]
+		for i := s; i < len(src); i += streams {
+			strided = append(strided, src[i])
+		}
+		var buf bytes.Buffer
+		bw := NewBitWriter(&buf)
+		if err := e.EncodeBytes(strided, bw); err != nil {
+			return dst, err
+		}
+		if err := bw.Flush(); err != nil {
+			return dst, err
+		}
+		parts[s] = buf.Bytes()
+		n := binary.PutUvarint(tmp[:], uint64(len(parts[s])))
+		dst = append(dst, tmp[:n]...)
+	}
+	for _, part := range parts {
+		dst = append(dst, part...)
+	}
+	return dst, nil
+}
+
+// DecodeInterleaved decodes n bytes from data produced by EncodeInterleaved
+// with the same number of streams, appends them to dst, and returns the
+// extended slice.
+func DecodeInterleaved(streams int, d *Decoder, dst []byte, src []byte, n int) ([]byte, error) {
+	checkInterleavedStreams(streams)
+
+	lengths := make([]uint64, streams)
+	for s := range lengths {
+		length, k := binary.Uvarint(src)
+		if k <= 0 {
+			return dst, fmt.Errorf("invalid length for interleaved stream %d", s)
+		}
+		lengths[s] = length
+		src = src[k:]
+	}
+
+	parts := make([][]byte, streams)
+	for s, length := range lengths {
+		if length > uint64(len(src)) {
+			return dst, fmt.Errorf("interleaved stream %d: length %d exceeds input", s, length)
+		}
+		parts[s] = src[:length]
+		src = src[length:]
+	}
+	return decodeInterleaved(d, dst, parts, n)
+}
+
+func checkInterleavedStreams(streams int) {
+	if streams < 1 || streams > MaxInterleavedStreams {
+		panic(fmt.Errorf("streams %d not in range [1..%d]", streams, MaxInterleavedStreams))
+	}
+}
+
+// interleavedStream is the decoding state of one stream.  Once src is
+// exhausted, the reservoir is refilled with zero bits, which are counted in
+// phantom so that reading them can be detected as truncation.
+type interleavedStream struct {
+	src     []byte
+	acc     uint64
+	n       uint
+	phantom uint
+}
+
+// refill tops up the reservoir to at least 57 bits, enough for any code.
+func (r *interleavedStream) refill() {
+	if r.n > 56 {
+		return
+	}
+	if len(r.src) >= 8 {
+		// Bits above the new r.n come from bytes still in src, so
+		// loading them again on the next refill is harmless.
+		r.acc |= binary.LittleEndian.Uint64(r.src) << r.n
+		k := (63 - r.n) >> 3
+		r.src = r.src[k:]
+		r.n += 8 * k
+		return
+	}
+	for r.n <= 56 {
+		if len(r.src) == 0 {
+			r.phantom += 8
+		} else {
+			r.acc |= uint64(r.src[0]) << r.n
+			r.src = r.src[1:]
+		}
+		r.n += 8
+	}
+}
+
+func (r *interleavedStream) next(d *Decoder, mask uint64) (byte, error) {
+	r.refill()
+	var symbol Symbol
+	var size byte
+	if d.direct != nil {
+		de := d.direct[r.acc&mask]
+		symbol, size = de.symbol(), de.size()
+		if size == 0 {
+			return 0, fmt.Errorf("invalid Huffman code %s", MakeCode(d.maxSize, uint32(r.acc&mask)))
+		}
+	} else {
+		var err error
+		symbol, size, err = decodeBits(d, r.acc)
+		if err != nil {
+			return 0, err
+		}
+	}
+	r.acc >>= size
+	r.n -= uint(size)
+	if r.n < r.phantom {
+		return 0, fmt.Errorf("truncated interleaved stream")
+	}
+	if symbol > 0xff {
+		return 0, fmt.Errorf("symbol %d out of range", symbol)
+	}
+	return byte(symbol), nil
+}
+
+// decodeInterleaved decodes n bytes from the given streams, taking one code
+// from each stream in turn.
+func decodeInterleaved(d *Decoder, dst []byte, parts [][]byte, n int) ([]byte, error) {
+	if d.maxSize == 0 && n != 0 {
+		return dst, fmt.Errorf("cannot decode %d bytes with an empty code", n)
+	}
+
+	rs := make([]interleavedStream, len(parts))
+	for s, part := range parts {
+		rs[s].src = part
+	}
+	mask := uint64(len(d.direct) - 1)
+
+	base := len(dst)
+	dst = append(dst, make([]byte, n)...)
+	out := dst[base:]
+
+	streams := len(rs)
+	full := n - n%streams
+	i := 0
+	if d.direct != nil {
+		switch streams {
+		case 1:
+			i = decodeInterleaved1(d.direct, out[:full], rs)
+		case 2:
+			i = decodeInterleaved2(d.direct, out[:full], rs)
+		case 4:
+			i = decodeInterleaved4(d.direct, out[:full], rs)
+		}
+	}
+	for ; i < full; i += streams {
+		for s := range rs {
+			b, err := rs[s].next(d, mask)
+			if err != nil {
+				return dst[:base+i+s], err
+			}
+			out[i+s] = b
+		}
+	}
+	for i := full; i < n; i++ {
+		b, err := rs[i-full].next(d, mask)
+		if err != nil {
+			return dst[:base+i], err
+		}
+		out[i] = b
+	}
+	return dst, nil
+}
+
+// directOK reports whether a direct table entry holds a legal code for a
+// byte Symbol.
+func directOK(de directEntry) bool {
+	return de.size() != 0 && de < (1<<16)
+}
+
+// decodeInterleaved1 is the fast path of decodeInterleaved for 1 stream
+// with a direct table.  It keeps the stream's reservoir in local variables
+// and decodes 4 rounds per refill, stopping as soon as the stream is within 8
+// bytes of its end or yields an unusual code.  It returns the number of bytes
+// written to out, and leaves rs ready for the general loop to resume.
+func decodeInterleaved1(direct []directEntry, out []byte, rs []interleavedStream) int {
+	mask := uint64(len(direct) - 1)
+	src0, acc0, n0 := rs[0].src, rs[0].acc, rs[0].n
+
+	i := 0
+outer:
+	for i+4 <= len(out) && len(src0) >= 8 {
+		acc0 |= binary.LittleEndian.Uint64(src0) << n0
+		k0 := (63 - n0) >> 3
+		src0 = src0[k0:]
+		n0 += 8 * k0
+
+		// 4 rounds of at most 11 bits each fit within the 56 bits
+		// guaranteed after a refill.
+		for round := 0; round < 4; round++ {
+			de0 := direct[acc0&mask]
+			if !directOK(de0) {
+				break outer
+			}
+			s0 := de0.size()
+			acc0 >>= s0
+			n0 -= uint(s0)
+			out[i] = byte(de0 >> 8)
+			i++
+		}
+	}
+
+	rs[0].src, rs[0].acc, rs[0].n = src0, acc0, n0
+	return i
+}
+
+// decodeInterleaved2 is decodeInterleaved1 for 2 streams.  Each round takes
+// one code from each stream; the lookups are independent, so the CPU can
+// overlap them.
+func decodeInterleaved2(direct []directEntry, out []byte, rs []interleavedStream) int {
+	mask := uint64(len(direct) - 1)
+	src0, acc0, n0 := rs[0].src, rs[0].acc, rs[0].n
+	src1, acc1, n1 := rs[1].src, rs[1].acc, rs[1].n
+
+	i := 0
+outer:
+	for i+8 <= len(out) && len(src0) >= 8 && len(src1) >= 8 {
+		acc0 |= binary.LittleEndian.Uint64(src0) << n0
+		k0 := (63 - n0) >> 3
+		src0 = src0[k0:]
+		n0 += 8 * k0
+		acc1 |= binary.LittleEndian.Uint64(src1) << n1
+		k1 := (63 - n1) >> 3
+		src1 = src1[k1:]
+		n1 += 8 * k1
+
+		for round := 0; round < 4; round++ {
+			de0 := direct[acc0&mask]
+			de1 := direct[acc1&mask]
+			if !directOK(de0) || !directOK(de1) {
+				break outer
+			}
+			s0, s1 := de0.size(), de1.size()
+			acc0 >>= s0
+			n0 -= uint(s0)
+			acc1 >>= s1
+			n1 -= uint(s1)
+			out[i] = byte(de0 >> 8)
+			out[i+1] = byte(de1 >> 8)
+			i += 2
+		}
+	}
+
+	rs[0].src, rs[0].acc, rs[0].n = src0, acc0, n0
+	rs[1].src, rs[1].acc, rs[1].n = src1, acc1, n1
+	return i
+}
+
+// decodeInterleaved4 is decodeInterleaved2 for 4 streams.
+func decodeInterleaved4(direct []directEntry, out []byte, rs []interleavedStream) int {
+	mask := uint64(len(direct) - 1)
+	src0, acc0, n0 := rs[0].src, rs[0].acc, rs[0].n
+	src1, acc1, n1 := rs[1].src, rs[1].acc, rs[1].n
+	src2, acc2, n2 := rs[2].src, rs[2].acc, rs[2].n
+	src3, acc3, n3 := rs[3].src, rs[3].acc, rs[3].n
+
+	i := 0
+outer:
+	for i+16 <= len(out) && len(src0) >= 8 && len(src1) >= 8 && len(src2) >= 8 && len(src3) >= 8 {
+		acc0 |= binary.LittleEndian.Uint64(src0) << n0
+		k0 := (63 - n0) >> 3
+		src0 = src0[k0:]
+		n0 += 8 * k0
+		acc1 |= binary.LittleEndian.Uint64(src1) << n1
+		k1 := (63 - n1) >> 3
+		src1 = src1[k1:]
+		n1 += 8 * k1
+		acc2 |= binary.LittleEndian.Uint64(src2) << n2
+		k2 := (63 - n2) >> 3
+		src2 = src2[k2:]
+		n2 += 8 * k2
+		acc3 |= binary.LittleEndian.Uint64(src3) << n3
+		k3 := (63 - n3) >> 3
+		src3 = src3[k3:]
+		n3 += 8 * k3
+
+		for round := 0; round < 4; round++ {
+			de0 := direct[acc0&mask]
+			de1 := direct[acc1&mask]
+			de2 := direct[acc2&mask]
+			de3 := direct[acc3&mask]
+			if !directOK(de0) || !directOK(de1) || !directOK(de2) || !directOK(de3) {
+				break outer
+			}
+			s0, s1, s2, s3 := de0.size(), de1.size(), de2.size(), de3.size()
+			acc0 >>= s0
+			n0 -= uint(s0)
+			acc1 >>= s1
+			n1 -= uint(s1)
+			acc2 >>= s2
+			n2 -= uint(s2)
+			acc3 >>= s3
+			n3 -= uint(s3)
+			out[i] = byte(de0 >> 8)
+			out[i+1] = byte(de1 >> 8)
+			out[i+2] = byte(de2 >> 8)
+			out[i+3] = byte(de3 >> 8)
+			i += 4
+		}
+	}
+
+	rs[0].src, rs[0].acc, rs[0].n = src0, acc0, n0
+	rs[1].src, rs[1].acc, rs[1].n = src1, acc1, n1
+	rs[2].src, rs[2].acc, rs[2].n = src2, acc2, n2
+	rs[3].src, rs[3].acc, rs[3].n = src3, acc3, n3
+	return i
+}
+
+// decodeBits decodes the first code in acc, least significant bit first,
+// using the Decoder's prefix map.  It returns the Symbol and the code's size.
+func decodeBits(d *Decoder, acc uint64) (Symbol, byte, error) {
+	var hc Code
+	for hc.Size < maxBitsPerCode {
+		hc.Bits |= uint32((acc>>hc.Size)&1) << hc.Size
+		hc.Size++
+		symbol, minSize, _ := d.Decode(hc)
+		if symbol >= 0 {
+			return symbol, hc.Size, nil
+		}
+		if minSize == 0 {
+			break
+		}
+	}
+	return InvalidSymbol, 0, fmt.Errorf("invalid Huffman code %s", hc)
+}
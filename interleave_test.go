@@ -0,0 +1,133 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestInterleaved_RoundTrip(t *testing.T) {
+	text := bytes.Repeat([]byte("Peter Piper picked a peck of pickled peppers. "), 20)
+	type testRow struct {
+		name string
+		e    *Encoder
+		data []byte
+	}
+	testData := [...]testRow{
+		{"text", makeByteEncoder(text, true), text},
+		{"text-short", makeByteEncoder(text, true), text[:7]},
+		{"empty", makeByteEncoder(text, true), nil},
+		{"skewed", makeByteEncoder(skewedBytes(), true), skewedBytes()},
+		{"all", makeByteEncoder(allBytes(1), true), allBytes(2)},
+	}
+	for _, row := range testData {
+		for _, streams := range []int{1, 2, 3, 4, 8} {
+			t.Run(fmt.Sprintf("%s/%d", row.name, streams), func(t *testing.T) {
+				encoded, err := EncodeInterleaved(streams, row.e, []byte("prefix"), row.data)
+				if err != nil {
+					t.Fatalf("EncodeInterleaved: unexpected error: %v", err)
+				}
+				if !bytes.HasPrefix(encoded, []byte("prefix")) {
+					t.Fatalf("EncodeInterleaved: dst was not preserved")
+				}
+
+				d := row.e.Decoder()
+				for _, kind := range []DecoderKind{DirectTableDecoder, PrefixMapDecoder} {
+					dd := *d
+					if kind == PrefixMapDecoder {
+						dd.direct = nil
+					}
+					decoded, err := DecodeInterleaved(streams, &dd, []byte("x"), encoded[6:], len(row.data))
+					if err != nil {
+						t.Fatalf("%v: DecodeInterleaved: unexpected error: %v", kind, err)
+					}
+					if !bytes.Equal(decoded[1:], row.data) || decoded[0] != 'x' {
+						t.Errorf("%v: wrong output:\n\texpect: %q\n\tactual: %q", kind, row.data, decoded[1:])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestInterleaved_Truncated(t *testing.T) {
+	text := bytes.Repeat([]byte("Peter Piper picked a peck of pickled peppers. "), 20)
+	e := makeByteEncoder(text, true)
+	encoded, err := EncodeInterleaved(4, e, nil, text)
+	if err != nil {
+		t.Fatalf("EncodeInterleaved: unexpected error: %v", err)
+	}
+
+	if _, err := DecodeInterleaved(4, e.Decoder(), nil, encoded[:len(encoded)-10], len(text)); err == nil {
+		t.Errorf("expected error for truncated input")
+	}
+	if _, err := DecodeInterleaved(4, e.Decoder(), nil, encoded, len(text)+40); err == nil {
+		t.Errorf("expected error for overlong count")
+	}
+}
+
+func TestStream_Interleaved(t *testing.T) {
+	input := append(bytes.Repeat([]byte("Peter Piper picked a peck of pickled peppers. "), 50), allBytes(1)...)
+	for _, streams := range []int{2, 4} {
+		t.Run(fmt.Sprintf("streams=%d", streams), func(t *testing.T) {
+			stream := makeIndexedStream(t, input, WriterOptions{
+				BlockSize:     500,
+				Checksum:      ChecksumXXH64,
+				LengthTrailer: true,
+				Index:         true,
+				Streams:       streams,
+			})
+			if !bytes.Contains(stream[:8], []byte{blockTypeStreams, byte(streams)}) {
+				t.Errorf("expected an interleaved block, got %x", stream[:8])
+			}
+
+			zr, err := NewReader(bytes.NewReader(stream))
+			if err != nil {
+				t.Fatalf("NewReader: unexpected error: %v", err)
+			}
+			output, err := io.ReadAll(iotest.OneByteReader(zr))
+			if err != nil {
+				t.Fatalf("ReadAll: unexpected error: %v", err)
+			}
+			if !bytes.Equal(input, output) {
+				t.Errorf("wrong output:\n\texpect: %q\n\tactual: %q", input, output)
+			}
+
+			ir, err := NewIndexedReader(bytes.NewReader(stream), int64(len(stream)))
+			if err != nil {
+				t.Fatalf("NewIndexedReader: unexpected error: %v", err)
+			}
+			block, err := ir.ReadBlock(2)
+			if err != nil {
+				t.Fatalf("ReadBlock: unexpected error: %v", err)
+			}
+			if expect := input[1000:1500]; !bytes.Equal(expect, block) {
+				t.Errorf("ReadBlock: wrong output:\n\texpect: %q\n\tactual: %q", expect, block)
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeInterleaved(b *testing.B) {
+	text := bytes.Repeat([]byte("Peter Piper picked a peck of pickled peppers. "), 2000)
+	e := makeByteEncoder(text, true)
+	d := e.Decoder()
+	var dst []byte
+	for _, streams := range []int{1, 2, 4} {
+		encoded, err := EncodeInterleaved(streams, e, nil, text)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("streams=%d", streams), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				dst, err = DecodeInterleaved(streams, d, dst[:0], encoded, len(text))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The format produced by EncodeInts is:
+//
+//     ints := mode:byte count:uvarint sizes:RLE payload
+//
+// The values are delta coded, zigzag coded, and written as uvarints; the
+// resulting bytes (mode 0) or nibbles (mode 1, low nibble first) are then
+// Huffman coded using the code described by sizes.  EncodeInts tries both
+// modes and keeps whichever is smaller.
+//
+
+const (
+	intsModeBytes   = 0
+	intsModeNibbles = 1
+)
+
+// EncodeInts appends a compact encoding of values to dst and returns the
+// extended slice.  It works best on slowly-changing sequences, such as
+// timestamps or file offsets.
+func EncodeInts(dst []byte, values []int64) []byte {
+	var raw []byte
+	var tmp [binary.MaxVarintLen64]byte
+	var prev int64
+	for _, value := range values {
+		delta := uint64(value) - uint64(prev)
+		prev = value
+		zigzag := (delta << 1) ^ uint64(int64(delta)>>63)
+		n := binary.PutUvarint(tmp[:], zigzag)
+		raw = append(raw, tmp[:n]...)
+	}
+
+	nibbles := make([]byte, 0, 2*len(raw))
+	for _, b := range raw {
+		nibbles = append(nibbles, b&0x0f, b>>4)
+	}
+
+	asBytes := encodeIntsPayload(intsModeBytes, len(values), raw, 256)
+	asNibbles := encodeIntsPayload(intsModeNibbles, len(values), nibbles, 16)
+	if len(asNibbles) < len(asBytes) {
+		return append(dst, asNibbles...)
+	}
+	return append(dst, asBytes...)
+}
+
+func encodeIntsPayload(mode byte, count int, symbols []byte, numSymbols int) []byte {
+	frequencies := make([]uint32, numSymbols)
+	for _, symbol := range symbols {
+		frequencies[symbol]++
+	}
+	var e Encoder
+	initLimited(&e, numSymbols, frequencies)
+
+	var tmp [binary.MaxVarintLen64]byte
+	out := []byte{mode}
+	n := binary.PutUvarint(tmp[:], uint64(count))
+	out = append(out, tmp[:n]...)
+	out = appendSizesRLE(out, trimSizes(e.SizeBySymbol()))
+
+	buf := bytes.NewBuffer(out)
+	bw := NewBitWriter(buf)
+	for _, symbol := range symbols {
+		_ = bw.WriteCode(e.Encode(Symbol(symbol)))
+	}
+	_ = bw.Flush()
+	return buf.Bytes()
+}
+
+// DecodeInts decodes data produced by EncodeInts.  At most maxValues values
+// will be accepted.
+func DecodeInts(src []byte, maxValues int) ([]int64, error) {
+	br := NewBitReader(bytes.NewReader(src))
+	mode, err := br.ReadByte()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	numSymbols := uint(256)
+	switch mode {
+	case intsModeBytes:
+		// pass
+	case intsModeNibbles:
+		numSymbols = 16
+	default:
+		return nil, fmt.Errorf("invalid integer sequence mode %d", mode)
+	}
+
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if count > uint64(maxValues) {
+		return nil, fmt.Errorf("too many values: got %d, max %d", count, maxValues)
+	}
+
+	sizes, err := readSizesRLE(br, numSymbols)
+	if err != nil {
+		return nil, err
+	}
+	var d Decoder
+	if err := d.Init(sizes); err != nil {
+		return nil, err
+	}
+
+	r := &symbolByteReader{br: br, d: &d, nibbles: mode == intsModeNibbles}
+	out := make([]int64, count)
+	var prev int64
+	for index := range out {
+		zigzag, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		delta := int64(zigzag>>1) ^ -int64(zigzag&1)
+		prev += delta
+		out[index] = prev
+	}
+	return out, nil
+}
+
+// symbolByteReader reassembles bytes from a stream of Huffman-coded byte or
+// nibble Symbols.
+type symbolByteReader struct {
+	br      *BitReader
+	d       *Decoder
+	nibbles bool
+}
+
+func (r *symbolByteReader) ReadByte() (byte, error) {
+	lo, err := r.br.ReadSymbol(r.d)
+	if err != nil {
+		return 0, err
+	}
+	if !r.nibbles {
+		return byte(lo), nil
+	}
+	hi, err := r.br.ReadSymbol(r.d)
+	if err != nil {
+		return 0, noEOF(err)
+	}
+	return byte(lo) | byte(hi)<<4, nil
+}
+
+var _ io.ByteReader = (*symbolByteReader)(nil)
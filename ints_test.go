@@ -0,0 +1,47 @@
+package huffman
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInts_RoundTrip(t *testing.T) {
+	type testRow struct {
+		name   string
+		values []int64
+	}
+
+	timestamps := make([]int64, 1000)
+	for index := range timestamps {
+		timestamps[index] = 1700000000000 + int64(index)*1000 + int64(index%7)
+	}
+
+	testData := [...]testRow{
+		{name: "empty", values: nil},
+		{name: "one", values: []int64{42}},
+		{name: "extremes", values: []int64{math.MinInt64, math.MaxInt64, 0, -1, math.MinInt64}},
+		{name: "timestamps", values: timestamps},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			raw := EncodeInts(nil, row.values)
+			output, err := DecodeInts(raw, len(row.values))
+			if err != nil {
+				t.Fatalf("DecodeInts: unexpected error: %v", err)
+			}
+			if len(output) != len(row.values) {
+				t.Fatalf("wrong length: expected %d, got %d", len(row.values), len(output))
+			}
+			for index := range output {
+				if output[index] != row.values[index] {
+					t.Fatalf("wrong value at index %d: expected %d, got %d", index, row.values[index], output[index])
+				}
+			}
+		})
+	}
+
+	// Regular timestamps should compress to well under a byte each.
+	if raw := EncodeInts(nil, timestamps); len(raw) >= len(timestamps) {
+		t.Errorf("expected fewer than %d bytes, got %d", len(timestamps), len(raw))
+	}
+}
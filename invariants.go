@@ -0,0 +1,116 @@
+package huffman
+
+import (
+	"fmt"
+)
+
+// The checks below verify the internal consistency of Encoders and Decoders.
+// Builds with the "huffmandebug" tag run them after every Init, and every
+// debugCheckInterval bits while streaming, panicking on any failure; this
+// helps track down bugs such as memory corruption or unsynchronized sharing
+// in code which uses this package.  Other builds skip them entirely.
+
+// debugCheckInterval is how often, in bits written or read, streaming
+// operations repeat the checks in huffmandebug builds.  It is a power of 2.
+const debugCheckInterval = 1 << 20
+
+// debugCheck panics if err, the result of the named check, is non-nil.
+func debugCheck(what string, err error) {
+	if err != nil {
+		panic(fmt.Errorf("huffmandebug: %s: internal invariant violated: %w", what, err))
+	}
+}
+
+// debugStreamCheck runs check if a streaming operation which started at bit
+// start and is now at bit *now has crossed a multiple of debugCheckInterval.
+func debugStreamCheck(what string, start uint64, now *uint64, check func() error) {
+	if start/debugCheckInterval != *now/debugCheckInterval {
+		debugCheck(what, check())
+	}
+}
+
+// checkInvariants verifies that this Encoder's codes are the canonical codes
+// for their lengths, which implies that they form a prefix code, and that
+// MinSize and MaxSize are correct.  If complete is true, it also verifies
+// that the Kraft sum of the code is exactly 1, as for any code built from
+// frequencies of 2 or more Symbols.
+func (e Encoder) checkInvariants(complete bool) error {
+	var minSize, maxSize byte
+	var count int
+	for _, hc := range e.codes {
+		if hc.Size == 0 {
+			continue
+		}
+		if count == 0 || hc.Size < minSize {
+			minSize = hc.Size
+		}
+		if hc.Size > maxSize {
+			maxSize = hc.Size
+		}
+		count++
+	}
+	// Builder gives a code with no Symbols MinSize and MaxSize of 1.
+	if count != 0 && (minSize != e.minSize || maxSize != e.maxSize) {
+		return fmt.Errorf("sizes range over [%d..%d], but MinSize and MaxSize are %d and %d", minSize, maxSize, e.minSize, e.maxSize)
+	}
+
+	// Codes longer than maxBitsPerCode are never numbered, so only their
+	// lengths can be checked.
+	sizes := e.SizeBySymbol()
+	if maxSize <= maxBitsPerCode {
+		codes, err := AssignCanonicalBits(sizes)
+		if err != nil {
+			return err
+		}
+		for symbol, hc := range e.codes {
+			if hc != codes[symbol] {
+				return fmt.Errorf("symbol %s: code %v is not the canonical code %v", e.opts.formatSymbol(Symbol(symbol)), hc, codes[symbol])
+			}
+		}
+	}
+
+	if complete && count >= 2 && maxSize < 64 {
+		var sum uint64
+		for _, size := range sizes {
+			if size != 0 {
+				sum += uint64(1) << (maxSize - size)
+			}
+		}
+		if sum != uint64(1)<<maxSize {
+			return fmt.Errorf("Kraft sum is %d/%d, not 1", sum, uint64(1)<<maxSize)
+		}
+	}
+	return nil
+}
+
+// checkInvariants verifies that every lookup table of this Decoder decodes
+// the canonical code for its lengths.
+func (d Decoder) checkInvariants() error {
+	codes, err := AssignCanonicalBits(d.sizes)
+	if err != nil {
+		return err
+	}
+	countBySize, _ := codeLengthHistogram(codes)
+	countBySize[0] = 0
+	if countBySize != d.countBySize {
+		return fmt.Errorf("code length histogram is %v, not %v", d.countBySize, countBySize)
+	}
+	for symbol, hc := range codes {
+		if hc.Size == 0 {
+			continue
+		}
+		if actual, _, _ := d.Decode(hc); actual != Symbol(symbol) {
+			return fmt.Errorf("code %v decodes to symbol %d, not %s", hc, actual, d.opts.formatSymbol(Symbol(symbol)))
+		}
+		if d.direct != nil {
+			step := uint32(1) << hc.Size
+			for index := hc.Bits; index < uint32(len(d.direct)); index += step {
+				de := d.direct[index]
+				if de.symbol() != Symbol(symbol) || de.size() != hc.Size {
+					return fmt.Errorf("direct table entry %d is (%d, %d), not (%s, %d)", index, de.symbol(), de.size(), d.opts.formatSymbol(Symbol(symbol)), hc.Size)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+//go:build huffmandebug
+
+package huffman
+
+// debugChecks enables the invariant checks.  See invariants.go.
+const debugChecks = true
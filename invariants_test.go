@@ -0,0 +1,72 @@
+package huffman
+
+import (
+	"testing"
+)
+
+func TestEncoder_checkInvariants(t *testing.T) {
+	e := NewEncoder(4, []uint32{9, 5, 3, 3})
+	if err := e.checkInvariants(true); err != nil {
+		t.Errorf("built Encoder: unexpected error: %v", err)
+	}
+	if err := NewEncoderFromSizes([]byte{2, 2, 2, 0}).checkInvariants(false); err != nil {
+		t.Errorf("incomplete Encoder: unexpected error: %v", err)
+	}
+	if err := NewEncoderFromSizes([]byte{2, 2, 2, 0}).checkInvariants(true); err == nil {
+		t.Errorf("incomplete Encoder: expected a Kraft sum error")
+	}
+
+	bad := e.Clone()
+	bad.codes[2], bad.codes[3] = bad.codes[3], bad.codes[2]
+	if err := bad.checkInvariants(false); err == nil {
+		t.Errorf("swapped codes: expected an error")
+	}
+
+	bad = e.Clone()
+	bad.maxSize++
+	if err := bad.checkInvariants(false); err == nil {
+		t.Errorf("wrong MaxSize: expected an error")
+	}
+}
+
+func TestDecoder_checkInvariants(t *testing.T) {
+	sizes := []byte{1, 2, 3, 3}
+	for _, strategy := range []TableStrategy{MapTable, SortedTable} {
+		d := NewDecoderWithOptions(sizes, Options{TableStrategy: strategy})
+		if err := d.checkInvariants(); err != nil {
+			t.Errorf("%v: unexpected error: %v", strategy, err)
+		}
+	}
+
+	d := NewDecoder(sizes)
+	d.direct[3] = d.direct[1]
+	if err := d.checkInvariants(); err == nil {
+		t.Errorf("corrupt direct table: expected an error")
+	}
+
+	d = NewDecoder(sizes)
+	d.sizes = []byte{1, 2, 3, 3, 3}
+	if err := d.checkInvariants(); err == nil {
+		t.Errorf("corrupt sizes: expected an error")
+	}
+}
+
+func TestDebugStreamCheck(t *testing.T) {
+	calls := 0
+	check := func() error {
+		calls++
+		return nil
+	}
+	for _, span := range [][2]uint64{
+		{0, 7},
+		{debugCheckInterval - 3, debugCheckInterval + 2},
+		{debugCheckInterval + 2, debugCheckInterval + 9},
+		{3*debugCheckInterval - 1, 3 * debugCheckInterval},
+	} {
+		now := span[1]
+		debugStreamCheck("test", span[0], &now, check)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 checks, got %d", calls)
+	}
+}
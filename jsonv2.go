@@ -0,0 +1,99 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonVersion is the current version of the JSON object form.
+const jsonVersion = 1
+
+// jsonObject is the JSON object form of a code, as written by
+// MarshalJSONV2.  Sizes omits the trailing Symbols without codes, which are
+// implied by Alphabet.  Order records the bit order of Code, so that readers
+// in other languages need not guess it; it does not affect the sizes.
+//
+// There is deliberately no field for the bits of each code.  Codes are
+// always assigned canonically from the sizes, so the bits carry no further
+// information, and a second copy of them would only be a way for a file to
+// contradict itself.
+type jsonObject struct {
+	Version  int    `json:"v"`
+	Sizes    []uint `json:"sizes"`
+	Order    string `json:"order,omitempty"`
+	Alphabet int    `json:"alphabet,omitempty"`
+}
+
+// marshalJSONV2 renders sizes in the JSON object form.
+func marshalJSONV2(sizes []byte) ([]byte, error) {
+	return json.Marshal(jsonObject{
+		Version:  jsonVersion,
+		Sizes:    sizesToUints(trimSizes(sizes)),
+		Order:    "lsb",
+		Alphabet: len(sizes),
+	})
+}
+
+// unmarshalJSONSizes parses either JSON form: a bare array of bit lengths, or
+// the object form written by MarshalJSONV2.  The alphabet may not exceed
+// maxDictSymbols, nor the limit set by opts.
+func unmarshalJSONSizes(raw []byte, opts Options) ([]byte, error) {
+	var arr []uint
+	alphabet := -1
+	if trimmed := bytes.TrimLeft(raw, " \t\r\n"); len(trimmed) != 0 && trimmed[0] == '{' {
+		var obj jsonObject
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, err
+		}
+		if obj.Version != jsonVersion {
+			return nil, fmt.Errorf("unsupported JSON version %d", obj.Version)
+		}
+		switch obj.Order {
+		case "", "lsb", "msb":
+		default:
+			return nil, fmt.Errorf("invalid bit order %q", obj.Order)
+		}
+		if obj.Alphabet != 0 && obj.Alphabet < len(obj.Sizes) {
+			return nil, fmt.Errorf("alphabet of %d symbols is smaller than %d sizes", obj.Alphabet, len(obj.Sizes))
+		}
+		arr = obj.Sizes
+		if obj.Alphabet != 0 {
+			alphabet = obj.Alphabet
+		}
+	} else if err := json.Unmarshal(raw, &arr); err != nil {
+		return nil, err
+	}
+
+	if alphabet < 0 {
+		alphabet = len(arr)
+	}
+	if alphabet > maxDictSymbols {
+		return nil, fmt.Errorf("too many symbols: got %d, max %d", alphabet, maxDictSymbols)
+	}
+	if err := opts.CheckAlphabet(alphabet); err != nil {
+		return nil, err
+	}
+	sizes := make([]byte, alphabet)
+	for index, size := range arr {
+		if size > maxBitsPerCode {
+			return nil, fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
+		}
+		sizes[index] = byte(size)
+	}
+	return sizes, nil
+}
+
+// MarshalJSONV2 renders this Encoder as a versioned JSON object, such as
+// {"v":1,"sizes":[4,4,3,3,3,1],"order":"lsb","alphabet":6}, which has room
+// for metadata that the bare array written by MarshalJSON does not.
+// UnmarshalJSON accepts both forms.
+func (e Encoder) MarshalJSONV2() ([]byte, error) {
+	return marshalJSONV2(e.SizeBySymbol())
+}
+
+// MarshalJSONV2 renders this Decoder as a versioned JSON object.  See
+// Encoder.MarshalJSONV2.
+func (d Decoder) MarshalJSONV2() ([]byte, error) {
+	return marshalJSONV2(d.sizes)
+}
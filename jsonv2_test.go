@@ -0,0 +1,95 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestMarshalJSONV2(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{2, 2, 1, 0, 0})
+	expect := `{"v":1,"sizes":[2,2,1],"order":"lsb","alphabet":5}`
+	raw, err := e.MarshalJSONV2()
+	if err != nil {
+		t.Fatalf("Encoder.MarshalJSONV2: unexpected error: %v", err)
+	}
+	if actual := string(raw); expect != actual {
+		t.Errorf("Encoder.MarshalJSONV2: expected %s, got %s", expect, actual)
+	}
+	raw, err = e.Decoder().MarshalJSONV2()
+	if err != nil {
+		t.Fatalf("Decoder.MarshalJSONV2: unexpected error: %v", err)
+	}
+	if actual := string(raw); expect != actual {
+		t.Errorf("Decoder.MarshalJSONV2: expected %s, got %s", expect, actual)
+	}
+}
+
+func TestUnmarshalJSON_V2(t *testing.T) {
+	type testRow struct {
+		name   string
+		input  string
+		expect []byte
+	}
+
+	testData := [...]testRow{
+		{"array", `[2,2,1,0,0]`, []byte{2, 2, 1, 0, 0}},
+		{"object", `{"v":1,"sizes":[2,2,1],"order":"lsb","alphabet":5}`, []byte{2, 2, 1, 0, 0}},
+		{"object-minimal", ` {"v":1,"sizes":[1,1]}`, []byte{1, 1}},
+		{"object-msb", `{"v":1,"sizes":[1,1],"order":"msb"}`, []byte{1, 1}},
+		{"bad-version", `{"v":2,"sizes":[1,1]}`, nil},
+		{"bad-order", `{"v":1,"sizes":[1,1],"order":"middle"}`, nil},
+		{"bad-alphabet", `{"v":1,"sizes":[1,1],"alphabet":1}`, nil},
+		{"bad-size", `{"v":1,"sizes":[17]}`, nil},
+		{"huge-alphabet", `{"v":1,"sizes":[1,1],"alphabet":1e12}`, nil},
+		{"large-alphabet", `{"v":1,"sizes":[1,1],"alphabet":100000000}`, nil},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var e Encoder
+			var d Decoder
+			errE := json.Unmarshal([]byte(row.input), &e)
+			errD := json.Unmarshal([]byte(row.input), &d)
+			if row.expect == nil {
+				if errE == nil || errD == nil {
+					t.Errorf("expected errors, got %v and %v", errE, errD)
+				}
+				return
+			}
+			if errE != nil || errD != nil {
+				t.Fatalf("unexpected errors: %v and %v", errE, errD)
+			}
+			if actual := e.SizeBySymbol(); !bytes.Equal(row.expect, actual) {
+				t.Errorf("Encoder: expected %v, got %v", row.expect, actual)
+			}
+			if actual := d.SizeBySymbol(); !bytes.Equal(row.expect, actual) {
+				t.Errorf("Decoder: expected %v, got %v", row.expect, actual)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSON_MaxAlphabet(t *testing.T) {
+	var e Encoder
+	if err := e.InitFromSizesWithOptions([]byte{1, 1}, Options{MaxAlphabet: 4}); err != nil {
+		t.Fatalf("InitFromSizesWithOptions: unexpected error: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"v":1,"sizes":[1,1],"alphabet":5}`), &e); !errors.Is(err, ErrAlphabetTooLarge) {
+		t.Errorf("Encoder: expected ErrAlphabetTooLarge, got %v", err)
+	}
+	if err := json.Unmarshal([]byte(`[1,0,0,1]`), &e); err != nil {
+		t.Errorf("Encoder: unexpected error within the limit: %v", err)
+	}
+	if max := e.Options().MaxAlphabet; max != 4 {
+		t.Errorf("Encoder: expected MaxAlphabet 4 to be kept, got %d", max)
+	}
+
+	var d Decoder
+	if err := d.InitWithOptions([]byte{1, 1}, Options{MaxAlphabet: 4}); err != nil {
+		t.Fatalf("InitWithOptions: unexpected error: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`[1,0,0,0,1]`), &d); !errors.Is(err, ErrAlphabetTooLarge) {
+		t.Errorf("Decoder: expected ErrAlphabetTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+// Package lzhuff is a small LZ77 compressor built on the huffman package, in
+// the style of DEFLATE.
+//
+// Input is parsed into literals and back-references by a hash-chain matcher,
+// and the resulting tokens are coded with two per-block Huffman codes: one
+// for literals, block ends and match lengths, and one for match distances.
+// The length and distance alphabets, including their extra bits, are those of
+// RFC 1951, but the container is simpler and is not DEFLATE-compatible.
+//
+// The compressed format is a single bit stream, least significant bit first:
+//
+//     stream := block+
+//     block  := final:1 nlit:5 ndist:5 litSizes:4*(nlit+257) distSizes:4*(ndist+1) codes
+//
+// The codes are literal/length Symbols, each followed by its extra bits and,
+// for lengths, a distance Symbol and its extra bits, terminated by the
+// EndOfBlock Symbol.  The stream ends after the block with final set, padded
+// with zero bits to a byte boundary.
+//
+package lzhuff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+const (
+	// NumLitLenSymbols is the size of the literal/length alphabet.
+	NumLitLenSymbols = huffman.DeflateMaxLitLenSymbols
+
+	// NumDistSymbols is the size of the distance alphabet.
+	NumDistSymbols = huffman.DeflateMaxDistSymbols
+
+	// WindowSize is the maximum distance of a back-reference.
+	WindowSize = huffman.DeflateWindowSize
+
+	// MinMatch is the length of the shortest back-reference.
+	MinMatch = huffman.DeflateMinMatch
+
+	// MaxMatch is the length of the longest back-reference.
+	MaxMatch = huffman.DeflateMaxMatch
+
+	// maxTokensPerBlock is the number of tokens after which a new block,
+	// with fresh codes, is started.
+	maxTokensPerBlock = 1 << 14
+
+	// maxCodeSize is the longest code that fits the 4-bit size tables.
+	maxCodeSize = 15
+)
+
+// ErrCorrupt is returned when decompressing invalid data.
+var ErrCorrupt = errors.New("corrupt lzhuff stream")
+
+// Token is one step of an LZ77 parse: either a literal byte, or a
+// back-reference copying Length bytes from Distance bytes earlier.
+type Token struct {
+	// Length is the length of the back-reference, or 0 for a literal.
+	Length uint16
+
+	// Distance is the distance of the back-reference.
+	Distance uint16
+
+	// Literal is the literal byte, if Length is 0.
+	Literal byte
+}
+
+// IsLiteral returns true iff this Token is a literal byte.
+func (tok Token) IsLiteral() bool {
+	return tok.Length == 0
+}
+
+// Compress appends the compressed form of src to dst and returns the
+// extended slice.
+func Compress(dst []byte, src []byte) []byte {
+	tokens := Tokenize(src)
+
+	buf := bytes.NewBuffer(dst)
+	bw := huffman.NewBitWriter(buf)
+	for {
+		n := len(tokens)
+		if n > maxTokensPerBlock {
+			n = maxTokensPerBlock
+		}
+		final := n == len(tokens)
+		writeBlock(bw, tokens[:n], final)
+		tokens = tokens[n:]
+		if final {
+			break
+		}
+	}
+	_ = bw.Flush()
+	return buf.Bytes()
+}
+
+// writeBlock writes one block holding the given tokens.
+func writeBlock(bw *huffman.BitWriter, tokens []Token, final bool) {
+	litFreqs := make([]uint32, NumLitLenSymbols)
+	distFreqs := make([]uint32, NumDistSymbols)
+	litFreqs[huffman.EndOfBlock] = 1
+	for _, tok := range tokens {
+		if tok.IsLiteral() {
+			litFreqs[tok.Literal]++
+			continue
+		}
+		lengthSymbol, _, _ := huffman.DeflateLengthSymbol(tok.Length)
+		distSymbol, _, _ := huffman.DeflateDistSymbol(tok.Distance)
+		litFreqs[lengthSymbol]++
+		distFreqs[distSymbol]++
+	}
+
+	// A block with no back-references still needs a well-formed distance
+	// table.
+	if isZero(distFreqs) {
+		distFreqs[0] = 1
+	}
+
+	lit := buildEncoder(litFreqs, huffman.Options{ExtraBits: huffman.DeflateLengthExtraBits()})
+	dist := buildEncoder(distFreqs, huffman.Options{ExtraBits: huffman.DeflateDistExtraBits()})
+	litSizes := trimSizes(lit.SizeBySymbol(), 257)
+	distSizes := trimSizes(dist.SizeBySymbol(), 1)
+
+	var finalBit uint32
+	if final {
+		finalBit = 1
+	}
+	_ = bw.WriteBits(1, finalBit)
+	_ = bw.WriteBits(5, uint32(len(litSizes)-257))
+	_ = bw.WriteBits(5, uint32(len(distSizes)-1))
+	for _, size := range litSizes {
+		_ = bw.WriteBits(4, uint32(size))
+	}
+	for _, size := range distSizes {
+		_ = bw.WriteBits(4, uint32(size))
+	}
+
+	for _, tok := range tokens {
+		if tok.IsLiteral() {
+			_ = bw.WriteSymbol(lit, huffman.Symbol(tok.Literal))
+			continue
+		}
+		lengthSymbol, _, lengthExtra := huffman.DeflateLengthSymbol(tok.Length)
+		distSymbol, _, distExtra := huffman.DeflateDistSymbol(tok.Distance)
+		_ = bw.WriteSymbolWithExtra(lit, lengthSymbol, lengthExtra)
+		_ = bw.WriteSymbolWithExtra(dist, distSymbol, distExtra)
+	}
+	_ = bw.WriteSymbol(lit, huffman.EndOfBlock)
+}
+
+// buildEncoder returns an Encoder for the given frequencies whose codes are
+// at most maxCodeSize bits long.  Frequencies are halved, keeping non-zero
+// ones non-zero, until the code fits.
+func buildEncoder(frequencies []uint32, opts huffman.Options) *huffman.Encoder {
+	e := new(huffman.Encoder)
+	for {
+		if err := e.InitWithOptions(len(frequencies), frequencies, opts); err != nil {
+			panic(err)
+		}
+		if e.MaxSize() <= maxCodeSize {
+			return e
+		}
+		scaled := make([]uint32, len(frequencies))
+		for index, freq := range frequencies {
+			if freq != 0 {
+				scaled[index] = (freq >> 1) | 1
+			}
+		}
+		frequencies = scaled
+	}
+}
+
+// trimSizes drops trailing zero-length entries, keeping at least min.
+func trimSizes(sizes []byte, min int) []byte {
+	end := len(sizes)
+	for end > min && sizes[end-1] == 0 {
+		end--
+	}
+	return sizes[:end]
+}
+
+func isZero(frequencies []uint32) bool {
+	for _, freq := range frequencies {
+		if freq != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Decompress decompresses data produced by Compress, appends it to dst, and
+// returns the extended slice.  At most maxSize bytes will be accepted.
+func Decompress(dst []byte, src []byte, maxSize int) ([]byte, error) {
+	br := huffman.NewBitReader(bytes.NewReader(src))
+	start := len(dst)
+	var lit, dist huffman.Decoder
+	for {
+		final, err := br.ReadBits(1)
+		if err != nil {
+			return dst, corrupt(err)
+		}
+		if err := readTables(br, &lit, &dist); err != nil {
+			return dst, err
+		}
+
+		for {
+			symbol, lengthExtra, err := br.ReadSymbolWithExtra(&lit)
+			if err != nil {
+				return dst, corrupt(err)
+			}
+			if symbol == huffman.EndOfBlock {
+				break
+			}
+			if symbol < huffman.EndOfBlock {
+				if len(dst)-start >= maxSize {
+					return dst, fmt.Errorf("decompressed size exceeds %d bytes", maxSize)
+				}
+				dst = append(dst, byte(symbol))
+				continue
+			}
+
+			distSymbol, distExtra, err := br.ReadSymbolWithExtra(&dist)
+			if err != nil {
+				return dst, corrupt(err)
+			}
+			lengthBase, _, ok := huffman.DeflateLengthBase(symbol)
+			if !ok {
+				return dst, fmt.Errorf("%w: invalid length symbol %d", ErrCorrupt, symbol)
+			}
+			distBase, _, ok := huffman.DeflateDistBase(distSymbol)
+			if !ok {
+				return dst, fmt.Errorf("%w: invalid distance symbol %d", ErrCorrupt, distSymbol)
+			}
+			length := int(lengthBase) + int(lengthExtra)
+			distance := int(distBase) + int(distExtra)
+			if distance > len(dst)-start {
+				return dst, fmt.Errorf("%w: distance %d exceeds output length %d", ErrCorrupt, distance, len(dst)-start)
+			}
+			if len(dst)-start+length > maxSize {
+				return dst, fmt.Errorf("decompressed size exceeds %d bytes", maxSize)
+			}
+
+			// The source and destination may overlap, so copy one byte
+			// at a time.
+			from := len(dst) - distance
+			for i := 0; i < length; i++ {
+				dst = append(dst, dst[from+i])
+			}
+		}
+
+		if final != 0 {
+			return dst, nil
+		}
+	}
+}
+
+// readTables reads the size tables at the start of a block and initializes
+// the literal/length and distance Decoders from them.
+func readTables(br *huffman.BitReader, lit *huffman.Decoder, dist *huffman.Decoder) error {
+	nlit, err := br.ReadBits(5)
+	if err != nil {
+		return corrupt(err)
+	}
+	ndist, err := br.ReadBits(5)
+	if err != nil {
+		return corrupt(err)
+	}
+	if nlit+257 > NumLitLenSymbols || ndist+1 > NumDistSymbols {
+		return fmt.Errorf("%w: table sizes %d and %d out of range", ErrCorrupt, nlit+257, ndist+1)
+	}
+
+	sizes := make([]byte, nlit+257+ndist+1)
+	for index := range sizes {
+		size, err := br.ReadBits(4)
+		if err != nil {
+			return corrupt(err)
+		}
+		sizes[index] = byte(size)
+	}
+
+	if err := lit.InitWithOptions(sizes[:nlit+257], huffman.Options{ExtraBits: huffman.DeflateLengthExtraBits()}); err != nil {
+		return fmt.Errorf("%w: literal/length table: %v", ErrCorrupt, err)
+	}
+	if err := dist.InitWithOptions(sizes[nlit+257:], huffman.Options{ExtraBits: huffman.DeflateDistExtraBits()}); err != nil {
+		return fmt.Errorf("%w: distance table: %v", ErrCorrupt, err)
+	}
+	return nil
+}
+
+// corrupt wraps an error from the bit stream as ErrCorrupt.
+func corrupt(err error) error {
+	return fmt.Errorf("%w: %v", ErrCorrupt, err)
+}
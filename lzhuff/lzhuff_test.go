@@ -0,0 +1,127 @@
+package lzhuff
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/chronos-tachyon/huffman"
+)
+
+func testInputs() map[string][]byte {
+	rng := rand.New(rand.NewSource(138))
+	random := make([]byte, 5000)
+	rng.Read(random)
+
+	var text strings.Builder
+	words := []string{"the", "quick", "brown", "fox", "jumps", "over", "lazy", "dog", "and", "then"}
+	for text.Len() < 300000 {
+		text.WriteString(words[rng.Intn(len(words))])
+		text.WriteByte(' ')
+	}
+
+	return map[string][]byte{
+		"empty":  {},
+		"one":    {'x'},
+		"short":  []byte("abcabcabcabc"),
+		"run":    bytes.Repeat([]byte{'a'}, 1000),
+		"random": random,
+		"text":   []byte(text.String()),
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, input := range testInputs() {
+		t.Run(name, func(t *testing.T) {
+			compressed := Compress([]byte{0xff}, input)
+			if compressed[0] != 0xff {
+				t.Fatalf("Compress did not preserve the existing contents of dst")
+			}
+			output, err := Decompress(nil, compressed[1:], len(input))
+			if err != nil {
+				t.Fatalf("Decompress: unexpected error: %v", err)
+			}
+			if !bytes.Equal(input, output) {
+				t.Fatalf("wrong output: expected %d bytes, got %d bytes", len(input), len(output))
+			}
+			if len(input) > 1000 && name != "random" && len(compressed) > len(input)/2 {
+				t.Errorf("poor compression: %d bytes -> %d bytes", len(input), len(compressed))
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	for name, input := range testInputs() {
+		t.Run(name, func(t *testing.T) {
+			var output []byte
+			for _, tok := range Tokenize(input) {
+				if tok.IsLiteral() {
+					output = append(output, tok.Literal)
+					continue
+				}
+				if tok.Length < MinMatch || tok.Length > MaxMatch || tok.Distance < 1 || tok.Distance > WindowSize {
+					t.Fatalf("invalid token %+v", tok)
+				}
+				from := len(output) - int(tok.Distance)
+				for i := 0; i < int(tok.Length); i++ {
+					output = append(output, output[from+i])
+				}
+			}
+			if !bytes.Equal(input, output) {
+				t.Fatalf("tokens do not reproduce the input")
+			}
+		})
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	for length := uint16(MinMatch); length <= MaxMatch; length++ {
+		symbol, _, extra := huffman.DeflateLengthSymbol(length)
+		base, numExtra, ok := huffman.DeflateLengthBase(symbol)
+		if !ok || extra >= 1<<numExtra || base+uint16(extra) != length {
+			t.Errorf("length %d: bad symbol %d extra %d", length, symbol, extra)
+		}
+	}
+	for distance := uint16(1); distance <= WindowSize; distance++ {
+		symbol, _, extra := huffman.DeflateDistSymbol(distance)
+		base, numExtra, ok := huffman.DeflateDistBase(symbol)
+		if !ok || extra >= 1<<numExtra || base+uint16(extra) != distance {
+			t.Errorf("distance %d: bad symbol %d extra %d", distance, symbol, extra)
+		}
+	}
+}
+
+func TestDecompress_Errors(t *testing.T) {
+	input := testInputs()["text"]
+	compressed := Compress(nil, input)
+
+	if _, err := Decompress(nil, compressed, len(input)-1); err == nil {
+		t.Errorf("expected error for oversized output, got nil")
+	}
+	if _, err := Decompress(nil, compressed[:len(compressed)/2], len(input)); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt for truncated input, got %v", err)
+	}
+	if _, err := Decompress(nil, nil, len(input)); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt for empty input, got %v", err)
+	}
+}
+
+func BenchmarkCompress(b *testing.B) {
+	input := testInputs()["text"]
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		Compress(nil, input)
+	}
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	input := testInputs()["text"]
+	compressed := Compress(nil, input)
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		_, _ = Decompress(nil, compressed, len(input))
+	}
+}
@@ -0,0 +1,84 @@
+package lzhuff
+
+const (
+	hashBits = 15
+	hashSize = 1 << hashBits
+
+	// maxChain is the number of earlier positions with the same hash that
+	// are examined when looking for the longest match.
+	maxChain = 64
+)
+
+// Tokenize parses src into literals and back-references, using a hash chain
+// of the positions of each 3-byte sequence to find the longest match at each
+// position.  Matches are taken greedily.
+func Tokenize(src []byte) []Token {
+	head := make([]int32, hashSize)
+	for index := range head {
+		head[index] = -1
+	}
+	prev := make([]int32, len(src))
+
+	insert := func(pos int) {
+		if pos+MinMatch > len(src) {
+			return
+		}
+		h := hash3(src[pos:])
+		prev[pos] = head[h]
+		head[h] = int32(pos)
+	}
+
+	tokens := make([]Token, 0, len(src)/2)
+	pos := 0
+	for pos < len(src) {
+		bestLen, bestDist := 0, 0
+		if pos+MinMatch <= len(src) {
+			maxLen := len(src) - pos
+			if maxLen > MaxMatch {
+				maxLen = MaxMatch
+			}
+			cand := head[hash3(src[pos:])]
+			for chain := 0; cand >= 0 && chain < maxChain; chain++ {
+				dist := pos - int(cand)
+				if dist > WindowSize {
+					break
+				}
+				n := matchLength(src[cand:], src[pos:], maxLen)
+				if n > bestLen {
+					bestLen, bestDist = n, dist
+					if n == maxLen {
+						break
+					}
+				}
+				cand = prev[cand]
+			}
+		}
+
+		if bestLen < MinMatch {
+			tokens = append(tokens, Token{Literal: src[pos]})
+			insert(pos)
+			pos++
+			continue
+		}
+		tokens = append(tokens, Token{Length: uint16(bestLen), Distance: uint16(bestDist)})
+		for end := pos + bestLen; pos < end; pos++ {
+			insert(pos)
+		}
+	}
+	return tokens
+}
+
+// hash3 hashes the first 3 bytes of p.
+func hash3(p []byte) uint32 {
+	x := uint32(p[0]) | uint32(p[1])<<8 | uint32(p[2])<<16
+	return (x * 0x9e3779b1) >> (32 - hashBits)
+}
+
+// matchLength returns the length of the common prefix of a and b, up to max.
+func matchLength(a []byte, b []byte, max int) int {
+	n := 0
+	for n < max && a[n] == b[n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,155 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sync"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+// The mapped dictionary format is a variant of the dictionary file format
+// which can be used in place, e.g. from a memory-mapped file:
+//
+//     mapped := "HUFM" version:byte flags:byte reserved:uint16le
+//               count:uint32le crc:uint32le sizes:byte*count
+//
+// The sizes table is stored raw, one byte per symbol, at offset 16, so that
+// NewDecoderFromMmap can use it without decoding or copying it.  The crc is
+// the CRC-32C of the first 12 bytes followed by the sizes.  The flags and
+// reserved fields must be zero.
+//
+
+const (
+	mappedVersion    = 1
+	mappedHeaderSize = 16
+
+	// No flags are defined yet.
+	mappedFlagsKnown = 0x00
+)
+
+var mappedMagic = [4]byte{'H', 'U', 'F', 'M'}
+
+// MarshalMapped renders this Dict in the mapped dictionary format, for use
+// with NewDecoderFromMmap.  The format has no room for a Remap.
+func (dict Dict) MarshalMapped() ([]byte, error) {
+	if err := dict.Validate(); err != nil {
+		return nil, err
+	}
+	if dict.Remap != nil {
+		return nil, fmt.Errorf("%w: the mapped format does not support Remap", ErrDict)
+	}
+	if err := checkMappedSizes(dict.Sizes); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, mappedHeaderSize, mappedHeaderSize+len(dict.Sizes))
+	copy(out[0:4], mappedMagic[:])
+	out[4] = mappedVersion
+	binary.LittleEndian.PutUint32(out[8:12], uint32(len(dict.Sizes)))
+	out = append(out, dict.Sizes...)
+	binary.LittleEndian.PutUint32(out[12:16], mappedChecksum(out))
+	return out, nil
+}
+
+// mappedChecksum returns the CRC-32C of data, skipping its crc field.
+func mappedChecksum(data []byte) uint32 {
+	crc := crc32.Checksum(data[:12], crc32cTable)
+	return crc32.Update(crc, crc32cTable, data[mappedHeaderSize:])
+}
+
+// checkMappedSizes checks that sizes describes a valid code, so that building
+// its Decoder later cannot fail.
+func checkMappedSizes(sizes []byte) error {
+	var used int64
+	for _, size := range sizes {
+		if size > maxBitsPerCode {
+			return fmt.Errorf("%w: invalid bit length: got %d, max %d", ErrDict, size, maxBitsPerCode)
+		}
+		used += kraftUnits(size)
+	}
+	if used > int64(1)<<maxBitsPerCode {
+		return fmt.Errorf("%w: code is oversubscribed", ErrDict)
+	}
+	return nil
+}
+
+// MappedDecoder is a Decoder for a dictionary in the mapped format, which
+// refers to the dictionary's bytes in place rather than copying them.  Its
+// lookup tables are not built until first needed, so that a process which
+// maps many dictionaries pays only for the ones it uses.
+//
+// A MappedDecoder is safe for concurrent use.  Like a DecoderView, it
+// borrows its size table.  The bytes it was created from
+// must not be modified or unmapped while it or its Decoder is in use.
+//
+type MappedDecoder struct {
+	sizes []byte
+	once  sync.Once
+	v     DecoderView
+}
+
+// NewDecoderFromMmap returns a MappedDecoder for the mapped dictionary held
+// in data, as written by Dict.MarshalMapped.  The header, checksum, and code
+// are validated immediately, but no memory proportional to the size of the
+// code is allocated until MappedDecoder.Decoder is called.
+func NewDecoderFromMmap(data []byte) (*MappedDecoder, error) {
+	if len(data) < mappedHeaderSize {
+		return nil, fmt.Errorf("%w: too short", ErrDict)
+	}
+	if !bytes.Equal(data[0:4], mappedMagic[:]) {
+		return nil, fmt.Errorf("%w: bad magic", ErrDict)
+	}
+	if data[4] != mappedVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrDict, data[4])
+	}
+	if unknown := data[5] &^ mappedFlagsKnown; unknown != 0 {
+		return nil, fmt.Errorf("%w: unknown flags 0x%02x", ErrDict, unknown)
+	}
+	if reserved := binary.LittleEndian.Uint16(data[6:8]); reserved != 0 {
+		return nil, fmt.Errorf("%w: reserved field is 0x%04x, expected 0", ErrDict, reserved)
+	}
+	count := binary.LittleEndian.Uint32(data[8:12])
+	if count > maxDictSymbols {
+		return nil, fmt.Errorf("%w: too many symbols: got %d, max %d", ErrDict, count, maxDictSymbols)
+	}
+	if uint64(len(data)) != mappedHeaderSize+uint64(count) {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrDict, mappedHeaderSize+uint64(count), len(data))
+	}
+	if binary.LittleEndian.Uint32(data[12:16]) != mappedChecksum(data) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrDict)
+	}
+
+	sizes := data[mappedHeaderSize:len(data):len(data)]
+	if err := checkMappedSizes(sizes); err != nil {
+		return nil, err
+	}
+	return &MappedDecoder{sizes: sizes}, nil
+}
+
+// NumSymbols returns the number of Symbols in the code.
+func (md *MappedDecoder) NumSymbols() int {
+	return len(md.sizes)
+}
+
+// Size returns the bit length of the given Symbol's code, or 0 if it has
+// none.  It does not build the lookup tables.
+func (md *MappedDecoder) Size(symbol Symbol) byte {
+	if symbol < 0 || int(symbol) >= len(md.sizes) {
+		return 0
+	}
+	return md.sizes[symbol]
+}
+
+// Decoder returns the Decoder for the code, building its lookup tables on the
+// first call.  It is a DecoderView which borrows the mapped data as its size
+// table.
+func (md *MappedDecoder) Decoder() *DecoderView {
+	md.once.Do(func() {
+		err := md.v.Init(md.sizes)
+		assert.Assertf(err == nil, "mapped code failed to build after validation: %v", err)
+	})
+	return &md.v
+}
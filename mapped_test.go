@@ -0,0 +1,84 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewDecoderFromMmap(t *testing.T) {
+	var e Encoder
+	e.Init(6, []uint32{40, 20, 20, 10, 0, 10})
+	dict := NewDict(&e)
+
+	data, err := dict.MarshalMapped()
+	if err != nil {
+		t.Fatalf("MarshalMapped: unexpected error: %v", err)
+	}
+	if expect := mappedHeaderSize + 6; len(data) != expect {
+		t.Errorf("MarshalMapped: expected %d bytes, got %d", expect, len(data))
+	}
+
+	md, err := NewDecoderFromMmap(data)
+	if err != nil {
+		t.Fatalf("NewDecoderFromMmap: unexpected error: %v", err)
+	}
+	if md.NumSymbols() != 6 || md.Size(0) != e.Encode(0).Size || md.Size(4) != 0 || md.Size(99) != 0 {
+		t.Errorf("NumSymbols/Size: wrong results")
+	}
+
+	d := md.Decoder()
+	if d != md.Decoder() {
+		t.Errorf("Decoder: expected the same Decoder from every call")
+	}
+	if !reflect.DeepEqual(d.SizeBySymbol(), e.SizeBySymbol()) {
+		t.Errorf("Decoder: wrong sizes: expected %v, got %v", e.SizeBySymbol(), d.SizeBySymbol())
+	}
+	for symbol := Symbol(0); symbol < 6; symbol++ {
+		hc := e.Encode(symbol)
+		if hc.Size == 0 {
+			continue
+		}
+		if actual, _, _ := d.Decode(hc); actual != symbol {
+			t.Errorf("Decode(%v): expected %d, got %d", hc, symbol, actual)
+		}
+	}
+
+	// The Decoder uses the mapped bytes in place.
+	if &d.SizeBySymbol()[0] != &data[mappedHeaderSize] {
+		t.Errorf("Decoder: expected the sizes to alias the mapped data")
+	}
+
+	type testRow struct {
+		name   string
+		mangle func([]byte) []byte
+	}
+
+	testData := [...]testRow{
+		{name: "short", mangle: func(b []byte) []byte { return b[:10] }},
+		{name: "truncated", mangle: func(b []byte) []byte { return b[:len(b)-1] }},
+		{name: "magic", mangle: func(b []byte) []byte { b[0] = 'X'; return b }},
+		{name: "version", mangle: func(b []byte) []byte { b[4] = 9; return b }},
+		{name: "flags", mangle: func(b []byte) []byte { b[5] = 1; return b }},
+		{name: "reserved", mangle: func(b []byte) []byte { b[7] = 1; return b }},
+		{name: "checksum", mangle: func(b []byte) []byte { b[mappedHeaderSize+1]++; return b }},
+		{name: "oversubscribed", mangle: func(b []byte) []byte {
+			b[mappedHeaderSize+4] = 1
+			binary.LittleEndian.PutUint32(b[12:16], mappedChecksum(b))
+			return b
+		}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			bad := row.mangle(append([]byte(nil), data...))
+			if _, err := NewDecoderFromMmap(bad); !errors.Is(err, ErrDict) {
+				t.Errorf("NewDecoderFromMmap: expected ErrDict, got %v", err)
+			}
+		})
+	}
+
+	if _, err := (Dict{Sizes: []byte{1, 1}, Remap: []Symbol{1, 0}}).MarshalMapped(); !errors.Is(err, ErrDict) {
+		t.Errorf("MarshalMapped with Remap: expected ErrDict, got %v", err)
+	}
+}
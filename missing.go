@@ -0,0 +1,115 @@
+package huffman
+
+import (
+	"fmt"
+	mathbits "math/bits"
+)
+
+// MissingCodePolicy selects what happens when a Symbol with no code, i.e. a
+// Symbol which had a frequency of 0, is encoded.  It is set via
+// Options.MissingCode, and applies to Encoder.Encode, Encoder.EncodeToUint64,
+// Encoder.EncodeAppendBits, Encoder.EncodeBytes, and BitWriter.WriteSymbol.
+type MissingCodePolicy byte
+
+const (
+	// MissingCodeError makes BitWriter.WriteSymbol return ErrNoCode,
+	// while Encoder.Encode returns a Code of size 0.  This is the default.
+	MissingCodeError MissingCodePolicy = iota
+
+	// MissingCodePanic makes both Encoder.Encode and BitWriter.WriteSymbol
+	// panic with an error which matches ErrNoCode under errors.Is.
+	MissingCodePanic
+
+	// MissingCodeEscape writes Options.FallbackSymbol, which must have a
+	// code, followed by the Symbol itself as a raw integer just wide enough
+	// to hold any Symbol in the alphabet.  A Decoder with the same Options
+	// reverses this in BitReader.ReadSymbol.  FallbackSymbol itself is
+	// always written this way, so it must not be used for anything else.
+	// Encoder.Encode returns the code of FallbackSymbol alone.
+	MissingCodeEscape
+
+	// MissingCodeSubstitute encodes Options.FallbackSymbol, which must
+	// have a code, in place of the Symbol.  The substitution is lossy.
+	MissingCodeSubstitute
+)
+
+var missingCodePolicyNames = [...]string{
+	"MissingCodeError",
+	"MissingCodePanic",
+	"MissingCodeEscape",
+	"MissingCodeSubstitute",
+}
+
+// String returns the name of this MissingCodePolicy.
+func (policy MissingCodePolicy) String() string {
+	if uint(policy) < uint(len(missingCodePolicyNames)) {
+		return missingCodePolicyNames[policy]
+	}
+	return fmt.Sprintf("MissingCodePolicy(%d)", uint(policy))
+}
+
+// usesFallback returns true if this policy relies on Options.FallbackSymbol.
+func (policy MissingCodePolicy) usesFallback() bool {
+	return policy == MissingCodeEscape || policy == MissingCodeSubstitute
+}
+
+// checkFallback returns an error if the policy needs a fallback Symbol and
+// sizes assigns it no code.
+func (opts *Options) checkFallback(sizes []byte) error {
+	if !opts.MissingCode.usesFallback() {
+		return nil
+	}
+	symbol := opts.FallbackSymbol
+	if uint(symbol) >= uint(len(sizes)) || sizes[symbol] == 0 {
+		return fmt.Errorf("%v: fallback symbol %s: %w", opts.MissingCode, opts.formatSymbol(symbol), ErrNoCode)
+	}
+	return nil
+}
+
+// rawSymbolBits returns the width of a raw Symbol written by
+// MissingCodeEscape for an alphabet of numSymbols Symbols.
+func rawSymbolBits(numSymbols int) byte {
+	return byte(mathbits.Len(uint(numSymbols - 1)))
+}
+
+// encodeMissing is Encode for a Symbol with no code, or for the escape.
+func (e *Encoder) encodeMissing(symbol Symbol) Code {
+	switch e.opts.MissingCode {
+	case MissingCodePanic:
+		panic(fmt.Errorf("symbol %s: %w", e.opts.formatSymbol(symbol), ErrNoCode))
+	case MissingCodeEscape, MissingCodeSubstitute:
+		return e.codes[e.opts.FallbackSymbol]
+	default:
+		return Code{}
+	}
+}
+
+// escapes reports whether symbol is written as an escape per
+// MissingCodeEscape.
+func (e *Encoder) escapes(symbol Symbol) bool {
+	if e.opts.MissingCode != MissingCodeEscape {
+		return false
+	}
+	return symbol == e.opts.FallbackSymbol || e.codes[symbol].Size == 0
+}
+
+// writeEscaped writes symbol per MissingCodeEscape.
+func (bw *BitWriter) writeEscaped(e *Encoder, symbol Symbol) error {
+	if err := bw.WriteCode(e.codes[e.opts.FallbackSymbol]); err != nil {
+		return err
+	}
+	return bw.WriteBits(rawSymbolBits(len(e.codes)), uint32(symbol))
+}
+
+// readEscaped reads the raw Symbol which follows an escape written per
+// MissingCodeEscape.
+func (br *BitReader) readEscaped(d *Decoder) (Symbol, error) {
+	raw, err := br.ReadBits(rawSymbolBits(len(d.sizes)))
+	if err != nil {
+		return InvalidSymbol, noEOF(err)
+	}
+	if raw >= uint32(len(d.sizes)) {
+		return InvalidSymbol, fmt.Errorf("escaped symbol %d out of range", raw)
+	}
+	return Symbol(raw), nil
+}
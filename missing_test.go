@@ -0,0 +1,152 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMissingCodePolicy(t *testing.T) {
+	frequencies := []uint32{5, 0, 3, 0, 1}
+	symbols := []Symbol{0, 1, 2, 3, 0}
+
+	newEncoder := func(opts Options) *Encoder {
+		e := new(Encoder)
+		if err := e.InitWithOptions(len(frequencies), frequencies, opts); err != nil {
+			t.Fatalf("InitWithOptions: unexpected error: %v", err)
+		}
+		return e
+	}
+
+	t.Run("error", func(t *testing.T) {
+		e := newEncoder(Options{})
+		if hc := e.Encode(1); hc.Size != 0 {
+			t.Errorf("Encode: expected a zero Code, got %v", hc)
+		}
+		bw := NewBitWriter(&bytes.Buffer{})
+		if err := bw.WriteSymbol(e, 1); !errors.Is(err, ErrNoCode) {
+			t.Errorf("WriteSymbol: expected ErrNoCode, got %v", err)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		e := newEncoder(Options{MissingCode: MissingCodePanic})
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, ErrNoCode) {
+				t.Errorf("expected a panic with ErrNoCode, got %v", err)
+			}
+		}()
+		e.Encode(3)
+	})
+
+	t.Run("escape", func(t *testing.T) {
+		opts := Options{MissingCode: MissingCodeEscape, FallbackSymbol: 4}
+		e := newEncoder(opts)
+		var buf bytes.Buffer
+		bw := NewBitWriter(&buf)
+		for _, symbol := range append(symbols, 4) {
+			if err := bw.WriteSymbol(e, symbol); err != nil {
+				t.Fatalf("WriteSymbol(%d): unexpected error: %v", symbol, err)
+			}
+		}
+		_ = bw.Flush()
+
+		var d Decoder
+		if err := d.InitWithOptions(e.SizeBySymbol(), opts); err != nil {
+			t.Fatalf("Decoder.InitWithOptions: unexpected error: %v", err)
+		}
+		br := NewBitReader(&buf)
+		for _, expect := range append(symbols, 4) {
+			if actual, err := br.ReadSymbol(&d); err != nil || actual != expect {
+				t.Fatalf("ReadSymbol: expected (%d, nil), got (%d, %v)", expect, actual, err)
+			}
+		}
+	})
+
+	t.Run("substitute", func(t *testing.T) {
+		e := newEncoder(Options{MissingCode: MissingCodeSubstitute, FallbackSymbol: 2})
+		if expect, actual := e.Encode(2), e.Encode(3); expect != actual {
+			t.Errorf("Encode: expected %v, got %v", expect, actual)
+		}
+	})
+
+	t.Run("no-fallback-code", func(t *testing.T) {
+		var e Encoder
+		err := e.InitWithOptions(len(frequencies), frequencies, Options{MissingCode: MissingCodeEscape, FallbackSymbol: 1})
+		if !errors.Is(err, ErrNoCode) {
+			t.Errorf("Encoder: expected ErrNoCode, got %v", err)
+		}
+		var d Decoder
+		err = d.InitWithOptions([]byte{1, 1}, Options{MissingCode: MissingCodeSubstitute, FallbackSymbol: 5})
+		if !errors.Is(err, ErrNoCode) {
+			t.Errorf("Decoder: expected ErrNoCode, got %v", err)
+		}
+	})
+}
+
+func TestMissingCodePolicy_FastPaths(t *testing.T) {
+	frequencies := make([]uint32, 256)
+	for symbol := range frequencies {
+		if symbol%3 != 1 {
+			frequencies[symbol] = uint32(1 + symbol%7)
+		}
+	}
+	data := append(allBytes(2), 3, 3, 1, 4, 3)
+
+	type result struct {
+		out      []byte
+		err      error
+		panicked bool
+	}
+	run := func(fn func(bw *BitWriter) error) (r result) {
+		var buf bytes.Buffer
+		bw := NewBitWriter(&buf)
+		defer func() {
+			if x := recover(); x != nil {
+				r.panicked = true
+			}
+		}()
+		r.err = fn(bw)
+		_ = bw.Flush()
+		r.out = buf.Bytes()
+		return r
+	}
+
+	policies := []MissingCodePolicy{MissingCodeError, MissingCodePanic, MissingCodeEscape, MissingCodeSubstitute}
+	for _, policy := range policies {
+		t.Run(policy.String(), func(t *testing.T) {
+			e := new(Encoder)
+			if err := e.InitWithOptions(len(frequencies), frequencies, Options{MissingCode: policy, FallbackSymbol: 3}); err != nil {
+				t.Fatalf("InitWithOptions: unexpected error: %v", err)
+			}
+
+			expect := run(func(bw *BitWriter) error {
+				for _, b := range data {
+					if err := bw.WriteSymbol(e, Symbol(b)); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			actual := run(func(bw *BitWriter) error {
+				return e.EncodeBytes(data, bw)
+			})
+			if expect.panicked != actual.panicked || (expect.err == nil) != (actual.err == nil) || !bytes.Equal(expect.out, actual.out) {
+				t.Errorf("EncodeBytes: expected (%x, %v, panic=%v), got (%x, %v, panic=%v)", expect.out, expect.err, expect.panicked, actual.out, actual.err, actual.panicked)
+			}
+			if expect.err != nil || expect.panicked {
+				return
+			}
+
+			var raw []byte
+			var bitPos uint
+			for _, b := range data {
+				raw, bitPos = e.EncodeAppendBits(raw, bitPos, Symbol(b))
+			}
+			if !bytes.Equal(expect.out, raw) {
+				t.Errorf("EncodeAppendBits: expected %x, got %x", expect.out, raw)
+			}
+		})
+	}
+}
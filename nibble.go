@@ -0,0 +1,196 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// NibbleAlphabetSize is the number of Symbols in the nibble alphabet.
+const NibbleAlphabetSize = 16
+
+// nibbleWindowBits is the number of bits examined by each lookup into the
+// multi-symbol decode table.
+const nibbleWindowBits = 8
+
+// NibbleCodec compresses bytes by Huffman coding each 4-bit half separately,
+// low nibble first, using a 16-symbol code.
+//
+// Because the alphabet is so small, NibbleCodec can afford a decode table
+// indexed by the next 8 bits of input, where each entry lists every complete
+// code within those 8 bits.  Most lookups therefore yield two or more nibbles
+// at once.  Codes longer than 8 bits fall back to a Decoder.
+//
+type NibbleCodec struct {
+	e     Encoder
+	d     Decoder
+	table [1 << nibbleWindowBits]nibbleEntry
+}
+
+// nibbleEntry describes the complete codes found in one 8-bit window.
+type nibbleEntry struct {
+	// packed holds the decoded nibbles, first nibble in the low bits.
+	packed uint32
+
+	// count is the number of nibbles in packed.
+	count byte
+
+	// used is the number of bits consumed by those nibbles.
+	used byte
+}
+
+// NewNibbleCodec returns a new NibbleCodec built from the frequency of each
+// nibble value.
+func NewNibbleCodec(frequencies []uint32) *NibbleCodec {
+	if len(frequencies) > NibbleAlphabetSize {
+		panic(fmt.Errorf("NewNibbleCodec: len(frequencies) %d > %d", len(frequencies), NibbleAlphabetSize))
+	}
+	nc := new(NibbleCodec)
+	initLimited(&nc.e, NibbleAlphabetSize, frequencies)
+	if err := nc.d.InitFromEncoder(nc.e); err != nil {
+		panic(err)
+	}
+	nc.buildTable()
+	return nc
+}
+
+// TrainNibbleCodec returns a new NibbleCodec built from the nibble
+// frequencies of the given samples.  Every nibble value is given a code,
+// even those which never appear in the samples.
+func TrainNibbleCodec(samples ...[]byte) *NibbleCodec {
+	frequencies := make([]uint32, NibbleAlphabetSize)
+	for index := range frequencies {
+		frequencies[index] = 1
+	}
+	for _, sample := range samples {
+		for _, b := range sample {
+			lo, hi := b&0x0f, b>>4
+			if frequencies[lo] < math.MaxUint32 {
+				frequencies[lo]++
+			}
+			if frequencies[hi] < math.MaxUint32 {
+				frequencies[hi]++
+			}
+		}
+	}
+	return NewNibbleCodec(frequencies)
+}
+
+func (nc *NibbleCodec) buildTable() {
+	for window := uint32(0); window < (1 << nibbleWindowBits); window++ {
+		var entry nibbleEntry
+		for {
+			hc := Code{}
+			remaining := window >> entry.used
+			symbol := InvalidSymbol
+			for hc.Size+entry.used < nibbleWindowBits {
+				hc.Bits |= ((remaining >> hc.Size) & 1) << hc.Size
+				hc.Size++
+				if s, _, _ := nc.d.Decode(hc); s >= 0 {
+					symbol = s
+					break
+				}
+			}
+			if symbol < 0 {
+				break
+			}
+			entry.packed |= uint32(symbol) << (4 * entry.count)
+			entry.count++
+			entry.used += hc.Size
+		}
+		nc.table[window] = entry
+	}
+}
+
+// Encoder returns the Encoder used by this NibbleCodec.
+func (nc *NibbleCodec) Encoder() *Encoder {
+	return &nc.e
+}
+
+// Encode appends the coded form of src to dst and returns the extended
+// slice.  The output is padded with zero bits to a byte boundary; the caller
+// is responsible for recording len(src).
+//
+// Every nibble in src must have a code.  This is always true of a NibbleCodec
+// from TrainNibbleCodec, but not of one built by NewNibbleCodec from
+// frequencies that include zeroes.
+func (nc *NibbleCodec) Encode(dst []byte, src []byte) []byte {
+	buf := bytes.NewBuffer(dst)
+	bw := NewBitWriter(buf)
+	for _, b := range src {
+		_ = bw.WriteCode(nc.e.Encode(Symbol(b & 0x0f)))
+		_ = bw.WriteCode(nc.e.Encode(Symbol(b >> 4)))
+	}
+	_ = bw.Flush()
+	return buf.Bytes()
+}
+
+// Decode decodes n bytes from src, appends them to dst, and returns the
+// extended slice.
+func (nc *NibbleCodec) Decode(dst []byte, src []byte, n int) ([]byte, error) {
+	var acc uint64
+	var accBits uint
+	var pending uint32
+	var havePending bool
+	availBits := 8 * uint64(len(src))
+	var usedBits uint64
+
+	refill := func() {
+		for accBits <= 56 {
+			var b byte
+			if len(src) != 0 {
+				b = src[0]
+				src = src[1:]
+			}
+			acc |= uint64(b) << accBits
+			accBits += 8
+		}
+	}
+
+	emit := func(nibble uint32) {
+		if havePending {
+			dst = append(dst, byte(pending|nibble<<4))
+			havePending = false
+			n--
+		} else {
+			pending = nibble
+			havePending = true
+		}
+	}
+
+	for n > 0 {
+		refill()
+		entry := nc.table[acc&((1<<nibbleWindowBits)-1)]
+		if entry.count == 0 {
+			symbol, size, err := decodeBits(&nc.d, acc)
+			if err != nil {
+				return dst, err
+			}
+			entry = nibbleEntry{packed: uint32(symbol), count: 1, used: size}
+		}
+
+		// Consume only as many nibbles as are still needed.
+		used := entry.used
+		needed := 2 * n
+		if havePending {
+			needed--
+		}
+		if int(entry.count) > needed {
+			used = 0
+			for i := 0; i < needed; i++ {
+				used += nc.e.Encode(Symbol((entry.packed >> (4 * i)) & 0x0f)).Size
+			}
+			entry.count = byte(needed)
+		}
+		for i := byte(0); i < entry.count; i++ {
+			emit((entry.packed >> (4 * i)) & 0x0f)
+		}
+		acc >>= used
+		accBits -= uint(used)
+		usedBits += uint64(used)
+		if usedBits > availBits {
+			return dst, fmt.Errorf("truncated nibble stream")
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,61 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNibbleCodec(t *testing.T) {
+	sensor := make([]byte, 2000)
+	for index := range sensor {
+		sensor[index] = byte(0x40 + (index/3)%5)
+	}
+
+	type testRow struct {
+		name  string
+		train []byte
+		input []byte
+	}
+
+	testData := [...]testRow{
+		{name: "empty", train: sensor, input: nil},
+		{name: "sensor", train: sensor, input: sensor},
+		{name: "all-bytes", train: allBytes(1), input: allBytes(3)},
+		{name: "skewed", train: skewedBytes(), input: allBytes(1)},
+		{name: "unseen", train: []byte{0x11, 0x12, 0x21}, input: []byte{0x11, 0x3f, 0x12}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			nc := TrainNibbleCodec(row.train)
+			raw := nc.Encode(nil, row.input)
+			output, err := nc.Decode(nil, raw, len(row.input))
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v", err)
+			}
+			if !bytes.Equal(row.input, output) {
+				t.Errorf("wrong output:\n\texpect: %x\n\tactual: %x", row.input, output)
+			}
+		})
+	}
+}
+
+func TestNibbleCodec_Table(t *testing.T) {
+	// With nibbles 0 and 1 as the most frequent, each gets a short code
+	// and a single lookup should yield several nibbles.
+	nc := NewNibbleCodec([]uint32{100, 100, 1, 1, 1, 1})
+	entry := nc.table[0]
+	if entry.count < 2 {
+		t.Errorf("expected multiple nibbles per lookup, got %d", entry.count)
+	}
+	if entry.used > nibbleWindowBits {
+		t.Errorf("entry uses %d bits, more than the %d-bit window", entry.used, nibbleWindowBits)
+	}
+}
+
+func TestNibbleCodec_Truncated(t *testing.T) {
+	nc := TrainNibbleCodec(allBytes(1))
+	raw := nc.Encode(nil, allBytes(1))
+	if _, err := nc.Decode(nil, raw[:len(raw)/2], 256); err == nil {
+		t.Errorf("expected error for truncated input, got nil")
+	}
+}
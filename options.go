@@ -0,0 +1,384 @@
+package huffman
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Options holds optional settings for an Encoder or Decoder.  The zero value
+// gives the default behavior of Init.
+type Options struct {
+	// SymbolName, if non-nil, returns a human-readable name for each
+	// Symbol, such as "EOB" or "LEN_258".  The name is shown alongside the
+	// Symbol's number in DebugString and in error messages.
+	SymbolName func(Symbol) string
+
+	// TieBreak chooses between subtrees of equal frequency while building
+	// an Encoder.  It is ignored by Decoder.
+	TieBreak TieBreak
+
+	// Trace, if non-nil, receives events and timings as an Encoder is
+	// built.  It is ignored by Decoder.
+	Trace Tracer
+
+	// ExtraBits, if non-nil, lists the number of raw bits (at most 32)
+	// which follow the code for each Symbol, as with the length and
+	// distance codes of DEFLATE.  Symbols beyond the end of the list
+	// carry no extra bits.  See BitWriter.WriteSymbolWithExtra and
+	// BitReader.ReadSymbolWithExtra.
+	ExtraBits []byte
+
+	// Profile selects the rules a code must follow.  A Decoder rejects
+	// sizes which break them, and an Encoder builds a code which obeys
+	// them, returning an error if it cannot.
+	Profile Profile
+
+	// MissingCode selects what happens when a Symbol with no code is
+	// encoded.  See MissingCodePolicy.
+	MissingCode MissingCodePolicy
+
+	// FallbackSymbol is the escape or substitute used by MissingCode.
+	FallbackSymbol Symbol
+
+	// FrequencyQuantization selects how an Encoder rounds its frequencies
+	// before building its code.  It is ignored by Decoder.
+	FrequencyQuantization FrequencyQuantization
+
+	// QuantizeKeep is the number of most frequent Symbols whose
+	// frequencies QuantizeTail leaves exact.  If 0, 16 are kept.
+	QuantizeKeep int
+
+	// MaxAlphabet, if non-zero, is the largest number of Symbols that
+	// InitWithOptions and the other functions taking Options accept.
+	// Larger alphabets are rejected with an *AlphabetError rather than
+	// by allocating tables for them, which makes it a cheap guard for
+	// code tables read from untrusted input.  Alphabets of more than
+	// MaxSymbol Symbols are always rejected.
+	MaxAlphabet int
+
+	// TableStrategy selects the data structure of a Decoder's lookup
+	// table.  See TableStrategy.  It is ignored by Encoder.
+	TableStrategy TableStrategy
+
+	// MaxFrequencyRatio, if non-zero, limits the skew of the frequencies
+	// an Encoder builds its code from: every non-zero frequency less than
+	// the largest divided by MaxFrequencyRatio is raised to that value,
+	// after any FrequencyQuantization.  This bounds the longest code at
+	// about 1.44*log2(N*MaxFrequencyRatio) bits for N Symbols, at a
+	// negligible cost in compression when the raised Symbols are rare,
+	// and is much cheaper than a length-limited construction such as
+	// BuildSizes.  It is ignored by Decoder.
+	MaxFrequencyRatio uint32
+}
+
+// ErrAlphabetTooLarge is matched by every *AlphabetError under errors.Is.
+var ErrAlphabetTooLarge = errors.New("Huffman alphabet too large")
+
+// AlphabetError reports an alphabet larger than Options.MaxAlphabet allows.
+type AlphabetError struct {
+	// Size is the number of Symbols in the rejected alphabet.
+	Size int
+
+	// Max is the largest number of Symbols allowed.
+	Max int
+}
+
+// Error fulfills the error interface.
+func (err *AlphabetError) Error() string {
+	return fmt.Sprintf("alphabet of %d symbols exceeds the limit of %d", err.Size, err.Max)
+}
+
+// Is returns true if target is ErrAlphabetTooLarge.
+func (err *AlphabetError) Is(target error) bool {
+	return target == ErrAlphabetTooLarge
+}
+
+// CheckAlphabet returns an *AlphabetError if an alphabet of numSymbols
+// Symbols is larger than these Options allow.  Builder.BuildE returns the
+// same error, while Builder.Build panics with it.
+func (opts Options) CheckAlphabet(numSymbols int) error {
+	max := int(MaxSymbol)
+	if opts.MaxAlphabet > 0 && opts.MaxAlphabet < max {
+		max = opts.MaxAlphabet
+	}
+	if numSymbols > max {
+		return &AlphabetError{Size: numSymbols, Max: max}
+	}
+	return nil
+}
+
+// validate returns an error if these Options are invalid.
+func (opts *Options) validate() error {
+	if uint(opts.TieBreak) >= uint(len(tieBreakNames)) {
+		return fmt.Errorf("invalid tie-break %v", opts.TieBreak)
+	}
+	if uint(opts.Profile) >= uint(len(profileNames)) {
+		return fmt.Errorf("invalid profile %v", opts.Profile)
+	}
+	if uint(opts.MissingCode) >= uint(len(missingCodePolicyNames)) {
+		return fmt.Errorf("invalid missing code policy %v", opts.MissingCode)
+	}
+	if uint(opts.FrequencyQuantization) >= uint(len(frequencyQuantizationNames)) {
+		return fmt.Errorf("invalid frequency quantization %v", opts.FrequencyQuantization)
+	}
+	if opts.QuantizeKeep < 0 {
+		return fmt.Errorf("QuantizeKeep %d < 0", opts.QuantizeKeep)
+	}
+	if opts.MaxAlphabet < 0 {
+		return fmt.Errorf("MaxAlphabet %d < 0", opts.MaxAlphabet)
+	}
+	if uint(opts.TableStrategy) >= uint(len(tableStrategyNames)) {
+		return fmt.Errorf("invalid table strategy %v", opts.TableStrategy)
+	}
+	for index, k := range opts.ExtraBits {
+		if k > 32 {
+			return fmt.Errorf("symbol %s: %d extra bits > 32", opts.formatSymbol(Symbol(index)), k)
+		}
+	}
+	return nil
+}
+
+// extraBits returns the number of extra bits which follow the code for the
+// given Symbol.
+func (opts *Options) extraBits(symbol Symbol) byte {
+	if uint(symbol) < uint(len(opts.ExtraBits)) {
+		return opts.ExtraBits[symbol]
+	}
+	return 0
+}
+
+// TieBreak selects how an Encoder chooses between subtrees of equal
+// frequency while building its code.  Every choice yields a code of the same
+// optimal total cost, but the shape of the tree, and therefore MaxSize(),
+// can differ.
+type TieBreak byte
+
+const (
+	// TieBreakShallow merges the oldest subtrees first, so that a subtree
+	// formed by merging always loses ties against a leaf or an older
+	// subtree.  As shown by Schwartz (1964), this gives the smallest
+	// MaxSize() of any optimal code, which keeps decoder tables small and
+	// bounds the worst-case cost of decoding one Symbol.  This is the
+	// default.
+	TieBreakShallow TieBreak = iota
+
+	// TieBreakDeep merges the newest subtrees first, giving the deepest
+	// optimal tree.  It is mainly useful for reproducing codes built by
+	// other implementations which break ties this way.
+	TieBreakDeep
+
+	// TieBreakMinVariance merges the shallowest subtrees first: of two
+	// subtrees with equal frequency, the one of smaller height wins, and
+	// subtrees of equal height are merged oldest first, as with
+	// TieBreakShallow.  This keeps the code lengths close together, which
+	// lowers Encoder.SizeVariance, for applications which want the
+	// decoding time of each Symbol to be as uniform as possible.
+	TieBreakMinVariance
+)
+
+var tieBreakNames = [...]string{
+	"TieBreakShallow",
+	"TieBreakDeep",
+	"TieBreakMinVariance",
+}
+
+// String returns the name of this TieBreak.
+func (tb TieBreak) String() string {
+	if uint(tb) < uint(len(tieBreakNames)) {
+		return tieBreakNames[tb]
+	}
+	return fmt.Sprintf("TieBreak(%d)", uint(tb))
+}
+
+// formatSymbol returns symbol as a string for use in diagnostics.
+func (opts *Options) formatSymbol(symbol Symbol) string {
+	str := strconv.FormatInt(int64(symbol), 10)
+	if opts.SymbolName != nil {
+		str = fmt.Sprintf("%s (%s)", str, opts.SymbolName(symbol))
+	}
+	return str
+}
+
+// InitWithOptions is like Init, but also applies the given Options.
+func (e *Encoder) InitWithOptions(numSymbols int, frequencies []uint32, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if err := opts.CheckAlphabet(numSymbols); err != nil {
+		return err
+	}
+	if opts.Profile != ProfileLenient {
+		if err := e.initProfile(numSymbols, frequencies, opts); err != nil {
+			return err
+		}
+	} else {
+		e.init(numSymbols, frequencies, opts)
+	}
+	return opts.checkFallback(e.SizeBySymbol())
+}
+
+// InitWithOptions is like Init, but also applies the given Options.
+func (d *Decoder) InitWithOptions(sizes []byte, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if err := opts.CheckAlphabet(len(sizes)); err != nil {
+		return err
+	}
+	if err := d.Init(sizes); err != nil {
+		return err
+	}
+	if err := opts.Profile.check(sizes); err != nil {
+		return err
+	}
+	if err := opts.checkFallback(sizes); err != nil {
+		return err
+	}
+	if opts.TableStrategy == SortedTable {
+		d.useSortedTable()
+	}
+	d.opts = opts
+	if debugChecks {
+		debugCheck("Decoder.InitWithOptions", d.checkInvariants())
+	}
+	return nil
+}
+
+// InitFromSizesWithOptions is like InitFromSizes, but also applies the given
+// Options.  Options which only affect how a code is built, such as TieBreak,
+// are recorded but have no effect.
+func (e *Encoder) InitFromSizesWithOptions(sizes []byte, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if err := opts.CheckAlphabet(len(sizes)); err != nil {
+		return err
+	}
+	if err := e.InitFromSizes(sizes); err != nil {
+		return err
+	}
+	if err := opts.Profile.check(sizes); err != nil {
+		return err
+	}
+	if err := opts.checkFallback(sizes); err != nil {
+		return err
+	}
+	e.opts = opts
+	return nil
+}
+
+// NewEncoderFromSizesWithOptions is a convenience function that allocates a
+// new Encoder and calls InitFromSizesWithOptions on it.  If that returns an
+// error, NewEncoderFromSizesWithOptions panics.
+func NewEncoderFromSizesWithOptions(sizes []byte, opts Options) *Encoder {
+	e := new(Encoder)
+	if err := e.InitFromSizesWithOptions(sizes, opts); err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// NewDecoderWithOptions is a convenience function that allocates a new
+// Decoder and calls InitWithOptions on it.  If InitWithOptions returns an
+// error, NewDecoderWithOptions panics.
+func NewDecoderWithOptions(sizes []byte, opts Options) *Decoder {
+	d := new(Decoder)
+	if err := d.InitWithOptions(sizes, opts); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// GoString returns a Go expression that would reconstruct these Options.
+// Only fields with non-zero values are listed.  SymbolName and Trace are
+// omitted, since functions and Tracers have no literal form.
+func (opts Options) GoString() string {
+	var buf strings.Builder
+	buf.WriteString("Options{")
+	opts.writeFieldsGo(&buf)
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// isZeroGo reports whether GoString would list no fields.
+func (opts Options) isZeroGo() bool {
+	var buf strings.Builder
+	opts.writeFieldsGo(&buf)
+	return buf.Len() == 0
+}
+
+func (opts Options) writeFieldsGo(buf *strings.Builder) {
+	first := true
+	field := func(name string) {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.WriteString(name)
+		buf.WriteByte(':')
+	}
+	if opts.TieBreak != TieBreakShallow {
+		field("TieBreak")
+		buf.WriteString(opts.TieBreak.String())
+	}
+	if opts.ExtraBits != nil {
+		field("ExtraBits")
+		writeBytesGo(buf, opts.ExtraBits)
+	}
+	if opts.Profile != ProfileLenient {
+		field("Profile")
+		buf.WriteString(opts.Profile.String())
+	}
+	if opts.MissingCode != MissingCodeError {
+		field("MissingCode")
+		buf.WriteString(opts.MissingCode.String())
+	}
+	if opts.FallbackSymbol != 0 {
+		field("FallbackSymbol")
+		buf.WriteString(strconv.FormatInt(int64(opts.FallbackSymbol), 10))
+	}
+	if opts.FrequencyQuantization != QuantizeNone {
+		field("FrequencyQuantization")
+		buf.WriteString(opts.FrequencyQuantization.String())
+	}
+	if opts.QuantizeKeep != 0 {
+		field("QuantizeKeep")
+		buf.WriteString(strconv.Itoa(opts.QuantizeKeep))
+	}
+	if opts.MaxAlphabet != 0 {
+		field("MaxAlphabet")
+		buf.WriteString(strconv.Itoa(opts.MaxAlphabet))
+	}
+	if opts.TableStrategy != MapTable {
+		field("TableStrategy")
+		buf.WriteString(opts.TableStrategy.String())
+	}
+	if opts.MaxFrequencyRatio != 0 {
+		field("MaxFrequencyRatio")
+		buf.WriteString(strconv.FormatUint(uint64(opts.MaxFrequencyRatio), 10))
+	}
+}
+
+// writeBytesGo writes a []byte literal holding list.
+func writeBytesGo(buf *strings.Builder, list []byte) {
+	buf.WriteString("[]byte{")
+	for index, b := range list {
+		if index != 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.FormatUint(uint64(b), 10))
+	}
+	buf.WriteString("}")
+}
+
+// Options returns the Options applied to this Encoder.
+func (e Encoder) Options() Options {
+	return e.opts
+}
+
+// Options returns the Options applied to this Decoder.
+func (d Decoder) Options() Options {
+	return d.opts
+}
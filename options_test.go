@@ -0,0 +1,355 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func deflateTestName(symbol Symbol) string {
+	if symbol == EndOfBlock {
+		return "EOB"
+	}
+	return "LIT_" + string(rune(symbol))
+}
+
+func TestOptions_SymbolName(t *testing.T) {
+	var e Encoder
+	opts := Options{SymbolName: deflateTestName}
+	if err := e.InitWithOptions(StreamAlphabetSize, WithEOB([]uint32{'a': 3, 'b': 1}, EndOfBlock), opts); err != nil {
+		t.Fatalf("InitWithOptions: unexpected error: %v", err)
+	}
+
+	debug := e.DebugStringWith(DebugOptions{AssignedOnly: true})
+	for _, want := range []string{"// EOB\n", "// LIT_a\n", "// LIT_b\n"} {
+		if !strings.Contains(debug, want) {
+			t.Errorf("DebugString: expected %q in output:\n%s", want, debug)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := NewBitWriter(&buf).WriteSymbol(&e, 'c')
+	if !errors.Is(err, ErrNoCode) {
+		t.Fatalf("WriteSymbol: expected ErrNoCode, got %v", err)
+	}
+	if expect, actual := "symbol 99 (LIT_c): symbol has no assigned Huffman code", err.Error(); expect != actual {
+		t.Errorf("WriteSymbol: expected %q, got %q", expect, actual)
+	}
+
+	var d Decoder
+	if err := d.InitWithOptions(e.SizeBySymbol(), opts); err != nil {
+		t.Fatalf("InitWithOptions: unexpected error: %v", err)
+	}
+	if debug := d.DebugString(); !strings.Contains(debug, "// EOB\n") {
+		t.Errorf("DebugString: expected EOB in output:\n%s", debug)
+	}
+	if d.Clone().Options().SymbolName == nil {
+		t.Errorf("Clone: lost Options")
+	}
+}
+
+func TestOptions_TieBreak(t *testing.T) {
+	type testRow struct {
+		name        string
+		frequencies []uint32
+		shallow     []byte
+		deep        []byte
+	}
+
+	testData := [...]testRow{
+		{
+			name:        "1-1-2-2",
+			frequencies: []uint32{1, 1, 2, 2},
+			shallow:     []byte{2, 2, 2, 2},
+			deep:        []byte{3, 3, 2, 1},
+		},
+		{
+			name:        "flat-6",
+			frequencies: []uint32{1, 1, 1, 1, 1, 1},
+			shallow:     []byte{3, 3, 3, 3, 2, 2},
+			deep:        []byte{2, 2, 3, 3, 3, 3},
+		},
+		{
+			name:        "no-ties",
+			frequencies: []uint32{1, 2, 4, 8},
+			shallow:     []byte{3, 3, 2, 1},
+			deep:        []byte{3, 3, 2, 1},
+		},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			for _, tb := range []TieBreak{TieBreakShallow, TieBreakDeep, TieBreakMinVariance} {
+				expect := row.shallow
+				if tb == TieBreakDeep {
+					expect = row.deep
+				}
+				var e Encoder
+				if err := e.InitWithOptions(len(row.frequencies), row.frequencies, Options{TieBreak: tb}); err != nil {
+					t.Fatalf("%v: InitWithOptions: unexpected error: %v", tb, err)
+				}
+				if actual := e.SizeBySymbol(); !bytes.Equal(expect, actual) {
+					t.Errorf("%v: expected sizes %v, got %v", tb, expect, actual)
+				}
+			}
+		})
+	}
+
+	var e Encoder
+	if err := e.InitWithOptions(2, []uint32{1, 1}, Options{TieBreak: 99}); err == nil {
+		t.Errorf("InitWithOptions: expected error for invalid TieBreak, got nil")
+	}
+}
+
+func TestOptions_TieBreakRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	for trial := 0; trial < 200; trial++ {
+		frequencies := make([]uint32, 2+rng.Intn(60))
+		for index := range frequencies {
+			frequencies[index] = uint32(rng.Intn(8))
+		}
+
+		var shallow, deep Encoder
+		_ = shallow.InitWithOptions(len(frequencies), frequencies, Options{TieBreak: TieBreakShallow})
+		_ = deep.InitWithOptions(len(frequencies), frequencies, Options{TieBreak: TieBreakDeep})
+		a, _ := codedBits(&shallow, frequencies)
+		b, _ := codedBits(&deep, frequencies)
+		if a != b {
+			t.Fatalf("trial %d: %v: cost %d differs from %v cost %d", trial, TieBreakShallow, a, TieBreakDeep, b)
+		}
+		if shallow.MaxSize() > deep.MaxSize() {
+			t.Fatalf("trial %d: %v: MaxSize %d > %v MaxSize %d", trial, TieBreakShallow, shallow.MaxSize(), TieBreakDeep, deep.MaxSize())
+		}
+	}
+}
+
+func TestOptions_TieBreakMinVariance(t *testing.T) {
+	frequencies := []uint32{1, 1, 2, 2}
+	var minVar, deep Encoder
+	_ = minVar.InitWithOptions(len(frequencies), frequencies, Options{TieBreak: TieBreakMinVariance})
+	_ = deep.InitWithOptions(len(frequencies), frequencies, Options{TieBreak: TieBreakDeep})
+	if expect, actual := 0.0, minVar.SizeVariance(frequencies); expect != actual {
+		t.Errorf("%v: expected variance %g, got %g", TieBreakMinVariance, expect, actual)
+	}
+	if expect, actual := 2.0/3.0, deep.SizeVariance(frequencies); math.Abs(expect-actual) > 1e-9 {
+		t.Errorf("%v: expected variance %g, got %g", TieBreakDeep, expect, actual)
+	}
+
+	rng := rand.New(rand.NewSource(133))
+	for trial := 0; trial < 200; trial++ {
+		frequencies := make([]uint32, 2+rng.Intn(60))
+		for index := range frequencies {
+			frequencies[index] = uint32(rng.Intn(8))
+		}
+		var shallow Encoder
+		_ = minVar.InitWithOptions(len(frequencies), frequencies, Options{TieBreak: TieBreakMinVariance})
+		_ = shallow.InitWithOptions(len(frequencies), frequencies, Options{TieBreak: TieBreakShallow})
+		_ = deep.InitWithOptions(len(frequencies), frequencies, Options{TieBreak: TieBreakDeep})
+		a, _ := codedBits(&minVar, frequencies)
+		b, _ := codedBits(&shallow, frequencies)
+		if a != b {
+			t.Fatalf("trial %d: %v: cost %d differs from %v cost %d", trial, TieBreakMinVariance, a, TieBreakShallow, b)
+		}
+		if a, b := minVar.SizeVariance(frequencies), shallow.SizeVariance(frequencies); a > b+1e-9 {
+			t.Fatalf("trial %d: %v variance %g > %v variance %g", trial, TieBreakMinVariance, a, TieBreakShallow, b)
+		}
+		if a, b := minVar.SizeVariance(frequencies), deep.SizeVariance(frequencies); a > b+1e-9 {
+			t.Fatalf("trial %d: %v variance %g > %v variance %g", trial, TieBreakMinVariance, a, TieBreakDeep, b)
+		}
+	}
+}
+
+func TestOptions_GoString(t *testing.T) {
+	type testRow struct {
+		name   string
+		value  fmt.GoStringer
+		expect string
+	}
+
+	// Each expect is the same text as the expression which built value,
+	// so that the compiler checks every emitted expression.
+	testData := [...]testRow{
+		{
+			name:   "encoder-plain",
+			value:  NewEncoderFromSizes([]byte{2, 2, 1}),
+			expect: "NewEncoderFromSizes([]byte{2,2,1})",
+		},
+		{
+			name:   "encoder-options",
+			value:  NewEncoderFromSizesWithOptions([]byte{2, 2, 1}, Options{ExtraBits: []byte{0, 3}, Profile: ProfileRFC1951}),
+			expect: "NewEncoderFromSizesWithOptions([]byte{2,2,1},Options{ExtraBits:[]byte{0,3},Profile:ProfileRFC1951})",
+		},
+		{
+			name:   "encoder-symbol-name-only",
+			value:  NewEncoderFromSizesWithOptions([]byte{1, 1}, Options{SymbolName: deflateTestName}),
+			expect: "NewEncoderFromSizes([]byte{1,1})",
+		},
+		{
+			name:   "decoder-plain",
+			value:  NewDecoder([]byte{1, 2, 2}),
+			expect: "NewDecoder([]byte{1,2,2})",
+		},
+		{
+			name:   "decoder-options",
+			value:  NewDecoderWithOptions([]byte{1, 2, 2}, Options{MissingCode: MissingCodeSubstitute, FallbackSymbol: 2}),
+			expect: "NewDecoderWithOptions([]byte{1,2,2},Options{MissingCode:MissingCodeSubstitute,FallbackSymbol:2})",
+		},
+		{
+			name:   "options-all",
+			value:  Options{TieBreak: TieBreakDeep, ExtraBits: []byte{}, Profile: ProfileJPEG, MissingCode: MissingCodeEscape, FallbackSymbol: 7, FrequencyQuantization: QuantizeTail, QuantizeKeep: 4, MaxAlphabet: 300, TableStrategy: SortedTable, MaxFrequencyRatio: 1000},
+			expect: "Options{TieBreak:TieBreakDeep,ExtraBits:[]byte{},Profile:ProfileJPEG,MissingCode:MissingCodeEscape,FallbackSymbol:7,FrequencyQuantization:QuantizeTail,QuantizeKeep:4,MaxAlphabet:300,TableStrategy:SortedTable,MaxFrequencyRatio:1000}",
+		},
+		{
+			name:   "options-unknown-enum",
+			value:  Options{TieBreak: TieBreak(9)},
+			expect: "Options{TieBreak:TieBreak(9)}",
+		},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			actual := row.value.GoString()
+			if row.expect != actual {
+				t.Errorf("wrong output:\n\texpect: %s\n\tactual: %s", row.expect, actual)
+			}
+			if _, err := format.Source([]byte(actual)); err != nil {
+				t.Errorf("go/format: %v", err)
+			}
+		})
+	}
+}
+
+func TestOptions_GoStringTypeCheck(t *testing.T) {
+	rng := rand.New(rand.NewSource(178))
+	sizes := []byte{3, 3, 3, 3, 3, 3, 3, 3}
+
+	var buf strings.Builder
+	buf.WriteString("package huffman\n\n")
+	var expect []string
+	for i := 0; i < 200; i++ {
+		opts := Options{
+			TieBreak:              TieBreak(rng.Intn(len(tieBreakNames))),
+			Profile:               []Profile{ProfileLenient, ProfileRFC1951}[rng.Intn(2)],
+			MissingCode:           MissingCodePolicy(rng.Intn(len(missingCodePolicyNames))),
+			FallbackSymbol:        Symbol(rng.Intn(len(sizes))),
+			FrequencyQuantization: FrequencyQuantization(rng.Intn(len(frequencyQuantizationNames))),
+			QuantizeKeep:          rng.Intn(3),
+			MaxAlphabet:           []int{0, 8, 100}[rng.Intn(3)],
+			TableStrategy:         TableStrategy(rng.Intn(len(tableStrategyNames))),
+			MaxFrequencyRatio:     []uint32{0, 1, 4096}[rng.Intn(3)],
+		}
+		if rng.Intn(2) == 0 {
+			opts.ExtraBits = []byte{byte(rng.Intn(5)), byte(rng.Intn(5))}
+		}
+		fmt.Fprintf(&buf, "var _ = %s\n", NewEncoderFromSizesWithOptions(sizes, opts).GoString())
+		expect = append(expect, "*github.com/chronos-tachyon/huffman.Encoder")
+		fmt.Fprintf(&buf, "var _ = %s\n", NewDecoderWithOptions(sizes, opts).GoString())
+		expect = append(expect, "*github.com/chronos-tachyon/huffman.Decoder")
+		fmt.Fprintf(&buf, "var _ = %s\n", opts.GoString())
+		expect = append(expect, "github.com/chronos-tachyon/huffman.Options")
+	}
+
+	// Type-check the emitted expressions against the real package, so that
+	// a GoString which names a missing identifier or builds the wrong type
+	// is caught without a hand-written evaluator.
+	pkg, err := build.ImportDir(".", 0)
+	if err != nil {
+		t.Fatalf("go/build: %v", err)
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range pkg.GoFiles {
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatalf("go/parser: %v", err)
+		}
+		files = append(files, file)
+	}
+	generated, err := parser.ParseFile(fset, "gostring_generated.go", buf.String(), 0)
+	if err != nil {
+		t.Fatalf("go/parser: generated file: %v", err)
+	}
+	files = append(files, generated)
+
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			if terr, ok := err.(types.Error); ok && terr.Fset.Position(terr.Pos).Filename == "gostring_generated.go" {
+				t.Errorf("go/types: %v", err)
+			}
+		},
+	}
+	_, _ = conf.Check("github.com/chronos-tachyon/huffman", fset, files, info)
+
+	for i, decl := range generated.Decls {
+		spec := decl.(*ast.GenDecl).Specs[0].(*ast.ValueSpec)
+		tv, found := info.Types[spec.Values[0]]
+		if !found {
+			t.Errorf("line %d: expression was not type-checked", i+3)
+			continue
+		}
+		if actual := tv.Type.String(); actual != expect[i] {
+			t.Errorf("line %d: wrong type:\n\texpect: %s\n\tactual: %s", i+3, expect[i], actual)
+		}
+	}
+}
+
+func TestOptions_MaxAlphabet(t *testing.T) {
+	opts := Options{MaxAlphabet: 4}
+	sizes := []byte{2, 2, 2, 3, 3}
+
+	var e Encoder
+	if err := e.InitWithOptions(4, []uint32{1, 2, 3, 4}, opts); err != nil {
+		t.Errorf("InitWithOptions: unexpected error at the limit: %v", err)
+	}
+	err := e.InitWithOptions(5, []uint32{1, 2, 3, 4, 5}, opts)
+	var alphabetErr *AlphabetError
+	if !errors.As(err, &alphabetErr) || alphabetErr.Size != 5 || alphabetErr.Max != 4 || !errors.Is(err, ErrAlphabetTooLarge) {
+		t.Errorf("Encoder.InitWithOptions: expected AlphabetError{5, 4}, got %v", err)
+	}
+	if err := e.InitFromSizesWithOptions(sizes, opts); !errors.Is(err, ErrAlphabetTooLarge) {
+		t.Errorf("InitFromSizesWithOptions: expected ErrAlphabetTooLarge, got %v", err)
+	}
+	var d Decoder
+	if err := d.InitWithOptions(sizes, opts); !errors.Is(err, ErrAlphabetTooLarge) {
+		t.Errorf("Decoder.InitWithOptions: expected ErrAlphabetTooLarge, got %v", err)
+	}
+
+	if err := (Options{}).CheckAlphabet(1 << 20); err != nil {
+		t.Errorf("CheckAlphabet: unexpected error without a limit: %v", err)
+	}
+	if err := e.InitWithOptions(4, nil, Options{MaxAlphabet: -1}); err == nil {
+		t.Errorf("InitWithOptions: expected error for negative MaxAlphabet")
+	}
+
+	var built Encoder
+	if err := NewBuilder(opts).BuildE([]uint32{1, 2, 3, 4}, &built); err != nil {
+		t.Errorf("BuildE: unexpected error at the limit: %v", err)
+	}
+	err = NewBuilder(opts).BuildE([]uint32{1, 2, 3, 4, 5}, &built)
+	if !errors.As(err, &alphabetErr) || alphabetErr.Size != 5 || alphabetErr.Max != 4 {
+		t.Errorf("BuildE: expected AlphabetError{5, 4}, got %v", err)
+	}
+	if expect, actual := uint(4), built.NumSymbols(); expect != actual {
+		t.Errorf("BuildE: expected the previous code with %d Symbols, got %d", expect, actual)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Build: expected panic above the limit")
+			}
+		}()
+		NewBuilder(opts).Build([]uint32{1, 2, 3, 4, 5}, &e)
+	}()
+}
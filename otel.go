@@ -0,0 +1,202 @@
+package huffman
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// OTelHistogramDataPoint holds the bucket fields of an OpenTelemetry
+// HistogramDataPoint (opentelemetry.proto.metrics.v1), which counts values in
+// buckets with explicit boundaries.  The field names match the protobuf
+// message, so a data point from any OpenTelemetry SDK or exporter can be
+// copied field by field without this package depending on OpenTelemetry.
+type OTelHistogramDataPoint struct {
+	// BucketCounts holds the count of each bucket.  It is either empty or
+	// one longer than ExplicitBounds.
+	BucketCounts []uint64
+
+	// ExplicitBounds holds the upper bound of each bucket but the last.
+	ExplicitBounds []float64
+}
+
+// OTelExponentialHistogramDataPoint holds the bucket fields of an
+// OpenTelemetry ExponentialHistogramDataPoint, which counts values in buckets
+// whose boundaries grow by a factor of 2**(2**-Scale).  Bucket index i covers
+// the values in (base**i, base**(i+1)].
+type OTelExponentialHistogramDataPoint struct {
+	// Scale sets the resolution of the buckets.
+	Scale int32
+
+	// ZeroCount is the count of values which are exactly zero.
+	ZeroCount uint64
+
+	// Positive holds the buckets for positive values.
+	Positive OTelBuckets
+
+	// Negative holds the buckets for negative values, which are not
+	// supported and must have no non-zero counts.
+	Negative OTelBuckets
+}
+
+// OTelBuckets holds a contiguous range of buckets of an
+// OTelExponentialHistogramDataPoint.
+type OTelBuckets struct {
+	// Offset is the bucket index of BucketCounts[0].
+	Offset int32
+
+	// BucketCounts holds the count of each bucket.
+	BucketCounts []uint64
+}
+
+// ErrHistogram is returned when importing histogram data which cannot be
+// converted into frequencies.
+var ErrHistogram = errors.New("invalid histogram data")
+
+// FrequenciesFromOTelHistogram returns the combined bucket counts of points
+// as frequencies, one Symbol per bucket, ready for Encoder.Init or for
+// training a Dict.  Symbol i is bucket i, the values no greater than
+// ExplicitBounds[i].  Every point with buckets must have the same
+// ExplicitBounds.
+//
+// Counts which do not fit in a uint32 are scaled down, all by the same
+// factor, keeping every non-zero count non-zero.
+//
+func FrequenciesFromOTelHistogram(points ...OTelHistogramDataPoint) ([]uint32, error) {
+	var bounds []float64
+	var counts []uint64
+	for index, point := range points {
+		if len(point.BucketCounts) == 0 {
+			continue
+		}
+		if len(point.BucketCounts) != len(point.ExplicitBounds)+1 {
+			return nil, fmt.Errorf("%w: point %d has %d buckets for %d bounds", ErrHistogram, index, len(point.BucketCounts), len(point.ExplicitBounds))
+		}
+		if counts == nil {
+			bounds = point.ExplicitBounds
+			counts = make([]uint64, len(point.BucketCounts))
+		} else if !equalFloat64s(bounds, point.ExplicitBounds) {
+			return nil, fmt.Errorf("%w: point %d has different bounds than the points before it", ErrHistogram, index)
+		}
+		for i, count := range point.BucketCounts {
+			counts[i] = addSaturating(counts[i], count)
+		}
+	}
+	return scaleToUint32(counts), nil
+}
+
+// FrequenciesFromOTelExponential returns the combined bucket counts of points
+// as frequencies, ready for Encoder.Init or for training a Dict.  Symbol 0 is
+// the zero bucket, and Symbol i > 0 is the positive bucket with index
+// firstIndex+i-1.  Points with different Scales are merged at the smallest
+// Scale among them, as OpenTelemetry does, so that firstIndex is relative to
+// that Scale, which is also returned.
+//
+// Counts which do not fit in a uint32 are scaled down as by
+// FrequenciesFromOTelHistogram.  Returns an error wrapping ErrHistogram if
+// the buckets in use span more than 1<<16 Symbols, including the zero
+// bucket, since no code of at most 16 bits could cover them.
+//
+func FrequenciesFromOTelExponential(points ...OTelExponentialHistogramDataPoint) (frequencies []uint32, firstIndex int, scale int32, err error) {
+	if len(points) == 0 {
+		return nil, 0, 0, nil
+	}
+
+	scale = points[0].Scale
+	for index, point := range points {
+		for _, count := range point.Negative.BucketCounts {
+			if count != 0 {
+				return nil, 0, 0, fmt.Errorf("%w: point %d has negative values", ErrHistogram, index)
+			}
+		}
+		if point.Scale < scale {
+			scale = point.Scale
+		}
+	}
+
+	// Find the range of bucket indices at the merged scale.
+	lo, hi := math.MaxInt64, math.MinInt64
+	for _, point := range points {
+		shift := uint(point.Scale - scale)
+		for i, count := range point.Positive.BucketCounts {
+			if count == 0 {
+				continue
+			}
+			bucket := (int(point.Positive.Offset) + i) >> shift
+			if bucket < lo {
+				lo = bucket
+			}
+			if bucket > hi {
+				hi = bucket
+			}
+		}
+	}
+	width := 0
+	if lo <= hi {
+		width = hi - lo + 1
+	} else {
+		lo = 0
+	}
+	if 1+width > 1<<maxBitsPerCode {
+		return nil, 0, 0, fmt.Errorf("%w: %d buckets is too many Symbols, max %d", ErrHistogram, 1+width, 1<<maxBitsPerCode)
+	}
+
+	counts := make([]uint64, 1+width)
+	for _, point := range points {
+		counts[0] = addSaturating(counts[0], point.ZeroCount)
+		shift := uint(point.Scale - scale)
+		for i, count := range point.Positive.BucketCounts {
+			if count != 0 {
+				bucket := (int(point.Positive.Offset) + i) >> shift
+				counts[1+bucket-lo] = addSaturating(counts[1+bucket-lo], count)
+			}
+		}
+	}
+	return scaleToUint32(counts), lo, scale, nil
+}
+
+func equalFloat64s(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func addSaturating(a, b uint64) uint64 {
+	if sum := a + b; sum >= a {
+		return sum
+	}
+	return math.MaxUint64
+}
+
+// scaleToUint32 converts counts to frequencies, dividing them all by the same
+// factor if any is too large, and rounding non-zero counts up to 1.
+func scaleToUint32(counts []uint64) []uint32 {
+	if counts == nil {
+		return nil
+	}
+	var max uint64
+	for _, count := range counts {
+		if count > max {
+			max = count
+		}
+	}
+	divisor := uint64(1)
+	if max > math.MaxUint32 {
+		divisor = max/math.MaxUint32 + 1
+	}
+	frequencies := make([]uint32, len(counts))
+	for i, count := range counts {
+		freq := count / divisor
+		if freq == 0 && count != 0 {
+			freq = 1
+		}
+		frequencies[i] = uint32(freq)
+	}
+	return frequencies
+}
@@ -0,0 +1,99 @@
+package huffman
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFrequenciesFromOTelHistogram(t *testing.T) {
+	bounds := []float64{1, 10, 100}
+	freqs, err := FrequenciesFromOTelHistogram(
+		OTelHistogramDataPoint{BucketCounts: []uint64{5, 3, 0, 1}, ExplicitBounds: bounds},
+		OTelHistogramDataPoint{},
+		OTelHistogramDataPoint{BucketCounts: []uint64{1, 1, 0, 0}, ExplicitBounds: []float64{1, 10, 100}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expect := []uint32{6, 4, 0, 1}; !reflect.DeepEqual(expect, freqs) {
+		t.Errorf("expected %v, got %v", expect, freqs)
+	}
+
+	big, err := FrequenciesFromOTelHistogram(OTelHistogramDataPoint{BucketCounts: []uint64{1 << 40, 1 << 38, 1}, ExplicitBounds: []float64{1, 2}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if big[0] == 0 || big[0] > math.MaxUint32 || big[0] != 4*big[1] || big[2] != 1 {
+		t.Errorf("large counts: expected proportional scaling, got %v", big)
+	}
+
+	if _, err := FrequenciesFromOTelHistogram(OTelHistogramDataPoint{BucketCounts: []uint64{1, 2}, ExplicitBounds: bounds}); !errors.Is(err, ErrHistogram) {
+		t.Errorf("bucket count mismatch: expected ErrHistogram, got %v", err)
+	}
+	if _, err := FrequenciesFromOTelHistogram(
+		OTelHistogramDataPoint{BucketCounts: []uint64{1, 2}, ExplicitBounds: []float64{1}},
+		OTelHistogramDataPoint{BucketCounts: []uint64{1, 2}, ExplicitBounds: []float64{2}},
+	); !errors.Is(err, ErrHistogram) {
+		t.Errorf("bounds mismatch: expected ErrHistogram, got %v", err)
+	}
+}
+
+func TestFrequenciesFromOTelExponential(t *testing.T) {
+	type testRow struct {
+		name       string
+		points     []OTelExponentialHistogramDataPoint
+		expect     []uint32
+		firstIndex int
+		scale      int32
+	}
+
+	testData := [...]testRow{
+		{name: "empty"},
+		{
+			name: "one",
+			points: []OTelExponentialHistogramDataPoint{
+				{Scale: 2, ZeroCount: 4, Positive: OTelBuckets{Offset: -1, BucketCounts: []uint64{0, 3, 0, 2}}},
+			},
+			expect:     []uint32{4, 3, 0, 2},
+			firstIndex: 0,
+			scale:      2,
+		},
+		{
+			name: "merged",
+			points: []OTelExponentialHistogramDataPoint{
+				{Scale: 1, Positive: OTelBuckets{Offset: 3, BucketCounts: []uint64{1, 1}}},
+				{Scale: 0, ZeroCount: 2, Positive: OTelBuckets{Offset: 1, BucketCounts: []uint64{5}}},
+			},
+			expect:     []uint32{2, 6, 1},
+			firstIndex: 1,
+			scale:      0,
+		},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			freqs, firstIndex, scale, err := FrequenciesFromOTelExponential(row.points...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(row.expect, freqs) || row.firstIndex != firstIndex || row.scale != scale {
+				t.Errorf("expected %v %d %d, got %v %d %d", row.expect, row.firstIndex, row.scale, freqs, firstIndex, scale)
+			}
+		})
+	}
+
+	// Values from 1e-300 to 1e300 at scale 20 span about 2e9 buckets.
+	wide := []OTelExponentialHistogramDataPoint{
+		{Scale: 20, Positive: OTelBuckets{Offset: -1040187392, BucketCounts: []uint64{1}}},
+		{Scale: 20, Positive: OTelBuckets{Offset: 1040187392, BucketCounts: []uint64{1}}},
+	}
+	if _, _, _, err := FrequenciesFromOTelExponential(wide...); !errors.Is(err, ErrHistogram) {
+		t.Errorf("wide range: expected ErrHistogram, got %v", err)
+	}
+
+	negative := OTelExponentialHistogramDataPoint{Negative: OTelBuckets{BucketCounts: []uint64{1}}}
+	if _, _, _, err := FrequenciesFromOTelExponential(negative); !errors.Is(err, ErrHistogram) {
+		t.Errorf("negative values: expected ErrHistogram, got %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The packet format carries a coded Symbol stream in packets of at most a
+// fixed size, for datagram transports such as UDP or packet radio:
+//
+//     packet := seq:uint32le count:uint16le payload
+//
+// The seq field numbers the packets from 0, wrapping around.  The low 15
+// bits of the count field are the number of Symbols whose codes end within
+// this packet's payload, and the top bit is the end flag, set on packets
+// written by Flush.  The payload holds the codes, packed as by BitWriter
+// and padded to a byte boundary.
+//
+// Normally no code is split across packets: each packet holds only whole
+// codes and can be decoded on its own even if others are lost.  With
+// PacketOptions.SplitCodes, the codes instead form one continuous bit stream
+// which fills every packet to the brim, so that a code which does not fit
+// continues in the next packet.  Such packets must be decoded in order and
+// without loss, except that the stream starts afresh after each packet with
+// the end flag.
+//
+
+// PacketHeaderSize is the size of the header at the start of every packet.
+const PacketHeaderSize = 6
+
+// MaxPacketSize is the largest allowed PacketOptions.Size.  It keeps the
+// number of codes which can end within one packet below 32768.
+const MaxPacketSize = 4096
+
+// packetEndFlag is the end flag in the count field of the packet header.
+const packetEndFlag = 0x8000
+
+// ErrPacket is returned when decoding a malformed packet.
+var ErrPacket = errors.New("invalid Huffman packet")
+
+// ErrPacketLoss is returned by Depacketizer in SplitCodes mode when a packet
+// arrives out of sequence, and for each packet skipped until decoding can
+// resume.
+var ErrPacketLoss = errors.New("Huffman packet lost or out of order")
+
+// PacketOptions configures a Packetizer and the matching Depacketizer.
+type PacketOptions struct {
+	// Size is the maximum size of a packet in bytes, including its
+	// header.  It must be more than PacketHeaderSize and at most
+	// MaxPacketSize.
+	Size int
+
+	// SplitCodes, if true, allows a code to be split across packets.  See
+	// the packet format.
+	SplitCodes bool
+}
+
+func (opts PacketOptions) validate() error {
+	if opts.Size <= PacketHeaderSize || opts.Size > MaxPacketSize {
+		return fmt.Errorf("packet size %d not in range [%d..%d]", opts.Size, PacketHeaderSize+1, MaxPacketSize)
+	}
+	return nil
+}
+
+// PacketHeader is the decoded header of a packet.
+type PacketHeader struct {
+	// Seq is the packet's sequence number.
+	Seq uint32
+
+	// Count is the number of Symbols whose codes end within the packet.
+	Count uint16
+
+	// End is true if the packet was written by Packetizer.Flush.
+	End bool
+}
+
+// ParsePacketHeader splits a packet into its header and its payload.
+func ParsePacketHeader(packet []byte) (PacketHeader, []byte, error) {
+	if len(packet) < PacketHeaderSize {
+		return PacketHeader{}, nil, fmt.Errorf("%w: %d bytes is too short", ErrPacket, len(packet))
+	}
+	count := binary.LittleEndian.Uint16(packet[4:6])
+	hdr := PacketHeader{
+		Seq:   binary.LittleEndian.Uint32(packet[0:4]),
+		Count: count &^ packetEndFlag,
+		End:   (count & packetEndFlag) != 0,
+	}
+	return hdr, packet[PacketHeaderSize:], nil
+}
+
+// Packetizer encodes Symbols into packets, as described by the packet format,
+// writing each packet to an io.Writer in a single call to Write.  A UDP
+// net.Conn, for instance, sends each such call as one datagram.
+type Packetizer struct {
+	w       io.Writer
+	e       *Encoder
+	opts    PacketOptions
+	bw      BitWriter
+	packet  []byte
+	seq     uint32
+	count   int
+	maxBits uint64
+}
+
+// NewPacketizer returns a new Packetizer which encodes Symbols with e and
+// writes packets to w.  Unless opts.SplitCodes is set, returns an error if
+// the longest code written for any Symbol, including an escape written per
+// MissingCodeEscape, does not fit in one packet.
+func NewPacketizer(w io.Writer, e *Encoder, opts PacketOptions) (*Packetizer, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	p := &Packetizer{w: w, e: e, opts: opts, packet: make([]byte, PacketHeaderSize, opts.Size)}
+	p.maxBits = 8 * uint64(opts.Size-PacketHeaderSize)
+	if !opts.SplitCodes {
+		longest := uint64(e.maxSize)
+		if e.opts.MissingCode == MissingCodeEscape {
+			longest = uint64(e.codes[e.opts.FallbackSymbol].Size + rawSymbolBits(len(e.codes)))
+		}
+		if longest > p.maxBits {
+			return nil, fmt.Errorf("a code of %d bits does not fit in a packet of %d bytes", longest, opts.Size)
+		}
+	}
+	return p, nil
+}
+
+// bits returns the number of payload bits in the current packet.
+func (p *Packetizer) bits() uint64 {
+	return 8*uint64(len(p.bw.buf)) + uint64(p.bw.n)
+}
+
+// Seq returns the sequence number of the next packet to be written.
+func (p *Packetizer) Seq() uint32 {
+	return p.seq
+}
+
+// WriteSymbol encodes the given Symbol, writing out the current packet first
+// if the Symbol's code does not fit in it.
+func (p *Packetizer) WriteSymbol(symbol Symbol) error {
+	if p.bw.err != nil {
+		return p.bw.err
+	}
+	if !p.opts.SplitCodes {
+		if p.bits()+uint64(p.e.symbolBits(symbol)) > p.maxBits {
+			if err := p.emit(false); err != nil {
+				return err
+			}
+		}
+		if err := p.bw.WriteSymbol(p.e, symbol); err != nil {
+			return err
+		}
+		p.count++
+		return nil
+	}
+
+	if err := p.bw.WriteSymbol(p.e, symbol); err != nil {
+		return err
+	}
+	end, counted := p.bits(), false
+	if end <= p.maxBits {
+		p.count++
+		counted = true
+	}
+	payloadSize := p.opts.Size - PacketHeaderSize
+	for len(p.bw.buf) >= payloadSize {
+		rest := p.bw.buf[payloadSize:]
+		p.bw.buf = p.bw.buf[:payloadSize]
+		if err := p.emit(false); err != nil {
+			return err
+		}
+		p.bw.buf = append(p.bw.buf[:0], rest...)
+		end -= p.maxBits
+		if !counted && end <= p.maxBits {
+			p.count = 1
+			counted = true
+		}
+	}
+	return nil
+}
+
+// WriteSymbols encodes each of the given Symbols in turn, as by WriteSymbol.
+// Returns the number of Symbols encoded.
+func (p *Packetizer) WriteSymbols(symbols []Symbol) (int, error) {
+	for index, symbol := range symbols {
+		if err := p.WriteSymbol(symbol); err != nil {
+			return index, err
+		}
+	}
+	return len(symbols), nil
+}
+
+// Flush writes out the current packet with the end flag, if it holds any
+// bits.  In SplitCodes mode, the next Symbol then starts afresh in the packet
+// after it.
+func (p *Packetizer) Flush() error {
+	if p.bw.err != nil {
+		return p.bw.err
+	}
+	if p.bits() == 0 {
+		return nil
+	}
+	return p.emit(true)
+}
+
+// emit writes out the current packet.  In SplitCodes mode, a packet without
+// the end flag is full, and the bits left over in p.bw belong to the next.
+func (p *Packetizer) emit(end bool) error {
+	if end || !p.opts.SplitCodes {
+		_ = p.bw.Align()
+	}
+	count := uint16(p.count)
+	if end {
+		count |= packetEndFlag
+	}
+	binary.LittleEndian.PutUint32(p.packet[0:4], p.seq)
+	binary.LittleEndian.PutUint16(p.packet[4:6], count)
+	p.packet = append(p.packet[:PacketHeaderSize], p.bw.buf...)
+	p.bw.buf = p.bw.buf[:0]
+	p.seq++
+	p.count = 0
+	if _, err := p.w.Write(p.packet); err != nil {
+		p.bw.err = err
+		return err
+	}
+	return nil
+}
+
+// Depacketizer decodes the packets written by a Packetizer.
+type Depacketizer struct {
+	d       *Decoder
+	opts    PacketOptions
+	next    uint32
+	started bool
+	lost    bool
+	acc     uint64
+	n       byte
+}
+
+// NewDepacketizer returns a new Depacketizer which decodes Symbols with d.
+// The PacketOptions must match those of the Packetizer.
+func NewDepacketizer(d *Decoder, opts PacketOptions) (*Depacketizer, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &Depacketizer{d: d, opts: opts}, nil
+}
+
+// Decode decodes the Symbols of one packet and appends them to dst.  In
+// SplitCodes mode, packets must be passed in order.  At a gap in the
+// sequence, the codes which continue into later packets are lost, so Decode
+// returns ErrPacketLoss for every packet up to and including the next one
+// with the end flag, and decoding resumes after it.
+func (dp *Depacketizer) Decode(dst []Symbol, packet []byte) ([]Symbol, error) {
+	if len(packet) > dp.opts.Size {
+		return dst, fmt.Errorf("%w: %d bytes exceeds the packet size of %d", ErrPacket, len(packet), dp.opts.Size)
+	}
+	hdr, payload, err := ParsePacketHeader(packet)
+	if err != nil {
+		return dst, err
+	}
+
+	if dp.opts.SplitCodes && dp.started && hdr.Seq != dp.next {
+		expect := dp.next
+		dp.acc, dp.n = 0, 0
+		dp.next = hdr.Seq + 1
+		dp.lost = !hdr.End
+		return dst, fmt.Errorf("%w: expected packet %d, got %d", ErrPacketLoss, expect, hdr.Seq)
+	}
+	dp.started = true
+	dp.next = hdr.Seq + 1
+	if dp.lost {
+		dp.lost = !hdr.End
+		return dst, fmt.Errorf("%w: skipping packet %d", ErrPacketLoss, hdr.Seq)
+	}
+
+	r := bytes.NewReader(payload)
+	br := BitReader{r: r}
+	if dp.opts.SplitCodes {
+		br.acc, br.n = dp.acc, dp.n
+	}
+	dp.acc, dp.n = 0, 0
+	for i := 0; i < int(hdr.Count); i++ {
+		symbol, err := br.ReadSymbol(dp.d)
+		if err != nil {
+			dp.lost = dp.opts.SplitCodes && !hdr.End
+			return dst, fmt.Errorf("%w: packet %d: symbol %d of %d: %v", ErrPacket, hdr.Seq, i, hdr.Count, noEOF(err))
+		}
+		dst = append(dst, symbol)
+	}
+
+	// Carry the start of the next code over to the next packet, unless
+	// this packet ends with padding.
+	if dp.opts.SplitCodes && !hdr.End {
+		if uint(br.n)+8*uint(r.Len()) > 56 {
+			return dst, fmt.Errorf("%w: packet %d: too many bits left over", ErrPacket, hdr.Seq)
+		}
+		dp.acc, dp.n = br.acc, br.n
+		for r.Len() != 0 {
+			b, _ := r.ReadByte()
+			dp.acc |= uint64(b) << dp.n
+			dp.n += 8
+		}
+	}
+	return dst, nil
+}
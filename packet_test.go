@@ -0,0 +1,152 @@
+package huffman
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// packetRecorder is an io.Writer which records each Write as one packet.
+type packetRecorder struct {
+	packets [][]byte
+}
+
+func (pr *packetRecorder) Write(p []byte) (int, error) {
+	pr.packets = append(pr.packets, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestPacketizer(t *testing.T) {
+	rng := rand.New(rand.NewSource(191))
+	freqs := []uint32{1000, 300, 300, 100, 50, 20, 5, 1, 1}
+	e := NewEncoder(len(freqs), freqs)
+	d := e.Decoder()
+
+	input := make([]Symbol, 5000)
+	for i := range input {
+		input[i] = Symbol(rng.Intn(len(freqs)))
+	}
+
+	type testRow struct {
+		name string
+		opts PacketOptions
+	}
+
+	testData := [...]testRow{
+		{name: "whole-small", opts: PacketOptions{Size: PacketHeaderSize + 2}},
+		{name: "whole-mtu", opts: PacketOptions{Size: 1400}},
+		{name: "split-tiny", opts: PacketOptions{Size: PacketHeaderSize + 1, SplitCodes: true}},
+		{name: "split-small", opts: PacketOptions{Size: 37, SplitCodes: true}},
+		{name: "split-mtu", opts: PacketOptions{Size: 1400, SplitCodes: true}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var rec packetRecorder
+			p, err := NewPacketizer(&rec, e, row.opts)
+			if err != nil {
+				t.Fatalf("NewPacketizer: unexpected error: %v", err)
+			}
+			half := len(input) / 2
+			_, _ = p.WriteSymbols(input[:half])
+			_ = p.Flush()
+			_, _ = p.WriteSymbols(input[half:])
+			if err := p.Flush(); err != nil {
+				t.Fatalf("Flush: unexpected error: %v", err)
+			}
+
+			dp, _ := NewDepacketizer(d, row.opts)
+			var output []Symbol
+			for index, packet := range rec.packets {
+				if len(packet) > row.opts.Size {
+					t.Fatalf("packet %d: %d bytes exceeds %d", index, len(packet), row.opts.Size)
+				}
+				hdr, _, _ := ParsePacketHeader(packet)
+				if hdr.Seq != uint32(index) {
+					t.Errorf("packet %d: wrong seq %d", index, hdr.Seq)
+				}
+				if row.opts.SplitCodes && !hdr.End && len(packet) != row.opts.Size {
+					t.Errorf("packet %d: expected a full packet, got %d bytes", index, len(packet))
+				}
+				if output, err = dp.Decode(output, packet); err != nil {
+					t.Fatalf("packet %d: Decode: unexpected error: %v", index, err)
+				}
+			}
+			if !reflect.DeepEqual(input, output) {
+				t.Errorf("wrong output: expected %d symbols, got %d", len(input), len(output))
+			}
+		})
+	}
+}
+
+func TestPacketizer_Loss(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{1, 2, 3, 3})
+	d := e.Decoder()
+	input := []Symbol{3, 2, 1, 0, 3, 3, 2, 2, 1, 1, 0, 0}
+
+	// Without SplitCodes, every packet decodes on its own.
+	var rec packetRecorder
+	opts := PacketOptions{Size: PacketHeaderSize + 1}
+	p, _ := NewPacketizer(&rec, e, opts)
+	_, _ = p.WriteSymbols(input)
+	_ = p.Flush()
+	dp, _ := NewDepacketizer(d, opts)
+	var output []Symbol
+	for _, packet := range append(rec.packets[:1:1], rec.packets[2:]...) {
+		var err error
+		if output, err = dp.Decode(output, packet); err != nil {
+			t.Fatalf("Decode: unexpected error: %v", err)
+		}
+	}
+	if len(output) == 0 || len(output) >= len(input) {
+		t.Errorf("expected some but not all symbols, got %v", output)
+	}
+
+	// With SplitCodes, a lost packet loses everything up to the next
+	// packet with the end flag.
+	rec = packetRecorder{}
+	opts.SplitCodes = true
+	p, _ = NewPacketizer(&rec, e, opts)
+	_, _ = p.WriteSymbols(input)
+	_ = p.Flush()
+	_, _ = p.WriteSymbols(input)
+	_ = p.Flush()
+	dp, _ = NewDepacketizer(d, opts)
+	output = nil
+	var losses int
+	for index, packet := range rec.packets {
+		if index == 1 {
+			continue
+		}
+		var err error
+		output, err = dp.Decode(output, packet)
+		if errors.Is(err, ErrPacketLoss) {
+			losses++
+		} else if err != nil {
+			t.Fatalf("Decode: unexpected error: %v", err)
+		}
+	}
+	if losses == 0 {
+		t.Errorf("expected ErrPacketLoss")
+	}
+	if tail := output[len(output)-len(input):]; !reflect.DeepEqual(tail, input) {
+		t.Errorf("expected decoding to resume after the end flag, got %v", output)
+	}
+}
+
+func TestNewPacketizer_Errors(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10})
+	var rec packetRecorder
+	if _, err := NewPacketizer(&rec, e, PacketOptions{Size: PacketHeaderSize}); err == nil {
+		t.Errorf("expected error for an empty payload")
+	}
+	if _, err := NewPacketizer(&rec, e, PacketOptions{Size: MaxPacketSize + 1}); err == nil {
+		t.Errorf("expected error for an oversized packet")
+	}
+	if _, err := NewPacketizer(&rec, e, PacketOptions{Size: PacketHeaderSize + 1}); err == nil {
+		t.Errorf("expected error for a code longer than the payload")
+	}
+	if _, err := NewPacketizer(&rec, e, PacketOptions{Size: PacketHeaderSize + 1, SplitCodes: true}); err != nil {
+		t.Errorf("SplitCodes: unexpected error: %v", err)
+	}
+}
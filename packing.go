@@ -0,0 +1,174 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	mathbits "math/bits"
+)
+
+// Packing describes how a BitWriter lays out its bits in memory, and how a
+// BitReader expects to find them, for feeding hardware decoders which
+// consume codes in a fixed word format.  The zero value is the default
+// packing, with bits filling each byte from its least significant bit, as in
+// DEFLATE.
+//
+// The bits are grouped into words of WordSize bytes, each filled in stream
+// order starting from its least significant bit, or from its most
+// significant bit if MSBFirst is set, and each stored in little-endian or
+// big-endian byte order.  For instance:
+//
+//     Packing{MSBFirst: true}
+//         bytes filled from the most significant bit, as in JPEG
+//     Packing{WordSize: 2, MSBFirst: true}
+//         little-endian 16-bit words filled from the most significant bit,
+//         as in LZX and XPRESS
+//     Packing{WordSize: 2, BigEndian: true}
+//         big-endian 16-bit words filled from the least significant bit
+//
+// Only whole words can be written, so BitWriter.Flush pads the output to a
+// word boundary, and BitReader reads a whole word at a time.
+//
+type Packing struct {
+	// WordSize is the size of a word in bytes: 1, 2, or 4.  0 means 1.
+	WordSize int
+
+	// MSBFirst, if true, fills each word from its most significant bit.
+	MSBFirst bool
+
+	// BigEndian, if true, stores each word with its most significant byte
+	// first.  It has no effect on 1-byte words.
+	BigEndian bool
+}
+
+func (p Packing) wordSize() int {
+	if p.WordSize == 0 {
+		return 1
+	}
+	return p.WordSize
+}
+
+func (p Packing) validate() error {
+	switch p.WordSize {
+	case 0, 1, 2, 4:
+		return nil
+	default:
+		return fmt.Errorf("invalid packing word size %d", p.WordSize)
+	}
+}
+
+// isDefault reports whether this Packing leaves the bytes unchanged.
+func (p Packing) isDefault() bool {
+	return !p.MSBFirst && (p.wordSize() == 1 || !p.BigEndian)
+}
+
+// pack converts whole words of buf, in place, from the default packing to
+// this one.
+func (p Packing) pack(buf []byte) {
+	p.convert(buf, binary.LittleEndian, p.order())
+}
+
+// unpack converts whole words of buf, in place, from this packing to the
+// default one.
+func (p Packing) unpack(buf []byte) {
+	p.convert(buf, p.order(), binary.LittleEndian)
+}
+
+func (p Packing) order() binary.ByteOrder {
+	if p.BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// convert loads each word of buf in one byte order, reverses its bits if
+// MSBFirst is set, and stores it in the other byte order.
+func (p Packing) convert(buf []byte, from, to binary.ByteOrder) {
+	switch p.wordSize() {
+	case 1:
+		if p.MSBFirst {
+			for index, b := range buf {
+				buf[index] = mathbits.Reverse8(b)
+			}
+		}
+	case 2:
+		for index := 0; index+2 <= len(buf); index += 2 {
+			v := from.Uint16(buf[index:])
+			if p.MSBFirst {
+				v = mathbits.Reverse16(v)
+			}
+			to.PutUint16(buf[index:], v)
+		}
+	case 4:
+		for index := 0; index+4 <= len(buf); index += 4 {
+			v := from.Uint32(buf[index:])
+			if p.MSBFirst {
+				v = mathbits.Reverse32(v)
+			}
+			to.PutUint32(buf[index:], v)
+		}
+	}
+}
+
+// SetPacking selects the Packing of the output.  It must be called before
+// anything is written; Init restores the default packing.
+func (bw *BitWriter) SetPacking(p Packing) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	if bw.count != 0 {
+		return fmt.Errorf("SetPacking: %d bits already written", bw.count)
+	}
+	bw.packing = p
+	return nil
+}
+
+// SetPacking selects the Packing of the input.  It must be called before
+// anything is read; Init restores the default packing.  With a WordSize
+// above 1, the BitReader reads whole words from the underlying reader, so
+// it may read up to WordSize-1 bytes more than it needs.
+func (br *BitReader) SetPacking(p Packing) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	if br.count != 0 || br.n != 0 {
+		return fmt.Errorf("SetPacking: %d bits already read", br.count)
+	}
+	if !p.isDefault() {
+		br.r = &unpackingReader{r: br.r, p: p}
+	}
+	return nil
+}
+
+// unpackingReader reads words in a non-default Packing and returns their
+// bytes in the default one.
+type unpackingReader struct {
+	r    io.ByteReader
+	p    Packing
+	word [4]byte
+	pos  int
+	end  int
+}
+
+func (ur *unpackingReader) ReadByte() (byte, error) {
+	if ur.pos >= ur.end {
+		size := ur.p.wordSize()
+		for index := 0; index < size; index++ {
+			b, err := ur.r.ReadByte()
+			if err != nil {
+				if err == io.EOF && index != 0 {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			ur.word[index] = b
+		}
+		ur.p.unpack(ur.word[:size])
+		ur.pos, ur.end = 0, size
+	}
+	b := ur.word[ur.pos]
+	ur.pos++
+	return b, nil
+}
+
+var _ io.ByteReader = (*unpackingReader)(nil)
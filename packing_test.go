@@ -0,0 +1,113 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPacking(t *testing.T) {
+	type testRow struct {
+		packing Packing
+		expect  []byte
+	}
+
+	// The first bit written is 1, followed by 11 bits of 0 and 4 bits of 1.
+	testData := [...]testRow{
+		{packing: Packing{}, expect: []byte{0x01, 0xf0}},
+		{packing: Packing{WordSize: 1, BigEndian: true}, expect: []byte{0x01, 0xf0}},
+		{packing: Packing{MSBFirst: true}, expect: []byte{0x80, 0x0f}},
+		{packing: Packing{WordSize: 2}, expect: []byte{0x01, 0xf0}},
+		{packing: Packing{WordSize: 2, BigEndian: true}, expect: []byte{0xf0, 0x01}},
+		{packing: Packing{WordSize: 2, MSBFirst: true}, expect: []byte{0x0f, 0x80}},
+		{packing: Packing{WordSize: 2, MSBFirst: true, BigEndian: true}, expect: []byte{0x80, 0x0f}},
+		{packing: Packing{WordSize: 4}, expect: []byte{0x01, 0xf0, 0x00, 0x00}},
+		{packing: Packing{WordSize: 4, BigEndian: true}, expect: []byte{0x00, 0x00, 0xf0, 0x01}},
+		{packing: Packing{WordSize: 4, MSBFirst: true}, expect: []byte{0x00, 0x00, 0x0f, 0x80}},
+		{packing: Packing{WordSize: 4, MSBFirst: true, BigEndian: true}, expect: []byte{0x80, 0x0f, 0x00, 0x00}},
+	}
+	for _, row := range testData {
+		var buf bytes.Buffer
+		bw := NewBitWriter(&buf)
+		if err := bw.SetPacking(row.packing); err != nil {
+			t.Fatalf("%+v: SetPacking: unexpected error: %v", row.packing, err)
+		}
+		_ = bw.WriteBits(12, 0x001)
+		_ = bw.WriteBits(4, 0xf)
+		if err := bw.Flush(); err != nil {
+			t.Fatalf("%+v: Flush: unexpected error: %v", row.packing, err)
+		}
+		if !bytes.Equal(buf.Bytes(), row.expect) {
+			t.Errorf("%+v: expected %x, got %x", row.packing, row.expect, buf.Bytes())
+		}
+		if actual, expect := bw.BitsWritten(), 8*uint64(len(row.expect)); actual != expect {
+			t.Errorf("%+v: BitsWritten: expected %d, got %d", row.packing, expect, actual)
+		}
+
+		br := NewBitReader(bytes.NewReader(buf.Bytes()))
+		if err := br.SetPacking(row.packing); err != nil {
+			t.Fatalf("%+v: BitReader.SetPacking: unexpected error: %v", row.packing, err)
+		}
+		if bits, err := br.ReadBits(12); err != nil || bits != 0x001 {
+			t.Errorf("%+v: ReadBits(12): expected 0x001, got %#x, %v", row.packing, bits, err)
+		}
+		if bits, err := br.ReadBits(4); err != nil || bits != 0xf {
+			t.Errorf("%+v: ReadBits(4): expected 0xf, got %#x, %v", row.packing, bits, err)
+		}
+	}
+}
+
+func TestPacking_RoundTrip(t *testing.T) {
+	e := NewEncoderFromSizes([]byte{1, 2, 3, 4, 5, 6, 7, 7})
+	d := NewDecoder([]byte{1, 2, 3, 4, 5, 6, 7, 7})
+	symbols := []Symbol{7, 0, 6, 1, 5, 2, 4, 3, 3, 0, 0, 7}
+	for _, p := range []Packing{
+		{WordSize: 2, BigEndian: true},
+		{WordSize: 2, MSBFirst: true},
+		{WordSize: 4, MSBFirst: true, BigEndian: true},
+	} {
+		var buf bytes.Buffer
+		bw := NewBitWriter(&buf)
+		_ = bw.SetPacking(p)
+		for _, symbol := range symbols {
+			_ = bw.WriteSymbol(e, symbol)
+		}
+		if err := bw.Flush(); err != nil {
+			t.Fatalf("%+v: Flush: unexpected error: %v", p, err)
+		}
+		if buf.Len()%p.WordSize != 0 {
+			t.Errorf("%+v: %d bytes is not a whole number of words", p, buf.Len())
+		}
+
+		br := NewBitReader(bytes.NewReader(buf.Bytes()))
+		_ = br.SetPacking(p)
+		for index, expect := range symbols {
+			if actual, err := br.ReadSymbol(d); err != nil || actual != expect {
+				t.Fatalf("%+v: symbol %d: expected %d, got %d, %v", p, index, expect, actual, err)
+			}
+		}
+	}
+}
+
+func TestPacking_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	if err := bw.SetPacking(Packing{WordSize: 3}); err == nil {
+		t.Errorf("SetPacking(WordSize: 3): expected error")
+	}
+	_ = bw.WriteBits(1, 1)
+	if err := bw.SetPacking(Packing{MSBFirst: true}); err == nil {
+		t.Errorf("SetPacking after WriteBits: expected error")
+	}
+
+	br := NewBitReader(bytes.NewReader([]byte{0x01, 0x02, 0x03}))
+	_ = br.SetPacking(Packing{WordSize: 2, BigEndian: true})
+	if _, err := br.ReadBits(16); err != nil {
+		t.Errorf("ReadBits(16): unexpected error: %v", err)
+	}
+	if _, err := br.ReadBits(8); err == nil {
+		t.Errorf("ReadBits(8) of a partial word: expected error")
+	}
+	if err := br.SetPacking(Packing{}); err == nil {
+		t.Errorf("SetPacking after ReadBits: expected error")
+	}
+}
@@ -0,0 +1,102 @@
+package huffman
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+// DecompressParallel decompresses the size-byte stream stored in r, which
+// must have been written with WriterOptions.Index set, using up to workers
+// goroutines, and writes the decompressed data to w.  Since the index gives
+// the decompressed offset of every block, each block is written to its place
+// in w as soon as it is decoded.  If workers is 0, runtime.GOMAXPROCS(0) is
+// used.  Returns the decompressed length of the stream.
+//
+// Streams which use shared dictionaries need an IndexedReader with UseDicts;
+// see IndexedReader.DecompressParallel.
+//
+func DecompressParallel(ctx context.Context, r io.ReaderAt, size int64, workers int, w io.WriterAt) (int64, error) {
+	ir, err := NewIndexedReader(r, size)
+	if err != nil {
+		return 0, err
+	}
+	return ir.DecompressParallel(ctx, workers, w)
+}
+
+// DecompressParallel decompresses every block of the stream using up to
+// workers goroutines, and writes each to its decompressed offset in w.  If
+// workers is 0, runtime.GOMAXPROCS(0) is used.  Returns the decompressed
+// length of the stream.
+//
+// The first error, either from a block or from w, stops the remaining work;
+// so does cancellation of ctx, in which case ctx.Err() is returned.  On
+// error, w may hold any subset of the blocks.  w must support concurrent
+// calls to WriteAt for distinct ranges, as *os.File does.
+//
+// It must not be called concurrently with UseDicts.
+//
+func (ir *IndexedReader) DecompressParallel(ctx context.Context, workers int, w io.WriterAt) (int64, error) {
+	assert.Assertf(workers >= 0, "workers %d < 0", workers)
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	numBlocks := ir.NumBlocks()
+	if workers > numBlocks {
+		workers = numBlocks
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next     int64
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				block := int(atomic.AddInt64(&next, 1) - 1)
+				if block >= numBlocks {
+					return
+				}
+				data, err := ir.ReadBlock(block)
+				if err != nil {
+					fail(err)
+					return
+				}
+				if _, err := w.WriteAt(data, ir.BlockOffset(block)); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	if err := parent.Err(); err != nil {
+		return 0, err
+	}
+	return ir.Size(), nil
+}
@@ -0,0 +1,79 @@
+package huffman
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memWriterAt is an io.WriterAt backed by a growable byte slice.
+type memWriterAt struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (m *memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if end := int(off) + len(p); end > len(m.buf) {
+		m.buf = append(m.buf, make([]byte, end-len(m.buf))...)
+	}
+	return copy(m.buf[off:], p), nil
+}
+
+func TestDecompressParallel(t *testing.T) {
+	input := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 200))
+	stream := makeIndexedStream(t, input, WriterOptions{
+		BlockSize: 128,
+		Checksum:  ChecksumCRC32C,
+		Index:     true,
+	})
+
+	type testRow struct {
+		name    string
+		workers int
+	}
+
+	testData := [...]testRow{
+		{name: "default", workers: 0},
+		{name: "one", workers: 1},
+		{name: "three", workers: 3},
+		{name: "more-than-blocks", workers: 1000},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var out memWriterAt
+			n, err := DecompressParallel(context.Background(), bytes.NewReader(stream), int64(len(stream)), row.workers, &out)
+			if err != nil {
+				t.Fatalf("DecompressParallel: unexpected error: %v", err)
+			}
+			if n != int64(len(input)) {
+				t.Errorf("DecompressParallel: expected %d bytes, got %d", len(input), n)
+			}
+			if !bytes.Equal(input, out.buf) {
+				t.Errorf("DecompressParallel: wrong output")
+			}
+		})
+	}
+
+	damaged := append([]byte(nil), stream...)
+	damaged[len(stream)/2] ^= 0xff
+	var out memWriterAt
+	if _, err := DecompressParallel(context.Background(), bytes.NewReader(damaged), int64(len(damaged)), 4, &out); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("damaged stream: expected ErrCorrupt, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := DecompressParallel(ctx, bytes.NewReader(stream), int64(len(stream)), 4, &out); err != context.Canceled {
+		t.Errorf("canceled context: expected context.Canceled, got %v", err)
+	}
+
+	plain := makeIndexedStream(t, input, WriterOptions{})
+	if _, err := DecompressParallel(context.Background(), bytes.NewReader(plain), int64(len(plain)), 4, &out); err != ErrNoIndex {
+		t.Errorf("stream without index: expected ErrNoIndex, got %v", err)
+	}
+}
@@ -0,0 +1,403 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+// The format carried by EncoderPipe and DecoderPipe is:
+//
+//     pipe  := chunk* 0x00
+//     chunk := count:uvarint code* padding
+//
+// Each chunk holds count > 0 codes, least significant bit first, padded with
+// zero bits to the next byte boundary.  The explicit counts let the reading
+// side tell the last code apart from the padding after it.
+//
+
+// DefaultPipeBufferSize is the default number of compressed bytes buffered
+// between the two ends of an EncoderPipe or DecoderPipe.
+const DefaultPipeBufferSize = 1 << 16
+
+// pipeChunkSymbols is the number of Symbols which EncoderPipeWriter buffers
+// before writing out a chunk.
+const pipeChunkSymbols = 4096
+
+// pipeBuffer is a bounded FIFO of bytes shared by the two ends of a pipe.
+// Writers block while it is full, and readers block while it is empty.
+type pipeBuffer struct {
+	mu       sync.Mutex
+	cond     sync.Cond
+	data     []byte
+	start    int
+	length   int
+	writeErr error
+	readErr  error
+}
+
+func newPipeBuffer(size int) *pipeBuffer {
+	assert.Assertf(size >= 0, "buffer size %d < 0", size)
+	if size == 0 {
+		size = DefaultPipeBufferSize
+	}
+	p := &pipeBuffer{data: make([]byte, size)}
+	p.cond.L = &p.mu
+	return p
+}
+
+// Write appends all of b, blocking until there is room.  It fails if the
+// reading end has been closed.
+func (p *pipeBuffer) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var n int
+	for len(b) != 0 {
+		if p.readErr != nil {
+			return n, p.readErr
+		}
+		if p.writeErr != nil {
+			return n, io.ErrClosedPipe
+		}
+		if p.length == len(p.data) {
+			p.cond.Wait()
+			continue
+		}
+		end := (p.start + p.length) % len(p.data)
+		room := len(p.data) - p.length
+		if end+room > len(p.data) {
+			room = len(p.data) - end
+		}
+		copied := copy(p.data[end:end+room], b)
+		b = b[copied:]
+		n += copied
+		p.length += copied
+		p.cond.Broadcast()
+	}
+	return n, nil
+}
+
+// Read copies buffered bytes into b, blocking until there is at least one.
+// Once the buffer is drained, it returns the error with which the writing
+// end was closed.
+func (p *pipeBuffer) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.length == 0 {
+		if p.readErr != nil {
+			return 0, io.ErrClosedPipe
+		}
+		if p.writeErr != nil {
+			return 0, p.writeErr
+		}
+		if len(b) == 0 {
+			return 0, nil
+		}
+		p.cond.Wait()
+	}
+	avail := p.length
+	if p.start+avail > len(p.data) {
+		avail = len(p.data) - p.start
+	}
+	n := copy(b, p.data[p.start:p.start+avail])
+	p.start = (p.start + n) % len(p.data)
+	p.length -= n
+	p.cond.Broadcast()
+	return n, nil
+}
+
+func (p *pipeBuffer) ReadByte() (byte, error) {
+	var tmp [1]byte
+	_, err := p.Read(tmp[:])
+	return tmp[0], err
+}
+
+func (p *pipeBuffer) closeWrite(err error) {
+	if err == nil {
+		err = io.EOF
+	}
+	p.mu.Lock()
+	if p.writeErr == nil {
+		p.writeErr = err
+	}
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+func (p *pipeBuffer) closeRead(err error) {
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	p.mu.Lock()
+	if p.readErr == nil {
+		p.readErr = err
+	}
+	p.cond.Broadcast()
+	p.mu.Unlock()
+}
+
+// EncoderPipe creates a synchronous in-memory pipe which codes Symbols.
+// Symbols written to the EncoderPipeWriter are coded with e, and the
+// compressed bytes can be read from the EncoderPipeReader by another
+// goroutine.  At most bufSize compressed bytes are buffered; once that many
+// are waiting, writes block until they are read.  If bufSize is 0,
+// DefaultPipeBufferSize is used.
+//
+// As with io.Pipe, closing either end with an error makes that error visible
+// at the other end: the reading end sees it once the buffered bytes have been
+// read, and the writing end sees it immediately.
+//
+func EncoderPipe(e *Encoder, bufSize int) (*EncoderPipeReader, *EncoderPipeWriter) {
+	p := newPipeBuffer(bufSize)
+	pw := &EncoderPipeWriter{p: p, e: e}
+	pw.bw.Init(p)
+	return &EncoderPipeReader{p: p}, pw
+}
+
+// EncoderPipeWriter is the writing half of an EncoderPipe.  Its methods must
+// not be called concurrently with each other.
+type EncoderPipeWriter struct {
+	p       *pipeBuffer
+	e       *Encoder
+	bw      BitWriter
+	pending []Symbol
+	closed  bool
+}
+
+// WriteSymbol writes one Symbol to the pipe.
+func (pw *EncoderPipeWriter) WriteSymbol(symbol Symbol) error {
+	_, err := pw.WriteSymbols([]Symbol{symbol})
+	return err
+}
+
+// WriteSymbols writes symbols to the pipe, blocking as needed until enough
+// compressed bytes have been read.  Returns the number of Symbols accepted.
+// Every Symbol must have a code, unless the Encoder's Options.MissingCode
+// says otherwise; ErrNoCode is returned for the first one which does not.
+func (pw *EncoderPipeWriter) WriteSymbols(symbols []Symbol) (int, error) {
+	if pw.closed {
+		return 0, io.ErrClosedPipe
+	}
+	for n, symbol := range symbols {
+		if pw.e.opts.MissingCode != MissingCodeEscape && pw.e.Encode(symbol).Size == 0 {
+			return n, fmt.Errorf("symbol %s: %w", pw.e.opts.formatSymbol(symbol), ErrNoCode)
+		}
+		pw.pending = append(pw.pending, symbol)
+		if len(pw.pending) >= pipeChunkSymbols {
+			if err := pw.writeChunk(); err != nil {
+				return n + 1, err
+			}
+		}
+	}
+	return len(symbols), nil
+}
+
+// Flush writes out the Symbols buffered so far, so that they can be decoded
+// without waiting for more.
+func (pw *EncoderPipeWriter) Flush() error {
+	if pw.closed {
+		return io.ErrClosedPipe
+	}
+	return pw.writeChunk()
+}
+
+// Close flushes any buffered Symbols and marks the end of the data.  The
+// reading end sees io.EOF after the last byte.
+func (pw *EncoderPipeWriter) Close() error {
+	if pw.closed {
+		return nil
+	}
+	err := pw.writeChunk()
+	if err == nil {
+		err = pw.writeEnd()
+	}
+	pw.closed = true
+	pw.p.closeWrite(err)
+	return err
+}
+
+// CloseWithError discards any buffered Symbols and closes the pipe, so that
+// the reading end sees err, or io.EOF if err is nil, after the bytes already
+// written.  Without the end marker written by Close, io.EOF reaches a
+// DecoderPipe as io.ErrUnexpectedEOF.
+func (pw *EncoderPipeWriter) CloseWithError(err error) error {
+	pw.closed = true
+	pw.pending = pw.pending[:0]
+	pw.p.closeWrite(err)
+	return nil
+}
+
+func (pw *EncoderPipeWriter) writeChunk() error {
+	if len(pw.pending) == 0 {
+		return nil
+	}
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64(len(pw.pending)))
+	if err := pw.bw.WriteBytes(tmp[:n]); err != nil {
+		return err
+	}
+	for _, symbol := range pw.pending {
+		if err := pw.bw.WriteSymbol(pw.e, symbol); err != nil {
+			return err
+		}
+	}
+	pw.pending = pw.pending[:0]
+	return pw.bw.Flush()
+}
+
+func (pw *EncoderPipeWriter) writeEnd() error {
+	if err := pw.bw.WriteBytes([]byte{0}); err != nil {
+		return err
+	}
+	return pw.bw.Flush()
+}
+
+// EncoderPipeReader is the reading half of an EncoderPipe.
+type EncoderPipeReader struct {
+	p *pipeBuffer
+}
+
+// Read reads compressed bytes from the pipe, blocking until some are
+// available or the writing end is closed.
+func (pr *EncoderPipeReader) Read(b []byte) (int, error) {
+	return pr.p.Read(b)
+}
+
+// Close closes the pipe; later writes fail with io.ErrClosedPipe.
+func (pr *EncoderPipeReader) Close() error {
+	return pr.CloseWithError(nil)
+}
+
+// CloseWithError closes the pipe; later writes fail with err, or with
+// io.ErrClosedPipe if err is nil.
+func (pr *EncoderPipeReader) CloseWithError(err error) error {
+	pr.p.closeRead(err)
+	return nil
+}
+
+// DecoderPipe creates a synchronous in-memory pipe which decodes Symbols.
+// Compressed bytes written to the DecoderPipeWriter, in the format produced
+// by EncoderPipe, are decoded with d, and the Symbols can be read from the
+// DecoderPipeReader by another goroutine.  At most bufSize compressed bytes
+// are buffered; once that many are waiting, writes block until they are
+// decoded.  If bufSize is 0, DefaultPipeBufferSize is used.
+//
+// Errors propagate as for EncoderPipe.  In addition, invalid compressed data
+// closes the pipe with the decoding error, which is returned at both ends.
+//
+func DecoderPipe(d *Decoder, bufSize int) (*DecoderPipeReader, *DecoderPipeWriter) {
+	p := newPipeBuffer(bufSize)
+	pr := &DecoderPipeReader{p: p, d: d}
+	pr.br.Init(p)
+	return pr, &DecoderPipeWriter{p: p}
+}
+
+// DecoderPipeWriter is the writing half of a DecoderPipe.
+type DecoderPipeWriter struct {
+	p *pipeBuffer
+}
+
+// Write writes compressed bytes to the pipe, blocking as needed until
+// enough of them have been decoded.
+func (pw *DecoderPipeWriter) Write(b []byte) (int, error) {
+	return pw.p.Write(b)
+}
+
+// Close closes the pipe; the reading end sees io.EOF once the buffered bytes
+// have been decoded.
+func (pw *DecoderPipeWriter) Close() error {
+	return pw.CloseWithError(nil)
+}
+
+// CloseWithError closes the pipe; the reading end sees err, or io.EOF if
+// err is nil, once the buffered bytes have been decoded.
+func (pw *DecoderPipeWriter) CloseWithError(err error) error {
+	pw.p.closeWrite(err)
+	return nil
+}
+
+// DecoderPipeReader is the reading half of a DecoderPipe.  Its methods must
+// not be called concurrently with each other.
+type DecoderPipeReader struct {
+	p         *pipeBuffer
+	d         *Decoder
+	br        BitReader
+	remaining uint64
+	err       error
+}
+
+// ReadSymbol reads one Symbol from the pipe.
+func (pr *DecoderPipeReader) ReadSymbol() (Symbol, error) {
+	var tmp [1]Symbol
+	_, err := pr.ReadSymbols(tmp[:])
+	if err != nil {
+		return InvalidSymbol, err
+	}
+	return tmp[0], nil
+}
+
+// ReadSymbols decodes Symbols from the pipe into dst, blocking until at
+// least one is available.  Returns io.EOF after the end marker written by
+// EncoderPipeWriter.Close, or io.ErrUnexpectedEOF if the writing end is
+// closed without one.
+func (pr *DecoderPipeReader) ReadSymbols(dst []Symbol) (int, error) {
+	var n int
+	for n < len(dst) && pr.err == nil {
+		if pr.remaining == 0 {
+			if n != 0 {
+				break
+			}
+			pr.br.Align()
+			count, err := binary.ReadUvarint(&pr.br)
+			if err != nil {
+				pr.fail(noEOF(err))
+				break
+			}
+			if count == 0 {
+				pr.err = io.EOF
+				pr.p.closeRead(nil)
+				break
+			}
+			pr.remaining = count
+		}
+		symbol, err := pr.br.ReadSymbol(pr.d)
+		if err != nil {
+			pr.fail(noEOF(err))
+			break
+		}
+		dst[n] = symbol
+		n++
+		pr.remaining--
+	}
+	if n != 0 {
+		return n, nil
+	}
+	return 0, pr.err
+}
+
+// Close closes the pipe; later writes fail with io.ErrClosedPipe.
+func (pr *DecoderPipeReader) Close() error {
+	return pr.CloseWithError(nil)
+}
+
+// CloseWithError closes the pipe; later writes fail with err, or with
+// io.ErrClosedPipe if err is nil.
+func (pr *DecoderPipeReader) CloseWithError(err error) error {
+	pr.p.closeRead(err)
+	return nil
+}
+
+func (pr *DecoderPipeReader) fail(err error) {
+	pr.err = err
+	pr.p.closeRead(err)
+}
+
+var (
+	_ io.ReadCloser  = (*EncoderPipeReader)(nil)
+	_ io.WriteCloser = (*DecoderPipeWriter)(nil)
+)
@@ -0,0 +1,133 @@
+package huffman
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestEncoderPipe_DecoderPipe(t *testing.T) {
+	rng := rand.New(rand.NewSource(176))
+	freqs := []uint32{50, 20, 10, 10, 5, 3, 1, 1}
+	var e Encoder
+	e.Init(len(freqs), freqs)
+	d := e.Decoder()
+
+	input := make([]Symbol, 10000)
+	for i := range input {
+		input[i] = Symbol(rng.Intn(len(freqs)))
+	}
+
+	// symbols -> EncoderPipe -> bytes -> DecoderPipe -> symbols, with
+	// small buffers so that every stage blocks on the next.
+	er, ew := EncoderPipe(&e, 64)
+	dr, dw := DecoderPipe(d, 32)
+	go func() {
+		for i := 0; i < len(input); i += 333 {
+			end := i + 333
+			if end > len(input) {
+				end = len(input)
+			}
+			if _, err := ew.WriteSymbols(input[i:end]); err != nil {
+				_ = ew.CloseWithError(err)
+				return
+			}
+		}
+		_ = ew.Close()
+	}()
+	go func() {
+		_, err := io.Copy(dw, er)
+		_ = dw.CloseWithError(err)
+	}()
+
+	var output []Symbol
+	buf := make([]Symbol, 100)
+	for {
+		n, err := dr.ReadSymbols(buf)
+		output = append(output, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadSymbols: unexpected error: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(input, output) {
+		t.Errorf("wrong output: expected %d symbols, got %d", len(input), len(output))
+	}
+	if _, err := dr.ReadSymbol(); err != io.EOF {
+		t.Errorf("ReadSymbol after end: expected io.EOF, got %v", err)
+	}
+}
+
+func TestEncoderPipe_Errors(t *testing.T) {
+	var e Encoder
+	e.Init(4, []uint32{1, 1, 1, 0})
+	errTest := errors.New("test error")
+
+	// Closing the reading end fails the writer.
+	er, ew := EncoderPipe(&e, 16)
+	_ = er.CloseWithError(errTest)
+	if _, err := ew.WriteSymbols([]Symbol{0, 1, 2}); err != nil {
+		t.Errorf("WriteSymbols: unexpected error before Flush: %v", err)
+	}
+	if err := ew.Flush(); err != errTest {
+		t.Errorf("Flush: expected errTest, got %v", err)
+	}
+
+	// Closing the writing end with an error reaches the reader after the
+	// data already written.
+	er, ew = EncoderPipe(&e, 16)
+	_ = ew.WriteSymbol(1)
+	_ = ew.Flush()
+	_ = ew.CloseWithError(errTest)
+	if n, err := er.Read(make([]byte, 16)); n == 0 || err != nil {
+		t.Errorf("Read: expected data, got %d bytes and %v", n, err)
+	}
+	if _, err := er.Read(make([]byte, 16)); err != errTest {
+		t.Errorf("Read: expected errTest, got %v", err)
+	}
+	if err := ew.WriteSymbol(1); err != io.ErrClosedPipe {
+		t.Errorf("WriteSymbol after close: expected io.ErrClosedPipe, got %v", err)
+	}
+
+	// Symbols without codes are rejected up front.
+	_, ew = EncoderPipe(&e, 16)
+	if n, err := ew.WriteSymbols([]Symbol{0, 3}); n != 1 || !errors.Is(err, ErrNoCode) {
+		t.Errorf("WriteSymbols: expected 1 and ErrNoCode, got %d and %v", n, err)
+	}
+}
+
+func TestDecoderPipe_Errors(t *testing.T) {
+	var e Encoder
+	e.Init(4, []uint32{1, 1, 1, 1})
+	d := e.Decoder()
+
+	// Data without the end marker is truncated.
+	dr, dw := DecoderPipe(d, 16)
+	go func() {
+		_, _ = dw.Write([]byte{0x03, 0x1b})
+		_ = dw.Close()
+	}()
+	symbols := make([]Symbol, 10)
+	if n, err := dr.ReadSymbols(symbols); n != 3 || err != nil {
+		t.Errorf("ReadSymbols: expected 3 symbols, got %d and %v", n, err)
+	}
+	if _, err := dr.ReadSymbols(symbols); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadSymbols: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	// Closing the reading end fails the writer, even while it is blocked.
+	dr, dw = DecoderPipe(d, 4)
+	done := make(chan error)
+	go func() {
+		_, err := dw.Write(make([]byte, 100))
+		done <- err
+	}()
+	_ = dr.Close()
+	if err := <-done; err != io.ErrClosedPipe {
+		t.Errorf("Write: expected io.ErrClosedPipe, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package huffman
+
+import (
+	"fmt"
+)
+
+// Profile selects a set of validation rules for the codes accepted by a
+// Decoder and built by an Encoder, so that a code can be held to the rules
+// of a particular format in one place.  It is set via Options.Profile.
+type Profile byte
+
+const (
+	// ProfileLenient accepts any code that can be decoded unambiguously:
+	// codes may be incomplete, and may have no Symbols at all.  This is
+	// the default.
+	ProfileLenient Profile = iota
+
+	// ProfileRFC1951 follows DEFLATE (RFC 1951): codes are at most 15
+	// bits and must be complete, except that a code may consist of a
+	// single 1-bit code or of no codes at all.  An alphabet large enough
+	// to include the end-of-block Symbol 256 must assign it a code.
+	ProfileRFC1951
+
+	// ProfileJPEG follows JPEG (ITU T.81 Annex C): codes are at most 16
+	// bits, and no code may consist entirely of 1 bits, which means the
+	// code must be incomplete.  An Encoder reserves the all-ones code by
+	// building the code with an extra Symbol, as in Annex K.2.  At least
+	// one Symbol must have a code.
+	ProfileJPEG
+
+	// ProfileZstd follows the Huffman literals of Zstandard (RFC 8878):
+	// codes are at most 11 bits and must be complete, with at least 2
+	// Symbols.
+	ProfileZstd
+)
+
+var profileNames = [...]string{
+	"ProfileLenient",
+	"ProfileRFC1951",
+	"ProfileJPEG",
+	"ProfileZstd",
+}
+
+// String returns the name of this Profile.
+func (p Profile) String() string {
+	if uint(p) < uint(len(profileNames)) {
+		return profileNames[p]
+	}
+	return fmt.Sprintf("Profile(%d)", uint(p))
+}
+
+type profileRules struct {
+	maxSize       byte
+	minCodes      int
+	allowEmpty    bool
+	allowSingle   bool
+	complete      bool
+	reserveAllOne bool
+	requireEOB    bool
+}
+
+var profileRulesTable = [...]profileRules{
+	ProfileLenient: {maxSize: maxBitsPerCode, allowEmpty: true, allowSingle: true},
+	ProfileRFC1951: {maxSize: 15, allowEmpty: true, allowSingle: true, complete: true, requireEOB: true},
+	ProfileJPEG:    {maxSize: 16, minCodes: 1, allowSingle: true, reserveAllOne: true},
+	ProfileZstd:    {maxSize: 11, minCodes: 2, complete: true},
+}
+
+func (p Profile) rules() profileRules {
+	return profileRulesTable[p]
+}
+
+// check returns an error if the given size table breaks the rules of this
+// Profile.  The table must already describe a valid prefix code.
+func (p Profile) check(sizes []byte) error {
+	rules := p.rules()
+
+	var numCodes int
+	var lastSize byte
+	var kraft int64
+	for _, size := range sizes {
+		if size == 0 {
+			continue
+		}
+		if size > rules.maxSize {
+			return fmt.Errorf("%v: code length %d exceeds maximum %d", p, size, rules.maxSize)
+		}
+		numCodes++
+		lastSize = size
+		kraft += kraftUnits(size)
+	}
+
+	if numCodes < rules.minCodes {
+		return fmt.Errorf("%v: %d codes, need at least %d", p, numCodes, rules.minCodes)
+	}
+	if rules.requireEOB && len(sizes) > int(EndOfBlock) && sizes[EndOfBlock] == 0 {
+		return fmt.Errorf("%v: no code for end of block", p)
+	}
+	full := int64(1) << maxBitsPerCode
+	switch {
+	case numCodes == 0:
+		if !rules.allowEmpty {
+			return fmt.Errorf("%v: no codes", p)
+		}
+	case numCodes == 1 && lastSize == 1 && rules.allowSingle:
+		// a lone 1-bit code is always allowed where permitted
+	case rules.complete && kraft != full:
+		return fmt.Errorf("%v: incomplete code", p)
+	case rules.reserveAllOne && kraft == full:
+		return fmt.Errorf("%v: code uses the all-ones code", p)
+	}
+	return nil
+}
+
+// initProfile initializes e like init, but builds a code which obeys the
+// rules of opts.Profile, or returns an error if the frequencies make that
+// impossible.
+func (e *Encoder) initProfile(numSymbols int, frequencies []uint32, opts Options) error {
+	rules := opts.Profile.rules()
+
+	n := numSymbols
+	scaled := make([]uint32, numSymbols, numSymbols+1)
+	copy(scaled, frequencies)
+	if rules.reserveAllOne {
+		scaled = append(scaled, 1)
+		n++
+	}
+
+	e.init(n, scaled, opts)
+	for e.maxSize > rules.maxSize {
+		for index, freq := range scaled {
+			if freq != 0 {
+				scaled[index] = (freq >> 1) | 1
+			}
+		}
+		e.init(n, scaled, opts)
+	}
+
+	if n != numSymbols {
+		// The reserved Symbol must take the all-ones code, which is the
+		// last code of the longest size.  It has the lowest frequency,
+		// so trading places with a longest code never adds to the cost.
+		sizes := e.SizeBySymbol()
+		if sizes[numSymbols] < e.maxSize {
+			for symbol := numSymbols - 1; symbol >= 0; symbol-- {
+				if sizes[symbol] == e.maxSize {
+					sizes[symbol], sizes[numSymbols] = sizes[numSymbols], sizes[symbol]
+					break
+				}
+			}
+		}
+		if err := e.InitFromSizes(sizes[:numSymbols]); err != nil {
+			return err
+		}
+		e.opts = opts
+	}
+	return opts.Profile.check(e.SizeBySymbol())
+}
@@ -0,0 +1,113 @@
+package huffman
+
+import (
+	"testing"
+)
+
+func TestProfile_Decoder(t *testing.T) {
+	type testRow struct {
+		name    string
+		sizes   []byte
+		profile Profile
+		ok      bool
+	}
+
+	eob := make([]byte, 257)
+	eob[EndOfBlock] = 1
+	eob[65] = 1
+	noEOB := make([]byte, 257)
+	noEOB[0] = 1
+	noEOB[65] = 1
+
+	testData := [...]testRow{
+		{"lenient-empty", []byte{0, 0}, ProfileLenient, true},
+		{"lenient-incomplete", []byte{1, 2}, ProfileLenient, true},
+		{"rfc-empty", []byte{0, 0}, ProfileRFC1951, true},
+		{"rfc-single", []byte{0, 1}, ProfileRFC1951, true},
+		{"rfc-single-long", []byte{0, 2}, ProfileRFC1951, false},
+		{"rfc-incomplete", []byte{1, 2}, ProfileRFC1951, false},
+		{"rfc-complete", []byte{1, 2, 2}, ProfileRFC1951, true},
+		{"rfc-16-bits", append(makeSizes(15), 16, 16), ProfileRFC1951, false},
+		{"rfc-eob", eob, ProfileRFC1951, true},
+		{"rfc-no-eob", noEOB, ProfileRFC1951, false},
+		{"jpeg-empty", []byte{0, 0}, ProfileJPEG, false},
+		{"jpeg-incomplete", []byte{1, 2}, ProfileJPEG, true},
+		{"jpeg-complete", []byte{1, 2, 2}, ProfileJPEG, false},
+		{"jpeg-16-bits", append(makeSizes(15), 16), ProfileJPEG, true},
+		{"zstd-single", []byte{1}, ProfileZstd, false},
+		{"zstd-complete", []byte{1, 2, 2}, ProfileZstd, true},
+		{"zstd-incomplete", []byte{1, 2}, ProfileZstd, false},
+		{"zstd-12-bits", append(makeSizes(11), 12, 12), ProfileZstd, false},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var d Decoder
+			err := d.InitWithOptions(row.sizes, Options{Profile: row.profile})
+			if row.ok && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !row.ok && err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+// makeSizes returns the sizes of a complete code with one code of each size
+// from 1 to n, minus the last code of size n.
+func makeSizes(n byte) []byte {
+	sizes := make([]byte, n)
+	for index := range sizes {
+		sizes[index] = byte(index + 1)
+	}
+	return sizes
+}
+
+func TestProfile_Encoder(t *testing.T) {
+	// A Fibonacci-like distribution gives a very deep unlimited code.
+	frequencies := make([]uint32, 24)
+	a, b := uint32(1), uint32(1)
+	for index := range frequencies {
+		frequencies[index] = a
+		a, b = b, a+b
+	}
+
+	for _, profile := range []Profile{ProfileRFC1951, ProfileJPEG, ProfileZstd} {
+		t.Run(profile.String(), func(t *testing.T) {
+			var e Encoder
+			if err := e.InitWithOptions(len(frequencies), frequencies, Options{Profile: profile}); err != nil {
+				t.Fatalf("InitWithOptions: unexpected error: %v", err)
+			}
+			if expect, actual := profile.rules().maxSize, e.MaxSize(); actual > expect {
+				t.Errorf("MaxSize: expected at most %d, got %d", expect, actual)
+			}
+			var d Decoder
+			if err := d.InitWithOptions(e.SizeBySymbol(), Options{Profile: profile}); err != nil {
+				t.Errorf("Decoder rejected the Encoder's code: %v", err)
+			}
+			if profile == ProfileJPEG {
+				for symbol, hc := range e.codes {
+					if hc.Size != 0 && hc.Bits == (uint32(1)<<hc.Size)-1 {
+						t.Errorf("symbol %d has the all-ones code %s", symbol, hc)
+					}
+				}
+			}
+		})
+	}
+
+	var e Encoder
+	if err := e.InitWithOptions(2, []uint32{5}, Options{Profile: ProfileZstd}); err == nil {
+		t.Errorf("ProfileZstd: expected an error for a single Symbol")
+	}
+	if err := e.InitWithOptions(2, []uint32{5}, Options{Profile: Profile(99)}); err == nil {
+		t.Errorf("Profile(99): expected an error")
+	}
+}
+
+func TestProfile_String(t *testing.T) {
+	if expect, actual := "ProfileJPEG", ProfileJPEG.String(); expect != actual {
+		t.Errorf("expected %q, got %q", expect, actual)
+	}
+	if expect, actual := "Profile(99)", Profile(99).String(); expect != actual {
+		t.Errorf("expected %q, got %q", expect, actual)
+	}
+}
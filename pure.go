@@ -0,0 +1,87 @@
+package huffman
+
+import (
+	"fmt"
+)
+
+// The functions below expose the core operations of this package on plain
+// slices of integers, with no methods or structs, so that they are easy to
+// export through cgo or WASM and to bind from other languages.  Bits are in
+// the same order as Code.Bits: the first bit of each code is its least
+// significant bit.
+
+// BuildSizes returns the bit length of the optimal code for each Symbol,
+// given the frequency of each, with no code longer than maxSize bits (at
+// most 16).  Symbols with a frequency of 0 get a bit length of 0, meaning
+// no code.  If the frequencies call for longer codes, they are flattened
+// until maxSize is met, so the result is then no longer strictly optimal.
+func BuildSizes(frequencies []uint32, maxSize byte) ([]byte, error) {
+	if maxSize < 1 || maxSize > maxBitsPerCode {
+		return nil, fmt.Errorf("maxSize %d not in range [1..%d]", maxSize, maxBitsPerCode)
+	}
+	if len(frequencies) == 0 {
+		return []byte{}, nil
+	}
+	if uint(len(frequencies)) > (uint(1) << maxSize) {
+		return nil, fmt.Errorf("%d symbols cannot fit in codes of at most %d bits", len(frequencies), maxSize)
+	}
+	var e Encoder
+	initLimitedTo(&e, len(frequencies), frequencies, maxSize)
+	return e.SizeBySymbol(), nil
+}
+
+// CanonicalBits returns the canonical code for each Symbol, given the bit
+// length of each, per RFC 1951 Section 3.2.2.  Symbols with a bit length of
+// 0 get 0.  Returns an error if the lengths do not describe a prefix code.
+func CanonicalBits(sizes []byte) ([]uint32, error) {
+	codes := make([]Code, len(sizes))
+	for symbol, size := range sizes {
+		codes[symbol].Size = size
+	}
+	if err := secondPass(codes); err != nil {
+		return nil, err
+	}
+	out := make([]uint32, len(codes))
+	for symbol, hc := range codes {
+		out[symbol] = hc.Bits
+	}
+	return out, nil
+}
+
+// DecodeTableFlat returns a single-level decoding table for the code with
+// the given bit lengths.  The table has 2**n entries, where n is the longest
+// bit length, and is indexed by the next n bits of input, first bit least
+// significant.  Each entry holds Symbol<<8 | size, where size is the length
+// of the code at the start of the input; a size of 0 means that the input
+// does not begin with any code.  Alphabets may have at most 2**24 Symbols.
+//
+// A code with no Symbols gives a table with the single entry 0.
+//
+func DecodeTableFlat(sizes []byte) ([]uint32, error) {
+	if len(sizes) > 1<<24 {
+		return nil, fmt.Errorf("%d symbols > maximum of %d", len(sizes), 1<<24)
+	}
+	bits, err := CanonicalBits(sizes)
+	if err != nil {
+		return nil, err
+	}
+
+	var maxSize byte
+	for _, size := range sizes {
+		if size > maxSize {
+			maxSize = size
+		}
+	}
+
+	table := make([]uint32, 1<<maxSize)
+	for symbol, size := range sizes {
+		if size == 0 {
+			continue
+		}
+		entry := uint32(symbol)<<8 | uint32(size)
+		for index := bits[symbol]; index < uint32(len(table)); index += uint32(1) << size {
+			table[index] = entry
+		}
+	}
+	return table, nil
+}
@@ -0,0 +1,85 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestBuildSizes(t *testing.T) {
+	sizes, err := BuildSizes([]uint32{5, 9, 12, 13, 16, 45}, 15)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expect := []byte{4, 4, 3, 3, 3, 1}; !bytes.Equal(expect, sizes) {
+		t.Errorf("expected %v, got %v", expect, sizes)
+	}
+
+	sizes, err = BuildSizes([]uint32{1, 1, 2, 4, 8, 16}, 3)
+	if err != nil {
+		t.Fatalf("limited: unexpected error: %v", err)
+	}
+	for _, size := range sizes {
+		if size > 3 {
+			t.Errorf("limited: got size %d > 3 in %v", size, sizes)
+		}
+	}
+
+	for _, maxSize := range []byte{0, 17} {
+		if _, err := BuildSizes([]uint32{1, 1}, maxSize); err == nil {
+			t.Errorf("maxSize %d: expected an error", maxSize)
+		}
+	}
+	if _, err := BuildSizes([]uint32{1, 1, 1}, 1); err == nil {
+		t.Errorf("3 symbols in 1 bit: expected an error")
+	}
+}
+
+func TestCanonicalBits(t *testing.T) {
+	sizes := []byte{3, 3, 3, 3, 3, 2, 4, 4, 0}
+	bits, err := CanonicalBits(sizes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e := NewEncoderFromSizes(sizes)
+	for symbol := range sizes {
+		if expect := e.Encode(Symbol(symbol)).Bits; expect != bits[symbol] {
+			t.Errorf("symbol %d: expected %b, got %b", symbol, expect, bits[symbol])
+		}
+	}
+	if _, err := CanonicalBits([]byte{1, 1, 1}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestDecodeTableFlat(t *testing.T) {
+	rng := rand.New(rand.NewSource(160))
+	frequencies := make([]uint32, 40)
+	for index := range frequencies {
+		frequencies[index] = uint32(rng.Intn(100))
+	}
+	sizes, err := BuildSizes(frequencies, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := DecodeTableFlat(sizes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	d := NewDecoder(sizes)
+	if expect := 1 << d.MaxSize(); len(table) != expect {
+		t.Fatalf("expected %d entries, got %d", expect, len(table))
+	}
+	for index, entry := range table {
+		size := byte(entry)
+		symbol, _, _ := d.Decode(Code{Size: size, Bits: uint32(index) & ((1 << size) - 1)})
+		if size == 0 || symbol != Symbol(entry>>8) {
+			t.Fatalf("entry %d: %#x does not match Decoder (symbol %d)", index, entry, symbol)
+		}
+	}
+
+	if table, err := DecodeTableFlat([]byte{0, 0}); err != nil || len(table) != 1 || table[0] != 0 {
+		t.Errorf("empty code: expected ([0], nil), got (%v, %v)", table, err)
+	}
+}
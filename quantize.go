@@ -0,0 +1,182 @@
+package huffman
+
+import (
+	"fmt"
+	mathbits "math/bits"
+)
+
+// FrequencyQuantization selects how an Encoder rounds the frequencies it is
+// given before building its code.  Rounding loses a little compression, but
+// makes the code depend only on the rough shape of the frequencies, so blocks
+// with similar contents get identical tables which can be reused (see
+// WriterOptions.ReuseTables).  QuantizationPenalty measures the loss.
+type FrequencyQuantization byte
+
+const (
+	// QuantizeNone uses the frequencies as given.  This is the default.
+	QuantizeNone FrequencyQuantization = iota
+
+	// QuantizePowerOfTwo rounds every non-zero frequency to the nearest
+	// power of 2.
+	QuantizePowerOfTwo
+
+	// QuantizeTail keeps the frequencies of the Options.QuantizeKeep most
+	// frequent Symbols exact, and rounds the rest as QuantizePowerOfTwo
+	// does.  Symbols tied with the last one kept are also kept.
+	QuantizeTail
+)
+
+// defaultQuantizeKeep is the number of Symbols kept exact by QuantizeTail
+// when Options.QuantizeKeep is 0.
+const defaultQuantizeKeep = 16
+
+var frequencyQuantizationNames = [...]string{
+	"QuantizeNone",
+	"QuantizePowerOfTwo",
+	"QuantizeTail",
+}
+
+// String returns the name of this FrequencyQuantization.
+func (q FrequencyQuantization) String() string {
+	if uint(q) < uint(len(frequencyQuantizationNames)) {
+		return frequencyQuantizationNames[q]
+	}
+	return fmt.Sprintf("FrequencyQuantization(%d)", uint(q))
+}
+
+// roundPowerOfTwo rounds freq to the nearest power of 2, rounding halfway
+// values up.  Zero stays zero.
+func roundPowerOfTwo(freq uint32) uint32 {
+	if freq == 0 {
+		return 0
+	}
+	k := uint(mathbits.Len32(freq) - 1)
+	lo := uint32(1) << k
+	if k < 31 && freq-lo >= lo>>1 && lo > 1 {
+		return lo << 1
+	}
+	return lo
+}
+
+// quantizeThreshold returns the smallest frequency which QuantizeTail keeps
+// exact, i.e. the frequency of the keep'th most frequent Symbol, or 0 if
+// there are no more than keep Symbols with non-zero frequencies.  The heap
+// holds the keep largest frequencies seen so far, smallest on top.
+func quantizeThreshold(nodes []symbolAndFreq, keep int, heap []uint32) (uint32, []uint32) {
+	heap = heap[:0]
+	if len(nodes) <= keep {
+		return 0, heap
+	}
+
+	for _, node := range nodes {
+		freq := node.freq
+		if len(heap) < keep {
+			heap = append(heap, freq)
+			for i := len(heap) - 1; i > 0; {
+				parent := (i - 1) / 2
+				if heap[parent] <= heap[i] {
+					break
+				}
+				heap[parent], heap[i] = heap[i], heap[parent]
+				i = parent
+			}
+			continue
+		}
+		if freq <= heap[0] {
+			continue
+		}
+		heap[0] = freq
+		for i := 0; ; {
+			least := i
+			if l := 2*i + 1; l < len(heap) && heap[l] < heap[least] {
+				least = l
+			}
+			if r := 2*i + 2; r < len(heap) && heap[r] < heap[least] {
+				least = r
+			}
+			if least == i {
+				break
+			}
+			heap[i], heap[least] = heap[least], heap[i]
+			i = least
+		}
+	}
+	return heap[0], heap
+}
+
+// quantize rounds the frequencies of nodes in place, as selected by opts.
+func (b *Builder) quantize(nodes []symbolAndFreq) {
+	opts := &b.opts
+	switch opts.FrequencyQuantization {
+	case QuantizePowerOfTwo:
+		for index := range nodes {
+			nodes[index].freq = roundPowerOfTwo(nodes[index].freq)
+		}
+
+	case QuantizeTail:
+		keep := opts.QuantizeKeep
+		if keep == 0 {
+			keep = defaultQuantizeKeep
+		}
+		var threshold uint32
+		threshold, b.heap = quantizeThreshold(nodes, keep, b.heap)
+		if threshold == 0 {
+			return
+		}
+		for index := range nodes {
+			if freq := nodes[index].freq; freq < threshold {
+				nodes[index].freq = roundPowerOfTwo(freq)
+			}
+		}
+	}
+}
+
+// clampFrequencyRatio raises every frequency less than the largest divided
+// by ratio, rounded up, to that value.  A ratio of 0 means no limit.
+func clampFrequencyRatio(nodes []symbolAndFreq, ratio uint32) {
+	if ratio == 0 || len(nodes) == 0 {
+		return
+	}
+	var max uint32
+	for _, node := range nodes {
+		if node.freq > max {
+			max = node.freq
+		}
+	}
+	floor := max / ratio
+	if max%ratio != 0 {
+		floor++
+	}
+	for index := range nodes {
+		if nodes[index].freq < floor {
+			nodes[index].freq = floor
+		}
+	}
+}
+
+// QuantizationPenalty returns the cost in bits of coding data with the given
+// frequencies using the code built with opts, divided by the cost using the
+// code built with the same opts but no FrequencyQuantization.  The result is
+// at least 1; for example, 1.02 means that quantization makes the coded data
+// 2% larger.  Returns 1 if every frequency is 0.
+func QuantizationPenalty(frequencies []uint32, opts Options) float64 {
+	if len(frequencies) == 0 {
+		return 1
+	}
+	exactOpts := opts
+	exactOpts.FrequencyQuantization = QuantizeNone
+
+	var quantized, exact Encoder
+	NewBuilder(opts).Build(frequencies, &quantized)
+	NewBuilder(exactOpts).Build(frequencies, &exact)
+
+	var quantizedCost, exactCost uint64
+	for symbol, freq := range frequencies {
+		quantizedCost += uint64(freq) * uint64(quantized.codes[symbol].Size)
+		exactCost += uint64(freq) * uint64(exact.codes[symbol].Size)
+	}
+	if exactCost == 0 {
+		return 1
+	}
+	return float64(quantizedCost) / float64(exactCost)
+}
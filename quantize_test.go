@@ -0,0 +1,168 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestRoundPowerOfTwo(t *testing.T) {
+	for freq, expect := range map[uint32]uint32{
+		0:          0,
+		1:          1,
+		2:          2,
+		3:          4,
+		5:          4,
+		6:          8,
+		11:         8,
+		12:         16,
+		1 << 31:    1 << 31,
+		0xffffffff: 1 << 31,
+	} {
+		if actual := roundPowerOfTwo(freq); actual != expect {
+			t.Errorf("%d: expected %d, got %d", freq, expect, actual)
+		}
+	}
+}
+
+func TestOptions_FrequencyQuantization(t *testing.T) {
+	type testRow struct {
+		name  string
+		opts  Options
+		freqs []uint32
+		sizes []byte
+	}
+
+	testData := [...]testRow{
+		{
+			name:  "none",
+			opts:  Options{},
+			freqs: []uint32{9, 5, 3, 3},
+			sizes: []byte{1, 2, 3, 3},
+		},
+		{
+			name:  "power-of-two",
+			opts:  Options{FrequencyQuantization: QuantizePowerOfTwo},
+			freqs: []uint32{9, 5, 3, 3},
+			sizes: []byte{2, 2, 2, 2},
+		},
+		{
+			name:  "tail",
+			opts:  Options{FrequencyQuantization: QuantizeTail, QuantizeKeep: 1},
+			freqs: []uint32{9, 5, 3, 3},
+			sizes: []byte{1, 3, 3, 2},
+		},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var e Encoder
+			if err := e.InitWithOptions(len(row.freqs), row.freqs, row.opts); err != nil {
+				t.Fatalf("InitWithOptions: unexpected error: %v", err)
+			}
+			if actual := e.SizeBySymbol(); !bytes.Equal(row.sizes, actual) {
+				t.Errorf("expected sizes %v, got %v", row.sizes, actual)
+			}
+		})
+	}
+
+	var e Encoder
+	if err := e.InitWithOptions(2, []uint32{1, 1}, Options{FrequencyQuantization: 99}); err == nil {
+		t.Errorf("InitWithOptions: expected error for invalid FrequencyQuantization, got nil")
+	}
+	if err := e.InitWithOptions(2, []uint32{1, 1}, Options{QuantizeKeep: -1}); err == nil {
+		t.Errorf("InitWithOptions: expected error for negative QuantizeKeep, got nil")
+	}
+}
+
+func TestQuantizeThreshold(t *testing.T) {
+	rng := rand.New(rand.NewSource(164))
+	for trial := 0; trial < 100; trial++ {
+		nodes := make([]symbolAndFreq, 1+rng.Intn(100))
+		for index := range nodes {
+			nodes[index] = symbolAndFreq{Symbol(index), 1 + uint32(rng.Intn(1000))}
+		}
+		keep := 1 + rng.Intn(20)
+		threshold, _ := quantizeThreshold(nodes, keep, nil)
+
+		var above, atOrAbove int
+		for _, node := range nodes {
+			if node.freq > threshold {
+				above++
+			}
+			if node.freq >= threshold {
+				atOrAbove++
+			}
+		}
+		if len(nodes) <= keep {
+			if threshold != 0 {
+				t.Errorf("trial %d: expected 0 for %d nodes, keep %d, got %d", trial, len(nodes), keep, threshold)
+			}
+			continue
+		}
+		if above >= keep || atOrAbove < keep {
+			t.Errorf("trial %d: threshold %d: %d above, %d at or above, keep %d", trial, threshold, above, atOrAbove, keep)
+		}
+	}
+}
+
+func TestQuantizationPenalty(t *testing.T) {
+	rng := rand.New(rand.NewSource(1640))
+	freqs := make([]uint32, 256)
+	for index := range freqs {
+		freqs[index] = uint32(rng.ExpFloat64() * 500)
+	}
+
+	if actual := QuantizationPenalty(freqs, Options{}); actual != 1 {
+		t.Errorf("%v: expected 1, got %g", QuantizeNone, actual)
+	}
+	for _, q := range []FrequencyQuantization{QuantizePowerOfTwo, QuantizeTail} {
+		actual := QuantizationPenalty(freqs, Options{FrequencyQuantization: q})
+		if actual < 1 || actual > 1.05 {
+			t.Errorf("%v: expected penalty in [1, 1.05], got %g", q, actual)
+		}
+	}
+	if actual := QuantizationPenalty(make([]uint32, 4), Options{FrequencyQuantization: QuantizePowerOfTwo}); actual != 1 {
+		t.Errorf("all zero: expected 1, got %g", actual)
+	}
+}
+
+func TestOptions_MaxFrequencyRatio(t *testing.T) {
+	// Fibonacci frequencies give the deepest possible tree.
+	freqs := make([]uint32, 40)
+	freqs[0], freqs[1] = 1, 1
+	for index := 2; index < len(freqs); index++ {
+		freqs[index] = freqs[index-1] + freqs[index-2]
+	}
+	freqs = append(freqs, 4000000000)
+
+	type testRow struct {
+		ratio   uint32
+		maxSize byte
+	}
+
+	testData := [...]testRow{
+		{ratio: 0, maxSize: 40},
+		{ratio: 1 << 20, maxSize: 15},
+		{ratio: 1 << 10, maxSize: 8},
+		{ratio: 1, maxSize: 6},
+	}
+	for _, row := range testData {
+		var e Encoder
+		if err := e.InitWithOptions(len(freqs), freqs, Options{MaxFrequencyRatio: row.ratio}); err != nil {
+			t.Fatalf("ratio %d: InitWithOptions: unexpected error: %v", row.ratio, err)
+		}
+		if actual := e.MaxSize(); actual != row.maxSize {
+			t.Errorf("ratio %d: expected MaxSize %d, got %d", row.ratio, row.maxSize, actual)
+		}
+	}
+
+	nodes := []symbolAndFreq{{0, 1000}, {1, 1}, {2, 99}, {3, 100}}
+	clampFrequencyRatio(nodes, 10)
+	expect := []symbolAndFreq{{0, 1000}, {1, 100}, {2, 100}, {3, 100}}
+	for index := range nodes {
+		if nodes[index] != expect[index] {
+			t.Errorf("clampFrequencyRatio: expected %v, got %v", expect, nodes)
+			break
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package huffman
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// DictEntry is a dictionary which has been loaded into a DictRegistry.
+type DictEntry struct {
+	// ID is the dictionary's identifier within the stream format.
+	ID byte
+
+	// Dict is the dictionary itself.
+	Dict Dict
+
+	// Encoder and Decoder are ready-to-use coders for the dictionary's
+	// code.  They must not be modified.
+	Encoder *Encoder
+	Decoder *Decoder
+
+	// Fingerprint is the dictionary's Dict.Fingerprint.
+	Fingerprint uint64
+}
+
+// DictRegistry holds a set of dictionaries, keyed by a one-byte ID, for use
+// with the stream format.
+//
+// A Writer configured with a DictRegistry codes each block with the
+// registry's current dictionary whenever that is cheaper than transmitting a
+// new table, tagging the block with the dictionary's ID.  A Reader configured
+// with the same registry looks up each tagged block's dictionary by ID.
+// Because older IDs can stay registered after a newer dictionary becomes
+// current, data written before an upgrade remains decodable.
+//
+// All methods are safe to call concurrently.  Register, Unregister,
+// SetCurrent, and Reload each replace the registry's contents atomically, so
+// a concurrent lookup sees either the old state or the new one, never a mix.
+//
+type DictRegistry struct {
+	mu    sync.Mutex
+	state atomic.Value
+}
+
+type dictRegistryState struct {
+	entries    map[byte]*DictEntry
+	current    byte
+	hasCurrent bool
+}
+
+// NewDictRegistry returns a new, empty DictRegistry.
+func NewDictRegistry() *DictRegistry {
+	reg := new(DictRegistry)
+	reg.state.Store(&dictRegistryState{})
+	return reg
+}
+
+func (reg *DictRegistry) load() *dictRegistryState {
+	state, _ := reg.state.Load().(*dictRegistryState)
+	if state == nil {
+		state = &dictRegistryState{}
+	}
+	return state
+}
+
+// Register adds a dictionary with the given ID, replacing any existing
+// dictionary with that ID.  The dictionary must be usable with the stream
+// format, i.e. it must not have more than StreamAlphabetSize symbols and it
+// must assign a code to EndOfBlock.
+func (reg *DictRegistry) Register(id byte, dict Dict) error {
+	entry, err := newDictEntry(id, dict)
+	if err != nil {
+		return err
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	old := reg.load()
+	state := old.clone()
+	state.entries[id] = entry
+	reg.state.Store(state)
+	return nil
+}
+
+// Unregister removes the dictionary with the given ID, if any.  If it was the
+// current dictionary, the registry is left with no current dictionary.
+func (reg *DictRegistry) Unregister(id byte) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	old := reg.load()
+	state := old.clone()
+	delete(state.entries, id)
+	if state.hasCurrent && state.current == id {
+		state.hasCurrent = false
+		state.current = 0
+	}
+	reg.state.Store(state)
+}
+
+// SetCurrent selects the registered dictionary with the given ID as the one
+// Writers should use.
+func (reg *DictRegistry) SetCurrent(id byte) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	old := reg.load()
+	if _, found := old.entries[id]; !found {
+		return fmt.Errorf("no dictionary registered with ID %d", id)
+	}
+	state := old.clone()
+	state.current = id
+	state.hasCurrent = true
+	reg.state.Store(state)
+	return nil
+}
+
+// Reload atomically replaces the registry's entire contents with the given
+// dictionaries and selects current as the current dictionary.  If any
+// dictionary is invalid, the registry is left unchanged.
+func (reg *DictRegistry) Reload(dicts map[byte]Dict, current byte) error {
+	state := &dictRegistryState{
+		entries:    make(map[byte]*DictEntry, len(dicts)),
+		current:    current,
+		hasCurrent: true,
+	}
+	for id, dict := range dicts {
+		entry, err := newDictEntry(id, dict)
+		if err != nil {
+			return err
+		}
+		state.entries[id] = entry
+	}
+	if _, found := state.entries[current]; !found {
+		return fmt.Errorf("no dictionary registered with ID %d", current)
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.state.Store(state)
+	return nil
+}
+
+// Lookup returns the dictionary with the given ID.
+func (reg *DictRegistry) Lookup(id byte) (*DictEntry, bool) {
+	entry, found := reg.load().entries[id]
+	return entry, found
+}
+
+// Current returns the current dictionary.
+func (reg *DictRegistry) Current() (*DictEntry, bool) {
+	state := reg.load()
+	if !state.hasCurrent {
+		return nil, false
+	}
+	entry, found := state.entries[state.current]
+	return entry, found
+}
+
+// IDs returns the IDs of all registered dictionaries, in ascending order.
+func (reg *DictRegistry) IDs() []byte {
+	state := reg.load()
+	out := make([]byte, 0, len(state.entries))
+	for id := range state.entries {
+		out = append(out, id)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// Snapshot returns a new DictRegistry holding the registry's current
+// contents.  Later changes to either registry do not affect the other, so a
+// Writer given the snapshot keeps using the dictionary that is current now.
+func (reg *DictRegistry) Snapshot() *DictRegistry {
+	snap := new(DictRegistry)
+	snap.state.Store(reg.load())
+	return snap
+}
+
+func (state *dictRegistryState) clone() *dictRegistryState {
+	dupe := &dictRegistryState{
+		entries:    make(map[byte]*DictEntry, len(state.entries)+1),
+		current:    state.current,
+		hasCurrent: state.hasCurrent,
+	}
+	for id, entry := range state.entries {
+		dupe.entries[id] = entry
+	}
+	return dupe
+}
+
+func newDictEntry(id byte, dict Dict) (*DictEntry, error) {
+	if err := dict.Validate(); err != nil {
+		return nil, err
+	}
+	if dict.Remap != nil {
+		return nil, fmt.Errorf("%w: dictionary %d: remapped dictionaries cannot be used with the stream format", ErrDict, id)
+	}
+	if len(dict.Sizes) > StreamAlphabetSize {
+		return nil, fmt.Errorf("%w: dictionary %d: too many symbols: got %d, max %d", ErrDict, id, len(dict.Sizes), StreamAlphabetSize)
+	}
+	if len(dict.Sizes) <= int(EndOfBlock) || dict.Sizes[EndOfBlock] == 0 {
+		return nil, fmt.Errorf("%w: dictionary %d: no code for EndOfBlock", ErrDict, id)
+	}
+
+	e, err := dict.Encoder()
+	if err != nil {
+		return nil, fmt.Errorf("%w: dictionary %d: %v", ErrDict, id, err)
+	}
+	d, err := dict.Decoder()
+	if err != nil {
+		return nil, fmt.Errorf("%w: dictionary %d: %v", ErrDict, id, err)
+	}
+	return &DictEntry{ID: id, Dict: dict, Encoder: e, Decoder: d, Fingerprint: dict.Fingerprint()}, nil
+}
@@ -0,0 +1,106 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDictRegistry_Stream(t *testing.T) {
+	sample1 := []byte(strings.Repeat("GET /index.html HTTP/1.1\r\n", 20))
+	sample2 := []byte(strings.Repeat("POST /api/v2/items HTTP/1.1\r\n", 20))
+
+	reg := NewDictRegistry()
+	if err := reg.Reload(map[byte]Dict{1: TrainStreamDict(sample1)}, 1); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+
+	compress := func(input []byte) []byte {
+		var buf bytes.Buffer
+		zw := NewWriterOptions(&buf, WriterOptions{Dicts: reg})
+		_, _ = zw.Write(input)
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Close: unexpected error: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	input := []byte("GET /index.html HTTP/1.1\r\n")
+	old := compress(input)
+	if blockType := old[6]; blockType != blockTypeDict || old[7] != 1 {
+		t.Errorf("expected a block using dictionary 1, got type 0x%02x", blockType)
+	}
+
+	// Upgrade to a new dictionary, keeping the old one for decoding.
+	if err := reg.Register(2, TrainStreamDict(sample2)); err != nil {
+		t.Fatalf("Register: unexpected error: %v", err)
+	}
+	if err := reg.SetCurrent(2); err != nil {
+		t.Fatalf("SetCurrent: unexpected error: %v", err)
+	}
+	newer := compress([]byte("POST /api/v2/items HTTP/1.1\r\n"))
+	if newer[7] != 2 {
+		t.Errorf("expected dictionary 2, got %d", newer[7])
+	}
+
+	for _, stream := range [][]byte{old, newer} {
+		zr, err := NewReader(bytes.NewReader(stream))
+		if err != nil {
+			t.Fatalf("NewReader: unexpected error: %v", err)
+		}
+		zr.UseDicts(reg)
+		if _, err := io.ReadAll(zr); err != nil {
+			t.Errorf("ReadAll: unexpected error: %v", err)
+		}
+	}
+
+	zr, err := NewReader(bytes.NewReader(old))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	if _, err := io.ReadAll(zr); err == nil {
+		t.Errorf("ReadAll: expected error for unknown dictionary, got nil")
+	}
+
+	if expect, actual := "[1 2]", fmt.Sprint(reg.IDs()); expect != actual {
+		t.Errorf("IDs: expected %s, got %s", expect, actual)
+	}
+}
+
+func TestDictRegistry_Invalid(t *testing.T) {
+	reg := NewDictRegistry()
+	if err := reg.Register(1, Dict{Sizes: []byte{1, 1}}); err == nil {
+		t.Errorf("Register: expected error for dictionary without EndOfBlock, got nil")
+	}
+	if err := reg.SetCurrent(1); err == nil {
+		t.Errorf("SetCurrent: expected error for unknown ID, got nil")
+	}
+	if _, found := reg.Current(); found {
+		t.Errorf("Current: expected no current dictionary")
+	}
+}
+
+func TestDictRegistry_Snapshot(t *testing.T) {
+	reg := NewDictRegistry()
+	if err := reg.Reload(map[byte]Dict{1: TrainStreamDict([]byte("aaaa")), 2: TrainStreamDict([]byte("bbbb"))}, 1); err != nil {
+		t.Fatalf("Reload: unexpected error: %v", err)
+	}
+
+	snap := reg.Snapshot()
+	if err := reg.SetCurrent(2); err != nil {
+		t.Fatalf("SetCurrent: unexpected error: %v", err)
+	}
+	reg.Unregister(1)
+
+	if entry, found := snap.Current(); !found || entry.ID != 1 {
+		t.Errorf("Current: expected dictionary 1 in the snapshot, got %v, %v", entry, found)
+	}
+	if _, found := snap.Lookup(1); !found {
+		t.Errorf("Lookup: expected dictionary 1 to remain in the snapshot")
+	}
+	if entry, found := reg.Current(); !found || entry.ID != 2 {
+		t.Errorf("Current: expected dictionary 2 in the registry, got %v, %v", entry, found)
+	}
+}
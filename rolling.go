@@ -0,0 +1,117 @@
+package huffman
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+// RollingHistogram counts the Symbols within a sliding window of the most
+// recent ones, so that a code rebuilt from its Frequencies reflects recent
+// data rather than all of history.  It keeps the window in a ring buffer, so
+// its counts are exact; each Add takes O(1) time.
+//
+// A typical use is to Add each Symbol as it is coded and, every so often,
+// rebuild the code with Builder.Build from the Frequencies.
+//
+// A RollingHistogram is not safe for concurrent use.
+//
+type RollingHistogram struct {
+	counts []uint32
+	ring   []Symbol
+	next   int
+	full   bool
+	total  uint64
+}
+
+// NewRollingHistogram returns a new, empty RollingHistogram for numSymbols
+// Symbols, which counts the last window Symbols added.
+func NewRollingHistogram(numSymbols int, window int) *RollingHistogram {
+	if numSymbols <= 0 || numSymbols > int(MaxSymbol)+1 {
+		panic(fmt.Errorf("NewRollingHistogram: numSymbols %d out of range", numSymbols))
+	}
+	if window <= 0 || uint64(window) > math.MaxUint32 {
+		panic(fmt.Errorf("NewRollingHistogram: window %d out of range", window))
+	}
+	return &RollingHistogram{
+		counts: make([]uint32, numSymbols),
+		ring:   make([]Symbol, window),
+	}
+}
+
+// Add counts the given Symbol, and forgets the oldest Symbol in the window
+// if the window is full.
+func (h *RollingHistogram) Add(symbol Symbol) {
+	assert.Assertf(symbol >= 0 && int(symbol) < len(h.counts), "symbol %d not in range [0..%d)", symbol, len(h.counts))
+	if h.full {
+		h.counts[h.ring[h.next]]--
+	}
+	h.ring[h.next] = symbol
+	h.counts[symbol]++
+	h.total++
+	h.next++
+	if h.next == len(h.ring) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// AddSymbols calls Add for each of the given Symbols in turn.
+func (h *RollingHistogram) AddSymbols(symbols []Symbol) {
+	for _, symbol := range symbols {
+		h.Add(symbol)
+	}
+}
+
+// NumSymbols returns the number of Symbols in the alphabet.
+func (h *RollingHistogram) NumSymbols() int {
+	return len(h.counts)
+}
+
+// Window returns the size of the window.
+func (h *RollingHistogram) Window() int {
+	return len(h.ring)
+}
+
+// Len returns the number of Symbols in the window, which is less than Window
+// only until the window first fills.
+func (h *RollingHistogram) Len() int {
+	if h.full {
+		return len(h.ring)
+	}
+	return h.next
+}
+
+// Total returns the number of Symbols added since the RollingHistogram was
+// created or Reset, including those which have left the window.
+func (h *RollingHistogram) Total() uint64 {
+	return h.total
+}
+
+// Count returns the number of times the given Symbol occurs in the window.
+func (h *RollingHistogram) Count(symbol Symbol) uint32 {
+	if symbol < 0 || int(symbol) >= len(h.counts) {
+		return 0
+	}
+	return h.counts[symbol]
+}
+
+// Frequencies returns the number of times each Symbol occurs in the window,
+// in a form suitable for Encoder.Init or Builder.Build.  The result is a
+// copy, which later calls to Add do not change.
+func (h *RollingHistogram) Frequencies() []uint32 {
+	out := make([]uint32, len(h.counts))
+	copy(out, h.counts)
+	return out
+}
+
+// Reset empties the window.
+func (h *RollingHistogram) Reset() {
+	for index := range h.counts {
+		h.counts[index] = 0
+	}
+	h.next = 0
+	h.full = false
+	h.total = 0
+}
@@ -0,0 +1,60 @@
+package huffman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRollingHistogram(t *testing.T) {
+	h := NewRollingHistogram(4, 5)
+	if h.NumSymbols() != 4 || h.Window() != 5 || h.Len() != 0 {
+		t.Fatalf("new: expected 4 symbols, window 5, len 0; got %d, %d, %d", h.NumSymbols(), h.Window(), h.Len())
+	}
+
+	type testRow struct {
+		add    []Symbol
+		expect []uint32
+		len    int
+	}
+
+	testData := [...]testRow{
+		{add: []Symbol{0, 0, 1}, expect: []uint32{2, 1, 0, 0}, len: 3},
+		{add: []Symbol{2, 3}, expect: []uint32{2, 1, 1, 1}, len: 5},
+		{add: []Symbol{3}, expect: []uint32{1, 1, 1, 2}, len: 5},
+		{add: []Symbol{3, 3, 3, 3}, expect: []uint32{0, 0, 0, 5}, len: 5},
+		{add: []Symbol{1, 2, 1, 2, 1, 2, 1}, expect: []uint32{0, 3, 2, 0}, len: 5},
+	}
+	total := 0
+	for index, row := range testData {
+		h.AddSymbols(row.add)
+		total += len(row.add)
+		if actual := h.Frequencies(); !reflect.DeepEqual(row.expect, actual) {
+			t.Errorf("step %d: expected %v, got %v", index, row.expect, actual)
+		}
+		if actual := h.Len(); actual != row.len {
+			t.Errorf("step %d: Len: expected %d, got %d", index, row.len, actual)
+		}
+		if actual := h.Total(); actual != uint64(total) {
+			t.Errorf("step %d: Total: expected %d, got %d", index, total, actual)
+		}
+	}
+
+	if h.Count(1) != 3 || h.Count(-1) != 0 || h.Count(4) != 0 {
+		t.Errorf("Count: expected 3, 0, 0; got %d, %d, %d", h.Count(1), h.Count(-1), h.Count(4))
+	}
+
+	freqs := h.Frequencies()
+	h.Add(0)
+	if freqs[0] != 0 {
+		t.Errorf("Frequencies: result changed by a later Add")
+	}
+
+	h.Reset()
+	if h.Len() != 0 || h.Total() != 0 || !reflect.DeepEqual(h.Frequencies(), []uint32{0, 0, 0, 0}) {
+		t.Errorf("Reset: expected an empty histogram, got len %d, total %d, %v", h.Len(), h.Total(), h.Frequencies())
+	}
+	h.AddSymbols([]Symbol{2, 2, 2, 2, 2, 2})
+	if actual := h.Frequencies(); !reflect.DeepEqual(actual, []uint32{0, 0, 5, 0}) {
+		t.Errorf("after Reset: expected [0 0 5 0], got %v", actual)
+	}
+}
@@ -0,0 +1,122 @@
+package huffman
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// InitStable initializes this Encoder with a code for the given frequencies,
+// one per Symbol of the alphabet, which keeps as many code lengths from prev
+// as it can while costing at most a fraction epsilon more than the optimal
+// code.  Streaming systems which send each new table as a diff against the
+// previous one can use it to keep the diffs small.  No code is longer than
+// 16 bits.
+//
+// If prev's code is within budget as a whole, and gives a code to every
+// Symbol with a non-zero frequency, it is reused unchanged.  Otherwise, the
+// optimal code is built, and then Symbols are given back their lengths from
+// prev one at a time, cheapest first, for as long as the code stays a prefix
+// code and the total cost stays within budget.  If prev is nil, the optimal
+// code is used.
+//
+// Returns an error if epsilon is negative or if there are more than 65536
+// frequencies.
+//
+func (e *Encoder) InitStable(prev *Encoder, frequencies []uint32, epsilon float64) error {
+	if !(epsilon >= 0) {
+		return fmt.Errorf("epsilon %g < 0", epsilon)
+	}
+	numSymbols := len(frequencies)
+	if numSymbols > 1<<maxBitsPerCode {
+		return fmt.Errorf("%d symbols cannot fit in codes of at most %d bits", numSymbols, maxBitsPerCode)
+	}
+	if numSymbols == 0 {
+		*e = Encoder{}
+		return nil
+	}
+
+	var optimal Encoder
+	initLimited(&optimal, numSymbols, frequencies)
+	if prev == nil {
+		*e = optimal
+		return nil
+	}
+
+	sizes := optimal.SizeBySymbol()
+	prevSizes := make([]byte, numSymbols)
+	copy(prevSizes, prev.SizeBySymbol())
+
+	var optimalCost, prevCost uint64
+	prevCovers := true
+	for symbol, freq := range frequencies {
+		optimalCost += uint64(freq) * uint64(sizes[symbol])
+		prevCost += uint64(freq) * uint64(prevSizes[symbol])
+		if (freq != 0 && prevSizes[symbol] == 0) || prevSizes[symbol] > maxBitsPerCode {
+			prevCovers = false
+		}
+	}
+	budget := uint64(float64(optimalCost) * epsilon)
+
+	if prevCovers && prevCost <= optimalCost+budget {
+		return e.InitFromSizes(prevSizes)
+	}
+
+	// Each candidate is a Symbol whose length could be reverted to its
+	// length in prev, at the given change in cost.  Reverting to a longer
+	// length frees code space, which reverting to a shorter length needs,
+	// so two passes are made.
+
+	type candidate struct {
+		symbol Symbol
+		delta  int64
+	}
+	var candidates []candidate
+	for symbol, freq := range frequencies {
+		size, prevSize := sizes[symbol], prevSizes[symbol]
+		if prevSize == 0 || prevSize == size || prevSize > maxBitsPerCode {
+			continue
+		}
+		delta := int64(freq) * (int64(prevSize) - int64(size))
+		candidates = append(candidates, candidate{Symbol(symbol), delta})
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if c := cmp.Compare(a.delta, b.delta); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.symbol, b.symbol)
+	})
+
+	var used int64
+	for _, size := range sizes {
+		used += kraftUnits(size)
+	}
+
+	var spent int64
+	for pass := 0; pass < 2; pass++ {
+		for _, c := range candidates {
+			size, prevSize := sizes[c.symbol], prevSizes[c.symbol]
+			if size == prevSize {
+				continue
+			}
+			newUsed := used - kraftUnits(size) + kraftUnits(prevSize)
+			if newUsed > 1<<maxBitsPerCode || spent+c.delta > int64(budget) {
+				continue
+			}
+			sizes[c.symbol] = prevSize
+			used = newUsed
+			spent += c.delta
+		}
+	}
+	return e.InitFromSizes(sizes)
+}
+
+// kraftUnits returns the code space taken by a code of the given size, or 0
+// if size is 0.  The code space is measured in units of 2**-maxBitsPerCode,
+// so a complete code uses exactly 1<<maxBitsPerCode units.
+func kraftUnits(size byte) int64 {
+	if size == 0 {
+		return 0
+	}
+	return int64(1) << (maxBitsPerCode - size)
+}
@@ -0,0 +1,85 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestEncoder_InitStable(t *testing.T) {
+	prev := NewEncoder(4, []uint32{10, 6, 2, 2})
+	if expect, actual := []byte{1, 2, 3, 3}, prev.SizeBySymbol(); !bytes.Equal(expect, actual) {
+		t.Fatalf("prev: expected sizes %v, got %v", expect, actual)
+	}
+
+	type testRow struct {
+		name    string
+		prev    *Encoder
+		freqs   []uint32
+		epsilon float64
+		sizes   []byte
+	}
+
+	testData := [...]testRow{
+		{name: "nil-prev", prev: nil, freqs: []uint32{5, 5, 5, 5}, epsilon: 1, sizes: []byte{2, 2, 2, 2}},
+		{name: "keep", prev: prev, freqs: []uint32{5, 5, 5, 5}, epsilon: 0.25, sizes: []byte{1, 2, 3, 3}},
+		{name: "replace", prev: prev, freqs: []uint32{5, 5, 5, 5}, epsilon: 0, sizes: []byte{2, 2, 2, 2}},
+		{name: "uncovered", prev: prev, freqs: []uint32{10, 6, 2, 2, 1}, epsilon: 1, sizes: []byte{1, 2, 4, 3, 4}},
+		{name: "unused", prev: prev, freqs: []uint32{10, 6, 0, 2}, epsilon: 0.1, sizes: []byte{1, 2, 3, 3}},
+		{name: "unused-tight", prev: prev, freqs: []uint32{10, 6, 0, 2}, epsilon: 0, sizes: []byte{1, 2, 0, 2}},
+		{name: "partial", prev: NewEncoder(6, []uint32{1, 1, 1, 1, 1, 1}), freqs: []uint32{8, 8, 4, 4, 2, 1}, epsilon: 0, sizes: []byte{2, 2, 3, 2, 4, 4}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var e Encoder
+			if err := e.InitStable(row.prev, row.freqs, row.epsilon); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := e.SizeBySymbol(); !bytes.Equal(row.sizes, actual) {
+				t.Errorf("expected sizes %v, got %v", row.sizes, actual)
+			}
+		})
+	}
+
+	var e Encoder
+	if err := e.InitStable(prev, []uint32{1, 1}, -1); err == nil {
+		t.Errorf("expected error for negative epsilon, got nil")
+	}
+}
+
+func TestEncoder_InitStableRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(165))
+	freqs := make([]uint32, 64)
+	for index := range freqs {
+		freqs[index] = uint32(rng.ExpFloat64() * 100)
+	}
+	prev := NewEncoder(len(freqs), freqs)
+
+	for trial := 0; trial < 100; trial++ {
+		for index := range freqs {
+			freqs[index] = uint32(int(freqs[index]) + rng.Intn(21) - 10)
+			if int32(freqs[index]) < 0 {
+				freqs[index] = 0
+			}
+		}
+		optimal, _ := BuildSizes(freqs, maxBitsPerCode)
+
+		var e Encoder
+		if err := e.InitStable(prev, freqs, 0.01); err != nil {
+			t.Fatalf("trial %d: unexpected error: %v", trial, err)
+		}
+		var optimalCost, actualCost uint64
+		for symbol, freq := range freqs {
+			size := e.Encode(Symbol(symbol)).Size
+			if freq != 0 && size == 0 {
+				t.Fatalf("trial %d: symbol %d has no code", trial, symbol)
+			}
+			optimalCost += uint64(freq) * uint64(optimal[symbol])
+			actualCost += uint64(freq) * uint64(size)
+		}
+		if float64(actualCost) > float64(optimalCost)*1.01 {
+			t.Fatalf("trial %d: cost %d exceeds budget over optimal cost %d", trial, actualCost, optimalCost)
+		}
+		prev = &e
+	}
+}
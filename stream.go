@@ -0,0 +1,1124 @@
+package huffman
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+
+	"github.com/chronos-tachyon/assert"
+)
+
+// The stream format produced by Writer and consumed by Reader is:
+//
+//     stream := header block* end
+//     header := "HUFF" version:byte flags:byte
+//     block  := 0x01 sizes:RLE payload checksum?
+//             | 0x02 dict:byte payload checksum?
+//             | 0x03 streams:byte sizes:RLE count:uvarint interleaved checksum?
+//             | 0x04 payload checksum?
+//             | sync
+//     sync   := 0x05 "SYNC" 0x8e 0x3b 0xd1 block:uvarint offset:uvarint
+//     end    := 0x00 length:uint64le? index?
+//
+// Each payload is a sequence of Huffman codes over the byte alphabet plus
+// EndOfBlock, least significant bit first, terminated by the code for
+// EndOfBlock and padded with zero bits to the next byte boundary.  Because
+// every block carries its own terminator, no out-of-band length is needed.
+// Blocks of type 0x02 use the code of a shared dictionary, identified by its
+// ID within a DictRegistry, instead of carrying a size table.  Blocks of type
+// 0x03 hold count bytes split across several streams in the format described
+// at EncodeInterleaved, with no EndOfBlock code.  Blocks of type 0x04 reuse
+// the code of the previous block in the same stream, whatever its type.
+// Each size table is written by the same code as EncodeInts uses, which
+// lists the Symbols explicitly when a code has at most 4 of them and that is
+// shorter than the usual run-length encoding.
+//
+// A sync marker is not a block; it records the number of the block which
+// follows it and the total decompressed length of the blocks before it, so
+// that Reader.Resync can find a known position after damage to the stream.
+// The block after a sync marker never has type 0x04.
+//
+// The low 2 bits of flags hold the Checksum used for every block, if any,
+// which is computed over the block's decompressed contents and stored in
+// little-endian order.  Bit 2 of flags indicates the presence of the total
+// decompressed length after the end-of-stream marker.  Bit 3 of flags
+// indicates the presence of a block index after that; see IndexedReader.  Bit
+// 4 of flags indicates that the stream may contain sync markers.  Bit 5 of
+// flags indicates that everything after the header is encrypted with a
+// caller-supplied cipher.Stream, and bit 6 that the decompressed data must be
+// passed through caller-supplied Transforms; see WriterOptions.  All other
+// bits of flags must be zero.
+//
+
+// EndOfBlock is the Symbol which terminates each block in the stream format.
+// It is the first Symbol after the 256 byte values.
+const EndOfBlock = Symbol(256)
+
+// StreamAlphabetSize is the number of Symbols in the stream format's
+// alphabet: the 256 byte values plus EndOfBlock.
+const StreamAlphabetSize = 257
+
+// DefaultBlockSize is the default number of input bytes per block.
+const DefaultBlockSize = 1 << 16
+
+const (
+	streamVersion = 1
+
+	blockTypeEnd     = 0x00
+	blockTypeHuffman = 0x01
+	blockTypeDict    = 0x02
+	blockTypeStreams = 0x03
+	blockTypeRepeat  = 0x04
+	blockTypeSync    = 0x05
+
+	flagChecksumMask  = 0x03
+	flagLengthTrailer = 0x04
+	flagIndex         = 0x08
+	flagSync          = 0x10
+	flagEncrypted     = 0x20
+	flagTransformed   = 0x40
+	flagsKnown        = flagChecksumMask | flagLengthTrailer | flagIndex | flagSync | flagEncrypted | flagTransformed
+)
+
+var streamMagic = [4]byte{'H', 'U', 'F', 'F'}
+
+// syncMarker begins every sync marker.  The bytes after the block type were
+// chosen to be unlikely in Huffman-coded data.
+var syncMarker = [8]byte{blockTypeSync, 'S', 'Y', 'N', 'C', 0x8e, 0x3b, 0xd1}
+
+var (
+	// ErrHeader is returned when reading a stream with an invalid header.
+	ErrHeader = errors.New("invalid Huffman stream header")
+
+	// ErrCorrupt is returned when reading a stream with invalid block data.
+	ErrCorrupt = errors.New("corrupt Huffman stream")
+)
+
+// WithEOB returns a copy of frequencies which is long enough to include eob
+// and which assigns eob a non-zero frequency, guaranteeing that eob will be
+// assigned a code by Encoder.Init.
+func WithEOB(frequencies []uint32, eob Symbol) []uint32 {
+	assert.Assertf(eob >= 0, "eob %d < 0", eob)
+
+	length := len(frequencies)
+	if length <= int(eob) {
+		length = int(eob) + 1
+	}
+	out := make([]uint32, length)
+	copy(out, frequencies)
+	if out[eob] == 0 {
+		out[eob] = 1
+	}
+	return out
+}
+
+// WriterOptions holds optional settings for a Writer.
+type WriterOptions struct {
+	// BlockSize is the maximum number of input bytes per block.  If zero,
+	// DefaultBlockSize is used.
+	BlockSize int
+
+	// Checksum selects the checksum to append to each block.
+	Checksum Checksum
+
+	// LengthTrailer, if true, appends the total decompressed length of the
+	// stream after the end-of-stream marker.
+	LengthTrailer bool
+
+	// Index, if true, appends a block index to the end of the stream,
+	// allowing random access via IndexedReader.
+	Index bool
+
+	// Dicts, if non-nil, supplies a shared dictionary for the Writer to
+	// use instead of a per-block table whenever that is smaller.  The
+	// registry's current dictionary is consulted for every block, so
+	// changes to the registry take effect at the next block.
+	Dicts *DictRegistry
+
+	// Streams, if greater than 1, splits the codes of each block across
+	// that many interleaved streams, which can be decoded faster.  It may
+	// be at most MaxInterleavedStreams.  Blocks which use a shared
+	// dictionary are not interleaved.
+	Streams int
+
+	// ReuseTables, if true, lets a block reuse the code of the previous
+	// block instead of carrying its own, whenever that is smaller (see
+	// CostOfReuse).  This helps most with homogeneous data in small
+	// blocks.  It is ignored if Index is set, so that every block can
+	// still be decoded on its own.  Readers older than this option cannot
+	// read such streams.
+	ReuseTables bool
+
+	// SyncInterval, if greater than 0, writes a sync marker before every
+	// SyncInterval'th block, from which a Reader can recover after damage
+	// to earlier blocks (see Reader.Resync).  Each marker costs about 10
+	// bytes, and the block after it cannot reuse the previous code.
+	// Readers older than this option cannot read such streams.
+	SyncInterval int
+
+	// Transforms, if non-empty, are applied in order to the data of each
+	// block before it is compressed.  The checksum of each block covers
+	// the transformed data.  Readers must be given the same Transforms
+	// with Reader.UseTransforms.  Close and Reset reset them.  If the
+	// Transforms make a block of at least 1KiB look like random data,
+	// the Writer fails with ErrEncryptBeforeCompress.
+	Transforms []Transform
+
+	// Cipher, if non-nil, encrypts everything written after the stream
+	// header, which is left readable so that a Reader can tell that it
+	// needs a cipher.  Readers must be given a cipher.Stream in the same
+	// state with Reader.UseCipher.  Reset keeps using the same
+	// cipher.Stream, continuing its keystream.  The Cipher provides
+	// confidentiality only; combine it with a Checksum, or better an
+	// authenticated encryption of the whole output, to detect tampering.
+	Cipher cipher.Stream
+}
+
+// Writer is an io.WriteCloser which compresses data into the stream format.
+// Data is buffered into blocks, each with its own Huffman code.
+type Writer struct {
+	bw          BitWriter
+	buf         []byte
+	opts        WriterOptions
+	total       uint64
+	blocks      int
+	index       []indexEntry
+	builder     Builder
+	enc         Encoder
+	freqs       []uint32
+	prev        Encoder
+	hasPrev     bool
+	transformed bool
+	wroteHeader bool
+	closed      bool
+	err         error
+}
+
+// NewWriter returns a new Writer that writes compressed data to w using
+// DefaultBlockSize.
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterOptions(w, WriterOptions{})
+}
+
+// NewWriterSize returns a new Writer that writes compressed data to w, with
+// up to blockSize bytes of input per block.
+func NewWriterSize(w io.Writer, blockSize int) *Writer {
+	if blockSize < 1 {
+		panic(fmt.Errorf("NewWriterSize: blockSize %d < 1", blockSize))
+	}
+	return NewWriterOptions(w, WriterOptions{BlockSize: blockSize})
+}
+
+// NewWriterOptions returns a new Writer that writes compressed data to w,
+// configured by the given options.
+func NewWriterOptions(w io.Writer, opts WriterOptions) *Writer {
+	if opts.BlockSize == 0 {
+		opts.BlockSize = DefaultBlockSize
+	}
+	assert.Assertf(opts.BlockSize >= 1, "BlockSize %d < 1", opts.BlockSize)
+	assert.Assertf(opts.Checksum.isValid(), "unknown Checksum %v", opts.Checksum)
+	assert.Assertf(opts.Streams >= 0 && opts.Streams <= MaxInterleavedStreams, "Streams %d not in range [0..%d]", opts.Streams, MaxInterleavedStreams)
+	assert.Assertf(opts.SyncInterval >= 0, "SyncInterval %d < 0", opts.SyncInterval)
+
+	zw := &Writer{opts: opts}
+	zw.init(w)
+	return zw
+}
+
+func (zw *Writer) init(w io.Writer) {
+	if zw.opts.Cipher != nil {
+		w = &cipherWriter{w: w, s: zw.opts.Cipher, plain: len(streamMagic) + 2}
+	}
+	for _, t := range zw.opts.Transforms {
+		t.Reset()
+	}
+	zw.bw.Init(w)
+}
+
+// Reset discards the Writer's state and makes it equivalent to the result of
+// NewWriterOptions with the original options, but writing to w instead.  The
+// Writer's buffers and the storage for its code tables are retained, so that
+// a Writer can be pooled and reused.
+func (zw *Writer) Reset(w io.Writer) {
+	*zw = Writer{
+		bw:      zw.bw,
+		buf:     zw.buf[:0],
+		opts:    zw.opts,
+		index:   zw.index[:0],
+		builder: zw.builder,
+		enc:     zw.enc,
+		freqs:   zw.freqs,
+		prev:    zw.prev,
+	}
+	zw.init(w)
+}
+
+// Write buffers p, compressing and writing out each block as it fills.
+func (zw *Writer) Write(p []byte) (int, error) {
+	if zw.err != nil {
+		return 0, zw.err
+	}
+	if zw.closed {
+		return 0, errors.New("write to closed Huffman stream")
+	}
+
+	var n int
+	for len(p) != 0 {
+		room := zw.opts.BlockSize - len(zw.buf)
+		if room > len(p) {
+			room = len(p)
+		}
+		zw.buf = append(zw.buf, p[:room]...)
+		p = p[room:]
+		n += room
+
+		if len(zw.buf) >= zw.opts.BlockSize {
+			if err := zw.writeBlock(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Flush compresses any buffered data as a block and writes all pending
+// output to the underlying io.Writer.
+func (zw *Writer) Flush() error {
+	if zw.err != nil {
+		return zw.err
+	}
+	if err := zw.writeBlock(); err != nil {
+		return err
+	}
+	return zw.setErr(zw.bw.Flush())
+}
+
+// Close flushes any buffered data and writes the end-of-stream marker.  It
+// does not close the underlying io.Writer.
+func (zw *Writer) Close() error {
+	if zw.closed || zw.err != nil {
+		return zw.err
+	}
+	if err := zw.writeBlock(); err != nil {
+		return err
+	}
+	zw.closed = true
+	if err := zw.writeHeader(); err != nil {
+		return err
+	}
+
+	zw.addIndexEntry()
+	end := []byte{blockTypeEnd}
+	if zw.opts.LengthTrailer {
+		var tmp [8]byte
+		binary.LittleEndian.PutUint64(tmp[:], zw.total)
+		end = append(end, tmp[:]...)
+	}
+	if zw.opts.Index {
+		end = appendIndex(end, zw.bw.BitsWritten()/8, zw.index)
+	}
+	if err := zw.setErr(zw.bw.WriteBytes(end)); err != nil {
+		return err
+	}
+	return zw.setErr(zw.bw.Flush())
+}
+
+func (zw *Writer) setErr(err error) error {
+	if zw.err == nil {
+		zw.err = err
+	}
+	return err
+}
+
+func (zw *Writer) addIndexEntry() {
+	if zw.opts.Index {
+		zw.index = append(zw.index, indexEntry{
+			compressed:   zw.bw.BitsWritten() / 8,
+			uncompressed: zw.total,
+		})
+	}
+}
+
+func (zw *Writer) writeHeader() error {
+	if zw.wroteHeader {
+		return nil
+	}
+	zw.wroteHeader = true
+
+	flags := byte(zw.opts.Checksum)
+	if zw.opts.LengthTrailer {
+		flags |= flagLengthTrailer
+	}
+	if zw.opts.Index {
+		flags |= flagIndex
+	}
+	if zw.opts.SyncInterval > 0 {
+		flags |= flagSync
+	}
+	if zw.opts.Cipher != nil {
+		flags |= flagEncrypted
+	}
+	if len(zw.opts.Transforms) != 0 {
+		flags |= flagTransformed
+	}
+	header := append(streamMagic[:], streamVersion, flags)
+	return zw.setErr(zw.bw.WriteBytes(header))
+}
+
+func (zw *Writer) writeBlock() error {
+	if zw.err != nil {
+		return zw.err
+	}
+	if len(zw.buf) == 0 {
+		return nil
+	}
+	if err := zw.writeHeader(); err != nil {
+		return err
+	}
+	if n := zw.opts.SyncInterval; n > 0 && zw.blocks > 0 && zw.blocks%n == 0 {
+		if err := zw.writeSync(); err != nil {
+			return err
+		}
+	}
+	if err := zw.setErr(zw.applyTransforms()); err != nil {
+		return err
+	}
+
+	if zw.freqs == nil {
+		zw.freqs = make([]uint32, StreamAlphabetSize)
+	}
+	frequencies := zw.freqs
+	for index := range frequencies {
+		frequencies[index] = 0
+	}
+	for _, b := range zw.buf {
+		frequencies[b]++
+	}
+	frequencies[EndOfBlock] = 1
+
+	// Build into the storage of the previous block's code.  Codes longer
+	// than maxBitsPerCode are rare enough to take the allocating path.
+	e := &zw.enc
+	zw.builder.Build(frequencies, e)
+	if e.MaxSize() > maxBitsPerCode {
+		initLimited(e, StreamAlphabetSize, frequencies)
+	}
+
+	block := []byte{blockTypeHuffman}
+	block = appendSizesRLE(block, e.SizeBySymbol())
+	enc := e
+	interleaved := zw.opts.Streams > 1
+	bestCost := CostOfNewTable(e, frequencies)
+
+	if zw.opts.Dicts != nil {
+		if entry, found := zw.opts.Dicts.Current(); found {
+			dictCost, ok := codedBits(entry.Encoder, frequencies)
+			dictCost += 16
+			if ok && dictCost <= bestCost {
+				block = []byte{blockTypeDict, entry.ID}
+				enc = entry.Encoder
+				interleaved = false
+				bestCost = dictCost
+			}
+		}
+	}
+
+	if zw.opts.ReuseTables && !zw.opts.Index && zw.hasPrev {
+		if reuseCost, ok := CostOfReuse(&zw.prev, frequencies); ok && reuseCost <= bestCost {
+			block = []byte{blockTypeRepeat}
+			enc = &zw.prev
+			interleaved = false
+		}
+	}
+
+	if interleaved {
+		var tmp [binary.MaxVarintLen64]byte
+		block = append([]byte{blockTypeStreams, byte(zw.opts.Streams)}, block[1:]...)
+		n := binary.PutUvarint(tmp[:], uint64(len(zw.buf)))
+		block = append(block, tmp[:n]...)
+		var err error
+		block, err = EncodeInterleaved(zw.opts.Streams, enc, block, zw.buf)
+		if err != nil {
+			return zw.setErr(err)
+		}
+	}
+
+	zw.addIndexEntry()
+
+	if err := zw.setErr(zw.bw.WriteBytes(block)); err != nil {
+		return err
+	}
+	if !interleaved {
+		if err := zw.setErr(enc.EncodeBytes(zw.buf, &zw.bw)); err != nil {
+			return err
+		}
+		if err := zw.setErr(zw.bw.WriteSymbol(enc, EndOfBlock)); err != nil {
+			return err
+		}
+	}
+	if h := zw.opts.Checksum.newHash(); h != nil {
+		_, _ = h.Write(zw.buf)
+		sum := zw.opts.Checksum.appendSum(nil, h)
+		if err := zw.setErr(zw.bw.WriteBytes(sum)); err != nil {
+			return err
+		}
+	}
+	zw.prev.copyFrom(enc)
+	zw.hasPrev = true
+	zw.total += uint64(len(zw.buf))
+	zw.blocks++
+	zw.buf = zw.buf[:0]
+	zw.transformed = false
+	return zw.setErr(zw.bw.Flush())
+}
+
+// writeSync writes a sync marker for the next block.  The next block may not
+// reuse the code of the previous one, so that decoding can start at it.
+func (zw *Writer) writeSync() error {
+	marker := appendSyncPosition(syncMarker[:], zw.blocks, zw.total)
+	zw.hasPrev = false
+	return zw.setErr(zw.bw.WriteBytes(marker))
+}
+
+func appendSyncPosition(dst []byte, block int, total uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64(block))
+	dst = append(dst, tmp[:n]...)
+	n = binary.PutUvarint(tmp[:], total)
+	return append(dst, tmp[:n]...)
+}
+
+// CostOfNewTable returns the number of bits needed for a block of the stream
+// format which carries its own table for the code e, given the frequency of
+// each Symbol in the block.  The frequencies must include EndOfBlock, and
+// every Symbol with a non-zero frequency must have a code.  Checksums are
+// not included.
+func CostOfNewTable(e *Encoder, frequencies []uint32) uint64 {
+	header := appendSizesRLE([]byte{blockTypeHuffman}, e.SizeBySymbol())
+	cost, _ := codedBits(e, frequencies)
+	return cost + 8*uint64(len(header))
+}
+
+// CostOfReuse returns the number of bits needed for a block of the stream
+// format which reuses the code prev of the previous block, given the
+// frequency of each Symbol in the block.  It returns false if prev cannot
+// code the block, because some Symbol with a non-zero frequency has no code.
+// Checksums are not included.
+func CostOfReuse(prev *Encoder, frequencies []uint32) (uint64, bool) {
+	cost, ok := codedBits(prev, frequencies)
+	return cost + 8, ok
+}
+
+// codedBits returns the number of bits needed to code the given frequencies
+// with e.  It returns false if any Symbol with a non-zero frequency lacks a
+// code.
+func codedBits(e *Encoder, frequencies []uint32) (uint64, bool) {
+	var total uint64
+	numSymbols := e.NumSymbols()
+	for index, freq := range frequencies {
+		if freq == 0 {
+			continue
+		}
+		if uint(index) >= numSymbols {
+			return total, false
+		}
+		hc := e.Encode(Symbol(index))
+		if hc.Size == 0 {
+			return total, false
+		}
+		total += uint64(freq) * uint64(hc.Size)
+	}
+	return total, true
+}
+
+// initLimited initializes e like Encoder.Init, but repeatedly flattens the
+// frequencies until no code exceeds maxBitsPerCode bits.
+func initLimited(e *Encoder, numSymbols int, frequencies []uint32) {
+	initLimitedTo(e, numSymbols, frequencies, maxBitsPerCode)
+}
+
+// initLimitedTo is initLimited with a caller-chosen limit on the code size.
+func initLimitedTo(e *Encoder, numSymbols int, frequencies []uint32, maxSize byte) {
+	e.Init(numSymbols, frequencies)
+	if e.MaxSize() <= maxSize {
+		return
+	}
+
+	// Flattening stops at a frequency of 1, so it can only succeed if every
+	// Symbol with a non-zero frequency fits in a flat code of maxSize bits.
+	var nonzero int
+	for _, freq := range frequencies {
+		if freq != 0 {
+			nonzero++
+		}
+	}
+	assert.Assertf(nonzero <= 1<<maxSize, "%d symbols with non-zero frequency cannot be coded in %d bits", nonzero, maxSize)
+
+	scaled := make([]uint32, len(frequencies))
+	copy(scaled, frequencies)
+	for e.MaxSize() > maxSize {
+		for index, freq := range scaled {
+			if freq != 0 {
+				scaled[index] = (freq >> 1) | 1
+			}
+		}
+		e.Init(numSymbols, scaled)
+	}
+}
+
+// Reader is an io.Reader which decompresses data in the stream format.
+//
+// Like gzip.Reader, a Reader transparently decompresses multiple streams
+// which have been concatenated together, unless Multistream(false) is set.
+//
+// Errors caused by invalid block data are reported as *BlockError, which
+// identifies the failing block and matches ErrCorrupt under errors.Is.
+//
+type Reader struct {
+	br            BitReader
+	d             Decoder
+	cur           *Decoder
+	dicts         *DictRegistry
+	checksum      Checksum
+	lengthTrailer bool
+	hasIndex      bool
+	hasSync       bool
+	encrypted     bool
+	transformed   bool
+	hasCipher     bool
+	transforms    []Transform
+	h             hash.Hash64
+	block         int
+	total         uint64
+	pending       []byte
+	codes         []Code
+	interleaved   bool
+	inBlock       bool
+	single        bool
+	err           error
+}
+
+// NewReader returns a new Reader that reads compressed data from r.  The
+// stream header is read immediately.
+func NewReader(r io.Reader) (*Reader, error) {
+	zr := new(Reader)
+	zr.br.Init(r)
+	if err := zr.readHeader(); err != nil {
+		return nil, err
+	}
+	return zr, nil
+}
+
+// Reset discards the Reader's state and makes it equivalent to the result of
+// NewReader, but reading from r instead.  The registry set by UseDicts, the
+// Transforms set by UseTransforms, and the Multistream setting are kept, as
+// are the Reader's buffers and the storage for its code tables, so that a
+// Reader can be pooled and reused.  The stream header is read immediately; if
+// that fails, the error is also returned by later calls to Read.
+func (zr *Reader) Reset(r io.Reader) error {
+	*zr = Reader{
+		d:          zr.d,
+		dicts:      zr.dicts,
+		transforms: zr.transforms,
+		pending:    zr.pending[:0],
+		codes:      zr.codes,
+		single:     zr.single,
+	}
+	zr.resetTransforms()
+	zr.br.Init(r)
+	if err := zr.readHeader(); err != nil {
+		zr.err = err
+		return err
+	}
+	return nil
+}
+
+func (zr *Reader) readHeader() error {
+	var header [6]byte
+	for i := range header {
+		b, err := zr.br.ReadByte()
+		if err != nil {
+			if err == io.EOF && i != 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		header[i] = b
+	}
+	if header[0] != streamMagic[0] || header[1] != streamMagic[1] || header[2] != streamMagic[2] || header[3] != streamMagic[3] {
+		return ErrHeader
+	}
+	if header[4] != streamVersion {
+		return ErrHeader
+	}
+	return zr.setFlags(header[5])
+}
+
+func (zr *Reader) setFlags(flags byte) error {
+	if (flags &^ flagsKnown) != 0 {
+		return ErrHeader
+	}
+	zr.checksum = Checksum(flags & flagChecksumMask)
+	if !zr.checksum.isValid() {
+		return ErrHeader
+	}
+	zr.lengthTrailer = (flags & flagLengthTrailer) != 0
+	zr.hasIndex = (flags & flagIndex) != 0
+	zr.hasSync = (flags & flagSync) != 0
+	zr.encrypted = (flags & flagEncrypted) != 0
+	zr.transformed = (flags & flagTransformed) != 0
+	zr.h = zr.checksum.newHash()
+	return nil
+}
+
+// Read decompresses data into p.  It returns io.EOF after the end-of-stream
+// marker has been consumed.
+func (zr *Reader) Read(p []byte) (int, error) {
+	n, err := zr.read(p)
+	for i := len(zr.transforms) - 1; i >= 0; i-- {
+		zr.transforms[i].Inverse(p[:n])
+	}
+	return n, err
+}
+
+func (zr *Reader) resetTransforms() {
+	for _, t := range zr.transforms {
+		t.Reset()
+	}
+}
+
+func (zr *Reader) read(p []byte) (int, error) {
+	if zr.err != nil {
+		return 0, zr.err
+	}
+
+	var n, start int
+	for n < len(p) {
+		if !zr.inBlock {
+			err := zr.nextBlock()
+			if err == io.EOF && !zr.single && !zr.hasCipher {
+				err = zr.nextStream()
+				if err == nil {
+					continue
+				}
+			}
+			if err != nil {
+				zr.err = err
+				return n, err
+			}
+		}
+
+		var symbol Symbol
+		if zr.interleaved {
+			copied := copy(p[n:], zr.pending)
+			zr.pending = zr.pending[copied:]
+			n += copied
+			if len(zr.pending) != 0 {
+				continue
+			}
+			zr.interleaved = false
+			symbol = EndOfBlock
+		} else {
+			var err error
+			symbol, err = zr.br.ReadSymbol(zr.cur)
+			if err != nil {
+				zr.err = zr.blockError(err)
+				return n, zr.err
+			}
+		}
+		if symbol == EndOfBlock {
+			zr.update(p[start:n])
+			start = n
+			if err := zr.endBlock(); err != nil {
+				zr.err = err
+				return n, err
+			}
+			if n != 0 {
+				break
+			}
+			continue
+		}
+		if symbol > EndOfBlock {
+			zr.err = zr.blockError(fmt.Errorf("symbol %d out of range", symbol))
+			return n, zr.err
+		}
+		p[n] = byte(symbol)
+		n++
+	}
+	zr.update(p[start:n])
+	return n, nil
+}
+
+// UseDicts supplies the registry used to look up the dictionaries referenced
+// by the stream, if any.
+func (zr *Reader) UseDicts(reg *DictRegistry) {
+	zr.dicts = reg
+}
+
+// Multistream controls whether the Reader supports multistream files.
+//
+// If enabled (the default), the Reader expects the input to be a sequence of
+// individually compressed streams, each with its own header and end-of-stream
+// marker, and it reads them all as one continuous stream of bytes.
+//
+// If disabled, Read returns io.EOF at the end of the current stream.  The
+// Reader never consumes bytes past the end of the stream, so long as the
+// underlying io.Reader implements io.ByteReader; Multistream(false) can
+// therefore be combined with a fresh NewReader to read each stream
+// individually, or to locate data appended after a stream.
+//
+func (zr *Reader) Multistream(ok bool) {
+	zr.single = !ok
+}
+
+// nextStream reads the header of the next concatenated stream, if any.
+func (zr *Reader) nextStream() error {
+	if err := zr.readHeader(); err != nil {
+		return err
+	}
+	zr.block = 0
+	zr.total = 0
+	zr.cur = nil
+	zr.resetTransforms()
+	return nil
+}
+
+// Close releases resources associated with this Reader.  It does not close
+// the underlying io.Reader.
+func (zr *Reader) Close() error {
+	if zr.err == io.EOF {
+		return nil
+	}
+	return zr.err
+}
+
+// decodeBlock decodes the next block in its entirety, appending the
+// decompressed bytes to dst.
+func (zr *Reader) decodeBlock(dst []byte) ([]byte, error) {
+	if err := zr.nextBlock(); err != nil {
+		if err == io.EOF {
+			err = zr.blockError(errors.New("unexpected end-of-stream marker"))
+		}
+		return dst, err
+	}
+
+	start := len(dst)
+	if zr.interleaved {
+		dst = append(dst, zr.pending...)
+		zr.pending = zr.pending[:0]
+		zr.interleaved = false
+		zr.update(dst[start:])
+		return dst, zr.endBlock()
+	}
+	for {
+		symbol, err := zr.br.ReadSymbol(zr.cur)
+		if err != nil {
+			return dst, zr.blockError(err)
+		}
+		if symbol == EndOfBlock {
+			break
+		}
+		if symbol > EndOfBlock {
+			return dst, zr.blockError(fmt.Errorf("symbol %d out of range", symbol))
+		}
+		dst = append(dst, byte(symbol))
+	}
+	zr.update(dst[start:])
+	return dst, zr.endBlock()
+}
+
+func (zr *Reader) update(p []byte) {
+	zr.total += uint64(len(p))
+	if zr.h != nil {
+		_, _ = zr.h.Write(p)
+	}
+}
+
+func (zr *Reader) blockError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &BlockError{Block: zr.block, Err: err}
+}
+
+func (zr *Reader) nextBlock() error {
+	if err := zr.checkTransforms(); err != nil {
+		return err
+	}
+	blockType, err := zr.br.ReadByte()
+	if err != nil {
+		return zr.blockError(err)
+	}
+	switch blockType {
+	case blockTypeEnd:
+		return zr.readTrailer()
+	case blockTypeHuffman:
+		sizes, err := readSizesRLE(&zr.br, StreamAlphabetSize)
+		if err != nil {
+			return zr.blockError(err)
+		}
+		if err := zr.d.reinit(sizes, &zr.codes); err != nil {
+			return zr.blockError(err)
+		}
+		if len(sizes) <= int(EndOfBlock) || sizes[EndOfBlock] == 0 {
+			return zr.blockError(errors.New("block has no code for EndOfBlock"))
+		}
+		zr.cur = &zr.d
+	case blockTypeDict:
+		id, err := zr.br.ReadByte()
+		if err != nil {
+			return zr.blockError(err)
+		}
+		var entry *DictEntry
+		found := false
+		if zr.dicts != nil {
+			entry, found = zr.dicts.Lookup(id)
+		}
+		if !found {
+			return zr.blockError(fmt.Errorf("unknown dictionary ID %d", id))
+		}
+		zr.cur = entry.Decoder
+	case blockTypeStreams:
+		if err := zr.readInterleaved(); err != nil {
+			return zr.blockError(err)
+		}
+		zr.cur = &zr.d
+	case blockTypeRepeat:
+		if zr.cur == nil {
+			return zr.blockError(errors.New("block reuses the code of a nonexistent previous block"))
+		}
+	case blockTypeSync:
+		if !zr.hasSync {
+			return zr.blockError(errors.New("sync marker in a stream without sync markers"))
+		}
+		if err := zr.readSync(); err != nil {
+			return zr.blockError(err)
+		}
+		return zr.nextBlock()
+	default:
+		return zr.blockError(fmt.Errorf("unknown block type 0x%02x", blockType))
+	}
+	if zr.h != nil {
+		zr.h.Reset()
+	}
+	zr.inBlock = true
+	return nil
+}
+
+// readInterleaved reads and decodes the whole of a block of type 0x03 into
+// zr.pending, since the streams cannot be decoded in order.
+func (zr *Reader) readInterleaved() error {
+	streams, err := zr.br.ReadByte()
+	if err != nil {
+		return err
+	}
+	if streams < 1 || streams > MaxInterleavedStreams {
+		return fmt.Errorf("invalid number of interleaved streams %d", streams)
+	}
+	sizes, err := readSizesRLE(&zr.br, StreamAlphabetSize)
+	if err != nil {
+		return err
+	}
+	if err := zr.d.reinit(sizes, &zr.codes); err != nil {
+		return err
+	}
+	count, err := binary.ReadUvarint(&zr.br)
+	if err != nil {
+		return noEOF(err)
+	}
+
+	lengths := make([]uint64, streams)
+	var total uint64
+	for s := range lengths {
+		lengths[s], err = binary.ReadUvarint(&zr.br)
+		if err != nil {
+			return noEOF(err)
+		}
+		total += lengths[s]
+		if total < lengths[s] {
+			return errors.New("interleaved stream lengths overflow")
+		}
+	}
+	if count != 0 && (zr.d.minSize == 0 || count > total*8/uint64(zr.d.minSize)) {
+		return fmt.Errorf("%d bytes cannot fit in %d bytes of interleaved streams", count, total)
+	}
+
+	// Grow the buffer as the data arrives, rather than trusting total.
+	data := make([]byte, 0, minUint64(total, DefaultBlockSize))
+	for uint64(len(data)) < total {
+		b, err := zr.br.ReadByte()
+		if err != nil {
+			return noEOF(err)
+		}
+		data = append(data, b)
+	}
+	parts := make([][]byte, streams)
+	for s, length := range lengths {
+		parts[s], data = data[:length], data[length:]
+	}
+
+	zr.pending, err = decodeInterleaved(&zr.d, zr.pending[:0], parts, int(count))
+	if err != nil {
+		return err
+	}
+	zr.interleaved = true
+	return nil
+}
+
+// readSync reads the rest of a sync marker, after its block type, and checks
+// that it agrees with the Reader's position.
+func (zr *Reader) readSync() error {
+	for _, expect := range syncMarker[1:] {
+		b, err := zr.br.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != expect {
+			return errors.New("invalid sync marker")
+		}
+	}
+	block, total, err := zr.readSyncPosition()
+	if err != nil {
+		return err
+	}
+	if block != uint64(zr.block) || total != zr.total {
+		return fmt.Errorf("sync marker for block %d at offset %d found at block %d offset %d", block, total, zr.block, zr.total)
+	}
+	zr.cur = nil
+	return nil
+}
+
+func (zr *Reader) readSyncPosition() (uint64, uint64, error) {
+	block, err := binary.ReadUvarint(&zr.br)
+	if err != nil {
+		return 0, 0, noEOF(err)
+	}
+	total, err := binary.ReadUvarint(&zr.br)
+	if err != nil {
+		return 0, 0, noEOF(err)
+	}
+	return block, total, nil
+}
+
+// Resync recovers from an error caused by damage to the stream, by skipping
+// forward to the next sync marker in the current stream.  On success, the
+// error is cleared and Read continues with the block after the marker;
+// the data between the failure and the marker is lost.  The block numbers of
+// later errors and the checks of the length trailer and block index still
+// count the lost blocks.
+//
+// Resync returns io.EOF if the input ends before another sync marker is
+// found.  It returns an error immediately if the stream was written without
+// WriterOptions.SyncInterval.
+//
+func (zr *Reader) Resync() error {
+	if !zr.hasSync {
+		return errors.New("Huffman stream has no sync markers")
+	}
+	zr.br.Align()
+
+	var window [len(syncMarker)]byte
+	filled := 0
+	for {
+		b, err := zr.br.ReadByte()
+		if err != nil {
+			return io.EOF
+		}
+		copy(window[:], window[1:])
+		window[len(window)-1] = b
+		if filled++; filled < len(window) || window != syncMarker {
+			continue
+		}
+
+		block, total, err := zr.readSyncPosition()
+		if err != nil {
+			return io.EOF
+		}
+		if block < uint64(zr.block) || block > math.MaxInt32 {
+			// A false match within the damaged data; keep looking.
+			filled = 0
+			continue
+		}
+		zr.block = int(block)
+		zr.total = total
+		zr.cur = nil
+		zr.pending = zr.pending[:0]
+		zr.interleaved = false
+		zr.inBlock = false
+		zr.err = nil
+		return nil
+	}
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (zr *Reader) endBlock() error {
+	zr.br.Align()
+	zr.inBlock = false
+
+	if zr.h != nil {
+		expect := make([]byte, zr.checksum.Size())
+		for i := range expect {
+			b, err := zr.br.ReadByte()
+			if err != nil {
+				return zr.blockError(err)
+			}
+			expect[i] = b
+		}
+		actual := zr.checksum.appendSum(nil, zr.h)
+		if !bytes.Equal(expect, actual) {
+			return zr.blockError(ErrChecksum)
+		}
+	}
+
+	zr.block++
+	return nil
+}
+
+func (zr *Reader) readTrailer() error {
+	if zr.lengthTrailer {
+		var tmp [8]byte
+		for i := range tmp {
+			b, err := zr.br.ReadByte()
+			if err != nil {
+				return noEOF(err)
+			}
+			tmp[i] = b
+		}
+		if expect := binary.LittleEndian.Uint64(tmp[:]); expect != zr.total {
+			return fmt.Errorf("%w: expected %d bytes, got %d", ErrLength, expect, zr.total)
+		}
+	}
+	if zr.hasIndex {
+		// The index holds one entry per block plus one for the
+		// end-of-stream marker; the streaming Reader doesn't need it.
+		indexLen := uint64(zr.block+1)*indexEntrySize + indexFooterSize
+		buf := make([]byte, indexLen)
+		for i := range buf {
+			b, err := zr.br.ReadByte()
+			if err != nil {
+				return noEOF(err)
+			}
+			buf[i] = b
+		}
+		if !bytes.Equal(buf[indexLen-4:], indexMagic[:]) {
+			return fmt.Errorf("%w: invalid block index", ErrCorrupt)
+		}
+	}
+	return io.EOF
+}
+
+var (
+	_ io.WriteCloser = (*Writer)(nil)
+	_ io.ReadCloser  = (*Reader)(nil)
+)
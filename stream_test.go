@@ -0,0 +1,590 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStream_RoundTrip(t *testing.T) {
+	type testRow struct {
+		name      string
+		input     []byte
+		blockSize int
+	}
+
+	testData := [...]testRow{
+		{name: "empty", input: nil, blockSize: DefaultBlockSize},
+		{name: "one-byte", input: []byte("a"), blockSize: DefaultBlockSize},
+		{name: "one-symbol", input: bytes.Repeat([]byte("a"), 1000), blockSize: DefaultBlockSize},
+		{name: "text", input: []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 100)), blockSize: DefaultBlockSize},
+		{name: "small-blocks", input: []byte(strings.Repeat("abracadabra", 100)), blockSize: 7},
+		{name: "all-bytes", input: allBytes(4), blockSize: 300},
+		{name: "skewed", input: skewedBytes(), blockSize: DefaultBlockSize},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := NewWriterSize(&buf, row.blockSize)
+			if _, err := zw.Write(row.input); err != nil {
+				t.Fatalf("Write: unexpected error: %v", err)
+			}
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Close: unexpected error: %v", err)
+			}
+
+			zr, err := NewReader(&buf)
+			if err != nil {
+				t.Fatalf("NewReader: unexpected error: %v", err)
+			}
+			output, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("ReadAll: unexpected error: %v", err)
+			}
+			if !bytes.Equal(row.input, output) {
+				t.Errorf("wrong output:\n\texpect: %q\n\tactual: %q", row.input, output)
+			}
+		})
+	}
+}
+
+func TestStream_EndOfBlock(t *testing.T) {
+	var buf bytes.Buffer
+	zw := NewWriter(&buf)
+	_, _ = zw.Write([]byte("hello"))
+	_ = zw.Flush()
+	_, _ = zw.Write([]byte("world"))
+	_ = zw.Close()
+
+	zr, err := NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+
+	// Each Read stops at the end of a block, so block boundaries are
+	// visible without any out-of-band length information.
+	p := make([]byte, 64)
+	for _, expect := range []string{"hello", "world"} {
+		n, err := zr.Read(p)
+		if err != nil {
+			t.Fatalf("Read: unexpected error: %v", err)
+		}
+		if actual := string(p[:n]); expect != actual {
+			t.Errorf("Read: expected %q, got %q", expect, actual)
+		}
+	}
+	if _, err := zr.Read(p); err != io.EOF {
+		t.Errorf("Read: expected io.EOF, got %v", err)
+	}
+}
+
+func TestStream_Corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	zw := NewWriter(&buf)
+	_, _ = zw.Write([]byte("hello, world"))
+	_ = zw.Close()
+	good := buf.Bytes()
+
+	if _, err := NewReader(bytes.NewReader([]byte("HUFX\x01\x00"))); err != ErrHeader {
+		t.Errorf("NewReader: expected ErrHeader, got %v", err)
+	}
+
+	truncated := good[:len(good)-3]
+	zr, err := NewReader(bytes.NewReader(truncated))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	if _, err := io.ReadAll(zr); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadAll: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	badType := append([]byte(nil), good...)
+	badType[6] = 0x7f
+	zr, err = NewReader(bytes.NewReader(badType))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	if _, err := io.ReadAll(zr); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("ReadAll: expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestStream_Checksum(t *testing.T) {
+	input := []byte(strings.Repeat("abracadabra", 100))
+
+	for _, checksum := range []Checksum{NoChecksum, ChecksumCRC32C, ChecksumXXH64} {
+		t.Run(checksum.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := NewWriterOptions(&buf, WriterOptions{
+				BlockSize:     256,
+				Checksum:      checksum,
+				LengthTrailer: true,
+			})
+			_, _ = zw.Write(input)
+			if err := zw.Close(); err != nil {
+				t.Fatalf("Close: unexpected error: %v", err)
+			}
+			good := buf.Bytes()
+
+			zr, err := NewReader(bytes.NewReader(good))
+			if err != nil {
+				t.Fatalf("NewReader: unexpected error: %v", err)
+			}
+			output, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("ReadAll: unexpected error: %v", err)
+			}
+			if !bytes.Equal(input, output) {
+				t.Errorf("wrong output:\n\texpect: %q\n\tactual: %q", input, output)
+			}
+
+			if checksum == NoChecksum {
+				return
+			}
+
+			// Corrupt the checksum of the third block.  Each Read
+			// returns at most one block, so the reader's offset after
+			// three Reads is just past the third block's checksum.
+			zr, _ = NewReader(bytes.NewReader(good))
+			p := make([]byte, 512)
+			for block := 0; block < 3; block++ {
+				_, _ = zr.Read(p)
+			}
+			offset := zr.br.BitsRead() / 8
+			bad := append([]byte(nil), good...)
+			bad[offset-1] ^= 0xff
+
+			zr, err = NewReader(bytes.NewReader(bad))
+			if err != nil {
+				t.Fatalf("NewReader: unexpected error: %v", err)
+			}
+			_, err = io.ReadAll(zr)
+			var blockErr *BlockError
+			if !errors.As(err, &blockErr) {
+				t.Fatalf("ReadAll: expected *BlockError, got %v", err)
+			}
+			if blockErr.Block != 2 {
+				t.Errorf("wrong block index: expected 2, got %d", blockErr.Block)
+			}
+			if !errors.Is(err, ErrChecksum) || !errors.Is(err, ErrCorrupt) {
+				t.Errorf("expected ErrChecksum and ErrCorrupt, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStream_LengthTrailer(t *testing.T) {
+	var buf bytes.Buffer
+	zw := NewWriterOptions(&buf, WriterOptions{LengthTrailer: true})
+	_, _ = zw.Write([]byte("hello, world"))
+	_ = zw.Close()
+
+	bad := buf.Bytes()
+	bad[len(bad)-8]++
+
+	zr, err := NewReader(bytes.NewReader(bad))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	if _, err := io.ReadAll(zr); !errors.Is(err, ErrLength) {
+		t.Errorf("ReadAll: expected ErrLength, got %v", err)
+	}
+}
+
+func TestXXH64(t *testing.T) {
+	type testRow struct {
+		input  string
+		expect uint64
+	}
+
+	testData := [...]testRow{
+		{input: "", expect: 0xef46db3751d8e999},
+		{input: "a", expect: 0xd24ec4f1a98c6e5b},
+		{input: "abc", expect: 0x44bc2cf5ad770999},
+		{input: "Nobody inspects the spammish repetition", expect: 0xfbcea83c8a378bf1},
+	}
+	for _, row := range testData {
+		t.Run(row.input, func(t *testing.T) {
+			h := newXXH64()
+			_, _ = h.Write([]byte(row.input))
+			if actual := h.Sum64(); row.expect != actual {
+				t.Errorf("expected %#016x, got %#016x", row.expect, actual)
+			}
+		})
+	}
+}
+
+func TestWithEOB(t *testing.T) {
+	frequencies := WithEOB([]uint32{3, 0, 2}, 4)
+	expect := []uint32{3, 0, 2, 0, 1}
+	if len(frequencies) != len(expect) {
+		t.Fatalf("wrong length: expected %d, got %d", len(expect), len(frequencies))
+	}
+	for index := range expect {
+		if expect[index] != frequencies[index] {
+			t.Errorf("wrong frequencies:\n\texpect: %v\n\tactual: %v", expect, frequencies)
+			break
+		}
+	}
+
+	var e Encoder
+	e.Init(5, frequencies)
+	if hc := e.Encode(4); hc.Size == 0 {
+		t.Errorf("EOB symbol was not assigned a code")
+	}
+}
+
+func allBytes(repeat int) []byte {
+	out := make([]byte, 0, 256*repeat)
+	for i := 0; i < repeat; i++ {
+		for b := 0; b < 256; b++ {
+			out = append(out, byte(b))
+		}
+	}
+	return out
+}
+
+// skewedBytes returns data with Fibonacci-distributed byte frequencies,
+// which would produce codes longer than maxBitsPerCode without limiting.
+func skewedBytes() []byte {
+	var out []byte
+	a, b := 1, 1
+	for symbol := 0; symbol < 22; symbol++ {
+		out = append(out, bytes.Repeat([]byte{byte(symbol)}, a)...)
+		a, b = b, a+b
+	}
+	return out
+}
+
+func TestStream_Multistream(t *testing.T) {
+	var buf bytes.Buffer
+	for _, part := range []string{"first ", "second ", "", "third"} {
+		zw := NewWriterOptions(&buf, WriterOptions{LengthTrailer: true, Index: true})
+		_, _ = zw.Write([]byte(part))
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Close: unexpected error: %v", err)
+		}
+	}
+	stream := buf.Bytes()
+
+	zr, err := NewReader(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	output, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("ReadAll: unexpected error: %v", err)
+	}
+	if expect, actual := "first second third", string(output); expect != actual {
+		t.Errorf("wrong output: expected %q, got %q", expect, actual)
+	}
+
+	r := bytes.NewReader(stream)
+	var parts []string
+	for r.Len() != 0 {
+		zr, err := NewReader(r)
+		if err != nil {
+			t.Fatalf("NewReader: unexpected error: %v", err)
+		}
+		zr.Multistream(false)
+		output, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("ReadAll: unexpected error: %v", err)
+		}
+		parts = append(parts, string(output))
+	}
+	if expect, actual := "[first  second   third]", fmt.Sprint(parts); expect != actual {
+		t.Errorf("wrong parts: expected %q, got %q", expect, actual)
+	}
+}
+
+func TestStream_Reset(t *testing.T) {
+	inputs := [][]byte{
+		[]byte(strings.Repeat("abracadabra", 100)),
+		skewedBytes(),
+		nil,
+		allBytes(2),
+	}
+
+	var zw *Writer
+	var zr *Reader
+	for index, input := range inputs {
+		var buf bytes.Buffer
+		if zw == nil {
+			zw = NewWriterOptions(&buf, WriterOptions{BlockSize: 500, Checksum: ChecksumCRC32C, Index: true})
+		} else {
+			zw.Reset(&buf)
+		}
+		if _, err := zw.Write(input); err != nil {
+			t.Fatalf("input %d: Write: unexpected error: %v", index, err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("input %d: Close: unexpected error: %v", index, err)
+		}
+
+		var fresh bytes.Buffer
+		expect := NewWriterOptions(&fresh, WriterOptions{BlockSize: 500, Checksum: ChecksumCRC32C, Index: true})
+		_, _ = expect.Write(input)
+		_ = expect.Close()
+		if !bytes.Equal(fresh.Bytes(), buf.Bytes()) {
+			t.Errorf("input %d: Reset Writer output differs from a new Writer", index)
+		}
+
+		if zr == nil {
+			var err error
+			if zr, err = NewReader(&buf); err != nil {
+				t.Fatalf("input %d: NewReader: unexpected error: %v", index, err)
+			}
+		} else if err := zr.Reset(&buf); err != nil {
+			t.Fatalf("input %d: Reset: unexpected error: %v", index, err)
+		}
+		output, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("input %d: ReadAll: unexpected error: %v", index, err)
+		}
+		if !bytes.Equal(input, output) {
+			t.Errorf("input %d: wrong output", index)
+		}
+	}
+
+	if err := zr.Reset(strings.NewReader("nope!!")); !errors.Is(err, ErrHeader) {
+		t.Errorf("Reset: expected ErrHeader, got %v", err)
+	}
+	if _, err := zr.Read(make([]byte, 1)); !errors.Is(err, ErrHeader) {
+		t.Errorf("Read after failed Reset: expected ErrHeader, got %v", err)
+	}
+}
+
+func TestStream_ResetKeepsTables(t *testing.T) {
+	input := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 50))
+
+	var buf bytes.Buffer
+	zw := NewWriterOptions(&buf, WriterOptions{BlockSize: 300, ReuseTables: true})
+	var zr Reader
+	roundTrip := func(pass int) {
+		buf.Reset()
+		zw.Reset(&buf)
+		if _, err := zw.Write(input); err != nil {
+			t.Fatalf("pass %d: Write: unexpected error: %v", pass, err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("pass %d: Close: unexpected error: %v", pass, err)
+		}
+		if err := zr.Reset(&buf); err != nil {
+			t.Fatalf("pass %d: Reset: unexpected error: %v", pass, err)
+		}
+		output, err := io.ReadAll(&zr)
+		if err != nil {
+			t.Fatalf("pass %d: ReadAll: unexpected error: %v", pass, err)
+		}
+		if !bytes.Equal(input, output) {
+			t.Errorf("pass %d: wrong output", pass)
+		}
+	}
+
+	roundTrip(0)
+	codes, direct := &zw.enc.codes[0], &zr.d.direct[0]
+	roundTrip(1)
+	if &zw.enc.codes[0] != codes {
+		t.Errorf("Writer: code storage was not reused after Reset")
+	}
+	if &zr.d.direct[0] != direct {
+		t.Errorf("Reader: direct table storage was not reused after Reset")
+	}
+}
+
+func TestStream_ReuseTables(t *testing.T) {
+	input := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 200))
+
+	compress := func(opts WriterOptions) []byte {
+		var buf bytes.Buffer
+		zw := NewWriterOptions(&buf, opts)
+		_, _ = zw.Write(input)
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Close: unexpected error: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	plain := compress(WriterOptions{BlockSize: 256})
+	reused := compress(WriterOptions{BlockSize: 256, ReuseTables: true})
+	if len(reused) >= len(plain) {
+		t.Errorf("ReuseTables did not help: %d >= %d bytes", len(reused), len(plain))
+	}
+
+	zr, err := NewReader(bytes.NewReader(reused))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	output, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("ReadAll: unexpected error: %v", err)
+	}
+	if !bytes.Equal(input, output) {
+		t.Errorf("wrong output")
+	}
+
+	indexed := compress(WriterOptions{BlockSize: 256, ReuseTables: true, Index: true})
+	ir, err := NewIndexedReader(bytes.NewReader(indexed), int64(len(indexed)))
+	if err != nil {
+		t.Fatalf("NewIndexedReader: unexpected error: %v", err)
+	}
+	if block, err := ir.ReadBlock(ir.NumBlocks() - 1); err != nil || len(block) == 0 {
+		t.Errorf("ReadBlock: expected the last block to decode on its own, got %v", err)
+	}
+
+	// A repeat block with no previous block is corrupt.
+	bad := append(append([]byte(nil), streamMagic[:]...), streamVersion, 0, blockTypeRepeat, 0)
+	zr, err = NewReader(bytes.NewReader(bad))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	if _, err := io.ReadAll(zr); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("leading repeat block: expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestCostOfReuse(t *testing.T) {
+	frequencies := WithEOB([]uint32{'a': 10, 'b': 5}, EndOfBlock)
+	var e Encoder
+	e.Init(StreamAlphabetSize, frequencies)
+
+	reuse, ok := CostOfReuse(&e, frequencies)
+	if !ok {
+		t.Fatalf("CostOfReuse: expected ok")
+	}
+	if expect := uint64(8 + 10*1 + 5*2 + 1*2); reuse != expect {
+		t.Errorf("CostOfReuse: expected %d, got %d", expect, reuse)
+	}
+	if table := CostOfNewTable(&e, frequencies); table <= reuse {
+		t.Errorf("CostOfNewTable: expected more than %d, got %d", reuse, table)
+	}
+	if _, ok := CostOfReuse(&e, WithEOB([]uint32{'c': 1}, EndOfBlock)); ok {
+		t.Errorf("CostOfReuse: expected !ok for a Symbol with no code")
+	}
+}
+
+func TestStream_Resync(t *testing.T) {
+	const blockSize = 256
+	input := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 60))[:10*blockSize]
+
+	// Write one block per Flush, recording where each block ends.
+	compress := func(opts WriterOptions) ([]byte, []int) {
+		var buf bytes.Buffer
+		zw := NewWriterOptions(&buf, opts)
+		var ends []int
+		for i := 0; i < len(input); i += blockSize {
+			_, _ = zw.Write(input[i : i+blockSize])
+			if err := zw.Flush(); err != nil {
+				t.Fatalf("Flush: unexpected error: %v", err)
+			}
+			ends = append(ends, buf.Len())
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Close: unexpected error: %v", err)
+		}
+		return buf.Bytes(), ends
+	}
+
+	opts := WriterOptions{BlockSize: blockSize, Checksum: ChecksumXXH64, LengthTrailer: true, Index: true, SyncInterval: 4}
+	good, ends := compress(opts)
+	zr, err := NewReader(bytes.NewReader(good))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	if output, err := io.ReadAll(zr); err != nil || !bytes.Equal(input, output) {
+		t.Errorf("ReadAll: expected the input, got %d bytes and %v", len(output), err)
+	}
+
+	reuse := opts
+	reuse.Index = false
+	reuse.ReuseTables = true
+	reused, _ := compress(reuse)
+	zr, err = NewReader(bytes.NewReader(reused))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	if output, err := io.ReadAll(zr); err != nil || !bytes.Equal(input, output) {
+		t.Errorf("ReadAll with ReuseTables: expected the input, got %d bytes and %v", len(output), err)
+	}
+
+	type testRow struct {
+		name    string
+		block   int
+		resumed int
+	}
+
+	testData := [...]testRow{
+		{name: "block-0", block: 0, resumed: 4},
+		{name: "block-1", block: 1, resumed: 4},
+		{name: "block-5", block: 5, resumed: 8},
+		{name: "block-8", block: 8, resumed: -1},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			start := 6
+			if row.block > 0 {
+				start = ends[row.block-1]
+			}
+			damaged := append([]byte(nil), good...)
+			damaged[(start+ends[row.block])/2] ^= 0x5a
+
+			zr, err := NewReader(bytes.NewReader(damaged))
+			if err != nil {
+				t.Fatalf("NewReader: unexpected error: %v", err)
+			}
+			before, err := io.ReadAll(zr)
+			var blockErr *BlockError
+			if !errors.As(err, &blockErr) || blockErr.Block != row.block {
+				t.Fatalf("ReadAll: expected a BlockError for block %d, got %v", row.block, err)
+			}
+			if !bytes.HasPrefix(input, before[:row.block*blockSize]) {
+				t.Errorf("wrong output before the damaged block")
+			}
+
+			err = zr.Resync()
+			if row.resumed < 0 {
+				if err != io.EOF {
+					t.Errorf("Resync: expected io.EOF, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resync: unexpected error: %v", err)
+			}
+			after, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatalf("ReadAll after Resync: unexpected error: %v", err)
+			}
+			if !bytes.Equal(input[row.resumed*blockSize:], after) {
+				t.Errorf("wrong output after Resync: expected %d bytes, got %d", len(input)-row.resumed*blockSize, len(after))
+			}
+		})
+	}
+
+	plain, _ := compress(WriterOptions{BlockSize: blockSize})
+	zr, err = NewReader(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	if err := zr.Resync(); err == nil || err == io.EOF {
+		t.Errorf("Resync without sync markers: expected an error, got %v", err)
+	}
+}
+
+func TestInitLimited_TooManySymbols(t *testing.T) {
+	frequencies := make([]uint32, 1<<16+1)
+	for index := range frequencies {
+		frequencies[index] = 1
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("initLimited: expected panic for %d non-zero frequencies", len(frequencies))
+		}
+	}()
+	var e Encoder
+	initLimited(&e, len(frequencies), frequencies)
+}
@@ -1,6 +1,7 @@
 package huffman
 
 import (
+	"fmt"
 	"math"
 )
 
@@ -14,3 +15,71 @@ const MaxSymbol = Symbol(math.MaxInt32)
 // InvalidSymbol is returned by some functions to clearly indicate that no
 // symbol is being returned.
 const InvalidSymbol = Symbol(-1)
+
+// SymbolFromInt converts x to a Symbol, returning an error if it is negative
+// or greater than MaxSymbol.
+func SymbolFromInt(x int) (Symbol, error) {
+	if x < 0 || int64(x) > int64(MaxSymbol) {
+		return InvalidSymbol, fmt.Errorf("symbol %d not in range [0..%d]", x, MaxSymbol)
+	}
+	return Symbol(x), nil
+}
+
+// SymbolFromByte converts b to a Symbol.  Every byte is a valid Symbol.
+func SymbolFromByte(b byte) Symbol {
+	return Symbol(b)
+}
+
+// SymbolsFromBytes appends the Symbol for each byte of src to dst and returns
+// the extended slice.
+func SymbolsFromBytes(dst []Symbol, src []byte) []Symbol {
+	start := len(dst)
+	dst = growSymbols(dst, len(src))
+	out := dst[start:]
+	out = out[:len(src)]
+
+	// Copy 8 at a time, so that the compiler can drop the bounds checks
+	// and keep the loop body in registers.
+	i := 0
+	for ; i+8 <= len(src); i += 8 {
+		s := src[i : i+8 : i+8]
+		o := out[i : i+8 : i+8]
+		o[0], o[1], o[2], o[3] = Symbol(s[0]), Symbol(s[1]), Symbol(s[2]), Symbol(s[3])
+		o[4], o[5], o[6], o[7] = Symbol(s[4]), Symbol(s[5]), Symbol(s[6]), Symbol(s[7])
+	}
+	for ; i < len(src); i++ {
+		out[i] = Symbol(src[i])
+	}
+	return dst
+}
+
+// SymbolsFromUint16s appends the Symbol for each uint16 of src to dst and
+// returns the extended slice.
+func SymbolsFromUint16s(dst []Symbol, src []uint16) []Symbol {
+	start := len(dst)
+	dst = growSymbols(dst, len(src))
+	out := dst[start:]
+	out = out[:len(src)]
+
+	i := 0
+	for ; i+8 <= len(src); i += 8 {
+		s := src[i : i+8 : i+8]
+		o := out[i : i+8 : i+8]
+		o[0], o[1], o[2], o[3] = Symbol(s[0]), Symbol(s[1]), Symbol(s[2]), Symbol(s[3])
+		o[4], o[5], o[6], o[7] = Symbol(s[4]), Symbol(s[5]), Symbol(s[6]), Symbol(s[7])
+	}
+	for ; i < len(src); i++ {
+		out[i] = Symbol(src[i])
+	}
+	return dst
+}
+
+// growSymbols extends dst by n Symbols, reallocating at most once.
+func growSymbols(dst []Symbol, n int) []Symbol {
+	if need := len(dst) + n; need > cap(dst) {
+		grown := make([]Symbol, len(dst), need)
+		copy(grown, dst)
+		dst = grown
+	}
+	return dst[:len(dst)+n]
+}
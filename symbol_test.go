@@ -0,0 +1,61 @@
+package huffman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSymbolFromInt(t *testing.T) {
+	type testRow struct {
+		input  int
+		expect Symbol
+		ok     bool
+	}
+
+	testData := [...]testRow{
+		{input: 0, expect: 0, ok: true},
+		{input: 256, expect: 256, ok: true},
+		{input: int(MaxSymbol), expect: MaxSymbol, ok: true},
+		{input: -1, expect: InvalidSymbol, ok: false},
+	}
+	for _, row := range testData {
+		actual, err := SymbolFromInt(row.input)
+		if (err == nil) != row.ok {
+			t.Errorf("%d: expected ok=%v, got error %v", row.input, row.ok, err)
+		}
+		if actual != row.expect {
+			t.Errorf("%d: expected %d, got %d", row.input, row.expect, actual)
+		}
+	}
+
+	if SymbolFromByte(0xff) != 255 {
+		t.Errorf("SymbolFromByte: expected 255, got %d", SymbolFromByte(0xff))
+	}
+}
+
+func TestSymbolsFrom(t *testing.T) {
+	bytes := []byte("hello, world!\xff")
+	words := make([]uint16, len(bytes))
+	expect := []Symbol{-1}
+	for index, b := range bytes {
+		words[index] = uint16(b) << 8
+		expect = append(expect, Symbol(b))
+	}
+
+	if actual := SymbolsFromBytes([]Symbol{-1}, bytes); !reflect.DeepEqual(expect, actual) {
+		t.Errorf("SymbolsFromBytes: expected %v, got %v", expect, actual)
+	}
+	if actual := SymbolsFromBytes(nil, nil); len(actual) != 0 {
+		t.Errorf("SymbolsFromBytes: expected empty, got %v", actual)
+	}
+
+	actual := SymbolsFromUint16s(make([]Symbol, 0, 64), words)
+	for index, symbol := range actual {
+		if expect := Symbol(words[index]); symbol != expect {
+			t.Errorf("SymbolsFromUint16s: index %d: expected %d, got %d", index, expect, symbol)
+		}
+	}
+	if len(actual) != len(words) {
+		t.Errorf("SymbolsFromUint16s: expected %d Symbols, got %d", len(words), len(actual))
+	}
+}
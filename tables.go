@@ -0,0 +1,290 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// simpleTableMarker, in place of the first run's bit length, introduces a
+// simple table.  It can never be a valid bit length.
+const simpleTableMarker = 0xff
+
+// simpleShapes lists the bit lengths implied by each simple table, in the
+// order its Symbols are listed, indexed by the shape byte.  The shape byte
+// holds the number of Symbols minus 1 in its low 2 bits; bit 2 selects the
+// alternate shape for 4 Symbols.
+var simpleShapes = [...][]byte{
+	0: {1},
+	1: {1, 1},
+	2: {1, 2, 2},
+	3: {2, 2, 2, 2},
+	7: {1, 2, 3, 3},
+}
+
+// appendSizesRLE appends a compact representation of sizes to dst.
+//
+// The encoding is the alphabet length as a uvarint, followed by zero or more
+// runs.  Each run is a single byte holding the bit length, followed by the
+// run length as a uvarint.
+//
+// Alternatively, a code with 1 to 4 Symbols whose bit lengths have one of
+// the shapes of a Brotli "simple" prefix code can be written as the alphabet
+// length, simpleTableMarker, a shape byte, and each Symbol as a uvarint, in
+// the order given by simpleShapes.  This form is used whenever it is
+// shorter.
+//
+func appendSizesRLE(dst []byte, sizes []byte) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+
+	n := binary.PutUvarint(tmp[:], uint64(len(sizes)))
+	dst = append(dst, tmp[:n]...)
+	start := len(dst)
+
+	length := uint(len(sizes))
+	for i := uint(0); i < length; {
+		size := sizes[i]
+		j := i + 1
+		for j < length && sizes[j] == size {
+			j++
+		}
+		n = binary.PutUvarint(tmp[:], uint64(j-i))
+		dst = append(dst, size)
+		dst = append(dst, tmp[:n]...)
+		i = j
+	}
+
+	if simple, ok := appendSimpleSizes(nil, sizes); ok && len(simple) < len(dst)-start {
+		dst = append(dst[:start], simple...)
+	}
+	return dst
+}
+
+// appendSimpleSizes appends the simple table for sizes, less the alphabet
+// length, to dst.  It returns false if sizes have no simple table.
+func appendSimpleSizes(dst []byte, sizes []byte) ([]byte, bool) {
+	var symbols [4]Symbol
+	var numCodes int
+	for size := byte(1); size <= 3; size++ {
+		for symbol, actual := range sizes {
+			if actual != size {
+				continue
+			}
+			if numCodes == len(symbols) {
+				return dst, false
+			}
+			symbols[numCodes] = Symbol(symbol)
+			numCodes++
+		}
+	}
+
+	var shape byte
+	switch {
+	case numCodes == 0:
+		return dst, false
+	case numCodes == 4 && sizes[symbols[0]] == 1:
+		shape = 7
+	default:
+		shape = byte(numCodes - 1)
+	}
+	for index, size := range simpleShapes[shape] {
+		if sizes[symbols[index]] != size {
+			return dst, false
+		}
+	}
+	for _, size := range sizes {
+		if size > 3 {
+			return dst, false
+		}
+	}
+
+	var tmp [binary.MaxVarintLen64]byte
+	dst = append(dst, simpleTableMarker, shape)
+	for _, symbol := range symbols[:numCodes] {
+		n := binary.PutUvarint(tmp[:], uint64(symbol))
+		dst = append(dst, tmp[:n]...)
+	}
+	return dst, true
+}
+
+// readSimpleSizes parses a simple table for an alphabet of the given length,
+// starting just after simpleTableMarker.
+func readSimpleSizes(r io.ByteReader, length uint64) ([]byte, error) {
+	shape, err := r.ReadByte()
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if uint(shape) >= uint(len(simpleShapes)) || simpleShapes[shape] == nil {
+		return nil, fmt.Errorf("invalid simple Huffman size table shape 0x%02x", shape)
+	}
+
+	sizes := make([]byte, length)
+	for _, size := range simpleShapes[shape] {
+		symbol, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if symbol >= length || sizes[symbol] != 0 {
+			return nil, fmt.Errorf("invalid symbol %d in simple Huffman size table", symbol)
+		}
+		sizes[symbol] = size
+	}
+	return sizes, nil
+}
+
+// readSizesRLE parses the output of appendSizesRLE.  Alphabets with more than
+// maxSymbols symbols are rejected.
+func readSizesRLE(r io.ByteReader, maxSymbols uint) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if length > uint64(maxSymbols) {
+		return nil, fmt.Errorf("too many symbols in Huffman size table: got %d, max %d", length, maxSymbols)
+	}
+
+	sizes := make([]byte, length)
+	for i := uint64(0); i < length; {
+		size, err := r.ReadByte()
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if i == 0 && size == simpleTableMarker {
+			return readSimpleSizes(r, length)
+		}
+		if size > maxBitsPerCode {
+			return nil, fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
+		}
+
+		run, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if run == 0 || run > length-i {
+			return nil, fmt.Errorf("invalid run length in Huffman size table: got %d, max %d", run, length-i)
+		}
+
+		for j := uint64(0); j < run; j++ {
+			sizes[i+j] = size
+		}
+		i += run
+	}
+	return sizes, nil
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// EncodeSizesDelta returns a compact encoding of the changes from the size
+// table old to the size table new, for streams which rebuild their code from
+// time to time and would rather send only the lengths which changed; see also
+// Encoder.InitStable.  DecodeSizesDelta reverses it, given old.
+//
+// The encoding is the length of new as a uvarint, then the number of changed
+// Symbols as a uvarint, then for each changed Symbol in ascending order, the
+// number of unchanged Symbols since the previous one as a uvarint and the new
+// bit length as a byte.  Symbols beyond the end of old or new are treated as
+// having a bit length of 0.
+//
+func EncodeSizesDelta(old, new []byte) []byte {
+	sizeAt := func(sizes []byte, symbol int) byte {
+		if symbol < len(sizes) {
+			return sizes[symbol]
+		}
+		return 0
+	}
+
+	var numChanged int
+	for symbol := range new {
+		if new[symbol] != sizeAt(old, symbol) {
+			numChanged++
+		}
+	}
+
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64(len(new)))
+	out := append([]byte(nil), tmp[:n]...)
+	n = binary.PutUvarint(tmp[:], uint64(numChanged))
+	out = append(out, tmp[:n]...)
+
+	next := 0
+	for symbol := range new {
+		if new[symbol] == sizeAt(old, symbol) {
+			continue
+		}
+		n = binary.PutUvarint(tmp[:], uint64(symbol-next))
+		out = append(out, tmp[:n]...)
+		out = append(out, new[symbol])
+		next = symbol + 1
+	}
+	return out
+}
+
+// DecodeSizesDelta applies delta, the output of EncodeSizesDelta, to the size
+// table old and returns the new size table.  Size tables with more than
+// maxSymbols Symbols are rejected.  The result does not share storage with
+// old.
+func DecodeSizesDelta(old, delta []byte, maxSymbols uint) ([]byte, error) {
+	r := bytes.NewReader(delta)
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if length > uint64(maxSymbols) {
+		return nil, fmt.Errorf("too many symbols in Huffman size table: got %d, max %d", length, maxSymbols)
+	}
+	numChanged, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if numChanged > length {
+		return nil, fmt.Errorf("too many changes in Huffman size table delta: got %d, max %d", numChanged, length)
+	}
+
+	sizes := make([]byte, length)
+	copy(sizes, old)
+
+	next := uint64(0)
+	for i := uint64(0); i < numChanged; i++ {
+		skip, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if skip >= length-next {
+			return nil, fmt.Errorf("invalid skip in Huffman size table delta: got %d, max %d", skip, length-next-1)
+		}
+		size, err := r.ReadByte()
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if size > maxBitsPerCode {
+			return nil, fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
+		}
+		next += skip
+		sizes[next] = size
+		next++
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d bytes of trailing garbage after Huffman size table delta", r.Len())
+	}
+	return sizes, nil
+}
+
+// AppendSizes appends the compact encoding of a size table used throughout
+// this package (see EncodeInts and the stream format) to dst and returns the
+// extended slice.  ReadSizes reverses it.
+func AppendSizes(dst []byte, sizes []byte) []byte {
+	return appendSizesRLE(dst, sizes)
+}
+
+// ReadSizes parses a size table written by AppendSizes.  Alphabets with more
+// than maxSymbols Symbols are rejected.  The bit lengths are not checked for
+// consistency; pass the result to Decoder.Init for that.
+func ReadSizes(r io.ByteReader, maxSymbols uint) ([]byte, error) {
+	return readSizesRLE(r, maxSymbols)
+}
@@ -0,0 +1,131 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSizesRLE(t *testing.T) {
+	type testRow struct {
+		name   string
+		sizes  []byte
+		simple bool
+	}
+
+	testData := [...]testRow{
+		{name: "empty", sizes: []byte{}},
+		{name: "one", sizes: []byte{0, 0, 0, 1}, simple: true},
+		{name: "one-long", sizes: []byte{0, 3, 0}},
+		{name: "two", sizes: append(make([]byte, 300), 1, 0, 1), simple: true},
+		{name: "three", sizes: []byte{2, 0, 0, 0, 0, 1, 0, 0, 0, 0, 2}, simple: true},
+		{name: "four", sizes: []byte{2, 0, 2, 0, 2, 0, 2, 0}, simple: true},
+		{name: "four-skewed", sizes: []byte{3, 0, 0, 1, 0, 0, 3, 0, 0, 2}, simple: true},
+		{name: "four-dense", sizes: []byte{2, 2, 2, 2}},
+		{name: "five", sizes: []byte{1, 0, 2, 0, 3, 0, 4, 0, 4}},
+		{name: "wide", sizes: []byte{1, 2, 3, 4, 5, 6, 7, 8, 8}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			encoded := appendSizesRLE([]byte{0xaa}, row.sizes)
+			if encoded[0] != 0xaa {
+				t.Fatalf("dst prefix was overwritten")
+			}
+			isSimple := bytes.IndexByte(encoded[1:], simpleTableMarker) >= 0
+			if isSimple != row.simple {
+				t.Errorf("simple: expected %v, got %v (% x)", row.simple, isSimple, encoded)
+			}
+			decoded, err := readSizesRLE(bytes.NewReader(encoded[1:]), 1024)
+			if err != nil {
+				t.Fatalf("readSizesRLE: unexpected error: %v", err)
+			}
+			if !bytes.Equal(decoded, row.sizes) {
+				t.Errorf("round trip: expected %v, got %v", row.sizes, decoded)
+			}
+		})
+	}
+}
+
+func TestSizesRLE_Errors(t *testing.T) {
+	type testRow struct {
+		name  string
+		input []byte
+	}
+
+	testData := [...]testRow{
+		{name: "truncated", input: []byte{4, simpleTableMarker}},
+		{name: "bad-shape", input: []byte{4, simpleTableMarker, 5, 0, 1}},
+		{name: "symbol-range", input: []byte{4, simpleTableMarker, 1, 0, 4}},
+		{name: "duplicate", input: []byte{4, simpleTableMarker, 1, 2, 2}},
+		{name: "missing-symbol", input: []byte{4, simpleTableMarker, 2, 0, 1}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			if _, err := readSizesRLE(bytes.NewReader(row.input), 1024); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestSizesDelta(t *testing.T) {
+	type testRow struct {
+		name   string
+		old    []byte
+		new    []byte
+		length int
+	}
+
+	testData := [...]testRow{
+		{name: "same", old: []byte{1, 2, 3, 3}, new: []byte{1, 2, 3, 3}, length: 2},
+		{name: "swap", old: []byte{1, 2, 3, 3}, new: []byte{2, 1, 3, 3}, length: 6},
+		{name: "grow", old: []byte{1, 1}, new: []byte{1, 2, 0, 2}, length: 6},
+		{name: "shrink", old: []byte{2, 2, 2, 2}, new: []byte{1, 1}, length: 6},
+		{name: "from-empty", old: nil, new: []byte{0, 0, 1, 1}, length: 6},
+		{name: "to-empty", old: []byte{1, 1}, new: []byte{}, length: 2},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			delta := EncodeSizesDelta(row.old, row.new)
+			if len(delta) != row.length {
+				t.Errorf("expected %d bytes, got %d (% x)", row.length, len(delta), delta)
+			}
+			actual, err := DecodeSizesDelta(row.old, delta, 1024)
+			if err != nil {
+				t.Fatalf("DecodeSizesDelta: unexpected error: %v", err)
+			}
+			if !bytes.Equal(row.new, actual) {
+				t.Errorf("expected %v, got %v", row.new, actual)
+			}
+		})
+	}
+
+	old := []byte{1, 2, 3, 3}
+	for _, delta := range [][]byte{
+		{},
+		{5, 0},
+		{4, 5},
+		{4, 1, 4, 1},
+		{4, 1, 0, 17},
+		{4, 1, 0},
+		{4, 0, 0},
+	} {
+		if _, err := DecodeSizesDelta(old, delta, 4); err == nil {
+			t.Errorf("% x: expected error, got nil", delta)
+		}
+	}
+}
+
+func TestAppendSizes(t *testing.T) {
+	sizes := []byte{0, 0, 3, 3, 2, 2, 2, 0, 3, 3}
+	encoded := AppendSizes(nil, sizes)
+	decoded, err := ReadSizes(bytes.NewReader(encoded), uint(len(sizes)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(sizes, decoded) {
+		t.Errorf("expected %v, got %v", sizes, decoded)
+	}
+	if _, err := ReadSizes(bytes.NewReader(encoded), uint(len(sizes)-1)); err == nil {
+		t.Errorf("expected error for too many symbols, got nil")
+	}
+}
@@ -0,0 +1,118 @@
+package huffman
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// TableStrategy selects the data structure a Decoder uses for its lookup
+// table, which maps each legal code and code prefix to its decoding.
+type TableStrategy byte
+
+const (
+	// MapTable keeps the lookup table in a Go map.  This is the default.
+	MapTable TableStrategy = iota
+
+	// SortedTable keeps the lookup table in an array of 8-byte entries,
+	// sorted by code and searched by binary search.  It takes several
+	// times less memory than MapTable, at the cost of a logarithmic
+	// lookup, which suits immutable, long-lived Decoders for large
+	// alphabets.  Decoders which also have a direct table use it only for
+	// Decode, since BitReader reads through the direct table.
+	SortedTable
+)
+
+var tableStrategyNames = [...]string{
+	"MapTable",
+	"SortedTable",
+}
+
+// String returns the name of this TableStrategy.
+func (ts TableStrategy) String() string {
+	if uint(ts) < uint(len(tableStrategyNames)) {
+		return tableStrategyNames[ts]
+	}
+	return fmt.Sprintf("TableStrategy(%d)", uint(ts))
+}
+
+// A sorted table entry packs a code and its decoding into a uint64.  The
+// high 32 bits hold the code's Size<<16 | Bits, which fits because no code
+// is longer than maxBitsPerCode bits, so that the entries sort by code in
+// the order of Code.Packed.  The low 32 bits hold the Symbol of a legal
+// code, whose minSize and maxSize are both its Size, or else sortedPrefix |
+// minSize<<8 | maxSize for a proper prefix.
+const sortedPrefix = 1 << 31
+
+// sortedKey returns the high 32 bits of the sorted table entry for hc, and
+// false if hc cannot be in the table.
+func sortedKey(hc Code) (uint32, bool) {
+	if hc.Size > maxBitsPerCode || (hc.Bits>>hc.Size) != 0 {
+		return 0, false
+	}
+	return uint32(hc.Size)<<16 | hc.Bits, true
+}
+
+func makeSortedEntry(hc Code, dd decoderData) uint64 {
+	key, _ := sortedKey(hc)
+	value := uint32(dd.symbol)
+	if dd.symbol < 0 {
+		value = sortedPrefix | uint32(dd.minSize)<<8 | uint32(dd.maxSize)
+	}
+	return uint64(key)<<32 | uint64(value)
+}
+
+func splitSortedEntry(entry uint64) (Code, decoderData) {
+	key, value := uint32(entry>>32), uint32(entry)
+	hc := MakeCode(byte(key>>16), key&0xffff)
+	if value&sortedPrefix != 0 {
+		return hc, decoderData{InvalidSymbol, byte(value >> 8), byte(value)}
+	}
+	return hc, decoderData{Symbol(value), hc.Size, hc.Size}
+}
+
+// useSortedTable replaces this Decoder's map with a sorted table.
+func (d *Decoder) useSortedTable() {
+	if d.table == nil {
+		return
+	}
+	sorted := make([]uint64, 0, len(d.table))
+	for hc, dd := range d.table {
+		sorted = append(sorted, makeSortedEntry(hc, dd))
+	}
+	slices.Sort(sorted)
+	d.sorted = sorted
+	d.table = nil
+}
+
+// lookup returns the decoding of hc from whichever lookup table this Decoder
+// has.
+func (d Decoder) lookup(hc Code) (decoderData, bool) {
+	if d.sorted == nil {
+		dd, found := d.table[hc]
+		return dd, found
+	}
+	key, ok := sortedKey(hc)
+	if !ok {
+		return decoderData{}, false
+	}
+	index, found := slices.BinarySearchFunc(d.sorted, key, func(entry uint64, key uint32) int {
+		return cmp.Compare(uint32(entry>>32), key)
+	})
+	if !found {
+		return decoderData{}, false
+	}
+	_, dd := splitSortedEntry(d.sorted[index])
+	return dd, true
+}
+
+// forEachEntry calls fn for every entry of this Decoder's lookup table, in no
+// particular order.
+func (d Decoder) forEachEntry(fn func(Code, decoderData)) {
+	for hc, dd := range d.table {
+		fn(hc, dd)
+	}
+	for _, entry := range d.sorted {
+		fn(splitSortedEntry(entry))
+	}
+}
@@ -0,0 +1,90 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestTableStrategy_SortedTable(t *testing.T) {
+	type testRow struct {
+		name  string
+		sizes []byte
+		kind  DecoderKind
+	}
+
+	testData := [...]testRow{
+		{"empty", nil, PrefixMapDecoder},
+		{"test", []byte{4, 4, 3, 3, 3, 1}, DirectTableDecoder},
+		{"12-bit", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 12}, SortedTableDecoder},
+		{"16-bit", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 16}, SortedTableDecoder},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			expect := NewDecoder(row.sizes)
+			actual := NewDecoderWithOptions(row.sizes, Options{TableStrategy: SortedTable})
+			if kind := actual.Kind(); kind != row.kind {
+				t.Errorf("Kind: expected %v, got %v", row.kind, kind)
+			}
+			if a, b := expect.TableSize(), actual.TableSize(); a != b {
+				t.Errorf("TableSize: expected %d, got %d", a, b)
+			}
+			if a, b := expect.DebugString(), actual.DebugString(); a != b {
+				t.Errorf("DebugString: wrong output:\n\texpect: %q\n\tactual: %q", a, b)
+			}
+			if a, b := actual.DebugString(), actual.Clone().DebugString(); a != b {
+				t.Errorf("Clone: wrong output:\n\texpect: %q\n\tactual: %q", a, b)
+			}
+
+			// Every Code up to 17 bits decodes the same way, legal
+			// or not.
+			for size := byte(0); size <= 17; size++ {
+				for bits := uint32(0); bits < 1<<size && bits < 4096; bits++ {
+					hc := MakeCode(size, bits)
+					s1, min1, max1 := expect.Decode(hc)
+					s2, min2, max2 := actual.Decode(hc)
+					if s1 != s2 || min1 != min2 || max1 != max2 {
+						t.Fatalf("Decode(%v): expected {%d, %d, %d}, got {%d, %d, %d}", hc, s1, min1, max1, s2, min2, max2)
+					}
+				}
+			}
+			if symbol, _, _ := actual.Decode(MakeCode(1, 2)); symbol != InvalidSymbol {
+				t.Errorf("Decode of a Code with stray bits: expected InvalidSymbol, got %d", symbol)
+			}
+		})
+	}
+}
+
+func TestTableStrategy_MemoryFootprint(t *testing.T) {
+	rng := rand.New(rand.NewSource(186))
+	freqs := make([]uint32, 20000)
+	for i := range freqs {
+		freqs[i] = 1 + uint32(rng.ExpFloat64()*1000)
+	}
+	sizes, err := BuildSizes(freqs, 16)
+	if err != nil {
+		t.Fatalf("BuildSizes: unexpected error: %v", err)
+	}
+
+	m := NewDecoder(sizes)
+	s := NewDecoderWithOptions(sizes, Options{TableStrategy: SortedTable})
+	if a, b := m.MemoryFootprint(), s.MemoryFootprint(); b*2 > a {
+		t.Errorf("MemoryFootprint: expected SortedTable (%d) to be less than half of MapTable (%d)", b, a)
+	}
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	input := make([]Symbol, 1000)
+	e := s.Encoder()
+	for i := range input {
+		input[i] = Symbol(rng.Intn(len(sizes)))
+		_ = bw.WriteSymbol(e, input[i])
+	}
+	_ = bw.Flush()
+	br := NewBitReader(&buf)
+	for i, expect := range input {
+		if actual, err := br.ReadSymbol(s); err != nil || actual != expect {
+			t.Fatalf("ReadSymbol #%d: expected %d, got %d and %v", i, expect, actual, err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// maxRiceUnary is the longest unary quotient a TailCoder will write.  A
+// quotient this large is written as that many 1 bits followed by the raw
+// 32-bit tail index, bounding the cost of outliers.
+const maxRiceUnary = 24
+
+// TailCoder codes an alphabet with a long tail of rare Symbols: the Symbols
+// below a cutoff get Huffman codes of their own, while every Symbol at or
+// above it is coded as an escape code followed by its distance from the
+// cutoff, Golomb-Rice coded.  Since the tail needs no table, this suits
+// alphabets too large or too sparse for a Huffman code alone, and it can
+// code any Symbol, including ones never seen when the code was built.
+//
+// The escape is Symbol cutoff of the underlying Encoder and Decoder.  The
+// Rice parameter is chosen to minimize the coded size of the tail Symbols in
+// the frequencies given to NewTailCoder.
+//
+type TailCoder struct {
+	e      Encoder
+	d      Decoder
+	cutoff Symbol
+	k      byte
+}
+
+// NewTailCoder returns a new TailCoder for the given Symbol frequencies,
+// giving Huffman codes to the Symbols below cutoff.  cutoff may not exceed
+// (1<<16)-1, so that the Symbols below it and the escape all fit in 16-bit
+// codes.
+func NewTailCoder(frequencies []uint32, cutoff Symbol) *TailCoder {
+	if cutoff < 0 || cutoff >= 1<<maxBitsPerCode {
+		panic(fmt.Errorf("NewTailCoder: cutoff %d out of range", cutoff))
+	}
+
+	head := make([]uint32, cutoff+1)
+	var tail []uint32
+	if int(cutoff) < len(frequencies) {
+		copy(head, frequencies[:cutoff])
+		tail = frequencies[cutoff:]
+	} else {
+		copy(head, frequencies)
+	}
+
+	// The escape gets the combined frequency of the tail, and at least 1
+	// so that unseen tail Symbols can still be coded.
+	var escapes uint64 = 1
+	for _, freq := range tail {
+		escapes += uint64(freq)
+	}
+	if escapes > math.MaxUint32 {
+		escapes = math.MaxUint32
+	}
+	head[cutoff] = uint32(escapes)
+
+	tc := &TailCoder{cutoff: cutoff, k: chooseRiceParameter(tail)}
+	initLimited(&tc.e, len(head), head)
+	if err := tc.d.InitFromEncoder(tc.e); err != nil {
+		panic(err)
+	}
+	return tc
+}
+
+// chooseRiceParameter returns the Rice parameter which codes the given
+// frequencies of tail indices in the fewest bits, preferring the smaller
+// parameter in case of a tie.
+func chooseRiceParameter(tail []uint32) byte {
+	var best byte
+	var bestCost uint64 = math.MaxUint64
+	for k := byte(0); k < 32; k++ {
+		var cost uint64
+		for index, freq := range tail {
+			cost += uint64(freq) * uint64(riceBits(uint32(index), k))
+		}
+		if cost < bestCost {
+			best, bestCost = k, cost
+		}
+	}
+	return best
+}
+
+// riceBits returns the number of bits in the Rice code of value.
+func riceBits(value uint32, k byte) uint {
+	q := value >> k
+	if q >= maxRiceUnary {
+		return maxRiceUnary + 32
+	}
+	return uint(q) + 1 + uint(k)
+}
+
+// Cutoff returns the first Symbol which is coded in the tail.
+func (tc *TailCoder) Cutoff() Symbol {
+	return tc.cutoff
+}
+
+// RiceParameter returns the number of low bits of each tail index which are
+// written verbatim after its unary-coded quotient.
+func (tc *TailCoder) RiceParameter() byte {
+	return tc.k
+}
+
+// Encoder returns the Encoder for the Symbols below the cutoff and the
+// escape.
+func (tc *TailCoder) Encoder() *Encoder {
+	return &tc.e
+}
+
+// Decoder returns the Decoder for the Symbols below the cutoff and the
+// escape.
+func (tc *TailCoder) Decoder() *Decoder {
+	return &tc.d
+}
+
+// WriteSymbol writes the code for the given Symbol.
+func (tc *TailCoder) WriteSymbol(bw *BitWriter, symbol Symbol) error {
+	if symbol < 0 || symbol > MaxSymbol {
+		return fmt.Errorf("symbol %d out of range", symbol)
+	}
+	if symbol < tc.cutoff {
+		return bw.WriteSymbol(&tc.e, symbol)
+	}
+	if err := bw.WriteSymbol(&tc.e, tc.cutoff); err != nil {
+		return err
+	}
+
+	value := uint32(symbol - tc.cutoff)
+	q := value >> tc.k
+	if q >= maxRiceUnary {
+		if err := bw.WriteBits(maxRiceUnary, (1<<maxRiceUnary)-1); err != nil {
+			return err
+		}
+		return bw.WriteBits(32, value)
+	}
+	if err := bw.WriteBits(byte(q)+1, (uint32(1)<<q)-1); err != nil {
+		return err
+	}
+	return bw.WriteBits(tc.k, value&((uint32(1)<<tc.k)-1))
+}
+
+// ReadSymbol reads the code for one Symbol.
+func (tc *TailCoder) ReadSymbol(br *BitReader) (Symbol, error) {
+	symbol, err := br.ReadSymbol(&tc.d)
+	if err != nil || symbol != tc.cutoff {
+		return symbol, err
+	}
+
+	var q uint32
+	for q < maxRiceUnary {
+		bit, err := br.ReadBits(1)
+		if err != nil {
+			return InvalidSymbol, noEOF(err)
+		}
+		if bit == 0 {
+			break
+		}
+		q++
+	}
+
+	var value uint32
+	if q >= maxRiceUnary {
+		value, err = br.ReadBits(32)
+	} else {
+		var low uint32
+		low, err = br.ReadBits(tc.k)
+		value = (q << tc.k) | low
+	}
+	if err != nil {
+		return InvalidSymbol, noEOF(err)
+	}
+	if uint64(value)+uint64(tc.cutoff) > uint64(MaxSymbol) {
+		return InvalidSymbol, fmt.Errorf("tail symbol %d+%d out of range", tc.cutoff, value)
+	}
+	return tc.cutoff + Symbol(value), nil
+}
+
+// Encode appends the compressed form of symbols to dst and returns the
+// extended slice.
+func (tc *TailCoder) Encode(dst []byte, symbols []Symbol) ([]byte, error) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64(len(symbols)))
+	dst = append(dst, tmp[:n]...)
+
+	buf := bytes.NewBuffer(dst)
+	bw := NewBitWriter(buf)
+	for _, symbol := range symbols {
+		if err := tc.WriteSymbol(bw, symbol); err != nil {
+			return dst, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return dst, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode decompresses data produced by Encode.  At most maxSymbols Symbols
+// will be accepted.
+func (tc *TailCoder) Decode(src []byte, maxSymbols int) ([]Symbol, error) {
+	count, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid symbol count")
+	}
+	if count > uint64(maxSymbols) {
+		return nil, fmt.Errorf("too many symbols: got %d, max %d", count, maxSymbols)
+	}
+
+	br := NewBitReader(bytes.NewReader(src[n:]))
+	out := make([]Symbol, count)
+	for index := range out {
+		symbol, err := tc.ReadSymbol(br)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		out[index] = symbol
+	}
+	return out, nil
+}
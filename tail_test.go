@@ -0,0 +1,104 @@
+package huffman
+
+import (
+	"testing"
+)
+
+func TestTailCoder(t *testing.T) {
+	frequencies := make([]uint32, 1000)
+	for index := range frequencies {
+		frequencies[index] = uint32(1000 / (index + 1))
+	}
+	tc := NewTailCoder(frequencies, 16)
+	if expect, actual := Symbol(16), tc.Cutoff(); expect != actual {
+		t.Errorf("Cutoff: expected %d, got %d", expect, actual)
+	}
+	if expect, actual := uint(17), tc.Encoder().NumSymbols(); expect != actual {
+		t.Errorf("Encoder.NumSymbols: expected %d, got %d", expect, actual)
+	}
+
+	type testRow struct {
+		name    string
+		symbols []Symbol
+	}
+
+	testData := [...]testRow{
+		{name: "empty", symbols: []Symbol{}},
+		{name: "head", symbols: []Symbol{0, 1, 2, 15, 0}},
+		{name: "tail", symbols: []Symbol{16, 17, 100, 999, 0, 3}},
+		{name: "unseen", symbols: []Symbol{1000, 123456, MaxSymbol, 16}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			raw, err := tc.Encode([]byte{0xff}, row.symbols)
+			if err != nil {
+				t.Fatalf("Encode: unexpected error: %v", err)
+			}
+			if raw[0] != 0xff {
+				t.Fatalf("Encode did not preserve the existing contents of dst")
+			}
+			output, err := tc.Decode(raw[1:], len(row.symbols))
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v", err)
+			}
+			if len(output) != len(row.symbols) {
+				t.Fatalf("wrong length: expected %d, got %d", len(row.symbols), len(output))
+			}
+			for index := range output {
+				if output[index] != row.symbols[index] {
+					t.Errorf("wrong symbol at index %d: expected %d, got %d", index, row.symbols[index], output[index])
+				}
+			}
+		})
+	}
+
+	if _, err := tc.Encode(nil, []Symbol{-1}); err == nil {
+		t.Errorf("Encode: expected error for negative symbol, got nil")
+	}
+	raw, _ := tc.Encode(nil, []Symbol{500, 600})
+	if _, err := tc.Decode(raw, 1); err == nil {
+		t.Errorf("Decode: expected error for too many symbols, got nil")
+	}
+	if _, err := tc.Decode(raw[:len(raw)-1], 2); err == nil {
+		t.Errorf("Decode: expected error for truncated input, got nil")
+	}
+}
+
+func TestChooseRiceParameter(t *testing.T) {
+	type testRow struct {
+		name   string
+		tail   []uint32
+		expect byte
+	}
+
+	flat := func(n int, scale uint32) []uint32 {
+		out := make([]uint32, n)
+		for index := range out {
+			out[index] = scale
+		}
+		return out
+	}
+
+	testData := [...]testRow{
+		{name: "empty", tail: nil, expect: 0},
+		{name: "first-only", tail: []uint32{100}, expect: 0},
+		{name: "flat-16", tail: flat(16, 1), expect: 2},
+		{name: "flat-256", tail: flat(256, 1), expect: 6},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			if actual := chooseRiceParameter(row.tail); actual != row.expect {
+				t.Errorf("expected k=%d, got k=%d", row.expect, actual)
+			}
+		})
+	}
+}
+
+func TestTailCoder_CutoffTooLarge(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewTailCoder: expected panic for cutoff 1<<16")
+		}
+	}()
+	NewTailCoder(nil, 1<<16)
+}
@@ -0,0 +1,113 @@
+package huffman
+
+import (
+	"encoding"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MarshalText renders this Code as a string of '0' and '1' characters, in
+// the same format as String but without the quotes.
+func (hc Code) MarshalText() ([]byte, error) {
+	if hc.Size > 32 {
+		return nil, fmt.Errorf("invalid Code size %d", hc.Size)
+	}
+	if hc.Size == 0 {
+		return []byte{}, nil
+	}
+	format := "%0" + strconv.FormatUint(uint64(hc.Size), 10) + "b"
+	return []byte(fmt.Sprintf(format, hc.Bits)), nil
+}
+
+// UnmarshalText parses a Code in the format produced by MarshalText.
+func (hc *Code) UnmarshalText(text []byte) error {
+	if len(text) > 32 {
+		return fmt.Errorf("invalid Code %q: more than 32 bits", text)
+	}
+	var bits uint32
+	for _, ch := range text {
+		switch ch {
+		case '0':
+			bits <<= 1
+		case '1':
+			bits = (bits << 1) | 1
+		default:
+			return fmt.Errorf("invalid Code %q: unexpected character %q", text, ch)
+		}
+	}
+	*hc = MakeCode(byte(len(text)), bits)
+	return nil
+}
+
+// MarshalText renders this Encoder as a comma-separated list of bit lengths,
+// one for each Symbol, e.g. "4,4,3,3,3,1".
+func (e Encoder) MarshalText() ([]byte, error) {
+	return formatSizes(e.SizeBySymbol()), nil
+}
+
+// UnmarshalText initializes this Encoder from text in the format produced by
+// MarshalText.
+func (e *Encoder) UnmarshalText(text []byte) error {
+	sizes, err := parseSizes(text)
+	if err != nil {
+		return err
+	}
+	return e.InitFromSizes(sizes)
+}
+
+// MarshalText renders this Decoder as a comma-separated list of bit lengths,
+// one for each Symbol, e.g. "4,4,3,3,3,1".
+func (d Decoder) MarshalText() ([]byte, error) {
+	return formatSizes(d.sizes), nil
+}
+
+// UnmarshalText initializes this Decoder from text in the format produced by
+// MarshalText.
+func (d *Decoder) UnmarshalText(text []byte) error {
+	sizes, err := parseSizes(text)
+	if err != nil {
+		return err
+	}
+	return d.init(sizes)
+}
+
+func formatSizes(sizes []byte) []byte {
+	out := make([]byte, 0, 3*len(sizes))
+	for index, size := range sizes {
+		if index != 0 {
+			out = append(out, ',')
+		}
+		out = strconv.AppendUint(out, uint64(size), 10)
+	}
+	return out
+}
+
+func parseSizes(text []byte) ([]byte, error) {
+	str := strings.TrimSpace(string(text))
+	if str == "" {
+		return []byte{}, nil
+	}
+	fields := strings.Split(str, ",")
+	sizes := make([]byte, len(fields))
+	for index, field := range fields {
+		size, err := strconv.ParseUint(strings.TrimSpace(field), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bit length %q at index %d", field, index)
+		}
+		if size > maxBitsPerCode {
+			return nil, fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
+		}
+		sizes[index] = byte(size)
+	}
+	return sizes, nil
+}
+
+var (
+	_ encoding.TextMarshaler   = Code{}
+	_ encoding.TextUnmarshaler = (*Code)(nil)
+	_ encoding.TextMarshaler   = Encoder{}
+	_ encoding.TextUnmarshaler = (*Encoder)(nil)
+	_ encoding.TextMarshaler   = Decoder{}
+	_ encoding.TextUnmarshaler = (*Decoder)(nil)
+)
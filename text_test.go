@@ -0,0 +1,104 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestCode_MarshalText(t *testing.T) {
+	type testRow struct {
+		hc   Code
+		text string
+	}
+
+	testData := [...]testRow{
+		{Code{}, ""},
+		{MakeCode(1, 0), "0"},
+		{MakeCode(4, 0x7), "0111"},
+		{MakeCode(3, 0x5), "101"},
+	}
+	for _, row := range testData {
+		text, err := row.hc.MarshalText()
+		if err != nil {
+			t.Errorf("%v: MarshalText: unexpected error: %v", row.hc, err)
+			continue
+		}
+		if row.text != string(text) {
+			t.Errorf("%v: MarshalText: expected %q, got %q", row.hc, row.text, text)
+		}
+
+		var hc Code
+		if err := hc.UnmarshalText(text); err != nil {
+			t.Errorf("%q: UnmarshalText: unexpected error: %v", text, err)
+		} else if hc != row.hc {
+			t.Errorf("%q: UnmarshalText: expected %v, got %v", text, row.hc, hc)
+		}
+	}
+
+	var hc Code
+	for _, bad := range []string{"012", "1 0", "000000000000000000000000000000000"} {
+		if err := hc.UnmarshalText([]byte(bad)); err == nil {
+			t.Errorf("%q: UnmarshalText: expected error", bad)
+		}
+	}
+}
+
+func TestEncoder_MarshalText(t *testing.T) {
+	e := makeTestEncoder()
+	text, err := e.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText: unexpected error: %v", err)
+	}
+	if expect := "4,4,3,3,3,1"; expect != string(text) {
+		t.Errorf("MarshalText: expected %q, got %q", expect, text)
+	}
+
+	var e2 Encoder
+	if err := e2.UnmarshalText([]byte(" 4, 4,3,3,3,1 ")); err != nil {
+		t.Fatalf("UnmarshalText: unexpected error: %v", err)
+	}
+	if expect, actual := e.DebugString(), e2.DebugString(); expect != actual {
+		t.Errorf("UnmarshalText: wrong Encoder:\n\texpect: %s\n\tactual: %s", expect, actual)
+	}
+
+	for _, bad := range []string{"4,x", "4,,4", "17,1", "1,1,1"} {
+		if err := e2.UnmarshalText([]byte(bad)); err == nil {
+			t.Errorf("%q: UnmarshalText: expected error", bad)
+		}
+	}
+
+	if err := e2.UnmarshalText(nil); err != nil {
+		t.Errorf("UnmarshalText: unexpected error for empty input: %v", err)
+	}
+}
+
+func TestDecoder_MarshalText(t *testing.T) {
+	d := makeTestDecoder()
+	text, err := d.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText: unexpected error: %v", err)
+	}
+	if expect := "4,4,3,3,3,1"; expect != string(text) {
+		t.Errorf("MarshalText: expected %q, got %q", expect, text)
+	}
+
+	var d2 Decoder
+	if err := d2.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText: unexpected error: %v", err)
+	}
+	if !bytes.Equal(d.SizeBySymbol(), d2.SizeBySymbol()) {
+		t.Errorf("UnmarshalText: wrong sizes: %v", d2.SizeBySymbol())
+	}
+}
+
+func TestCode_TextAsMapKey(t *testing.T) {
+	// TextMarshaler lets a Code be used as a JSON object key.
+	raw, err := json.Marshal(map[Code]int{MakeCode(3, 0x5): 1})
+	if err != nil {
+		t.Fatalf("json.Marshal: unexpected error: %v", err)
+	}
+	if expect := `{"101":1}`; expect != string(raw) {
+		t.Errorf("json.Marshal: expected %s, got %s", expect, raw)
+	}
+}
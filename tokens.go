@@ -0,0 +1,235 @@
+package huffman
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// maxTokenSymbols is the largest number of distinct tokens a TokenCodec will
+// assign codes to.  One more Symbol is needed for the escape.
+const maxTokenSymbols = (1 << maxBitsPerCode) - 1
+
+// tokenEscape is the Symbol which introduces a token with no code of its own.
+const tokenEscape = Symbol(0)
+
+// TokenCodec compresses sequences of token IDs, such as the output of an LLM
+// tokenizer, whose vocabulary may be far larger than any one code can hold.
+//
+// The most frequent tokens in the training data (up to 65535 of them) are
+// assigned Huffman codes.  Every other token is written as an escape code
+// followed by the token's zigzag varint encoding, so any int32 can be coded,
+// including tokens never seen during training.
+//
+type TokenCodec struct {
+	toSymbol   map[int32]Symbol
+	fromSymbol []int32
+	e          Encoder
+	d          Decoder
+}
+
+// NewTokenCodec returns a new TokenCodec trained on the given token
+// sequences.
+func NewTokenCodec(training ...[]int32) *TokenCodec {
+	counts := make(map[int32]uint32)
+	for _, tokens := range training {
+		for _, token := range tokens {
+			if counts[token] < math.MaxUint32 {
+				counts[token]++
+			}
+		}
+	}
+
+	type tokenAndCount struct {
+		token int32
+		count uint32
+	}
+	list := make([]tokenAndCount, 0, len(counts))
+	for token, count := range counts {
+		list = append(list, tokenAndCount{token, count})
+	}
+	// The most frequent tokens come first, and equally frequent tokens in
+	// ascending order, so that the same training data always assigns the
+	// same Symbols.
+	slices.SortFunc(list, func(a, b tokenAndCount) int {
+		if c := cmp.Compare(b.count, a.count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.token, b.token)
+	})
+
+	var escapes uint32 = 1
+	if len(list) > maxTokenSymbols {
+		for _, item := range list[maxTokenSymbols:] {
+			escapes += item.count
+		}
+		list = list[:maxTokenSymbols]
+	}
+
+	numSymbols := len(list) + 1
+	frequencies := make([]uint32, numSymbols)
+	frequencies[tokenEscape] = escapes
+	tc := &TokenCodec{
+		toSymbol:   make(map[int32]Symbol, len(list)),
+		fromSymbol: make([]int32, numSymbols),
+	}
+	for index, item := range list {
+		symbol := Symbol(index + 1)
+		tc.toSymbol[item.token] = symbol
+		tc.fromSymbol[symbol] = item.token
+		frequencies[symbol] = item.count
+	}
+
+	initLimited(&tc.e, numSymbols, frequencies)
+	if err := tc.d.InitFromEncoder(tc.e); err != nil {
+		panic(err)
+	}
+	return tc
+}
+
+// NumTokens returns the number of tokens which have codes of their own.
+func (tc *TokenCodec) NumTokens() int {
+	return len(tc.fromSymbol) - 1
+}
+
+// Encoder returns the Encoder used by this TokenCodec.  Symbol 0 is the
+// escape; Symbol i > 0 codes the i'th most frequent training token.
+func (tc *TokenCodec) Encoder() *Encoder {
+	return &tc.e
+}
+
+// MarshalBinary renders this TokenCodec as the size table of its code (see
+// AppendSizes), followed by the token for each Symbol after the escape as a
+// zigzag varint.
+func (tc *TokenCodec) MarshalBinary() ([]byte, error) {
+	out := appendSizesRLE(nil, tc.e.SizeBySymbol())
+	var tmp [binary.MaxVarintLen64]byte
+	for _, token := range tc.fromSymbol[1:] {
+		n := binary.PutVarint(tmp[:], int64(token))
+		out = append(out, tmp[:n]...)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary initializes this TokenCodec from the output of
+// MarshalBinary.  The result encodes and decodes exactly as the original
+// did.
+func (tc *TokenCodec) UnmarshalBinary(raw []byte) error {
+	r := bytes.NewReader(raw)
+	sizes, err := readSizesRLE(r, maxTokenSymbols+1)
+	if err != nil {
+		return err
+	}
+	if len(sizes) == 0 {
+		return fmt.Errorf("no code for the escape")
+	}
+	for symbol, size := range sizes {
+		if size == 0 {
+			return fmt.Errorf("symbol %d has no code", symbol)
+		}
+	}
+
+	tmp := TokenCodec{
+		toSymbol:   make(map[int32]Symbol, len(sizes)-1),
+		fromSymbol: make([]int32, len(sizes)),
+	}
+	for symbol := Symbol(1); symbol < Symbol(len(sizes)); symbol++ {
+		value, err := binary.ReadVarint(r)
+		if err != nil {
+			return noEOF(err)
+		}
+		if value < math.MinInt32 || value > math.MaxInt32 {
+			return fmt.Errorf("token %d out of range", value)
+		}
+		token := int32(value)
+		if other, found := tmp.toSymbol[token]; found {
+			return fmt.Errorf("symbols %d and %d both have token %d", other, symbol, token)
+		}
+		tmp.toSymbol[token] = symbol
+		tmp.fromSymbol[symbol] = token
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d bytes of trailing garbage after tokens", r.Len())
+	}
+
+	if err := tmp.e.InitFromSizes(sizes); err != nil {
+		return err
+	}
+	if err := tmp.d.InitFromEncoder(tmp.e); err != nil {
+		return err
+	}
+	*tc = tmp
+	return nil
+}
+
+// Encode appends the compressed form of tokens to dst and returns the
+// extended slice.
+func (tc *TokenCodec) Encode(dst []byte, tokens []int32) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64(len(tokens)))
+	dst = append(dst, tmp[:n]...)
+
+	buf := bytes.NewBuffer(dst)
+	bw := NewBitWriter(buf)
+	for _, token := range tokens {
+		if symbol, found := tc.toSymbol[token]; found {
+			_ = bw.WriteCode(tc.e.Encode(symbol))
+			continue
+		}
+		_ = bw.WriteCode(tc.e.Encode(tokenEscape))
+		n := binary.PutVarint(tmp[:], int64(token))
+		for _, b := range tmp[:n] {
+			_ = bw.WriteBits(8, uint32(b))
+		}
+	}
+	_ = bw.Flush()
+	return buf.Bytes()
+}
+
+// Decode decompresses data produced by Encode.  At most maxTokens tokens will
+// be accepted.
+func (tc *TokenCodec) Decode(src []byte, maxTokens int) ([]int32, error) {
+	count, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid token count")
+	}
+	if count > uint64(maxTokens) {
+		return nil, fmt.Errorf("too many tokens: got %d, max %d", count, maxTokens)
+	}
+
+	br := NewBitReader(bytes.NewReader(src[n:]))
+	out := make([]int32, count)
+	for index := range out {
+		symbol, err := br.ReadSymbol(&tc.d)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if symbol != tokenEscape {
+			out[index] = tc.fromSymbol[symbol]
+			continue
+		}
+		value, err := binary.ReadVarint(unalignedByteReader{br})
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if value < math.MinInt32 || value > math.MaxInt32 {
+			return nil, fmt.Errorf("escaped token %d out of range", value)
+		}
+		out[index] = int32(value)
+	}
+	return out, nil
+}
+
+// unalignedByteReader reads whole bytes from a BitReader without first
+// aligning to a byte boundary.
+type unalignedByteReader struct {
+	br *BitReader
+}
+
+func (r unalignedByteReader) ReadByte() (byte, error) {
+	bits, err := r.br.ReadBits(8)
+	return byte(bits), err
+}
@@ -0,0 +1,88 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTokenCodec(t *testing.T) {
+	training := []int32{15339, 374, 264, 1296, 13, 15339, 374, 264, 1296, 13, 100257}
+	tc := NewTokenCodec(training)
+	if expect, actual := 6, tc.NumTokens(); expect != actual {
+		t.Errorf("NumTokens: expected %d, got %d", expect, actual)
+	}
+
+	type testRow struct {
+		name   string
+		tokens []int32
+	}
+
+	testData := [...]testRow{
+		{name: "empty", tokens: []int32{}},
+		{name: "seen", tokens: []int32{15339, 374, 264, 1296, 13}},
+		{name: "unseen", tokens: []int32{15339, 99999, 374, -5, 2147483647, -2147483648}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			raw := tc.Encode([]byte{0xff}, row.tokens)
+			if raw[0] != 0xff {
+				t.Fatalf("Encode did not preserve the existing contents of dst")
+			}
+			output, err := tc.Decode(raw[1:], len(row.tokens))
+			if err != nil {
+				t.Fatalf("Decode: unexpected error: %v", err)
+			}
+			if len(output) != len(row.tokens) {
+				t.Fatalf("wrong length: expected %d, got %d", len(row.tokens), len(output))
+			}
+			for index := range output {
+				if output[index] != row.tokens[index] {
+					t.Errorf("wrong token at index %d: expected %d, got %d", index, row.tokens[index], output[index])
+				}
+			}
+		})
+	}
+
+	if _, err := tc.Decode(tc.Encode(nil, training), 3); err == nil {
+		t.Errorf("Decode: expected error for too many tokens, got nil")
+	}
+}
+
+func TestTokenCodec_MarshalBinary(t *testing.T) {
+	training := []int32{15339, 374, 264, 1296, 13, 15339, 374, 264, 1296, 13, 100257, -7}
+	tokens := []int32{15339, 99999, 374, -7, 2147483647, -2147483648, 13}
+
+	for _, tc := range []*TokenCodec{NewTokenCodec(training), NewTokenCodec()} {
+		raw, err := tc.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary: unexpected error: %v", err)
+		}
+
+		var again TokenCodec
+		if err := again.UnmarshalBinary(raw); err != nil {
+			t.Fatalf("UnmarshalBinary: unexpected error: %v", err)
+		}
+		if expect, actual := tc.NumTokens(), again.NumTokens(); expect != actual {
+			t.Errorf("NumTokens: expected %d, got %d", expect, actual)
+		}
+		expect := tc.Encode(nil, tokens)
+		if actual := again.Encode(nil, tokens); !bytes.Equal(expect, actual) {
+			t.Errorf("Encode: wrong output:\n\texpect: %x\n\tactual: %x", expect, actual)
+		}
+		output, err := again.Decode(expect, len(tokens))
+		if err != nil {
+			t.Fatalf("Decode: unexpected error: %v", err)
+		}
+		for index := range output {
+			if output[index] != tokens[index] {
+				t.Errorf("wrong token at index %d: expected %d, got %d", index, tokens[index], output[index])
+			}
+		}
+
+		for _, bad := range [][]byte{raw[:len(raw)-1], append(raw[:len(raw):len(raw)], 0)} {
+			if err := again.UnmarshalBinary(bad); err == nil {
+				t.Errorf("UnmarshalBinary(%x): expected error, got nil", bad)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package huffman
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tracer receives events from Encoder construction, for diagnosing where
+// Init spends its time on large alphabets.  Set Options.Trace to use one.
+//
+// Tracer methods are called synchronously from within InitWithOptions, so
+// they should return quickly.
+//
+type Tracer interface {
+	// Merge is called each time two subtrees are merged while building
+	// the Huffman tree.  Natural Symbols are non-negative; the subtrees
+	// formed by earlier merges are numbered consecutively upward from
+	// math.MinInt32.  freq is the combined frequency.
+	Merge(a, b Symbol, freq uint32)
+
+	// Phase is called as each phase of construction completes, with the
+	// time it took.
+	Phase(phase TracePhase, elapsed time.Duration)
+}
+
+// TracePhase identifies one phase of Encoder construction.
+type TracePhase byte
+
+const (
+	// PhaseHeap builds the min-heap of Symbol frequencies.
+	PhaseHeap TracePhase = iota
+
+	// PhaseMerge repeatedly merges the two least frequent subtrees.
+	PhaseMerge
+
+	// PhaseSizes walks the tree to assign a code length to each Symbol.
+	PhaseSizes
+
+	// PhaseCanonical numbers the codes to form a canonical Huffman code.
+	PhaseCanonical
+)
+
+var tracePhaseNames = [...]string{
+	"PhaseHeap",
+	"PhaseMerge",
+	"PhaseSizes",
+	"PhaseCanonical",
+}
+
+// String returns the name of this TracePhase.
+func (phase TracePhase) String() string {
+	if uint(phase) < uint(len(tracePhaseNames)) {
+		return tracePhaseNames[phase]
+	}
+	return fmt.Sprintf("TracePhase(%d)", uint(phase))
+}
+
+// traceStart returns the start time of a phase, or the zero Time if there
+// is no Tracer to report it to.
+func (opts *Options) traceStart() time.Time {
+	if opts.Trace == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// traceMerge reports a merge to the Tracer, if any.
+func (opts *Options) traceMerge(a, b Symbol, freq uint32) {
+	if opts.Trace != nil {
+		opts.Trace.Merge(a, b, freq)
+	}
+}
+
+// tracePhase reports the completion of a phase that began at start to the
+// Tracer, if any.
+func (opts *Options) tracePhase(phase TracePhase, start time.Time) {
+	if opts.Trace != nil {
+		opts.Trace.Phase(phase, time.Since(start))
+	}
+}
@@ -0,0 +1,86 @@
+package huffman
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type recordingTracer struct {
+	merges [][3]int64
+	phases []TracePhase
+}
+
+func (tr *recordingTracer) Merge(a, b Symbol, freq uint32) {
+	tr.merges = append(tr.merges, [3]int64{int64(a), int64(b), int64(freq)})
+}
+
+func (tr *recordingTracer) Phase(phase TracePhase, elapsed time.Duration) {
+	if elapsed < 0 {
+		panic("negative elapsed time")
+	}
+	tr.phases = append(tr.phases, phase)
+}
+
+func TestOptions_Trace(t *testing.T) {
+	type testRow struct {
+		name        string
+		frequencies []uint32
+		merges      [][3]int64
+		phases      []TracePhase
+	}
+
+	synthetic := func(n int64) int64 {
+		return math.MinInt32 + n
+	}
+
+	testData := [...]testRow{
+		{
+			name:        "skewed",
+			frequencies: []uint32{1, 2, 4, 8},
+			merges: [][3]int64{
+				{0, 1, 3},
+				{synthetic(0), 2, 7},
+				{synthetic(1), 3, 15},
+			},
+			phases: []TracePhase{PhaseHeap, PhaseMerge, PhaseSizes, PhaseCanonical},
+		},
+		{
+			name:        "flat",
+			frequencies: []uint32{1, 1, 1, 1, 1},
+			merges:      nil,
+			phases:      []TracePhase{PhaseSizes, PhaseCanonical},
+		},
+		{
+			name:        "trivial",
+			frequencies: []uint32{1, 1},
+			merges:      nil,
+			phases:      nil,
+		},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			tr := new(recordingTracer)
+			var e Encoder
+			if err := e.InitWithOptions(len(row.frequencies), row.frequencies, Options{Trace: tr}); err != nil {
+				t.Fatalf("InitWithOptions: unexpected error: %v", err)
+			}
+			if len(tr.merges) != len(row.merges) {
+				t.Fatalf("merges: expected %v, got %v", row.merges, tr.merges)
+			}
+			for index := range tr.merges {
+				if tr.merges[index] != row.merges[index] {
+					t.Errorf("merge %d: expected %v, got %v", index, row.merges[index], tr.merges[index])
+				}
+			}
+			if len(tr.phases) != len(row.phases) {
+				t.Fatalf("phases: expected %v, got %v", row.phases, tr.phases)
+			}
+			for index := range tr.phases {
+				if tr.phases[index] != row.phases[index] {
+					t.Errorf("phase %d: expected %v, got %v", index, row.phases[index], tr.phases[index])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package huffman
+
+import (
+	"io"
+)
+
+// Transcode reads n Symbols coded with src from r and writes them coded with
+// dst to w, without collecting them in between.  It returns the number of
+// Symbols transcoded.
+//
+// If n is negative, Transcode continues until r is exhausted at a code
+// boundary, and returns a nil error in that case.  Otherwise, running out of
+// input before n Symbols is reported as io.EOF, as with io.CopyN.  Symbols
+// which src can decode but dst has no code for are reported as ErrNoCode.
+//
+// The caller must flush w when done.
+//
+func Transcode(dst *Encoder, src *Decoder, r *BitReader, w *BitWriter, n int) (int, error) {
+	var count int
+	for n < 0 || count < n {
+		symbol, err := r.ReadSymbol(src)
+		if err == io.EOF && n < 0 {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		if err := w.WriteSymbol(dst, symbol); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
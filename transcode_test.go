@@ -0,0 +1,62 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestTranscode(t *testing.T) {
+	symbols := []Symbol{0, 1, 2, 3, 3, 3, 2, 1, 0, 3, 3}
+	a := NewEncoder(4, []uint32{10, 1, 1, 1})
+	b := NewEncoder(4, []uint32{1, 2, 3, 10})
+
+	encode := func(e *Encoder) []byte {
+		var buf bytes.Buffer
+		bw := NewBitWriter(&buf)
+		for _, symbol := range symbols {
+			_ = bw.WriteSymbol(e, symbol)
+		}
+		_ = bw.Flush()
+		return buf.Bytes()
+	}
+	input, expect := encode(a), encode(b)
+
+	var buf bytes.Buffer
+	w := NewBitWriter(&buf)
+	n, err := Transcode(b, a.Decoder(), NewBitReader(bytes.NewReader(input)), w, len(symbols))
+	if err != nil || n != len(symbols) {
+		t.Fatalf("Transcode: expected (%d, nil), got (%d, %v)", len(symbols), n, err)
+	}
+	_ = w.Flush()
+	if actual := buf.Bytes(); !bytes.Equal(expect, actual) {
+		t.Errorf("expected %x, got %x", expect, actual)
+	}
+
+	// Too few Symbols available.
+	buf.Reset()
+	w.Init(&buf)
+	n, err = Transcode(b, a.Decoder(), NewBitReader(bytes.NewReader(input[:1])), w, len(symbols))
+	if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("short input: expected EOF, got (%d, %v)", n, err)
+	}
+
+	// Transcode to the end of the input.  The padding bits of the last
+	// byte decode as extra Symbols, so only check the prefix.
+	buf.Reset()
+	w.Init(&buf)
+	n, err = Transcode(b, a.Decoder(), NewBitReader(bytes.NewReader(input)), w, -1)
+	if err != nil || n < len(symbols) {
+		t.Errorf("n < 0: expected at least (%d, nil), got (%d, %v)", len(symbols), n, err)
+	}
+
+	// A Symbol with no code in dst.
+	c := NewEncoder(4, []uint32{1, 1, 1, 0})
+	buf.Reset()
+	w.Init(&buf)
+	n, err = Transcode(c, a.Decoder(), NewBitReader(bytes.NewReader(input)), w, len(symbols))
+	if !errors.Is(err, ErrNoCode) || n != 3 {
+		t.Errorf("missing code: expected (3, ErrNoCode), got (%d, %v)", n, err)
+	}
+}
@@ -0,0 +1,203 @@
+package huffman
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Transform is a reversible, length-preserving filter which a Writer applies
+// to the data before compressing it, and which a Reader reverses after
+// decompressing it.  See WriterOptions.Transforms and Reader.UseTransforms.
+//
+// A good Transform makes the data more predictable, as DeltaTransform does
+// for slowly changing samples.  Encryption is not a good Transform: its
+// output cannot be compressed.  Use WriterOptions.Cipher instead, which
+// encrypts the compressed data.
+//
+type Transform interface {
+	// Forward transforms p in place.  It is called on the data of each
+	// block in order, so it may carry state from one call to the next.
+	Forward(p []byte)
+
+	// Inverse reverses Forward in place.  It is called on the data
+	// returned by each Read in order.
+	Inverse(p []byte)
+
+	// Reset restores the initial state, as at the start of a stream.
+	Reset()
+}
+
+// DeltaTransform is a Transform which replaces each byte with its difference
+// from the byte before it.  The zero value is ready to use.
+type DeltaTransform struct {
+	forward byte
+	inverse byte
+}
+
+// Forward fulfills Transform.
+func (t *DeltaTransform) Forward(p []byte) {
+	prev := t.forward
+	for i, b := range p {
+		p[i] = b - prev
+		prev = b
+	}
+	t.forward = prev
+}
+
+// Inverse fulfills Transform.
+func (t *DeltaTransform) Inverse(p []byte) {
+	prev := t.inverse
+	for i, b := range p {
+		prev += b
+		p[i] = prev
+	}
+	t.inverse = prev
+}
+
+// Reset fulfills Transform.
+func (t *DeltaTransform) Reset() {
+	*t = DeltaTransform{}
+}
+
+var _ Transform = (*DeltaTransform)(nil)
+
+var (
+	// ErrMissingTransform is returned when reading a stream which was
+	// written with Transforms or a Cipher that the Reader was not given.
+	ErrMissingTransform = errors.New("Huffman stream needs a transform or cipher which was not supplied")
+
+	// ErrEncryptBeforeCompress is returned by a Writer when its
+	// Transforms turn a block of predictable data into what looks like
+	// random noise.  That usually means encryption is being done as a
+	// Transform, which defeats compression; use WriterOptions.Cipher.
+	ErrEncryptBeforeCompress = errors.New("Huffman stream transform output looks encrypted; encrypt after compressing instead")
+)
+
+const (
+	// guardMinBlock is the smallest block checked for the
+	// encrypt-then-compress mistake, since smaller samples of random data
+	// don't look random enough.
+	guardMinBlock = 1024
+
+	// guardMaxEntropy is the entropy in bits per byte above which the
+	// output of the Transforms is considered random.
+	guardMaxEntropy = 7.5
+
+	// guardMinGain is the least increase in entropy, in bits per byte,
+	// which the Transforms must cause for the Writer to complain.
+	guardMinGain = 1.0
+)
+
+// byteEntropy returns the empirical entropy of p in bits per byte.
+func byteEntropy(p []byte) float64 {
+	var counts [256]int
+	for _, b := range p {
+		counts[b]++
+	}
+	total := float64(len(p))
+	var sum float64
+	for _, count := range counts {
+		if count != 0 {
+			q := float64(count) / total
+			sum -= q * math.Log2(q)
+		}
+	}
+	return sum
+}
+
+// applyTransforms runs the Writer's Transforms over the buffered block, and
+// checks that they did not make predictable data random.  The Transforms run
+// at most once per block, even if writing the block is retried.
+func (zw *Writer) applyTransforms() error {
+	if len(zw.opts.Transforms) == 0 || zw.transformed {
+		return nil
+	}
+	zw.transformed = true
+	check := len(zw.buf) >= guardMinBlock
+	var before float64
+	if check {
+		before = byteEntropy(zw.buf)
+	}
+	for _, t := range zw.opts.Transforms {
+		t.Forward(zw.buf)
+	}
+	if check {
+		after := byteEntropy(zw.buf)
+		if after > guardMaxEntropy && after-before > guardMinGain {
+			return ErrEncryptBeforeCompress
+		}
+	}
+	return nil
+}
+
+// UseTransforms supplies the Transforms with which the stream was written,
+// in the same order as WriterOptions.Transforms.  Read reverses them in the
+// opposite order.  Reset and the start of each concatenated stream reset
+// them.
+func (zr *Reader) UseTransforms(list ...Transform) {
+	zr.transforms = list
+}
+
+// UseCipher supplies the cipher.Stream with which the stream was encrypted
+// (see WriterOptions.Cipher).  It must be called before the first Read.
+// Encrypted streams cannot be concatenated, so the Reader stops at the end
+// of the current stream, as with Multistream(false).  Reset forgets the
+// cipher.Stream.
+func (zr *Reader) UseCipher(s cipher.Stream) {
+	zr.br.r = &cipherByteReader{r: zr.br.r, s: s}
+	zr.hasCipher = true
+}
+
+// checkTransforms reports an error if the stream needs a Transform or cipher
+// which the Reader was not given.
+func (zr *Reader) checkTransforms() error {
+	if zr.encrypted && !zr.hasCipher {
+		return fmt.Errorf("%w: stream is encrypted", ErrMissingTransform)
+	}
+	if zr.transformed && len(zr.transforms) == 0 {
+		return fmt.Errorf("%w: stream is transformed", ErrMissingTransform)
+	}
+	return nil
+}
+
+// cipherWriter encrypts all bytes written to it after the first plain bytes.
+type cipherWriter struct {
+	w     io.Writer
+	s     cipher.Stream
+	plain int
+	buf   []byte
+}
+
+func (cw *cipherWriter) Write(p []byte) (int, error) {
+	cw.buf = append(cw.buf[:0], p...)
+	data := cw.buf
+	if cw.plain > 0 {
+		n := cw.plain
+		if n > len(data) {
+			n = len(data)
+		}
+		cw.plain -= n
+		data = data[n:]
+	}
+	cw.s.XORKeyStream(data, data)
+	return cw.w.Write(cw.buf)
+}
+
+// cipherByteReader decrypts each byte read from r.
+type cipherByteReader struct {
+	r io.ByteReader
+	s cipher.Stream
+}
+
+func (cr *cipherByteReader) ReadByte() (byte, error) {
+	b, err := cr.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	tmp := [1]byte{b}
+	cr.s.XORKeyStream(tmp[:], tmp[:])
+	return tmp[0], nil
+}
@@ -0,0 +1,158 @@
+package huffman
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func newTestCipher(t *testing.T) cipher.Stream {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("aes.NewCipher: unexpected error: %v", err)
+	}
+	return cipher.NewCTR(block, make([]byte, aes.BlockSize))
+}
+
+// cipherTransform is the mistake which ErrEncryptBeforeCompress catches.
+type cipherTransform struct {
+	s cipher.Stream
+}
+
+func (t cipherTransform) Forward(p []byte) { t.s.XORKeyStream(p, p) }
+func (t cipherTransform) Inverse(p []byte) { t.s.XORKeyStream(p, p) }
+func (t cipherTransform) Reset()           {}
+
+func TestDeltaTransform(t *testing.T) {
+	input := []byte{10, 12, 14, 13, 13, 255, 0}
+	data := append([]byte(nil), input...)
+
+	var dt DeltaTransform
+	dt.Forward(data[:3])
+	dt.Forward(data[3:])
+	if expect := []byte{10, 2, 2, 255, 0, 242, 1}; !bytes.Equal(expect, data) {
+		t.Errorf("Forward: expected %v, got %v", expect, data)
+	}
+	dt.Inverse(data[:5])
+	dt.Inverse(data[5:])
+	if !bytes.Equal(input, data) {
+		t.Errorf("Inverse: expected %v, got %v", input, data)
+	}
+}
+
+func TestStream_Transforms(t *testing.T) {
+	// A slow ramp compresses far better as deltas.
+	input := make([]byte, 20000)
+	for i := range input {
+		input[i] = byte(i / 3)
+	}
+
+	compress := func(opts WriterOptions) []byte {
+		var buf bytes.Buffer
+		zw := NewWriterOptions(&buf, opts)
+		if _, err := zw.Write(input); err != nil {
+			t.Fatalf("Write: unexpected error: %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Close: unexpected error: %v", err)
+		}
+		return buf.Bytes()
+	}
+	decompress := func(data []byte, setup func(zr *Reader)) ([]byte, error) {
+		zr, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("NewReader: unexpected error: %v", err)
+		}
+		setup(zr)
+		buf := make([]byte, 777)
+		var out []byte
+		for {
+			n, err := zr.Read(buf)
+			out = append(out, buf[:n]...)
+			if err == io.EOF {
+				return out, nil
+			}
+			if err != nil {
+				return out, err
+			}
+		}
+	}
+
+	plain := compress(WriterOptions{BlockSize: 4096})
+	delta := compress(WriterOptions{BlockSize: 4096, Transforms: []Transform{new(DeltaTransform)}})
+	if len(delta) >= len(plain)/2 {
+		t.Errorf("DeltaTransform: expected much better compression, got %d bytes vs %d", len(delta), len(plain))
+	}
+	output, err := decompress(delta, func(zr *Reader) { zr.UseTransforms(new(DeltaTransform)) })
+	if err != nil || !bytes.Equal(input, output) {
+		t.Errorf("DeltaTransform: expected the input, got %d bytes and %v", len(output), err)
+	}
+	if _, err := decompress(delta, func(zr *Reader) {}); !errors.Is(err, ErrMissingTransform) {
+		t.Errorf("DeltaTransform without UseTransforms: expected ErrMissingTransform, got %v", err)
+	}
+
+	both := compress(WriterOptions{
+		BlockSize:  4096,
+		Checksum:   ChecksumCRC32C,
+		Transforms: []Transform{new(DeltaTransform)},
+		Cipher:     newTestCipher(t),
+	})
+	if !bytes.Equal(both[:4], streamMagic[:]) {
+		t.Errorf("Cipher: expected a plain header, got %q", both[:6])
+	}
+	if bytes.Contains(both, []byte{0x00, 0x00, 0x00, 0x00}) {
+		t.Errorf("Cipher: output does not look encrypted")
+	}
+	output, err = decompress(both, func(zr *Reader) {
+		zr.UseTransforms(new(DeltaTransform))
+		zr.UseCipher(newTestCipher(t))
+	})
+	if err != nil || !bytes.Equal(input, output) {
+		t.Errorf("Cipher: expected the input, got %d bytes and %v", len(output), err)
+	}
+	if _, err := decompress(both, func(zr *Reader) { zr.UseTransforms(new(DeltaTransform)) }); !errors.Is(err, ErrMissingTransform) {
+		t.Errorf("Cipher without UseCipher: expected ErrMissingTransform, got %v", err)
+	}
+
+	indexed := compress(WriterOptions{Index: true, Transforms: []Transform{new(DeltaTransform)}})
+	if _, err := NewIndexedReader(bytes.NewReader(indexed), int64(len(indexed))); !errors.Is(err, ErrMissingTransform) {
+		t.Errorf("NewIndexedReader: expected ErrMissingTransform, got %v", err)
+	}
+}
+
+func TestStream_EncryptBeforeCompress(t *testing.T) {
+	text := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 100))
+
+	var buf bytes.Buffer
+	zw := NewWriterOptions(&buf, WriterOptions{Transforms: []Transform{cipherTransform{newTestCipher(t)}}})
+	_, _ = zw.Write(text)
+	if err := zw.Close(); !errors.Is(err, ErrEncryptBeforeCompress) {
+		t.Errorf("Close: expected ErrEncryptBeforeCompress, got %v", err)
+	}
+
+	// Once the guard has tripped, a later Close must not retry the block.
+	buf.Reset()
+	zw = NewWriterOptions(&buf, WriterOptions{Transforms: []Transform{cipherTransform{newTestCipher(t)}}})
+	_, _ = zw.Write(text)
+	if err := zw.Flush(); !errors.Is(err, ErrEncryptBeforeCompress) {
+		t.Errorf("Flush: expected ErrEncryptBeforeCompress, got %v", err)
+	}
+	if err := zw.Close(); !errors.Is(err, ErrEncryptBeforeCompress) {
+		t.Errorf("Close after Flush: expected ErrEncryptBeforeCompress, got %v", err)
+	}
+
+	// Data which is already random passes, since the Transforms are not
+	// to blame.
+	random := make([]byte, 4096)
+	newTestCipher(t).XORKeyStream(random, random)
+	buf.Reset()
+	zw = NewWriterOptions(&buf, WriterOptions{Transforms: []Transform{new(DeltaTransform)}})
+	_, _ = zw.Write(random)
+	if err := zw.Close(); err != nil {
+		t.Errorf("Close: unexpected error for random input: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package huffman
+
+import (
+	"errors"
+	"sync"
+)
+
+//go:generate go run gen_uvarint.go
+
+// maxUvarintNibbles is the number of nibbles in a uint64.
+const maxUvarintNibbles = 16
+
+// ErrUvarintOverflow is returned by DecodeUvarint if the value does not fit
+// in a uint64.
+var ErrUvarintOverflow = errors.New("Huffman-coded uvarint overflows a 64-bit integer")
+
+var (
+	uvarintOnce    sync.Once
+	uvarintEncoder Encoder
+	uvarintDecoder Decoder
+)
+
+func uvarintCode() (*Encoder, *Decoder) {
+	uvarintOnce.Do(func() {
+		if err := uvarintDecoder.Init(uvarintSizes[:]); err != nil {
+			panic(err)
+		}
+		if err := uvarintEncoder.InitFromDecoder(uvarintDecoder); err != nil {
+			panic(err)
+		}
+	})
+	return &uvarintEncoder, &uvarintDecoder
+}
+
+// EncodeUvarint writes x to bw as a sequence of 4-bit groups, least
+// significant first, each Huffman coded together with a flag saying whether
+// more groups follow.  The code is fixed and favors small values: 0 to 3
+// take 3 or 4 bits, and values below 256 take at most 12 bits, where
+// binary.PutUvarint needs 8 or 16.  The largest values take 82 bits.
+func EncodeUvarint(bw *BitWriter, x uint64) error {
+	e, _ := uvarintCode()
+	for x >= 16 {
+		if err := bw.WriteCode(e.Encode(Symbol(x & 15))); err != nil {
+			return err
+		}
+		x >>= 4
+	}
+	return bw.WriteCode(e.Encode(Symbol(16 + x)))
+}
+
+// DecodeUvarint reads a value written by EncodeUvarint from br.
+func DecodeUvarint(br *BitReader) (uint64, error) {
+	_, d := uvarintCode()
+	var x uint64
+	for n := uint(0); n < maxUvarintNibbles; n++ {
+		symbol, err := br.ReadSymbol(d)
+		if err != nil {
+			if n != 0 {
+				err = noEOF(err)
+			}
+			return 0, err
+		}
+		x |= uint64(symbol&15) << (4 * n)
+		if symbol >= 16 {
+			return x, nil
+		}
+	}
+	return 0, ErrUvarintOverflow
+}
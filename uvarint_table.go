@@ -0,0 +1,13 @@
+// Code generated by gen_uvarint.go; DO NOT EDIT.
+
+package huffman
+
+// uvarintSizes is the default nibble group code for EncodeUvarint.  Symbols
+// 0 to 15 are a nibble followed by more nibbles; Symbols 16 to 31 are the
+// final nibble, plus 16.
+var uvarintSizes = [...]byte{
+	5, 5, 5, 5, 5, 5, 5, 5,
+	5, 5, 5, 5, 5, 5, 5, 5,
+	4, 3, 4, 4, 5, 5, 5, 5,
+	7, 7, 7, 7, 7, 7, 7, 7,
+}
@@ -0,0 +1,69 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestUvarint(t *testing.T) {
+	type testRow struct {
+		x    uint64
+		bits uint64
+	}
+
+	testData := [...]testRow{
+		{0, 4},
+		{1, 3},
+		{3, 4},
+		{15, 7},
+		{16, 8},
+		{255, 12},
+		{1 << 32, 5*8 + 3},
+		{math.MaxUint64, 82},
+	}
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	for _, row := range testData {
+		before := bw.BitsWritten()
+		if err := EncodeUvarint(bw, row.x); err != nil {
+			t.Fatalf("EncodeUvarint(%d): unexpected error: %v", row.x, err)
+		}
+		if actual := bw.BitsWritten() - before; actual != row.bits {
+			t.Errorf("EncodeUvarint(%d): expected %d bits, got %d", row.x, row.bits, actual)
+		}
+	}
+	_ = bw.Flush()
+
+	br := NewBitReader(&buf)
+	for _, row := range testData {
+		if actual, err := DecodeUvarint(br); err != nil || actual != row.x {
+			t.Errorf("DecodeUvarint: expected (%d, nil), got (%d, %v)", row.x, actual, err)
+		}
+	}
+}
+
+func TestDecodeUvarint_Errors(t *testing.T) {
+	e, _ := uvarintCode()
+
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	for i := 0; i < maxUvarintNibbles+1; i++ {
+		_ = bw.WriteSymbol(e, 3)
+	}
+	_ = bw.WriteSymbol(e, 16)
+	_ = bw.Flush()
+	if _, err := DecodeUvarint(NewBitReader(bytes.NewReader(buf.Bytes()))); !errors.Is(err, ErrUvarintOverflow) {
+		t.Errorf("overflow: expected ErrUvarintOverflow, got %v", err)
+	}
+
+	if _, err := DecodeUvarint(NewBitReader(bytes.NewReader(nil))); err != io.EOF {
+		t.Errorf("empty: expected io.EOF, got %v", err)
+	}
+	if _, err := DecodeUvarint(NewBitReader(bytes.NewReader(buf.Bytes()[:1]))); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated: expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
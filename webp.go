@@ -0,0 +1,472 @@
+package huffman
+
+import (
+	"fmt"
+)
+
+// WebP lossless (VP8L) prefix code groups.  Each group holds five codes: one
+// for green, length prefixes, and color cache indices; one each for red,
+// blue, and alpha; and one for distance prefixes.  See the "WebP Lossless
+// Bitstream Specification", Sections 3.7.2.1 and 3.7.2.2.
+const (
+	// VP8LCodesPerGroup is the number of prefix codes in a group.
+	VP8LCodesPerGroup = 5
+
+	// VP8LNumLiteralCodes is the number of literal Symbols in the green,
+	// red, blue, and alpha alphabets.
+	VP8LNumLiteralCodes = 256
+
+	// VP8LNumLengthCodes is the number of length prefix Symbols in the
+	// green alphabet.
+	VP8LNumLengthCodes = 24
+
+	// VP8LNumDistanceCodes is the size of the distance prefix alphabet.
+	VP8LNumDistanceCodes = 40
+
+	// VP8LMaxColorCacheBits is the largest allowed color cache size, in
+	// bits.  A size of 0 means that there is no color cache.
+	VP8LMaxColorCacheBits = 11
+
+	// vp8lMaxCodeSize is the longest code allowed.
+	vp8lMaxCodeSize = 15
+
+	// vp8lMaxCLenCodeSize is the longest code allowed in the code length
+	// code.
+	vp8lMaxCLenCodeSize = 7
+
+	// vp8lNumCLenSymbols is the size of the code length alphabet.
+	vp8lNumCLenSymbols = 19
+
+	// vp8lDefaultRepeatSize is the code length repeated by code length
+	// Symbol 16 before any non-zero code length has been seen.
+	vp8lDefaultRepeatSize = 8
+)
+
+// vp8lCLenOrder is the order in which the code length code's sizes are
+// written.
+var vp8lCLenOrder = [vp8lNumCLenSymbols]byte{
+	17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// VP8LAlphabetSize returns the size of the alphabet of the index'th code of a
+// prefix code group (0 for green, 1 for red, 2 for blue, 3 for alpha, and 4
+// for distance), given the size of the color cache in bits.  The green
+// alphabet gains 2**colorCacheBits Symbols for the color cache, if there is
+// one.  Returns 0 if either argument is out of range.
+func VP8LAlphabetSize(index int, colorCacheBits int) int {
+	if colorCacheBits < 0 || colorCacheBits > VP8LMaxColorCacheBits {
+		return 0
+	}
+	switch index {
+	case 0:
+		size := VP8LNumLiteralCodes + VP8LNumLengthCodes
+		if colorCacheBits > 0 {
+			size += 1 << uint(colorCacheBits)
+		}
+		return size
+	case 1, 2, 3:
+		return VP8LNumLiteralCodes
+	case 4:
+		return VP8LNumDistanceCodes
+	default:
+		return 0
+	}
+}
+
+// VP8LCode is one prefix code of a VP8L prefix code group.
+//
+// Unlike the other codes in this package, a VP8LCode with only one Symbol
+// writes and reads that Symbol as zero bits, as VP8L requires.
+//
+type VP8LCode struct {
+	sizes []byte
+	e     Encoder
+	d     Decoder
+	sole  Symbol
+}
+
+// initSizes initializes this VP8LCode from a list of bit lengths.
+func (c *VP8LCode) initSizes(sizes []byte) error {
+	*c = VP8LCode{sizes: sizes, sole: InvalidSymbol}
+	var numCodes int
+	for symbol, size := range sizes {
+		if size != 0 {
+			numCodes++
+			c.sole = Symbol(symbol)
+		}
+	}
+	switch numCodes {
+	case 0:
+		return fmt.Errorf("VP8L prefix code has no Symbols")
+	case 1:
+		return nil
+	}
+	c.sole = InvalidSymbol
+	if err := c.e.InitFromSizes(sizes); err != nil {
+		return err
+	}
+	return c.d.Init(sizes)
+}
+
+// initFrequencies initializes this VP8LCode with the optimal code for the
+// given frequencies, limited to maxSize bits.  If every frequency is 0, the
+// code holds Symbol 0 alone.
+func (c *VP8LCode) initFrequencies(numSymbols int, frequencies []uint32, maxSize byte) {
+	var e Encoder
+	initLimitedTo(&e, numSymbols, frequencies, maxSize)
+	sizes := e.SizeBySymbol()
+	if e.IsDegenerate() {
+		sizes = make([]byte, numSymbols)
+		symbol, _ := e.SoleSymbol()
+		if symbol < 0 {
+			symbol = 0
+		}
+		sizes[symbol] = 1
+	}
+	if err := c.initSizes(sizes); err != nil {
+		panic(fmt.Errorf("BUG: %w", err))
+	}
+}
+
+// SizeBySymbol returns the bit length of each Symbol's code.  If the code
+// has only one Symbol, its bit length is given as 1, although it is written
+// as zero bits.
+func (c *VP8LCode) SizeBySymbol() []byte {
+	return append([]byte(nil), c.sizes...)
+}
+
+// WriteSymbol writes the code for the given Symbol to bw.
+func (c *VP8LCode) WriteSymbol(bw *BitWriter, symbol Symbol) error {
+	if c.sole >= 0 {
+		if symbol != c.sole {
+			return fmt.Errorf("symbol %d: %w", symbol, ErrNoCode)
+		}
+		return nil
+	}
+	return bw.WriteSymbol(&c.e, symbol)
+}
+
+// ReadSymbol reads one Symbol from br.
+func (c *VP8LCode) ReadSymbol(br *BitReader) (Symbol, error) {
+	if c.sole >= 0 {
+		return c.sole, nil
+	}
+	return br.ReadSymbol(&c.d)
+}
+
+// VP8LGroup is a VP8L prefix code group.
+type VP8LGroup struct {
+	// Codes holds the five codes of the group, in the order green, red,
+	// blue, alpha, and distance.
+	Codes [VP8LCodesPerGroup]VP8LCode
+
+	colorCacheBits int
+}
+
+// NewVP8LGroup returns a new VP8LGroup with the optimal codes for the given
+// frequencies, one list for each code in the group, and a color cache of
+// 2**colorCacheBits entries (or none, if 0).  No code is longer than the 15
+// bits allowed by VP8L.
+func NewVP8LGroup(frequencies [VP8LCodesPerGroup][]uint32, colorCacheBits int) (*VP8LGroup, error) {
+	if colorCacheBits < 0 || colorCacheBits > VP8LMaxColorCacheBits {
+		return nil, fmt.Errorf("color cache bits %d not in range [0..%d]", colorCacheBits, VP8LMaxColorCacheBits)
+	}
+	g := &VP8LGroup{colorCacheBits: colorCacheBits}
+	for index, freqs := range frequencies {
+		numSymbols := VP8LAlphabetSize(index, colorCacheBits)
+		if len(freqs) > numSymbols {
+			return nil, fmt.Errorf("VP8L code %d: %d frequencies > alphabet size %d", index, len(freqs), numSymbols)
+		}
+		g.Codes[index].initFrequencies(numSymbols, freqs, vp8lMaxCodeSize)
+	}
+	return g, nil
+}
+
+// ColorCacheBits returns the size of the color cache in bits, or 0 if there
+// is no color cache.
+func (g *VP8LGroup) ColorCacheBits() int {
+	return g.colorCacheBits
+}
+
+// WriteTo writes the five codes of this group to bw, in the format read by
+// ReadVP8LGroup.  Each code is written in the simple form if it has at most
+// two Symbols, both less than 256, and in the normal form otherwise.
+func (g *VP8LGroup) WriteTo(bw *BitWriter) error {
+	for index := range g.Codes {
+		if err := writeVP8LCode(bw, g.Codes[index].sizes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadVP8LGroup reads the five codes of a prefix code group from br, given
+// the size of the color cache in bits (0 if there is none).
+func ReadVP8LGroup(br *BitReader, colorCacheBits int) (*VP8LGroup, error) {
+	if colorCacheBits < 0 || colorCacheBits > VP8LMaxColorCacheBits {
+		return nil, fmt.Errorf("color cache bits %d not in range [0..%d]", colorCacheBits, VP8LMaxColorCacheBits)
+	}
+	g := &VP8LGroup{colorCacheBits: colorCacheBits}
+	for index := range g.Codes {
+		sizes, err := readVP8LCode(br, VP8LAlphabetSize(index, colorCacheBits))
+		if err != nil {
+			return nil, fmt.Errorf("VP8L code %d: %w", index, err)
+		}
+		if err := g.Codes[index].initSizes(sizes); err != nil {
+			return nil, fmt.Errorf("VP8L code %d: %w", index, err)
+		}
+	}
+	return g, nil
+}
+
+// writeVP8LCode writes one code, given its bit lengths, to bw.
+func writeVP8LCode(bw *BitWriter, sizes []byte) error {
+	var symbols []Symbol
+	for symbol, size := range sizes {
+		if size != 0 {
+			symbols = append(symbols, Symbol(symbol))
+			if len(symbols) > 2 {
+				break
+			}
+		}
+	}
+
+	// Simple form: simple:1 numSymbols-1:1 isFirst8Bits:1 symbol0:(1|8)
+	// symbol1:8?
+
+	if len(symbols) <= 2 && symbols[len(symbols)-1] < 256 {
+		_ = bw.WriteBits(1, 1)
+		_ = bw.WriteBits(1, uint32(len(symbols)-1))
+		if symbols[0] < 2 {
+			_ = bw.WriteBits(1, 0)
+			_ = bw.WriteBits(1, uint32(symbols[0]))
+		} else {
+			_ = bw.WriteBits(1, 1)
+			_ = bw.WriteBits(8, uint32(symbols[0]))
+		}
+		if len(symbols) == 2 {
+			_ = bw.WriteBits(8, uint32(symbols[1]))
+		}
+		return bw.err
+	}
+
+	// Normal form: simple:1 numCLens-4:4 clenSizes:3*numCLens
+	// hasMax:1 (lengthBits-2)/2:3? maxSymbol-2:lengthBits? tokens
+
+	numTokens := len(sizes)
+	for sizes[numTokens-1] == 0 {
+		numTokens--
+	}
+	tokens := vp8lCLenSymbols(nil, sizes[:numTokens])
+
+	var clenFreqs [vp8lNumCLenSymbols]uint32
+	for _, tok := range tokens {
+		clenFreqs[tok.symbol]++
+	}
+	var clen VP8LCode
+	clen.initFrequencies(vp8lNumCLenSymbols, clenFreqs[:], vp8lMaxCLenCodeSize)
+
+	numCLens := vp8lNumCLenSymbols
+	for numCLens > 4 && clen.sizes[vp8lCLenOrder[numCLens-1]] == 0 {
+		numCLens--
+	}
+
+	_ = bw.WriteBits(1, 0)
+	_ = bw.WriteBits(4, uint32(numCLens-4))
+	for _, symbol := range vp8lCLenOrder[:numCLens] {
+		_ = bw.WriteBits(3, uint32(clen.sizes[symbol]))
+	}
+
+	if numTokens < len(sizes) {
+		lengthBits := byte(2)
+		for uint64(len(tokens)-2) >= uint64(1)<<lengthBits {
+			lengthBits += 2
+		}
+		_ = bw.WriteBits(1, 1)
+		_ = bw.WriteBits(3, uint32(lengthBits-2)/2)
+		_ = bw.WriteBits(lengthBits, uint32(len(tokens)-2))
+	} else {
+		_ = bw.WriteBits(1, 0)
+	}
+
+	for _, tok := range tokens {
+		if err := clen.WriteSymbol(bw, tok.symbol); err != nil {
+			return err
+		}
+		if tok.extraSize != 0 {
+			_ = bw.WriteBits(tok.extraSize, tok.extra)
+		}
+	}
+	return bw.err
+}
+
+// vp8lCLen is one Symbol of the code length code, with its extra bits.
+type vp8lCLen struct {
+	symbol    Symbol
+	extraSize byte
+	extra     uint32
+}
+
+// vp8lCLenSymbols appends the code length Symbols which describe sizes to
+// out.  Runs of zeros use Symbols 17 and 18; runs of a non-zero size use
+// Symbol 16, which repeats the last non-zero size.
+func vp8lCLenSymbols(out []vp8lCLen, sizes []byte) []vp8lCLen {
+	prev := byte(vp8lDefaultRepeatSize)
+	for i := 0; i < len(sizes); {
+		size := sizes[i]
+		j := i + 1
+		for j < len(sizes) && sizes[j] == size {
+			j++
+		}
+		run := j - i
+		i = j
+
+		if size == 0 {
+			for run >= 11 {
+				n := minInt(run, 138)
+				out = append(out, vp8lCLen{18, 7, uint32(n - 11)})
+				run -= n
+			}
+			if run >= 3 {
+				out = append(out, vp8lCLen{17, 3, uint32(run - 3)})
+				run = 0
+			}
+			for ; run > 0; run-- {
+				out = append(out, vp8lCLen{0, 0, 0})
+			}
+			continue
+		}
+
+		if size != prev {
+			out = append(out, vp8lCLen{Symbol(size), 0, 0})
+			prev = size
+			run--
+		}
+		for run >= 3 {
+			n := minInt(run, 6)
+			out = append(out, vp8lCLen{16, 2, uint32(n - 3)})
+			run -= n
+		}
+		for ; run > 0; run-- {
+			out = append(out, vp8lCLen{Symbol(size), 0, 0})
+		}
+	}
+	return out
+}
+
+// readVP8LCode reads one code from br and returns its bit lengths.
+func readVP8LCode(br *BitReader, numSymbols int) ([]byte, error) {
+	simple, err := br.ReadBits(1)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+
+	sizes := make([]byte, numSymbols)
+	if simple != 0 {
+		n, err := br.ReadBits(1)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		first8, err := br.ReadBits(1)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		symbol0, err := br.ReadBits(1 + 7*byte(first8))
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if int(symbol0) >= numSymbols {
+			return nil, fmt.Errorf("simple code symbol %d >= alphabet size %d", symbol0, numSymbols)
+		}
+		sizes[symbol0] = 1
+		if n != 0 {
+			symbol1, err := br.ReadBits(8)
+			if err != nil {
+				return nil, noEOF(err)
+			}
+			if int(symbol1) >= numSymbols || symbol1 == symbol0 {
+				return nil, fmt.Errorf("invalid simple code symbol %d", symbol1)
+			}
+			sizes[symbol1] = 1
+		}
+		return sizes, nil
+	}
+
+	numCLens, err := br.ReadBits(4)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	clenSizes := make([]byte, vp8lNumCLenSymbols)
+	for _, symbol := range vp8lCLenOrder[:numCLens+4] {
+		size, err := br.ReadBits(3)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		clenSizes[symbol] = byte(size)
+	}
+	var clen VP8LCode
+	if err := clen.initSizes(clenSizes); err != nil {
+		return nil, fmt.Errorf("invalid code length code: %w", err)
+	}
+
+	maxTokens := numSymbols
+	hasMax, err := br.ReadBits(1)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if hasMax != 0 {
+		k, err := br.ReadBits(3)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		n, err := br.ReadBits(2 + 2*byte(k))
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if int(n)+2 > numSymbols {
+			return nil, fmt.Errorf("max symbol %d > alphabet size %d", n+2, numSymbols)
+		}
+		maxTokens = int(n) + 2
+	}
+
+	prev := byte(vp8lDefaultRepeatSize)
+	for i, tokens := 0, 0; i < numSymbols && tokens < maxTokens; tokens++ {
+		symbol, err := clen.ReadSymbol(br)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		if symbol < 16 {
+			sizes[i] = byte(symbol)
+			if symbol != 0 {
+				prev = byte(symbol)
+			}
+			i++
+			continue
+		}
+
+		value := byte(0)
+		var extraSize byte
+		var repeat int
+		switch symbol {
+		case 16:
+			value, extraSize, repeat = prev, 2, 3
+		case 17:
+			extraSize, repeat = 3, 3
+		default:
+			extraSize, repeat = 7, 11
+		}
+		extra, err := br.ReadBits(extraSize)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		repeat += int(extra)
+		if i+repeat > numSymbols {
+			return nil, fmt.Errorf("code lengths overflow alphabet of %d symbols", numSymbols)
+		}
+		for ; repeat > 0; repeat-- {
+			sizes[i] = value
+			i++
+		}
+	}
+	return sizes, nil
+}
@@ -0,0 +1,173 @@
+package huffman
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestVP8LAlphabetSize(t *testing.T) {
+	type testRow struct {
+		index, bits, expect int
+	}
+
+	testData := [...]testRow{
+		{0, 0, 280},
+		{0, 1, 282},
+		{0, 11, 280 + 2048},
+		{1, 0, 256},
+		{3, 5, 256},
+		{4, 0, 40},
+		{5, 0, 0},
+		{0, 12, 0},
+	}
+	for _, row := range testData {
+		if actual := VP8LAlphabetSize(row.index, row.bits); actual != row.expect {
+			t.Errorf("(%d, %d): expected %d, got %d", row.index, row.bits, row.expect, actual)
+		}
+	}
+}
+
+func TestVP8LGroup(t *testing.T) {
+	rng := rand.New(rand.NewSource(169))
+	skewed := func(n int) []uint32 {
+		out := make([]uint32, n)
+		for index := range out {
+			out[index] = uint32(rng.ExpFloat64() * 50)
+		}
+		return out
+	}
+
+	type testRow struct {
+		name  string
+		freqs [VP8LCodesPerGroup][]uint32
+		bits  int
+	}
+
+	testData := [...]testRow{
+		{
+			name: "empty",
+		},
+		{
+			name:  "simple",
+			freqs: [VP8LCodesPerGroup][]uint32{{0, 5}, {0, 0, 7}, {3, 0, 0, 4}, {255: 1}, {0, 1, 1}},
+		},
+		{
+			name:  "single-high",
+			freqs: [VP8LCodesPerGroup][]uint32{{279: 3}, {200: 2, 201: 9}, nil, nil, {39: 1}},
+		},
+		{
+			name:  "normal",
+			freqs: [VP8LCodesPerGroup][]uint32{skewed(280), skewed(256), skewed(100), skewed(256), skewed(40)},
+		},
+		{
+			name:  "color-cache",
+			freqs: [VP8LCodesPerGroup][]uint32{skewed(280 + 64), skewed(10), skewed(256), {7, 7, 7}, skewed(30)},
+			bits:  6,
+		},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			g, err := NewVP8LGroup(row.freqs, row.bits)
+			if err != nil {
+				t.Fatalf("NewVP8LGroup: unexpected error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			bw := NewBitWriter(&buf)
+			if err := g.WriteTo(bw); err != nil {
+				t.Fatalf("WriteTo: unexpected error: %v", err)
+			}
+			var written [VP8LCodesPerGroup][]Symbol
+			for index := range g.Codes {
+				for symbol, freq := range row.freqs[index] {
+					if freq == 0 {
+						continue
+					}
+					if err := g.Codes[index].WriteSymbol(bw, Symbol(symbol)); err != nil {
+						t.Fatalf("code %d: WriteSymbol(%d): unexpected error: %v", index, symbol, err)
+					}
+					written[index] = append(written[index], Symbol(symbol))
+				}
+			}
+			_ = bw.WriteBits(8, 0xa5)
+			if err := bw.Flush(); err != nil {
+				t.Fatalf("Flush: unexpected error: %v", err)
+			}
+
+			br := NewBitReader(bytes.NewReader(buf.Bytes()))
+			h, err := ReadVP8LGroup(br, row.bits)
+			if err != nil {
+				t.Fatalf("ReadVP8LGroup: unexpected error: %v", err)
+			}
+			for index := range g.Codes {
+				if expect, actual := g.Codes[index].SizeBySymbol(), h.Codes[index].SizeBySymbol(); !bytes.Equal(expect, actual) {
+					t.Errorf("code %d: expected sizes %v, got %v", index, expect, actual)
+				}
+			}
+			for index := range h.Codes {
+				for _, expect := range written[index] {
+					actual, err := h.Codes[index].ReadSymbol(br)
+					if err != nil {
+						t.Fatalf("code %d: ReadSymbol: unexpected error: %v", index, err)
+					}
+					if actual != expect {
+						t.Fatalf("code %d: expected %d, got %d", index, expect, actual)
+					}
+				}
+			}
+			if trailer, err := br.ReadBits(8); err != nil || trailer != 0xa5 {
+				t.Errorf("trailer: expected 0xa5, got 0x%02x (error %v)", trailer, err)
+			}
+		})
+	}
+}
+
+func TestVP8LGroup_Simple(t *testing.T) {
+	// Green holds Symbol 1 alone, red holds Symbols 2 and 3, and the
+	// others hold Symbol 0 alone.
+	g, err := NewVP8LGroup([VP8LCodesPerGroup][]uint32{{0, 1}, {0, 0, 1, 1}}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	bw := NewBitWriter(&buf)
+	_ = g.WriteTo(bw)
+	_ = bw.Flush()
+
+	// 1 0 0 1 | 1 1 1 00000010 00000011 | 3 * (1 0 0 0), LSB first.
+	var expect bytes.Buffer
+	ew := NewBitWriter(&expect)
+	for _, field := range [][2]uint32{
+		{4, 0x9}, {3, 0x7}, {8, 2}, {8, 3},
+		{4, 0x1}, {4, 0x1}, {4, 0x1},
+	} {
+		_ = ew.WriteBits(byte(field[0]), field[1])
+	}
+	_ = ew.Flush()
+	if !bytes.Equal(expect.Bytes(), buf.Bytes()) {
+		t.Errorf("expected % x, got % x", expect.Bytes(), buf.Bytes())
+	}
+}
+
+func TestReadVP8LGroup_Errors(t *testing.T) {
+	type testRow struct {
+		name  string
+		input []byte
+		bits  int
+	}
+
+	testData := [...]testRow{
+		{name: "empty", input: nil},
+		{name: "bad-cache-bits", input: []byte{0xff}, bits: 12},
+		{name: "simple-same-symbol", input: []byte{0x0f, 0x04, 0x08}},
+		{name: "no-symbols", input: []byte{0x00, 0x00, 0x00, 0x00}},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			if _, err := ReadVP8LGroup(NewBitReader(bytes.NewReader(row.input)), row.bits); err == nil {
+				t.Errorf("expected error, got nil")
+			}
+		})
+	}
+}
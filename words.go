@@ -0,0 +1,108 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Uint16AlphabetSize is the number of Symbols in the 16-bit word alphabet.
+const Uint16AlphabetSize = 1 << 16
+
+// CountUint16s returns the frequency of each 16-bit word in data, suitable
+// for passing to Encoder.Init.  The returned slice is only as long as needed
+// to hold the largest word present, so data drawn from a small range of
+// values doesn't pay for the full 65536-entry alphabet.
+func CountUint16s(data []uint16) []uint32 {
+	var maxWord uint16
+	for _, word := range data {
+		if maxWord < word {
+			maxWord = word
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	frequencies := make([]uint32, uint(maxWord)+1)
+	for _, word := range data {
+		if frequencies[word] < math.MaxUint32 {
+			frequencies[word]++
+		}
+	}
+	return frequencies
+}
+
+// NewEncoderFromUint16s returns a new Encoder for the 16-bit word alphabet,
+// built from the frequencies of the words in data.  No code is longer than
+// the 16 bits that the Decoder supports.
+func NewEncoderFromUint16s(data []uint16) *Encoder {
+	e := new(Encoder)
+	initLimited(e, Uint16AlphabetSize, CountUint16s(data))
+	return e
+}
+
+// WriteUint16s compresses data and writes it to w.  The output is
+// self-describing: it holds the code's size table, the number of words, and
+// the coded words, padded to a byte boundary.
+func WriteUint16s(w io.Writer, data []uint16) error {
+	e := NewEncoderFromUint16s(data)
+
+	var tmp [binary.MaxVarintLen64]byte
+	header := appendSizesRLE(nil, trimSizes(e.SizeBySymbol()))
+	n := binary.PutUvarint(tmp[:], uint64(len(data)))
+	header = append(header, tmp[:n]...)
+
+	bw := NewBitWriter(w)
+	if err := bw.WriteBytes(header); err != nil {
+		return err
+	}
+	for _, word := range data {
+		if err := bw.WriteCode(e.Encode(Symbol(word))); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadUint16s reads data written by WriteUint16s from r.  At most maxWords
+// words will be accepted.
+func ReadUint16s(r io.Reader, maxWords int) ([]uint16, error) {
+	br := NewBitReader(r)
+	sizes, err := readSizesRLE(br, Uint16AlphabetSize)
+	if err != nil {
+		return nil, err
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, noEOF(err)
+	}
+	if count > uint64(maxWords) {
+		return nil, fmt.Errorf("too many words: got %d, max %d", count, maxWords)
+	}
+
+	var d Decoder
+	if err := d.Init(sizes); err != nil {
+		return nil, err
+	}
+
+	out := make([]uint16, count)
+	for index := range out {
+		symbol, err := br.ReadSymbol(&d)
+		if err != nil {
+			return nil, noEOF(err)
+		}
+		out[index] = uint16(symbol)
+	}
+	return out, nil
+}
+
+// trimSizes drops trailing zero-length entries.
+func trimSizes(sizes []byte) []byte {
+	end := len(sizes)
+	for end > 0 && sizes[end-1] == 0 {
+		end--
+	}
+	return sizes[:end]
+}
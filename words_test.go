@@ -0,0 +1,64 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUint16s_RoundTrip(t *testing.T) {
+	type testRow struct {
+		name string
+		data []uint16
+	}
+
+	sine := make([]uint16, 4096)
+	for index := range sine {
+		sine[index] = uint16(32768 + (index%64-32)*(index%64-32)*30)
+	}
+
+	testData := [...]testRow{
+		{name: "empty", data: nil},
+		{name: "one", data: []uint16{65535}},
+		{name: "repeated", data: []uint16{7, 7, 7, 7}},
+		{name: "wide", data: sine},
+	}
+	for _, row := range testData {
+		t.Run(row.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteUint16s(&buf, row.data); err != nil {
+				t.Fatalf("WriteUint16s: unexpected error: %v", err)
+			}
+			output, err := ReadUint16s(&buf, len(row.data))
+			if err != nil {
+				t.Fatalf("ReadUint16s: unexpected error: %v", err)
+			}
+			if len(output) != len(row.data) {
+				t.Fatalf("wrong length: expected %d, got %d", len(row.data), len(output))
+			}
+			for index := range output {
+				if output[index] != row.data[index] {
+					t.Fatalf("wrong word at index %d: expected %d, got %d", index, row.data[index], output[index])
+				}
+			}
+		})
+	}
+}
+
+func TestCountUint16s(t *testing.T) {
+	frequencies := CountUint16s([]uint16{3, 1, 3, 0})
+	expect := []uint32{1, 1, 0, 2}
+	if len(frequencies) != len(expect) {
+		t.Fatalf("wrong frequencies:\n\texpect: %v\n\tactual: %v", expect, frequencies)
+	}
+	for index := range expect {
+		if expect[index] != frequencies[index] {
+			t.Errorf("wrong frequencies:\n\texpect: %v\n\tactual: %v", expect, frequencies)
+			break
+		}
+	}
+
+	e := NewEncoderFromUint16s([]uint16{3, 1, 3, 0})
+	if expect, actual := uint(Uint16AlphabetSize), e.NumSymbols(); expect != actual {
+		t.Errorf("NumSymbols: expected %d, got %d", expect, actual)
+	}
+}
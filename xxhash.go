@@ -0,0 +1,145 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"hash"
+	mathbits "math/bits"
+)
+
+// xxh64 implements the 64-bit xxHash algorithm with a seed of 0.
+//
+// References:
+//
+//     <https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md>
+//
+type xxh64 struct {
+	v     [4]uint64
+	total uint64
+	mem   [32]byte
+	n     int
+}
+
+const (
+	xxhPrime1 uint64 = 11400714785074694791
+	xxhPrime2 uint64 = 14029467366897019727
+	xxhPrime3 uint64 = 1609587929392839161
+	xxhPrime4 uint64 = 9650029242287828579
+	xxhPrime5 uint64 = 2870177450012600261
+)
+
+func newXXH64() *xxh64 {
+	h := new(xxh64)
+	h.Reset()
+	return h
+}
+
+func (h *xxh64) Reset() {
+	p1, p2 := xxhPrime1, xxhPrime2
+	h.v[0] = p1 + p2
+	h.v[1] = p2
+	h.v[2] = 0
+	h.v[3] = -p1
+	h.total = 0
+	h.n = 0
+}
+
+func (h *xxh64) Size() int {
+	return 8
+}
+
+func (h *xxh64) BlockSize() int {
+	return 32
+}
+
+func (h *xxh64) Write(p []byte) (int, error) {
+	length := len(p)
+	h.total += uint64(length)
+
+	if h.n+len(p) < 32 {
+		h.n += copy(h.mem[h.n:], p)
+		return length, nil
+	}
+
+	if h.n != 0 {
+		c := copy(h.mem[h.n:], p)
+		p = p[c:]
+		h.stripe(h.mem[:])
+		h.n = 0
+	}
+
+	for len(p) >= 32 {
+		h.stripe(p[:32])
+		p = p[32:]
+	}
+
+	h.n = copy(h.mem[:], p)
+	return length, nil
+}
+
+func (h *xxh64) stripe(p []byte) {
+	h.v[0] = xxhRound(h.v[0], binary.LittleEndian.Uint64(p[0:8]))
+	h.v[1] = xxhRound(h.v[1], binary.LittleEndian.Uint64(p[8:16]))
+	h.v[2] = xxhRound(h.v[2], binary.LittleEndian.Uint64(p[16:24]))
+	h.v[3] = xxhRound(h.v[3], binary.LittleEndian.Uint64(p[24:32]))
+}
+
+func (h *xxh64) Sum64() uint64 {
+	var acc uint64
+	if h.total >= 32 {
+		acc = mathbits.RotateLeft64(h.v[0], 1) +
+			mathbits.RotateLeft64(h.v[1], 7) +
+			mathbits.RotateLeft64(h.v[2], 12) +
+			mathbits.RotateLeft64(h.v[3], 18)
+		acc = xxhMerge(acc, h.v[0])
+		acc = xxhMerge(acc, h.v[1])
+		acc = xxhMerge(acc, h.v[2])
+		acc = xxhMerge(acc, h.v[3])
+	} else {
+		acc = h.v[2] + xxhPrime5
+	}
+	acc += h.total
+
+	p := h.mem[:h.n]
+	for len(p) >= 8 {
+		acc ^= xxhRound(0, binary.LittleEndian.Uint64(p))
+		acc = mathbits.RotateLeft64(acc, 27)*xxhPrime1 + xxhPrime4
+		p = p[8:]
+	}
+	if len(p) >= 4 {
+		acc ^= uint64(binary.LittleEndian.Uint32(p)) * xxhPrime1
+		acc = mathbits.RotateLeft64(acc, 23)*xxhPrime2 + xxhPrime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		acc ^= uint64(b) * xxhPrime5
+		acc = mathbits.RotateLeft64(acc, 11) * xxhPrime1
+	}
+
+	acc ^= acc >> 33
+	acc *= xxhPrime2
+	acc ^= acc >> 29
+	acc *= xxhPrime3
+	acc ^= acc >> 32
+	return acc
+}
+
+func (h *xxh64) Sum(b []byte) []byte {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], h.Sum64())
+	return append(b, tmp[:]...)
+}
+
+func xxhRound(acc, input uint64) uint64 {
+	acc += input * xxhPrime2
+	acc = mathbits.RotateLeft64(acc, 31)
+	acc *= xxhPrime1
+	return acc
+}
+
+func xxhMerge(acc, v uint64) uint64 {
+	acc ^= xxhRound(0, v)
+	acc = acc*xxhPrime1 + xxhPrime4
+	return acc
+}
+
+var _ hash.Hash64 = (*xxh64)(nil)
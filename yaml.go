@@ -0,0 +1,68 @@
+package huffman
+
+import (
+	"fmt"
+)
+
+// The YAML methods below follow the Marshaler and (obsolete-style)
+// Unmarshaler interfaces of gopkg.in/yaml.v2 and gopkg.in/yaml.v3, which
+// can be implemented without importing either package.  A coder is rendered
+// as a flow sequence of bit lengths, e.g. "[4, 4, 3, 3, 3, 1]".
+
+// MarshalYAML renders this Encoder as a YAML sequence of bit lengths.
+func (e Encoder) MarshalYAML() (interface{}, error) {
+	return sizesToUints(e.SizeBySymbol()), nil
+}
+
+// UnmarshalYAML initializes this Encoder from a YAML sequence of bit lengths,
+// or from a string in the format produced by MarshalText.
+func (e *Encoder) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	sizes, err := unmarshalYAMLSizes(unmarshal)
+	if err != nil {
+		return err
+	}
+	return e.InitFromSizes(sizes)
+}
+
+// MarshalYAML renders this Decoder as a YAML sequence of bit lengths.
+func (d Decoder) MarshalYAML() (interface{}, error) {
+	return sizesToUints(d.sizes), nil
+}
+
+// UnmarshalYAML initializes this Decoder from a YAML sequence of bit lengths,
+// or from a string in the format produced by MarshalText.
+func (d *Decoder) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	sizes, err := unmarshalYAMLSizes(unmarshal)
+	if err != nil {
+		return err
+	}
+	return d.init(sizes)
+}
+
+func sizesToUints(sizes []byte) []uint {
+	arr := make([]uint, len(sizes))
+	for index, size := range sizes {
+		arr[index] = uint(size)
+	}
+	return arr
+}
+
+func unmarshalYAMLSizes(unmarshal func(interface{}) error) ([]byte, error) {
+	var arr []uint
+	if err := unmarshal(&arr); err != nil {
+		var str string
+		if unmarshal(&str) != nil {
+			return nil, err
+		}
+		return parseSizes([]byte(str))
+	}
+
+	sizes := make([]byte, len(arr))
+	for index, size := range arr {
+		if size > maxBitsPerCode {
+			return nil, fmt.Errorf("invalid bit length while constructing Huffman tree: got %d, max %d", size, maxBitsPerCode)
+		}
+		sizes[index] = byte(size)
+	}
+	return sizes, nil
+}
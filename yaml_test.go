@@ -0,0 +1,51 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncoder_YAML(t *testing.T) {
+	e := makeTestEncoder()
+	v, err := e.MarshalYAML()
+	if err != nil {
+		t.Fatalf("MarshalYAML: unexpected error: %v", err)
+	}
+	arr, ok := v.([]uint)
+	if !ok || len(arr) != 6 || arr[0] != 4 || arr[5] != 1 {
+		t.Errorf("MarshalYAML: unexpected value %#v", v)
+	}
+
+	// Simulate a YAML decoder handing over a sequence, then a string.
+	var e2 Encoder
+	err = e2.UnmarshalYAML(func(out interface{}) error {
+		p, ok := out.(*[]uint)
+		if !ok {
+			t.Fatalf("UnmarshalYAML: unexpected target %T", out)
+		}
+		*p = arr
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UnmarshalYAML: unexpected error: %v", err)
+	}
+	if expect, actual := e.DebugString(), e2.DebugString(); expect != actual {
+		t.Errorf("UnmarshalYAML: wrong Encoder:\n\texpect: %s\n\tactual: %s", expect, actual)
+	}
+
+	var d Decoder
+	err = d.UnmarshalYAML(func(out interface{}) error {
+		if p, ok := out.(*string); ok {
+			*p = "4,4,3,3,3,1"
+			return nil
+		}
+		return errors.New("not a string")
+	})
+	if err != nil {
+		t.Fatalf("UnmarshalYAML: unexpected error: %v", err)
+	}
+	if !bytes.Equal([]byte{4, 4, 3, 3, 3, 1}, d.SizeBySymbol()) {
+		t.Errorf("UnmarshalYAML: wrong sizes %v", d.SizeBySymbol())
+	}
+}